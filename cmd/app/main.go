@@ -38,6 +38,15 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Подкоманда migrate управляет схемой базы данных и не запускает сервер
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), cfg, os.Args[2:], os.Stdout); err != nil {
+			log.Printf("Migration failed: %v", err)
+			os.Exit(ExitCodeError)
+		}
+		os.Exit(ExitCodeSuccess)
+	}
+
 	// Создаем контекст для координации graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -54,11 +63,21 @@ func main() {
 	}()
 
 	// Инициализируем pub/sub систему для real-time подписок
-	ps := pubsub.NewWithConfig(cfg.ChannelBufferSize)
+	ps, err := initializePubSub(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize pub/sub: %v", err)
+	}
+	defer ps.Close()
 
 	// Создаем GraphQL сервис с использованием gqlgen
 	gqlgenService := service.NewGQLGenServiceWithConfig(storage, ps, cfg)
 
+	// Запускаем доставку событий из outbox
+	relayDone, err := startOutboxRelay(ctx, gqlgenService, cfg)
+	if err != nil {
+		log.Fatalf("Failed to start outbox relay: %v", err)
+	}
+
 	// Создаем HTTP обработчик с конфигурацией
 	handler := api.NewGQLGenHandlerWithConfig(gqlgenService, cfg)
 	router := handler.SetupRoutes()
@@ -99,6 +118,10 @@ func main() {
 		os.Exit(ExitCodeError)
 	}
 
+	// Останавливаем relay: недоставленные события останутся в outbox до следующего запуска
+	cancel()
+	<-relayDone
+
 	log.Println("Server stopped gracefully")
 	os.Exit(ExitCodeSuccess)
 }
@@ -111,7 +134,10 @@ func initializeStorage(ctx context.Context, cfg *config.Config) (repository.Stor
 	switch cfg.StorageType {
 	case "postgres":
 		log.Printf("Connecting to PostgreSQL database...")
-		storage, err := repository.NewPostgresStorage(ctx, cfg.DatabaseDSN)
+		storage, err := repository.NewPostgresStorageWithOptions(ctx, cfg.DatabaseDSN, repository.PostgresOptions{
+			Outbox:  cfg.OutboxEnabled,
+			Migrate: cfg.AutoMigrate,
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -122,12 +148,119 @@ func initializeStorage(ctx context.Context, cfg *config.Config) (repository.Stor
 		storage := repository.NewMemoryStorage()
 		log.Printf("In-memory storage initialized successfully")
 		return storage, nil
+	case "file":
+		log.Printf("Opening file storage in %s...", cfg.DataDir)
+		storage, err := repository.NewFileStorage(cfg.DataDir, repository.FileOptions{
+			SnapshotEvery: cfg.SnapshotEvery,
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("File storage initialized successfully")
+		return storage, nil
+	case "sqlite":
+		log.Printf("Opening SQLite database %s...", cfg.SQLitePath)
+		storage, err := repository.NewSQLiteStorage(ctx, cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("SQLite storage initialized successfully")
+		return storage, nil
 	default:
-		return nil, fmt.Errorf("%w: supported types are 'postgres' and 'memory', got '%s'",
+		return nil, fmt.Errorf("%w: supported types are 'postgres', 'memory', 'file' and 'sqlite', got '%s'",
 			repository.ErrUnsupportedStorageType, cfg.StorageType)
 	}
 }
 
+// startOutboxRelay запускает фоновую доставку событий outbox в pub/sub и вебхуки,
+// если outbox включен. Возвращает канал, который закрывается после остановки relay по отмене ctx.
+func startOutboxRelay(ctx context.Context, svc *service.GQLGenService, cfg *config.Config) (<-chan struct{}, error) {
+	done := make(chan struct{})
+	if !cfg.OutboxEnabled {
+		close(done)
+		return done, nil
+	}
+
+	relay, err := svc.NewOutboxRelay(service.OutboxRelayConfig{
+		PollInterval:   cfg.OutboxPollInterval,
+		BatchSize:      cfg.OutboxBatchSize,
+		Lease:          cfg.OutboxLease,
+		Retention:      cfg.OutboxRetention,
+		WebhookURLs:    cfg.WebhookURLs,
+		WebhookTimeout: cfg.WebhookTimeout,
+		OnError: func(err error) {
+			log.Printf("Outbox relay error: %v", err)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+
+	log.Printf("Outbox relay started with %d webhook(s)", len(cfg.WebhookURLs))
+	return done, nil
+}
+
+// initializePubSub создает систему pub/sub на основе конфигурации.
+// Backend "redis" или "postgres" нужен, когда запущено несколько экземпляров приложения:
+// события доставляются подписчикам, подключенным к любому из них.
+func initializePubSub(ctx context.Context, cfg *config.Config) (pubsub.PubSub, error) {
+	log.Printf("Initializing pub/sub backend: %s", cfg.PubSubBackend)
+
+	local := pubsub.Config{
+		ChannelBufferSize: cfg.ChannelBufferSize,
+		HistorySize:       cfg.HistorySize,
+		HistoryTTL:        cfg.HistoryTTL,
+		OverflowPolicy:    pubsub.OverflowPolicy(cfg.OverflowPolicy),
+		BlockTimeout:      cfg.BlockTimeout,
+	}
+
+	switch cfg.PubSubBackend {
+	case "memory":
+		return pubsub.NewWithOptions(local), nil
+	case "redis":
+		log.Printf("Connecting to Redis at %s...", cfg.RedisAddr)
+		ps, err := pubsub.NewRedis(pubsub.RedisConfig{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+			Channel:  cfg.RedisChannel,
+			Codec:    service.EventCodec{},
+			Local:    local,
+			OnError: func(err error) {
+				log.Printf("Redis pub/sub error: %v", err)
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Redis pub/sub initialized successfully")
+		return ps, nil
+	case "postgres":
+		log.Printf("Listening for PostgreSQL notifications on %s...", cfg.NotifyChannel)
+		ps, err := pubsub.NewPostgres(ctx, pubsub.PostgresConfig{
+			DSN:     cfg.DatabaseDSN,
+			Channel: cfg.NotifyChannel,
+			Codec:   service.EventCodec{},
+			Local:   local,
+			OnError: func(err error) {
+				log.Printf("PostgreSQL pub/sub error: %v", err)
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("PostgreSQL pub/sub initialized successfully")
+		return ps, nil
+	default:
+		return nil, fmt.Errorf("supported pub/sub backends are 'memory', 'redis' and 'postgres', got '%s'", cfg.PubSubBackend)
+	}
+}
+
 // waitForShutdownSignal блокирует выполнение до получения сигнала прерывания.
 // Прослушивает сигналы SIGINT (Ctrl+C) и SIGTERM для graceful shutdown.
 func waitForShutdownSignal() {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -39,3 +40,28 @@ func TestConstants(t *testing.T) {
 		t.Errorf("ExitCodeError should be 1, got %d", ExitCodeError)
 	}
 }
+
+func TestParseMigrateArgs(t *testing.T) {
+	valid := map[string]migrateCommand{
+		"up":     {action: "up", steps: 1},
+		"status": {action: "status", steps: 1},
+		"down":   {action: "down", steps: 1},
+		"down 3": {action: "down", steps: 3},
+	}
+	for args, want := range valid {
+		got, err := parseMigrateArgs(strings.Fields(args))
+		if err != nil {
+			t.Errorf("parseMigrateArgs(%q) error = %v", args, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("parseMigrateArgs(%q) = %+v, want %+v", args, got, want)
+		}
+	}
+
+	for _, args := range []string{"", "sideways", "up 1", "down 0", "down x", "down 1 2"} {
+		if _, err := parseMigrateArgs(strings.Fields(args)); err == nil {
+			t.Errorf("Expected error for %q", args)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/NarthurN/CommentsSystem/internal/config"
+	"github.com/NarthurN/CommentsSystem/internal/repository"
+)
+
+// migrateUsage - справка подкоманды migrate
+const migrateUsage = "usage: migrate up | down [N] | status"
+
+// migrateCommand - разобранные аргументы подкоманды migrate
+type migrateCommand struct {
+	action string // up, down или status
+	steps  int    // Сколько миграций откатить (для down)
+}
+
+// parseMigrateArgs разбирает аргументы подкоманды migrate
+func parseMigrateArgs(args []string) (migrateCommand, error) {
+	if len(args) == 0 {
+		return migrateCommand{}, errors.New(migrateUsage)
+	}
+
+	cmd := migrateCommand{action: args[0], steps: 1}
+	switch {
+	case (cmd.action == "up" || cmd.action == "down" || cmd.action == "status") && len(args) == 1:
+		return cmd, nil
+	case cmd.action == "down" && len(args) == 2:
+		steps, err := strconv.Atoi(args[1])
+		if err != nil || steps <= 0 {
+			return migrateCommand{}, fmt.Errorf("invalid number of migrations to revert %q", args[1])
+		}
+		cmd.steps = steps
+		return cmd, nil
+	default:
+		return migrateCommand{}, errors.New(migrateUsage)
+	}
+}
+
+// runMigrate выполняет подкоманду migrate: применяет, откатывает миграции
+// или выводит их состояние в out
+func runMigrate(ctx context.Context, cfg *config.Config, args []string, out io.Writer) error {
+	cmd, err := parseMigrateArgs(args)
+	if err != nil {
+		return err
+	}
+	if cfg.DatabaseDSN == "" {
+		return errors.New("DB_DSN is required for migrations")
+	}
+
+	storage, err := repository.NewPostgresStorage(ctx, cfg.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	migrator, err := storage.Migrator()
+	if err != nil {
+		return err
+	}
+
+	switch cmd.action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %03d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "schema is up to date")
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, cmd.steps)
+		for _, migration := range reverted {
+			fmt.Fprintf(out, "reverted %03d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	default:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(out, statuses)
+		return nil
+	}
+}
+
+// printMigrationStatus выводит состояние миграций таблицей
+func printMigrationStatus(out io.Writer, statuses []repository.MigrationStatus) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state := "pending"
+		switch {
+		case status.Missing:
+			state = "missing"
+		case status.Modified:
+			state = "modified"
+		case status.Applied:
+			state = "applied"
+		}
+
+		appliedAt := "-"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	w.Flush()
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/NarthurN/CommentsSystem/internal/auth"
 	"github.com/NarthurN/CommentsSystem/internal/repository"
 )
 
@@ -27,6 +28,7 @@ type ErrorResponse struct {
 const (
 	ErrCodeValidation       = "VALIDATION_ERROR"
 	ErrCodeNotFound         = "NOT_FOUND"
+	ErrCodeUnauthenticated  = "UNAUTHENTICATED"
 	ErrCodeForbidden        = "FORBIDDEN"
 	ErrCodeRateLimit        = "RATE_LIMIT_EXCEEDED"
 	ErrCodeTooLarge         = "PAYLOAD_TOO_LARGE"
@@ -35,6 +37,7 @@ const (
 	ErrCodeDuplicate        = "DUPLICATE_ENTITY"
 	ErrCodeInvalidInput     = "INVALID_INPUT"
 	ErrCodeCommentsDisabled = "COMMENTS_DISABLED"
+	ErrCodeUnknownAuthor    = "UNKNOWN_AUTHOR"
 )
 
 // ErrorHandler обрабатывает ошибки и возвращает правильные HTTP коды
@@ -80,6 +83,46 @@ func (h *ErrorHandler) HandleError(ctx context.Context, err error) (int, ErrorRe
 			Success: false,
 		}
 
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return http.StatusUnauthorized, ErrorResponse{
+			Error: APIError{
+				Code:    ErrCodeUnauthenticated,
+				Message: "Authentication required",
+				Details: "This operation requires an authenticated user",
+			},
+			Success: false,
+		}
+
+	case errors.Is(err, auth.ErrInvalidToken):
+		return http.StatusUnauthorized, ErrorResponse{
+			Error: APIError{
+				Code:    ErrCodeUnauthenticated,
+				Message: "Invalid access token",
+				Details: "The bearer token is malformed, expired or has an invalid signature",
+			},
+			Success: false,
+		}
+
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden, ErrorResponse{
+			Error: APIError{
+				Code:    ErrCodeForbidden,
+				Message: "Access denied",
+				Details: "Only the author or a moderator can modify this resource",
+			},
+			Success: false,
+		}
+
+	case errors.Is(err, repository.ErrUnknownAuthor), errors.Is(err, repository.ErrUnknownUser):
+		return http.StatusForbidden, ErrorResponse{
+			Error: APIError{
+				Code:    ErrCodeUnknownAuthor,
+				Message: "User account not found",
+				Details: "Create an account with createUser before posting, voting or reacting",
+			},
+			Success: false,
+		}
+
 	case errors.Is(err, repository.ErrInvalidInput):
 		return http.StatusBadRequest, ErrorResponse{
 			Error: APIError{
@@ -246,6 +289,18 @@ func (h *GraphQLErrorHandler) FormatGraphQLError(ctx context.Context, err error)
 	case errors.Is(err, repository.ErrNotFound):
 		return fmt.Errorf("requested resource not found")
 
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return fmt.Errorf("authentication required")
+
+	case errors.Is(err, auth.ErrInvalidToken):
+		return fmt.Errorf("invalid access token")
+
+	case errors.Is(err, auth.ErrForbidden):
+		return fmt.Errorf("access denied")
+
+	case errors.Is(err, repository.ErrUnknownAuthor), errors.Is(err, repository.ErrUnknownUser):
+		return fmt.Errorf("user account not found: create an account with createUser first")
+
 	case errors.Is(err, repository.ErrInvalidInput):
 		return fmt.Errorf("invalid input: %s", err.Error())
 
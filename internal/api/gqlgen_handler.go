@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -75,6 +76,7 @@ func NewGQLGenHandlerWithConfig(svc *service.GQLGenService, cfg *config.Config)
 //   - Восстановление после паник
 //   - Timeout для запросов
 //   - CORS политики
+//   - JWT аутентификацию
 //   - GraphQL endpoints
 //   - Health check endpoint
 func (h *GQLGenHandler) SetupRoutes() *chi.Mux {
@@ -88,8 +90,8 @@ func (h *GQLGenHandler) SetupRoutes() *chi.Mux {
 	// CORS middleware с настраиваемыми политиками
 	r.Use(h.corsMiddleware())
 
-	// GraphQL эндпоинт
-	r.Handle(h.config.GraphQLEndpoint, h.service.GetHandler())
+	// GraphQL эндпоинт с аутентификацией по заголовку Authorization
+	r.With(h.authMiddleware()).Handle(h.config.GraphQLEndpoint, h.service.GetHandler())
 
 	// GraphQL Playground (обычно на корневом пути)
 	r.Handle("/", h.service.GetPlaygroundHandler())
@@ -128,6 +130,41 @@ func (h *GQLGenHandler) corsMiddleware() func(http.Handler) http.Handler {
 	}
 }
 
+// authMiddleware создает middleware аутентификации по заголовку Authorization.
+// Помещает ID пользователя из JWT в контекст запроса, откуда его читают резолверы.
+// WebSocket соединения без заголовка пропускаются: браузер не может его передать,
+// поэтому токен проверяется в payload сообщения connection_init.
+func (h *GQLGenHandler) authMiddleware() func(http.Handler) http.Handler {
+	errorHandler := NewErrorHandler(log.Default())
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization := r.Header.Get("Authorization")
+			if authorization == "" && isWebsocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, err := h.service.Authenticate(r.Context(), authorization)
+			if err != nil {
+				status, response := errorHandler.HandleError(r.Context(), err)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				w.WriteHeader(status)
+				_ = json.NewEncoder(w).Encode(response)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// isWebsocketUpgrade проверяет, является ли запрос WebSocket рукопожатием
+func isWebsocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
 // isOriginAllowed проверяет, разрешен ли указанный origin.
 // Поддерживает как одиночные origins, так и списки через запятую.
 func (h *GQLGenHandler) isOriginAllowed(origin string) bool {
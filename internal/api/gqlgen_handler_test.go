@@ -1,15 +1,21 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/NarthurN/CommentsSystem/internal/config"
 	"github.com/NarthurN/CommentsSystem/internal/repository"
 	"github.com/NarthurN/CommentsSystem/internal/service"
 	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // Мок для Storage интерфейса
@@ -148,3 +154,64 @@ func TestGQLGenHandler_isOriginAllowed(t *testing.T) {
 		})
 	}
 }
+
+func TestGQLGenHandler_AuthMiddleware(t *testing.T) {
+	secret := "test-secret"
+	cfg := &config.Config{
+		RequestTimeout:     config.DefaultRequestTimeout,
+		AllowOrigin:        config.DefaultAllowOrigin,
+		AllowMethods:       config.DefaultAllowMethods,
+		AllowHeaders:       config.DefaultAllowHeaders,
+		GraphQLEndpoint:    config.DefaultGraphQLEndpoint,
+		JWTSecret:          secret,
+		AuthAllowAnonymous: true,
+	}
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	svc := service.NewGQLGenServiceWithConfig(storage, pubsub.New(), cfg)
+	router := NewGQLGenHandlerWithConfig(svc, cfg).SetupRoutes()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   uuid.New().String(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	do := func(query, authorization string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		req := httptest.NewRequest("POST", cfg.GraphQLEndpoint, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Некорректный токен отклоняется до выполнения запроса
+	if rr := do(`{ posts { id } }`, "Bearer garbage"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for invalid token, got %d", rr.Code)
+	}
+
+	// Анонимное чтение разрешено
+	if rr := do(`{ posts { id } }`, ""); rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "errors") {
+		t.Errorf("Anonymous read should succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Анонимная мутация запрещена
+	mutation := `mutation { createPost(title: "T", content: "C") { id author { id } } }`
+	if rr := do(mutation, ""); !strings.Contains(rr.Body.String(), "authentication required") {
+		t.Errorf("Anonymous mutation should be rejected, got: %s", rr.Body.String())
+	}
+
+	// Учетная запись создается под ID из токена, после чего пользователь может писать
+	if rr := do(`mutation { createUser(username: "alice") { id } }`, "Bearer "+token); strings.Contains(rr.Body.String(), "errors") {
+		t.Fatalf("Failed to create user: %s", rr.Body.String())
+	}
+	if rr := do(mutation, "Bearer "+token); strings.Contains(rr.Body.String(), "errors") {
+		t.Errorf("Authenticated mutation should succeed, got: %s", rr.Body.String())
+	}
+}
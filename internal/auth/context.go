@@ -0,0 +1,85 @@
+// Package auth содержит работу с идентичностью вызывающего пользователя.
+// Middleware аутентификации кладет ID пользователя и признак модератора
+// в контекст запроса, а резолверы читают их для проверки прав на изменение
+// постов и комментариев.
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// Ошибки авторизации
+var (
+	// ErrUnauthenticated означает, что операция требует аутентифицированного пользователя
+	ErrUnauthenticated = errors.New("authentication required")
+
+	// ErrForbidden означает, что пользователь не является владельцем ресурса и не модератор
+	ErrForbidden = errors.New("forbidden: only the author or a moderator can modify this resource")
+)
+
+// contextKey - приватный тип ключей контекста, исключающий коллизии с другими пакетами
+type contextKey int
+
+const (
+	userIDKey    contextKey = iota // ID вызывающего пользователя
+	moderatorKey                   // Признак модератора
+)
+
+// WithUserID возвращает контекст с ID вызывающего пользователя.
+func WithUserID(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserIDFromContext возвращает ID вызывающего пользователя.
+// Второе значение false, если запрос анонимный.
+func UserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(userIDKey).(uuid.UUID)
+	if !ok || userID == uuid.Nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// RequireUserID возвращает ID вызывающего пользователя или ErrUnauthenticated.
+func RequireUserID(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return uuid.Nil, ErrUnauthenticated
+	}
+	return userID, nil
+}
+
+// WithModerator возвращает контекст, в котором вызывающий считается модератором.
+// Модератор может изменять любые посты и комментарии, в том числе без автора.
+func WithModerator(ctx context.Context) context.Context {
+	return context.WithValue(ctx, moderatorKey, true)
+}
+
+// IsModerator сообщает, является ли вызывающий модератором.
+func IsModerator(ctx context.Context) bool {
+	moderator, _ := ctx.Value(moderatorKey).(bool)
+	return moderator
+}
+
+// AuthorizeAuthor проверяет, что вызывающий пользователь - автор ресурса или модератор.
+// Ресурсы без автора (созданные до появления учетных записей) может изменять
+// только модератор.
+func AuthorizeAuthor(ctx context.Context, authorID *uuid.UUID) error {
+	if IsModerator(ctx) {
+		return nil
+	}
+
+	userID, err := RequireUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if authorID == nil || userID != *authorID {
+		return ErrForbidden
+	}
+
+	return nil
+}
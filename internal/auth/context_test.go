@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestUserIDFromContext(t *testing.T) {
+	if _, ok := UserIDFromContext(context.Background()); ok {
+		t.Error("Expected anonymous context to have no user")
+	}
+
+	userID := uuid.New()
+	got, ok := UserIDFromContext(WithUserID(context.Background(), userID))
+	if !ok || got != userID {
+		t.Errorf("Expected user %v, got %v (ok=%v)", userID, got, ok)
+	}
+
+	if _, ok := UserIDFromContext(WithUserID(context.Background(), uuid.Nil)); ok {
+		t.Error("Expected nil UUID to be treated as anonymous")
+	}
+}
+
+func TestAuthorizeAuthor(t *testing.T) {
+	author := uuid.New()
+	other := uuid.New()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		authorID *uuid.UUID
+		wantErr  error
+	}{
+		{"ресурс без автора", WithUserID(context.Background(), author), nil, ErrForbidden},
+		{"ресурс без автора, анонимно", context.Background(), nil, ErrUnauthenticated},
+		{"ресурс без автора, модератор", WithModerator(WithUserID(context.Background(), other)), nil, nil},
+		{"чужой ресурс, модератор", WithModerator(WithUserID(context.Background(), other)), &author, nil},
+		{"автор", WithUserID(context.Background(), author), &author, nil},
+		{"анонимный пользователь", context.Background(), &author, ErrUnauthenticated},
+		{"чужой ресурс", WithUserID(context.Background(), other), &author, ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AuthorizeAuthor(tt.ctx, tt.authorID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidToken означает, что переданный токен не прошел проверку
+var ErrInvalidToken = errors.New("invalid token")
+
+// Роли из claim "roles", дающие права модератора
+const (
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Identity - вызывающий пользователь, извлеченный из проверенного токена
+type Identity struct {
+	UserID    uuid.UUID // ID пользователя из claim "sub"
+	Moderator bool      // В claim "roles" есть moderator или admin
+}
+
+// Context возвращает контекст с ID пользователя и, для модераторов, признаком модератора.
+func (i Identity) Context(ctx context.Context) context.Context {
+	ctx = WithUserID(ctx, i.UserID)
+	if i.Moderator {
+		ctx = WithModerator(ctx)
+	}
+	return ctx
+}
+
+// tokenClaims - claims токена: стандартные и список ролей
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Verifier проверяет JWT токены и извлекает из них вызывающего пользователя.
+// Поддерживает HS256 (общий секрет) и RS256 (публичный ключ RSA);
+// принимаются только алгоритмы, для которых настроен ключ.
+// ID пользователя берется из claim "sub", роли - из claim "roles".
+type Verifier struct {
+	hmacSecret []byte         // Секрет для HS256
+	rsaKey     *rsa.PublicKey // Публичный ключ для RS256
+	issuer     string         // Ожидаемый издатель (claim "iss"), пусто - не проверяется
+	methods    []string       // Разрешенные алгоритмы подписи
+}
+
+// NewVerifier создает Verifier для заданных ключей.
+// Возвращает nil, если не задан ни один ключ: аутентификация отключена.
+func NewVerifier(hmacSecret []byte, rsaKey *rsa.PublicKey, issuer string) *Verifier {
+	v := &Verifier{
+		hmacSecret: hmacSecret,
+		rsaKey:     rsaKey,
+		issuer:     issuer,
+	}
+
+	if len(hmacSecret) > 0 {
+		v.methods = append(v.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if rsaKey != nil {
+		v.methods = append(v.methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	if len(v.methods) == 0 {
+		return nil
+	}
+
+	return v
+}
+
+// Verify проверяет подпись и срок действия токена и возвращает вызывающего пользователя.
+func (v *Verifier) Verify(tokenString string) (Identity, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(v.methods),
+		jwt.WithExpirationRequired(),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, v.keyFunc, opts...)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil || userID == uuid.Nil {
+		return Identity{}, fmt.Errorf("%w: subject must be a user ID", ErrInvalidToken)
+	}
+
+	identity := Identity{UserID: userID}
+	for _, role := range claims.Roles {
+		if role == RoleModerator || role == RoleAdmin {
+			identity.Moderator = true
+		}
+	}
+
+	return identity, nil
+}
+
+// keyFunc выбирает ключ проверки по алгоритму из заголовка токена.
+// Сам алгоритм уже ограничен списком methods.
+func (v *Verifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return v.hmacSecret, nil
+	case *jwt.SigningMethodRSA:
+		return v.rsaKey, nil
+	default:
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+}
+
+// BearerToken извлекает токен из значения вида "Bearer <token>".
+// Возвращает пустую строку, если схема не Bearer.
+func BearerToken(header string) string {
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestNewVerifier_Disabled(t *testing.T) {
+	if v := NewVerifier(nil, nil, ""); v != nil {
+		t.Error("Expected nil verifier when no keys are configured")
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	secret := []byte("test-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+
+	userID := uuid.New()
+	valid := jwt.RegisteredClaims{
+		Subject:   userID.String(),
+		Issuer:    "comments",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	noExpiry := valid
+	noExpiry.ExpiresAt = nil
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "other"
+	badSubject := valid
+	badSubject.Subject = "not-a-uuid"
+
+	hsOnly := NewVerifier(secret, nil, "comments")
+	both := NewVerifier(secret, &rsaKey.PublicKey, "comments")
+
+	tests := []struct {
+		name     string
+		verifier *Verifier
+		token    string
+		wantErr  bool
+	}{
+		{"HS256", hsOnly, signToken(t, jwt.SigningMethodHS256, secret, valid), false},
+		{"RS256", both, signToken(t, jwt.SigningMethodRS256, rsaKey, valid), false},
+		{"RS256 без настроенного ключа", hsOnly, signToken(t, jwt.SigningMethodRS256, rsaKey, valid), true},
+		{"неверный секрет", hsOnly, signToken(t, jwt.SigningMethodHS256, []byte("wrong"), valid), true},
+		{"истекший токен", hsOnly, signToken(t, jwt.SigningMethodHS256, secret, expired), true},
+		{"без срока действия", hsOnly, signToken(t, jwt.SigningMethodHS256, secret, noExpiry), true},
+		{"чужой издатель", hsOnly, signToken(t, jwt.SigningMethodHS256, secret, wrongIssuer), true},
+		{"subject не UUID", hsOnly, signToken(t, jwt.SigningMethodHS256, secret, badSubject), true},
+		{"мусор", hsOnly, "not.a.token", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.verifier.Verify(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("Expected ErrInvalidToken, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.UserID != userID || got.Moderator {
+				t.Errorf("Expected user %v without moderator role, got %+v", userID, got)
+			}
+		})
+	}
+}
+
+func TestVerifier_VerifyRoles(t *testing.T) {
+	secret := []byte("test-secret")
+	verifier := NewVerifier(secret, nil, "")
+	userID := uuid.New()
+
+	tests := []struct {
+		roles []string
+		want  bool
+	}{
+		{nil, false},
+		{[]string{"reader"}, false},
+		{[]string{"reader", RoleModerator}, true},
+		{[]string{RoleAdmin}, true},
+	}
+
+	for _, tt := range tests {
+		token := signToken(t, jwt.SigningMethodHS256, secret, tokenClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   userID.String(),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Roles: tt.roles,
+		})
+		got, err := verifier.Verify(token)
+		if err != nil {
+			t.Fatalf("Verify(%v) error = %v", tt.roles, err)
+		}
+		if got.Moderator != tt.want {
+			t.Errorf("Verify(%v).Moderator = %v, expected %v", tt.roles, got.Moderator, tt.want)
+		}
+		if IsModerator(got.Context(context.Background())) != tt.want {
+			t.Errorf("Identity.Context(%v) moderator flag mismatch", tt.roles)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"Bearer abc", "abc"},
+		{"bearer abc", "abc"},
+		{"  Bearer   abc  ", "abc"},
+		{"Basic abc", ""},
+		{"abc", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := BearerToken(tt.header); got != tt.want {
+			t.Errorf("BearerToken(%q) = %q, expected %q", tt.header, got, tt.want)
+		}
+	}
+}
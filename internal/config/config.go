@@ -1,10 +1,16 @@
 package config
 
 import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
 )
 
 // AppName содержит имя приложения
@@ -21,8 +27,11 @@ const (
 	DefaultRequestTimeout  = 60 * time.Second
 
 	// Настройки базы данных по умолчанию
-	DefaultStorageType = "postgres"
-	DefaultLogLevel    = "info"
+	DefaultStorageType   = "postgres"
+	DefaultLogLevel      = "info"
+	DefaultDataDir       = "./data"
+	DefaultSnapshotEvery = 1000
+	DefaultSQLitePath    = "./data/comments.db"
 
 	// Настройки бизнес-логики по умолчанию
 	DefaultPostsPageLimit    = 10
@@ -34,6 +43,19 @@ const (
 	// Настройки PubSub по умолчанию
 	DefaultChannelBufferSize = 100
 	DefaultKeepAlivePing     = 10 * time.Second
+	DefaultHistorySize       = 256
+	DefaultHistoryTTL        = 10 * time.Minute
+	DefaultOverflowPolicy    = "drop_newest"
+	DefaultBlockTimeout      = 100 * time.Millisecond
+	DefaultPubSubBackend     = "memory"
+	DefaultRedisAddr         = "localhost:6379"
+
+	// Настройки outbox по умолчанию
+	DefaultOutboxPollInterval = time.Second
+	DefaultOutboxBatchSize    = 100
+	DefaultOutboxLease        = 30 * time.Second
+	DefaultOutboxRetention    = 24 * time.Hour
+	DefaultWebhookTimeout     = 5 * time.Second
 
 	// Настройки CORS по умолчанию
 	DefaultAllowOrigin  = "*"
@@ -43,6 +65,12 @@ const (
 	// Настройки GraphQL по умолчанию
 	DefaultPlaygroundTitle = "GraphQL Playground"
 	DefaultGraphQLEndpoint = "/graphql"
+
+	// Настройки аутентификации по умолчанию
+	DefaultAuthAllowAnonymous = true
+
+	// Разрешенные реакции на комментарии по умолчанию (через запятую)
+	DefaultReactionEmoji = "👍,❤️,😂,🎉,😮,😢"
 )
 
 // Config представляет конфигурацию приложения
@@ -58,6 +86,14 @@ type Config struct {
 	// Конфигурация базы данных
 	StorageType string `json:"storage_type"`
 	DatabaseDSN string `json:"database_dsn"`
+	AutoMigrate bool   `json:"auto_migrate"` // Применять встроенные миграции при запуске
+
+	// Конфигурация файлового хранилища (STORAGE_TYPE=file)
+	DataDir       string `json:"data_dir"`       // Каталог с журналом и снимками
+	SnapshotEvery int    `json:"snapshot_every"` // Через сколько записей журнала писать снимок
+
+	// Конфигурация SQLite (STORAGE_TYPE=sqlite)
+	SQLitePath string `json:"sqlite_path"` // Путь к файлу базы данных
 
 	// Конфигурация логирования
 	LogLevel string `json:"log_level"`
@@ -72,6 +108,27 @@ type Config struct {
 	// Конфигурация PubSub
 	ChannelBufferSize int           `json:"channel_buffer_size"`
 	KeepAlivePing     time.Duration `json:"keep_alive_ping"`
+	HistorySize       int           `json:"history_size"`    // Сколько последних событий топика хранится для повторной доставки
+	HistoryTTL        time.Duration `json:"history_ttl"`     // Время хранения истории топика без подписчиков
+	OverflowPolicy    string        `json:"overflow_policy"` // Поведение при переполнении канала подписчика
+	BlockTimeout      time.Duration `json:"block_timeout"`   // Ожидание места в канале для политики block
+
+	// Конфигурация распределенного PubSub
+	PubSubBackend string `json:"pubsub_backend"` // memory - в пределах процесса, redis или postgres - между экземплярами
+	RedisAddr     string `json:"redis_addr"`     // Адрес Redis (host:port)
+	RedisPassword string `json:"-"`              // Пароль Redis
+	RedisDB       int    `json:"redis_db"`       // Номер базы данных Redis
+	RedisChannel  string `json:"redis_channel"`  // Канал Redis для событий; пустой - pubsub.DefaultRedisChannel
+	NotifyChannel string `json:"notify_channel"` // Канал PostgreSQL LISTEN/NOTIFY для событий (PUBSUB_BACKEND=postgres)
+
+	// Конфигурация transactional outbox
+	OutboxEnabled      bool          `json:"outbox_enabled"`       // События комментариев пишутся в outbox и доставляются relay
+	OutboxPollInterval time.Duration `json:"outbox_poll_interval"` // Период опроса outbox
+	OutboxBatchSize    int           `json:"outbox_batch_size"`    // Сколько событий relay захватывает за раз
+	OutboxLease        time.Duration `json:"outbox_lease"`         // На сколько событие захватывается; после неудачи - пауза до повтора
+	OutboxRetention    time.Duration `json:"outbox_retention"`     // Сколько хранятся доставленные события
+	WebhookURLs        []string      `json:"webhook_urls"`         // Адреса вебхуков для событий outbox
+	WebhookTimeout     time.Duration `json:"webhook_timeout"`      // Таймаут доставки одного события вебхуку
 
 	// Конфигурация CORS
 	AllowOrigin  string `json:"allow_origin"`
@@ -82,6 +139,17 @@ type Config struct {
 	PlaygroundTitle     string `json:"playground_title"`
 	GraphQLEndpoint     string `json:"graphql_endpoint"`
 	EnableIntrospection bool   `json:"enable_introspection"`
+
+	// Конфигурация аутентификации (JWT).
+	// Если не задан ни секрет, ни публичный ключ, аутентификация отключена.
+	JWTSecret          string         `json:"-"`                    // Секрет для HS256
+	JWTPublicKeyFile   string         `json:"jwt_public_key_file"`  // Путь к PEM с публичным ключом RS256
+	JWTPublicKey       *rsa.PublicKey `json:"-"`                    // Загруженный публичный ключ RS256
+	JWTIssuer          string         `json:"jwt_issuer"`           // Ожидаемый издатель токенов
+	AuthAllowAnonymous bool           `json:"auth_allow_anonymous"` // Разрешены ли запросы на чтение без токена (при включенной аутентификации)
+
+	// Конфигурация реакций на комментарии
+	ReactionEmoji []string `json:"reaction_emoji"` // Разрешенные эмодзи реакций
 }
 
 // LoadFromEnv загружает конфигурацию из переменных окружения
@@ -98,6 +166,14 @@ func LoadFromEnv() (*Config, error) {
 		// База данных
 		StorageType: getEnv("STORAGE_TYPE", DefaultStorageType),
 		DatabaseDSN: getEnv("DB_DSN", ""),
+		AutoMigrate: getBoolEnv("DB_AUTO_MIGRATE", false),
+
+		// Файловое хранилище
+		DataDir:       getEnv("DATA_DIR", DefaultDataDir),
+		SnapshotEvery: getIntEnv("FILE_SNAPSHOT_EVERY", DefaultSnapshotEvery),
+
+		// SQLite
+		SQLitePath: getEnv("SQLITE_PATH", DefaultSQLitePath),
 
 		// Логирование
 		LogLevel: getEnv("LOG_LEVEL", DefaultLogLevel),
@@ -112,6 +188,25 @@ func LoadFromEnv() (*Config, error) {
 		// PubSub
 		ChannelBufferSize: getIntEnv("PUBSUB_CHANNEL_BUFFER_SIZE", DefaultChannelBufferSize),
 		KeepAlivePing:     getDurationEnv("PUBSUB_KEEP_ALIVE_PING", DefaultKeepAlivePing),
+		HistorySize:       getIntEnv("PUBSUB_HISTORY_SIZE", DefaultHistorySize),
+		HistoryTTL:        getDurationEnv("PUBSUB_HISTORY_TTL", DefaultHistoryTTL),
+		OverflowPolicy:    getEnv("PUBSUB_OVERFLOW_POLICY", DefaultOverflowPolicy),
+		BlockTimeout:      getDurationEnv("PUBSUB_BLOCK_TIMEOUT", DefaultBlockTimeout),
+		PubSubBackend:     getEnv("PUBSUB_BACKEND", DefaultPubSubBackend),
+		RedisAddr:         getEnv("REDIS_ADDR", DefaultRedisAddr),
+		RedisPassword:     getEnv("REDIS_PASSWORD", ""),
+		RedisDB:           getIntEnv("REDIS_DB", 0),
+		RedisChannel:      getEnv("REDIS_CHANNEL", pubsub.DefaultRedisChannel),
+		NotifyChannel:     getEnv("PG_NOTIFY_CHANNEL", pubsub.DefaultPostgresChannel),
+
+		// Outbox
+		OutboxEnabled:      getBoolEnv("OUTBOX_ENABLED", false),
+		OutboxPollInterval: getDurationEnv("OUTBOX_POLL_INTERVAL", DefaultOutboxPollInterval),
+		OutboxBatchSize:    getIntEnv("OUTBOX_BATCH_SIZE", DefaultOutboxBatchSize),
+		OutboxLease:        getDurationEnv("OUTBOX_LEASE", DefaultOutboxLease),
+		OutboxRetention:    getDurationEnv("OUTBOX_RETENTION", DefaultOutboxRetention),
+		WebhookURLs:        getListEnv("OUTBOX_WEBHOOK_URLS", ""),
+		WebhookTimeout:     getDurationEnv("OUTBOX_WEBHOOK_TIMEOUT", DefaultWebhookTimeout),
 
 		// CORS
 		AllowOrigin:  getEnv("CORS_ALLOW_ORIGIN", DefaultAllowOrigin),
@@ -122,6 +217,24 @@ func LoadFromEnv() (*Config, error) {
 		PlaygroundTitle:     getEnv("GRAPHQL_PLAYGROUND_TITLE", DefaultPlaygroundTitle),
 		GraphQLEndpoint:     getEnv("GRAPHQL_ENDPOINT", DefaultGraphQLEndpoint),
 		EnableIntrospection: getBoolEnv("GRAPHQL_ENABLE_INTROSPECTION", true),
+
+		// Аутентификация
+		JWTSecret:          getEnv("JWT_SECRET", ""),
+		JWTPublicKeyFile:   getEnv("JWT_PUBLIC_KEY_FILE", ""),
+		JWTIssuer:          getEnv("JWT_ISSUER", ""),
+		AuthAllowAnonymous: getBoolEnv("AUTH_ALLOW_ANONYMOUS", DefaultAuthAllowAnonymous),
+
+		// Реакции
+		ReactionEmoji: getListEnv("REACTION_EMOJI", DefaultReactionEmoji),
+	}
+
+	// Загружаем публичный ключ RS256
+	if cfg.JWTPublicKeyFile != "" {
+		key, err := loadRSAPublicKey(cfg.JWTPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT public key: %w", err)
+		}
+		cfg.JWTPublicKey = key
 	}
 
 	// Валидируем конфигурацию
@@ -138,6 +251,18 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("DB_DSN is required when STORAGE_TYPE is postgres")
 	}
 
+	if c.StorageType == "file" && c.DataDir == "" {
+		return fmt.Errorf("DATA_DIR is required when STORAGE_TYPE is file")
+	}
+
+	if c.StorageType == "file" && c.SnapshotEvery <= 0 {
+		return fmt.Errorf("FILE_SNAPSHOT_EVERY must be positive")
+	}
+
+	if c.StorageType == "sqlite" && c.SQLitePath == "" {
+		return fmt.Errorf("SQLITE_PATH is required when STORAGE_TYPE is sqlite")
+	}
+
 	if c.HTTPAddr == "" {
 		return fmt.Errorf("HTTP_ADDR cannot be empty")
 	}
@@ -158,9 +283,40 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("channel buffer size must be positive")
 	}
 
+	if c.OverflowPolicy != "" && !pubsub.OverflowPolicy(c.OverflowPolicy).IsValid() {
+		return fmt.Errorf("unknown pubsub overflow policy %q", c.OverflowPolicy)
+	}
+
+	if c.PubSubBackend == "redis" && c.RedisAddr == "" {
+		return fmt.Errorf("REDIS_ADDR is required when PUBSUB_BACKEND is redis")
+	}
+
+	if c.PubSubBackend == "postgres" && c.DatabaseDSN == "" {
+		return fmt.Errorf("DB_DSN is required when PUBSUB_BACKEND is postgres")
+	}
+
+	if c.OutboxEnabled && c.StorageType != "postgres" {
+		return fmt.Errorf("OUTBOX_ENABLED requires STORAGE_TYPE postgres")
+	}
+
+	// Событие outbox публикует в pub/sub только захвативший его экземпляр:
+	// с pub/sub в памяти подписчики других экземпляров его не получили бы
+	if c.OutboxEnabled && c.PubSubBackend != "redis" && c.PubSubBackend != "postgres" {
+		return fmt.Errorf("OUTBOX_ENABLED requires PUBSUB_BACKEND redis or postgres")
+	}
+
+	if len(c.WebhookURLs) > 0 && !c.OutboxEnabled {
+		return fmt.Errorf("OUTBOX_WEBHOOK_URLS requires OUTBOX_ENABLED")
+	}
+
 	return nil
 }
 
+// AuthEnabled сообщает, настроена ли проверка JWT токенов
+func (c *Config) AuthEnabled() bool {
+	return c.JWTSecret != "" || c.JWTPublicKey != nil
+}
+
 // GetDSNForTests возвращает DSN для тестов (может быть переопределено)
 func (c *Config) GetDSNForTests() string {
 	testDSN := getEnv("TEST_DB_DSN", c.DatabaseDSN)
@@ -209,3 +365,46 @@ func getBoolEnv(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getListEnv возвращает список значений переменной окружения, разделенных запятыми,
+// или список из значения по умолчанию. Пустые элементы отбрасываются.
+func getListEnv(key, defaultValue string) []string {
+	value := getEnv(key, defaultValue)
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadRSAPublicKey читает публичный ключ RSA из PEM файла (PKIX или PKCS#1)
+func loadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key in %s is not an RSA key", path)
+	}
+
+	return key, nil
+}
@@ -9,6 +9,7 @@
 package model
 
 import (
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
@@ -40,11 +41,15 @@ import (
 //		// Сохранение через репозиторий
 //	}
 type Post struct {
-	ID              uuid.UUID `json:"id" db:"id"`                            // Уникальный идентификатор поста
-	Title           string    `json:"title" db:"title"`                      // Заголовок поста (1-255 символов)
-	Content         string    `json:"content" db:"content"`                  // Содержимое поста (до 10000 символов)
-	CommentsEnabled bool      `json:"commentsEnabled" db:"comments_enabled"` // Флаг разрешения комментирования
-	CreatedAt       time.Time `json:"createdAt" db:"created_at"`             // Время создания поста (UTC)
+	ID              uuid.UUID  `json:"id" db:"id"`                            // Уникальный идентификатор поста
+	AuthorID        *uuid.UUID `json:"authorId,omitempty" db:"author_id"`     // ID автора (NULL для постов без автора)
+	Title           string     `json:"title" db:"title"`                      // Заголовок поста (1-255 символов)
+	Content         string     `json:"content" db:"content"`                  // Содержимое поста (до 10000 символов)
+	CommentsEnabled bool       `json:"commentsEnabled" db:"comments_enabled"` // Флаг разрешения комментирования
+	CreatedAt       time.Time  `json:"createdAt" db:"created_at"`             // Время создания поста (UTC)
+	CommentCount    int        `json:"commentCount" db:"comment_count"`       // Количество неудаленных комментариев на всех уровнях
+	Upvotes         int        `json:"upvotes" db:"upvotes"`                  // Количество голосов "за"
+	Downvotes       int        `json:"downvotes" db:"downvotes"`              // Количество голосов "против"
 }
 
 // Comment представляет комментарий к посту.
@@ -60,14 +65,63 @@ type Post struct {
 // Иерархия:
 //   - ParentID == nil: корневой комментарий
 //   - ParentID != nil: ответ на комментарий с указанным ID
+//
+// Удаление:
+//   - DeletedAt != nil: комментарий удален ("надгробие"), текст заменен на DeletedCommentContent
+//   - Надгробие остается в дереве, пока у него есть неудаленные потомки
+//
+// Редактирование:
+//   - EditedAt != nil: текст менялся после создания
+//   - Предыдущие версии текста хранятся как CommentRevision
+//
+// Счетчики:
+//   - ReplyCount и DescendantCount учитывают только неудаленные комментарии
+//   - Поддерживаются хранилищем при создании и удалении комментариев
+//   - Upvotes и Downvotes определяют порядок сортировки TOP и CONTROVERSIAL
 type Comment struct {
-	ID        uuid.UUID  `json:"id" db:"id"`                        // Уникальный идентификатор комментария
-	PostID    uuid.UUID  `json:"postId" db:"post_id"`               // ID поста, к которому относится комментарий
-	ParentID  *uuid.UUID `json:"parentId,omitempty" db:"parent_id"` // ID родительского комментария (NULL для корневых)
-	Content   string     `json:"content" db:"content"`              // Текст комментария (до 2000 символов)
-	CreatedAt time.Time  `json:"createdAt" db:"created_at"`         // Время создания комментария (UTC)
+	ID        uuid.UUID  `json:"id" db:"id"`                          // Уникальный идентификатор комментария
+	PostID    uuid.UUID  `json:"postId" db:"post_id"`                 // ID поста, к которому относится комментарий
+	AuthorID  *uuid.UUID `json:"authorId,omitempty" db:"author_id"`   // ID автора (NULL для комментариев без автора)
+	ParentID  *uuid.UUID `json:"parentId,omitempty" db:"parent_id"`   // ID родительского комментария (NULL для корневых)
+	Content   string     `json:"content" db:"content"`                // Текст комментария (до 2000 символов)
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`           // Время создания комментария (UTC)
+	EditedAt  *time.Time `json:"editedAt,omitempty" db:"edited_at"`   // Время последнего редактирования (NULL если не редактировался)
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"` // Время удаления (NULL для активных комментариев)
+
+	ReplyCount      int `json:"replyCount" db:"reply_count"`           // Количество неудаленных прямых ответов
+	DescendantCount int `json:"descendantCount" db:"descendant_count"` // Количество неудаленных ответов на всех уровнях
+	Upvotes         int `json:"upvotes" db:"upvotes"`                  // Количество голосов "за"
+	Downvotes       int `json:"downvotes" db:"downvotes"`              // Количество голосов "против"
+}
+
+// CommentRevision представляет предыдущую версию текста комментария.
+// Создается при каждом редактировании и хранит текст, который был заменен.
+// Используется модераторами для просмотра истории изменений.
+type CommentRevision struct {
+	ID        uuid.UUID `json:"id" db:"id"`                // Уникальный идентификатор ревизии
+	CommentID uuid.UUID `json:"commentId" db:"comment_id"` // ID отредактированного комментария
+	Content   string    `json:"content" db:"content"`      // Текст комментария до редактирования
+	CreatedAt time.Time `json:"createdAt" db:"created_at"` // Время редактирования, заменившего этот текст (UTC)
+}
+
+// DeletedCommentContent - текст, которым заменяется содержимое удаленного комментария
+const DeletedCommentContent = "[deleted]"
+
+// User представляет пользователя системы - автора постов и комментариев.
+//
+// Бизнес-правила:
+//   - Username: обязательный, уникальный, 3-50 символов (латиница, цифры, "_", "-", ".")
+//   - ID генерируется автоматически при создании
+//   - CreatedAt устанавливается в UTC при создании
+type User struct {
+	ID        uuid.UUID `json:"id" db:"id"`                // Уникальный идентификатор пользователя
+	Username  string    `json:"username" db:"username"`    // Уникальное имя пользователя
+	CreatedAt time.Time `json:"createdAt" db:"created_at"` // Время регистрации (UTC)
 }
 
+// usernamePattern описывает допустимые имена пользователей
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,50}$`)
+
 // PostWithComments объединяет пост с его комментариями.
 // Используется для передачи полной информации о посте с комментариями.
 type PostWithComments struct {
@@ -118,6 +172,12 @@ func (p *Post) Prepare() {
 	}
 }
 
+// IsAuthoredBy проверяет, является ли пользователь автором поста.
+// Посты без автора не принадлежат никому.
+func (p *Post) IsAuthoredBy(userID uuid.UUID) bool {
+	return p.AuthorID != nil && *p.AuthorID == userID
+}
+
 // Доменные методы для Comment
 
 // IsValidComment проверяет валидность комментария.
@@ -152,6 +212,51 @@ func (c *Comment) Prepare() {
 	}
 }
 
+// IsAuthoredBy проверяет, является ли пользователь автором комментария.
+// Комментарии без автора не принадлежат никому.
+func (c *Comment) IsAuthoredBy(userID uuid.UUID) bool {
+	return c.AuthorID != nil && *c.AuthorID == userID
+}
+
+// IsDeleted проверяет, удален ли комментарий (является ли он "надгробием").
+func (c *Comment) IsDeleted() bool {
+	return c.DeletedAt != nil
+}
+
+// MarkDeleted превращает комментарий в "надгробие".
+// Устанавливает время удаления и заменяет текст заглушкой.
+// Повторный вызов не меняет исходное время удаления.
+func (c *Comment) MarkDeleted(at time.Time) {
+	if c.DeletedAt == nil {
+		deletedAt := at.UTC()
+		c.DeletedAt = &deletedAt
+	}
+	c.Content = DeletedCommentContent
+}
+
+// IsEdited проверяет, редактировался ли комментарий после создания.
+func (c *Comment) IsEdited() bool {
+	return c.EditedAt != nil
+}
+
+// Доменные методы для User
+
+// IsValid проверяет валидность имени пользователя.
+func (u *User) IsValid() bool {
+	return usernamePattern.MatchString(u.Username)
+}
+
+// Prepare подготавливает пользователя к сохранению.
+// Устанавливает ID и время создания, если они не заданы.
+func (u *User) Prepare() {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now().UTC()
+	}
+}
+
 // Доменные методы для CommentTree
 
 // HasChildren проверяет, есть ли у комментария дочерние комментарии.
@@ -163,3 +268,30 @@ func (ct *CommentTree) HasChildren() bool {
 func (ct *CommentTree) GetChildrenCount() int {
 	return len(ct.Children)
 }
+
+// HasLiveComments проверяет, есть ли в поддереве хотя бы один неудаленный комментарий.
+func (ct *CommentTree) HasLiveComments() bool {
+	if !ct.Comment.IsDeleted() {
+		return true
+	}
+	for i := range ct.Children {
+		if ct.Children[i].HasLiveComments() {
+			return true
+		}
+	}
+	return false
+}
+
+// PruneDeletedBranches удаляет из дерева ветки, состоящие только из удаленных комментариев.
+// Надгробия с неудаленными потомками сохраняются, чтобы не терять ответы.
+func PruneDeletedBranches(trees []CommentTree) []CommentTree {
+	var result []CommentTree
+	for _, tree := range trees {
+		if !tree.HasLiveComments() {
+			continue
+		}
+		tree.Children = PruneDeletedBranches(tree.Children)
+		result = append(result, tree)
+	}
+	return result
+}
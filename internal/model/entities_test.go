@@ -483,6 +483,55 @@ func TestCommentTree_GetChildrenCount(t *testing.T) {
 }
 
 // Benchmark тесты для производительности
+func TestComment_MarkDeleted(t *testing.T) {
+	comment := &Comment{Content: "Original"}
+	if comment.IsDeleted() {
+		t.Fatal("New comment should not be deleted")
+	}
+
+	deletedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	comment.MarkDeleted(deletedAt)
+
+	if !comment.IsDeleted() {
+		t.Error("Comment should be deleted after MarkDeleted")
+	}
+	if comment.Content != DeletedCommentContent {
+		t.Errorf("Expected content %q, got %q", DeletedCommentContent, comment.Content)
+	}
+
+	comment.MarkDeleted(deletedAt.Add(time.Hour))
+	if !comment.DeletedAt.Equal(deletedAt) {
+		t.Error("Repeated MarkDeleted should keep the original deletion time")
+	}
+}
+
+func TestPruneDeletedBranches(t *testing.T) {
+	deletedAt := time.Now()
+	deleted := Comment{ID: uuid.New(), DeletedAt: &deletedAt}
+	live := Comment{ID: uuid.New()}
+
+	trees := []CommentTree{
+		// Надгробие с живым ответом остается
+		{Comment: deleted, Children: []CommentTree{{Comment: live}}},
+		// Ветка только из надгробий удаляется
+		{Comment: deleted, Children: []CommentTree{{Comment: deleted}}},
+		// Живой комментарий теряет только мертвые ветки
+		{Comment: live, Children: []CommentTree{{Comment: deleted}}},
+	}
+
+	result := PruneDeletedBranches(trees)
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 trees, got %d", len(result))
+	}
+	if len(result[0].Children) != 1 {
+		t.Errorf("Expected tombstone to keep its live reply, got %d children", len(result[0].Children))
+	}
+	if len(result[1].Children) != 0 {
+		t.Errorf("Expected dead branch under live comment to be pruned, got %d children", len(result[1].Children))
+	}
+}
+
 func BenchmarkPost_IsValid(b *testing.B) {
 	post := &Post{
 		Title:   "Тестовый заголовок для бенчмарка",
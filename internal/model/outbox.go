@@ -0,0 +1,70 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Виды событий outbox
+const (
+	// OutboxCommentAdded - создан комментарий; Payload содержит Comment в JSON
+	OutboxCommentAdded = "comment_added"
+	// OutboxCommentEdited - комментарий отредактирован; Payload содержит Comment в JSON
+	OutboxCommentEdited = "comment_edited"
+	// OutboxCommentDeleted - комментарий удален и стал надгробием; Payload содержит Comment в JSON
+	OutboxCommentDeleted = "comment_deleted"
+	// OutboxCommentsToggled - комментарии поста включены или отключены; Payload содержит CommentsToggledEvent
+	OutboxCommentsToggled = "comments_toggled"
+	// OutboxVoteTally - изменился рейтинг поста или комментария; Payload содержит VoteTally
+	OutboxVoteTally = "vote_tally"
+	// OutboxPostDeleted - пост удален вместе с комментариями; Payload содержит PostDeletedEvent
+	OutboxPostDeleted = "post_deleted"
+)
+
+// CommentsToggledEvent - данные события OutboxCommentsToggled
+type CommentsToggledEvent struct {
+	PostID          uuid.UUID `json:"postId"`          // ID поста
+	CommentsEnabled bool      `json:"commentsEnabled"` // Новое значение флага комментариев
+}
+
+// PostDeletedEvent - данные события OutboxPostDeleted
+type PostDeletedEvent struct {
+	PostID     uuid.UUID   `json:"postId"`     // ID удаленного поста
+	CommentIDs []uuid.UUID `json:"commentIds"` // Комментарии, удаленные вместе с постом
+}
+
+// OutboxEvent - событие, записанное в outbox в одной транзакции с изменением данных.
+// Доставляется подписчикам и вебхукам фоновым relay как минимум один раз,
+// поэтому получатели отбрасывают повторы по DedupKey.
+type OutboxEvent struct {
+	ID        int64           `json:"id" db:"id"`                // Порядковый номер события
+	Kind      string          `json:"kind" db:"kind"`            // Вид события (OutboxCommentAdded и другие)
+	DedupKey  string          `json:"dedupKey" db:"dedup_key"`   // Ключ дедупликации, уникален для события
+	Payload   json.RawMessage `json:"payload" db:"payload"`      // Данные события в JSON
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"` // Время записи события
+	Attempts  int             `json:"attempts" db:"attempts"`    // Количество неудачных попыток доставки
+}
+
+// CommentAddedDedupKey возвращает ключ дедупликации события о новом комментарии
+func CommentAddedDedupKey(commentID uuid.UUID) string {
+	return OutboxCommentAdded + ":" + commentID.String()
+}
+
+// CommentDeletedDedupKey возвращает ключ дедупликации события об удалении комментария.
+// Комментарий удаляется один раз, поэтому повторное удаление не записывает новое событие.
+func CommentDeletedDedupKey(commentID uuid.UUID) string {
+	return OutboxCommentDeleted + ":" + commentID.String()
+}
+
+// PostDeletedDedupKey возвращает ключ дедупликации события об удалении поста
+func PostDeletedDedupKey(postID uuid.UUID) string {
+	return OutboxPostDeleted + ":" + postID.String()
+}
+
+// NewOutboxDedupKey возвращает новый ключ дедупликации для события, которое может
+// повторяться для одного объекта (правка, переключение комментариев, голос)
+func NewOutboxDedupKey(kind string, targetID uuid.UUID) string {
+	return kind + ":" + targetID.String() + ":" + uuid.NewString()
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cursor - позиция элемента в keyset пагинации.
+// Элементы упорядочены по времени создания, а при равном времени - по ID,
+// поэтому позиция однозначна и не сдвигается при появлении новых элементов.
+type Cursor struct {
+	CreatedAt time.Time // Время создания элемента
+	ID        uuid.UUID // ID элемента для разрешения совпадений времени
+}
+
+// Less сообщает, идет ли позиция c раньше other в порядке (CreatedAt, ID) по возрастанию.
+// Порядок ID совпадает с побайтовым сравнением UUID в PostgreSQL.
+func (c Cursor) Less(other Cursor) bool {
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.Before(other.CreatedAt)
+	}
+	return bytes.Compare(c.ID[:], other.ID[:]) < 0
+}
+
+// Cursor возвращает позицию поста в keyset пагинации.
+func (p *Post) Cursor() Cursor {
+	return Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+// Cursor возвращает позицию комментария в keyset пагинации.
+func (c *Comment) Cursor() Cursor {
+	return Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
@@ -0,0 +1,17 @@
+package model
+
+// MaxReactionEmojiLength - максимальная длина эмодзи реакции в байтах.
+// Эмодзи с модификаторами (цвет кожи, ZWJ последовательности) занимают до нескольких десятков байт.
+const MaxReactionEmojiLength = 32
+
+// Reaction - сводка реакций одним эмодзи на комментарий
+type Reaction struct {
+	Emoji         string `json:"emoji"`         // Эмодзи реакции
+	Count         int    `json:"count"`         // Количество пользователей, поставивших реакцию
+	ViewerReacted bool   `json:"viewerReacted"` // Поставил ли реакцию текущий пользователь
+}
+
+// IsValidReactionEmoji проверяет, что эмодзи реакции не пустое и укладывается в лимит длины
+func IsValidReactionEmoji(emoji string) bool {
+	return emoji != "" && len(emoji) <= MaxReactionEmojiLength
+}
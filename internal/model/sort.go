@@ -0,0 +1,103 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// CommentSort определяет порядок комментариев в списках корневых комментариев и ответов.
+// При равенстве основного критерия комментарии упорядочиваются по (CreatedAt, ID),
+// поэтому порядок однозначен и одинаков во всех хранилищах.
+type CommentSort string
+
+const (
+	CommentSortNew           CommentSort = "NEW"           // Сначала новые
+	CommentSortOld           CommentSort = "OLD"           // Сначала старые (порядок по умолчанию)
+	CommentSortTop           CommentSort = "TOP"           // По рейтингу: больше голосов "за" за вычетом "против"
+	CommentSortControversial CommentSort = "CONTROVERSIAL" // Много голосов, поделенных примерно поровну
+)
+
+// AllCommentSort содержит все допустимые значения CommentSort
+var AllCommentSort = []CommentSort{
+	CommentSortNew,
+	CommentSortOld,
+	CommentSortTop,
+	CommentSortControversial,
+}
+
+// IsValid проверяет, что порядок сортировки известен
+func (s CommentSort) IsValid() bool {
+	switch s {
+	case CommentSortNew, CommentSortOld, CommentSortTop, CommentSortControversial:
+		return true
+	}
+	return false
+}
+
+// OrDefault возвращает порядок сортировки или CommentSortOld для пустого значения
+func (s CommentSort) OrDefault() CommentSort {
+	if s == "" {
+		return CommentSortOld
+	}
+	return s
+}
+
+// Less сообщает, должен ли комментарий a идти раньше b в данном порядке сортировки
+func (s CommentSort) Less(a, b *Comment) bool {
+	switch s {
+	case CommentSortNew:
+		return b.Cursor().Less(a.Cursor())
+	case CommentSortTop:
+		if a.Score() != b.Score() {
+			return a.Score() > b.Score()
+		}
+	case CommentSortControversial:
+		if a.Controversy() != b.Controversy() {
+			return a.Controversy() > b.Controversy()
+		}
+	}
+	return a.Cursor().Less(b.Cursor())
+}
+
+// String возвращает строковое представление порядка сортировки
+func (s CommentSort) String() string {
+	return string(s)
+}
+
+// UnmarshalGQL разбирает значение enum CommentSort из GraphQL запроса
+func (s *CommentSort) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*s = CommentSort(str)
+	if !s.IsValid() {
+		return fmt.Errorf("%s is not a valid CommentSort", str)
+	}
+	return nil
+}
+
+// MarshalGQL записывает значение enum CommentSort в GraphQL ответ
+func (s CommentSort) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(s.String()))
+}
+
+// Controversy возвращает меру спорности комментария: чем больше голосов
+// и чем ближе их соотношение к равному, тем выше значение.
+// Для комментария без голосов "за" или "против" возвращает 0.
+// Формула совпадает с генерируемой колонкой comments.controversy в PostgreSQL.
+func (c *Comment) Controversy() float64 {
+	if c.Upvotes <= 0 || c.Downvotes <= 0 {
+		return 0
+	}
+
+	minVotes, maxVotes := c.Upvotes, c.Downvotes
+	if minVotes > maxVotes {
+		minVotes, maxVotes = maxVotes, minVotes
+	}
+
+	return math.Pow(float64(c.Upvotes+c.Downvotes), float64(minVotes)/float64(maxVotes))
+}
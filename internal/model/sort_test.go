@@ -0,0 +1,82 @@
+package model
+
+import (
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestComment_Controversy(t *testing.T) {
+	tests := []struct {
+		name      string
+		upvotes   int
+		downvotes int
+		want      float64
+	}{
+		{"без голосов", 0, 0, 0},
+		{"только за", 10, 0, 0},
+		{"только против", 0, 10, 0},
+		{"поровну", 5, 5, 10},
+		{"перевес", 2, 8, math.Pow(10, 0.25)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Comment{Upvotes: tt.upvotes, Downvotes: tt.downvotes}
+			if got := c.Controversy(); got != tt.want {
+				t.Errorf("Controversy() = %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentSort_Less(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Комментарии в порядке создания
+	comments := []Comment{
+		{ID: uuid.New(), CreatedAt: base, Upvotes: 1, Downvotes: 1},
+		{ID: uuid.New(), CreatedAt: base.Add(time.Minute), Upvotes: 10},
+		{ID: uuid.New(), CreatedAt: base.Add(2 * time.Minute), Upvotes: 20, Downvotes: 18},
+		{ID: uuid.New(), CreatedAt: base.Add(3 * time.Minute), Upvotes: 10},
+	}
+
+	tests := []struct {
+		sort CommentSort
+		want []int // Индексы комментариев в ожидаемом порядке
+	}{
+		{CommentSortOld, []int{0, 1, 2, 3}},
+		{CommentSortNew, []int{3, 2, 1, 0}},
+		{CommentSortTop, []int{1, 3, 2, 0}},           // При равном рейтинге - сначала старые
+		{CommentSortControversial, []int{2, 0, 1, 3}}, // Без голосов "против" спорность 0
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.sort), func(t *testing.T) {
+			sorted := append([]Comment(nil), comments...)
+			sort.Slice(sorted, func(i, j int) bool {
+				return tt.sort.Less(&sorted[i], &sorted[j])
+			})
+			for i, idx := range tt.want {
+				if sorted[i].ID != comments[idx].ID {
+					t.Fatalf("Position %d: expected comment %d", i, idx)
+				}
+			}
+		})
+	}
+}
+
+func TestCommentSort_UnmarshalGQL(t *testing.T) {
+	var s CommentSort
+	if err := s.UnmarshalGQL("TOP"); err != nil || s != CommentSortTop {
+		t.Errorf("UnmarshalGQL(TOP) = %v, %v", s, err)
+	}
+	if err := s.UnmarshalGQL("BEST"); err == nil {
+		t.Error("Expected error for unknown sort")
+	}
+	if err := s.UnmarshalGQL(1); err == nil {
+		t.Error("Expected error for non-string value")
+	}
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// VoteValue - голос пользователя за пост или комментарий.
+// Каждый пользователь может иметь не больше одного голоса за каждую цель;
+// новый голос заменяет предыдущий, VoteNone снимает голос.
+type VoteValue string
+
+const (
+	VoteUp   VoteValue = "UP"   // Голос "за"
+	VoteDown VoteValue = "DOWN" // Голос "против"
+	VoteNone VoteValue = "NONE" // Нет голоса
+)
+
+// AllVoteValue содержит все допустимые значения VoteValue
+var AllVoteValue = []VoteValue{VoteUp, VoteDown, VoteNone}
+
+// IsValid проверяет, что значение голоса известно
+func (v VoteValue) IsValid() bool {
+	switch v {
+	case VoteUp, VoteDown, VoteNone:
+		return true
+	}
+	return false
+}
+
+// Int возвращает числовое значение голоса: 1, -1 или 0
+func (v VoteValue) Int() int {
+	switch v {
+	case VoteUp:
+		return 1
+	case VoteDown:
+		return -1
+	}
+	return 0
+}
+
+// VoteValueFromInt возвращает голос по числовому значению (1, -1 или 0)
+func VoteValueFromInt(value int) VoteValue {
+	switch {
+	case value > 0:
+		return VoteUp
+	case value < 0:
+		return VoteDown
+	}
+	return VoteNone
+}
+
+// String возвращает строковое представление голоса
+func (v VoteValue) String() string {
+	return string(v)
+}
+
+// UnmarshalGQL разбирает значение enum VoteValue из GraphQL запроса
+func (v *VoteValue) UnmarshalGQL(value interface{}) error {
+	str, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*v = VoteValue(str)
+	if !v.IsValid() {
+		return fmt.Errorf("%s is not a valid VoteValue", str)
+	}
+	return nil
+}
+
+// MarshalGQL записывает значение enum VoteValue в GraphQL ответ
+func (v VoteValue) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(v.String()))
+}
+
+// VoteTally - итог голосования за пост или комментарий после изменения голоса.
+// Публикуется подписчикам поста, чтобы открытые обсуждения обновляли рейтинг.
+type VoteTally struct {
+	TargetID  uuid.UUID `json:"targetId"`  // ID поста или комментария
+	PostID    uuid.UUID `json:"postId"`    // ID поста, к которому относится цель (совпадает с TargetID для поста)
+	Upvotes   int       `json:"upvotes"`   // Количество голосов "за"
+	Downvotes int       `json:"downvotes"` // Количество голосов "против"
+}
+
+// Score возвращает рейтинг цели: голоса "за" минус голоса "против"
+func (t *VoteTally) Score() int {
+	return t.Upvotes - t.Downvotes
+}
+
+// Score возвращает рейтинг поста: голоса "за" минус голоса "против"
+func (p *Post) Score() int {
+	return p.Upvotes - p.Downvotes
+}
+
+// Score возвращает рейтинг комментария: голоса "за" минус голоса "против"
+func (c *Comment) Score() int {
+	return c.Upvotes - c.Downvotes
+}
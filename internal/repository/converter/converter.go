@@ -25,10 +25,14 @@ func (c *PostConverter) ToRepositoryModel(domainPost *model.Post) *repoModel.Pos
 
 	return &repoModel.PostDB{
 		ID:              domainPost.ID,
+		AuthorID:        domainPost.AuthorID,
 		Title:           domainPost.Title,
 		Content:         domainPost.Content,
 		CommentsEnabled: domainPost.CommentsEnabled,
 		CreatedAt:       domainPost.CreatedAt,
+		CommentCount:    domainPost.CommentCount,
+		Upvotes:         domainPost.Upvotes,
+		Downvotes:       domainPost.Downvotes,
 	}
 }
 
@@ -40,10 +44,14 @@ func (c *PostConverter) ToDomainModel(repoPost *repoModel.PostDB) *model.Post {
 
 	return &model.Post{
 		ID:              repoPost.ID,
+		AuthorID:        repoPost.AuthorID,
 		Title:           repoPost.Title,
 		Content:         repoPost.Content,
 		CommentsEnabled: repoPost.CommentsEnabled,
 		CreatedAt:       repoPost.CreatedAt,
+		CommentCount:    repoPost.CommentCount,
+		Upvotes:         repoPost.Upvotes,
+		Downvotes:       repoPost.Downvotes,
 	}
 }
 
@@ -89,9 +97,17 @@ func (c *CommentConverter) ToRepositoryModel(domainComment *model.Comment) *repo
 	return &repoModel.CommentDB{
 		ID:        domainComment.ID,
 		PostID:    domainComment.PostID,
+		AuthorID:  domainComment.AuthorID,
 		ParentID:  domainComment.ParentID,
 		Content:   domainComment.Content,
 		CreatedAt: domainComment.CreatedAt,
+		EditedAt:  domainComment.EditedAt,
+		DeletedAt: domainComment.DeletedAt,
+
+		ReplyCount:      domainComment.ReplyCount,
+		DescendantCount: domainComment.DescendantCount,
+		Upvotes:         domainComment.Upvotes,
+		Downvotes:       domainComment.Downvotes,
 	}
 }
 
@@ -104,9 +120,17 @@ func (c *CommentConverter) ToDomainModel(repoComment *repoModel.CommentDB) *mode
 	return &model.Comment{
 		ID:        repoComment.ID,
 		PostID:    repoComment.PostID,
+		AuthorID:  repoComment.AuthorID,
 		ParentID:  repoComment.ParentID,
 		Content:   repoComment.Content,
 		CreatedAt: repoComment.CreatedAt,
+		EditedAt:  repoComment.EditedAt,
+		DeletedAt: repoComment.DeletedAt,
+
+		ReplyCount:      repoComment.ReplyCount,
+		DescendantCount: repoComment.DescendantCount,
+		Upvotes:         repoComment.Upvotes,
+		Downvotes:       repoComment.Downvotes,
 	}
 }
 
@@ -135,6 +159,74 @@ func (c *CommentConverter) CreateNewComment(postID uuid.UUID, parentID *uuid.UUI
 	}
 }
 
+// UserConverter отвечает за конвертацию пользователей
+type UserConverter struct{}
+
+// NewUserConverter создает новый экземпляр UserConverter
+func NewUserConverter() *UserConverter {
+	return &UserConverter{}
+}
+
+// ToRepositoryModel конвертирует доменную модель пользователя в модель репозитория
+func (c *UserConverter) ToRepositoryModel(domainUser *model.User) *repoModel.UserDB {
+	if domainUser == nil {
+		return nil
+	}
+
+	return &repoModel.UserDB{
+		ID:        domainUser.ID,
+		Username:  domainUser.Username,
+		CreatedAt: domainUser.CreatedAt,
+	}
+}
+
+// ToDomainModel конвертирует модель репозитория пользователя в доменную модель
+func (c *UserConverter) ToDomainModel(repoUser *repoModel.UserDB) *model.User {
+	if repoUser == nil {
+		return nil
+	}
+
+	return &model.User{
+		ID:        repoUser.ID,
+		Username:  repoUser.Username,
+		CreatedAt: repoUser.CreatedAt,
+	}
+}
+
+// RevisionConverter отвечает за конвертацию ревизий комментариев
+type RevisionConverter struct{}
+
+// NewRevisionConverter создает новый экземпляр RevisionConverter
+func NewRevisionConverter() *RevisionConverter {
+	return &RevisionConverter{}
+}
+
+// ToDomainModel конвертирует модель репозитория ревизии в доменную модель
+func (c *RevisionConverter) ToDomainModel(repoRevision *repoModel.CommentRevisionDB) *model.CommentRevision {
+	if repoRevision == nil {
+		return nil
+	}
+
+	return &model.CommentRevision{
+		ID:        repoRevision.ID,
+		CommentID: repoRevision.CommentID,
+		Content:   repoRevision.Content,
+		CreatedAt: repoRevision.CreatedAt,
+	}
+}
+
+// ToDomainModels конвертирует слайс ревизий репозитория в слайс доменных моделей.
+// В отличие от других конвертеров возвращает значения, а не указатели,
+// так как ревизии неизменяемы и передаются списком целиком.
+func (c *RevisionConverter) ToDomainModels(repoRevisions []*repoModel.CommentRevisionDB) []model.CommentRevision {
+	revisions := make([]model.CommentRevision, 0, len(repoRevisions))
+	for _, repoRevision := range repoRevisions {
+		revisions = append(revisions, *c.ToDomainModel(repoRevision))
+	}
+
+	return revisions
+}
+
 // TreeConverter отвечает за конвертацию древовидных структур комментариев
 type TreeConverter struct {
 	commentConverter *CommentConverter
@@ -194,27 +286,38 @@ func (c *TreeConverter) ToPostWithComments(repoResult []*repoModel.PostWithComme
 	postConverter := NewPostConverter()
 	post := postConverter.ToDomainModel(&firstRow.PostDB)
 
-	// Собираем уникальные комментарии
-	commentMap := make(map[uuid.UUID]*model.Comment)
+	// Собираем уникальные комментарии в порядке строк выборки
+	seen := make(map[uuid.UUID]bool)
+	comments := make([]model.Comment, 0, len(repoResult))
 	for _, row := range repoResult {
-		if row.CommentID != nil {
+		if row.CommentID != nil && !seen[*row.CommentID] {
 			comment := &model.Comment{
 				ID:        *row.CommentID,
 				PostID:    *row.CommentPostID,
+				AuthorID:  row.CommentAuthorID,
 				ParentID:  row.CommentParentID,
 				Content:   *row.CommentContent,
 				CreatedAt: *row.CommentCreatedAt,
+				EditedAt:  row.CommentEditedAt,
+				DeletedAt: row.CommentDeletedAt,
+			}
+			if row.CommentReplyCount != nil {
+				comment.ReplyCount = *row.CommentReplyCount
+			}
+			if row.CommentDescendantCount != nil {
+				comment.DescendantCount = *row.CommentDescendantCount
 			}
-			commentMap[comment.ID] = comment
+			if row.CommentUpvotes != nil {
+				comment.Upvotes = *row.CommentUpvotes
+			}
+			if row.CommentDownvotes != nil {
+				comment.Downvotes = *row.CommentDownvotes
+			}
+			seen[comment.ID] = true
+			comments = append(comments, *comment)
 		}
 	}
 
-	// Конвертируем в слайс
-	comments := make([]model.Comment, 0, len(commentMap))
-	for _, comment := range commentMap {
-		comments = append(comments, *comment)
-	}
-
 	return &model.PostWithComments{
 		Post:     *post,
 		Comments: comments,
@@ -0,0 +1,758 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/google/uuid"
+)
+
+// Файлы каталога данных FileStorage
+const (
+	walFileName      = "wal.log"       // Журнал изменений после последнего снимка
+	snapshotFileName = "snapshot.json" // Последний снимок состояния
+)
+
+// DefaultSnapshotEvery - через сколько записей WAL FileStorage по умолчанию пишет снимок
+const DefaultSnapshotEvery = 1000
+
+// Операции, записываемые в WAL
+const (
+	walCreatePost         = "create_post"
+	walUpdatePost         = "update_post"
+	walDeletePost         = "delete_post"
+	walTogglePostComments = "toggle_post_comments"
+	walCreateComment      = "create_comment"
+	walDeleteComment      = "delete_comment"
+	walSoftDeleteComment  = "soft_delete_comment"
+	walEditComment        = "edit_comment"
+	walSetVote            = "set_vote"
+	walAddReaction        = "add_reaction"
+	walRemoveReaction     = "remove_reaction"
+	walCreateUser         = "create_user"
+	walTx                 = "tx" // Изменения транзакции WithTx, записанные одной записью
+)
+
+// ErrCorruptedWAL indicates that the write-ahead log cannot be replayed
+var ErrCorruptedWAL = errors.New("corrupted write-ahead log")
+
+var _ Storage = (*FileStorage)(nil)
+
+// FileOptions задает параметры FileStorage
+type FileOptions struct {
+	SnapshotEvery int // Через сколько записей WAL писать снимок; 0 - DefaultSnapshotEvery
+}
+
+// memoryStorage позволяет встроить MemoryStorage в FileStorage, не открывая доступ
+// к нему в обход журнала
+type memoryStorage = MemoryStorage
+
+// FileStorage - MemoryStorage, сохраняющий данные в каталоге на диске.
+//
+// Каждое успешное изменение дописывается в журнал wal.log и сбрасывается на диск
+// до возврата из метода. Каждые SnapshotEvery записей состояние целиком записывается
+// в snapshot.json, а журнал очищается. При открытии загружается снимок и
+// воспроизводится журнал; оборванная при сбое последняя запись отбрасывается.
+//
+// Чтение выполняется из памяти, как в MemoryStorage. Каталог данных может
+// использовать только один процесс.
+type FileStorage struct {
+	*memoryStorage
+
+	mu            sync.Mutex    // Сериализует изменения и запись в журнал
+	dir           string        // Каталог данных
+	snapshotEvery int           // Через сколько записей журнала писать снимок
+	wal           *os.File      // Открытый журнал; nil после Close
+	seq           uint64        // Номер последней записи журнала
+	sinceSnapshot int           // Записей журнала после последнего снимка
+	gen           *walGenerator // Генератор ID и времени изменений
+	err           error         // Ошибка записи журнала; после нее изменения запрещены
+	txRecords     *[]walRecord  // Внутри WithTx: изменения, которые запишутся при фиксации
+}
+
+// NewFileStorage открывает хранилище в каталоге dir, создавая его при необходимости,
+// и восстанавливает состояние из снимка и журнала.
+func NewFileStorage(dir string, opts FileOptions) (*FileStorage, error) {
+	if opts.SnapshotEvery <= 0 {
+		opts.SnapshotEvery = DefaultSnapshotEvery
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	s := &FileStorage{
+		memoryStorage: NewMemoryStorage(),
+		dir:           dir,
+		snapshotEvery: opts.SnapshotEvery,
+		gen:           &walGenerator{},
+	}
+	s.memoryStorage.newID = s.gen.newID
+	s.memoryStorage.now = s.gen.now
+
+	if err := s.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	s.wal = wal
+
+	if err := s.replay(); err != nil {
+		wal.Close()
+		return nil, err
+	}
+
+	// Длинный журнал сразу сворачиваем в снимок, чтобы следующий запуск был быстрее
+	if s.sinceSnapshot >= s.snapshotEvery {
+		if err := s.writeSnapshot(); err != nil {
+			wal.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Close записывает снимок и закрывает хранилище
+func (s *FileStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.wal == nil {
+		return nil
+	}
+
+	var err error
+	if s.err == nil && s.sinceSnapshot > 0 {
+		err = s.writeSnapshot()
+	}
+	if closeErr := s.wal.Close(); err == nil {
+		err = closeErr
+	}
+	s.wal = nil
+
+	if closeErr := s.memoryStorage.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// HealthCheck проверяет, что хранилище открыто и журнал доступен для записи
+func (s *FileStorage) HealthCheck(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return fmt.Errorf("%w: %v", ErrConnectionFailed, s.err)
+	}
+	return s.memoryStorage.HealthCheck(ctx)
+}
+
+// Snapshot записывает снимок состояния и очищает журнал
+func (s *FileStorage) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.wal == nil {
+		return ErrConnectionFailed
+	}
+	if s.err != nil {
+		return s.err
+	}
+	return s.writeSnapshot()
+}
+
+// WithTx выполняет fn под блокировкой изменений, как MemoryStorage.WithTx.
+// Изменения транзакции записываются в журнал одной записью при фиксации,
+// поэтому после сбоя восстанавливаются целиком или не восстанавливаются вовсе.
+func (s *FileStorage) WithTx(ctx context.Context, fn func(tx Storage) error) error {
+	if s.txRecords != nil {
+		// Вложенная транзакция попадает в журнал вместе с внешней
+		records, err := s.runTx(fn)
+		if err != nil {
+			return err
+		}
+		*s.txRecords = append(*s.txRecords, records...)
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.wal == nil {
+		return ErrConnectionFailed
+	}
+	if s.err != nil {
+		return fmt.Errorf("%w: %v", ErrConnectionFailed, s.err)
+	}
+
+	var committed bool
+	err := s.memoryStorage.withTx(func(tx *MemoryStorage) error {
+		var records []walRecord
+		if err := fn(s.txView(tx, &records)); err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+
+		// Запись журнала до переноса изменений в память: при ошибке они откатываются
+		if err := s.append(walRecord{Seq: s.seq + 1, Op: walTx, Records: records}); err != nil {
+			s.err = err
+			return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+		}
+		committed = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Ошибка снимка не теряет данные: журнал остается, попытка повторится со следующей записью
+	if committed && s.sinceSnapshot >= s.snapshotEvery {
+		_ = s.writeSnapshot()
+	}
+	return nil
+}
+
+// runTx выполняет fn в транзакции MemoryStorage и возвращает записи журнала ее изменений
+func (s *FileStorage) runTx(fn func(tx Storage) error) ([]walRecord, error) {
+	var records []walRecord
+	err := s.memoryStorage.withTx(func(tx *MemoryStorage) error {
+		return fn(s.txView(tx, &records))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// txView создает FileStorage транзакции: изменения выполняются через
+// представление tx с журналом отката и накапливаются в records вместо записи в журнал
+func (s *FileStorage) txView(tx *MemoryStorage, records *[]walRecord) *FileStorage {
+	return &FileStorage{
+		memoryStorage: tx,
+		dir:           s.dir,
+		snapshotEvery: s.snapshotEvery,
+		gen:           s.gen,
+		txRecords:     records,
+	}
+}
+
+// Операции с постами
+
+// CreatePost создает пост и записывает его в журнал
+func (s *FileStorage) CreatePost(ctx context.Context, post *model.Post) (*model.Post, error) {
+	result, err := s.mutate(ctx, walRecord{Op: walCreatePost, Post: post})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.Post), nil
+}
+
+// UpdatePost обновляет пост и записывает изменение в журнал
+func (s *FileStorage) UpdatePost(ctx context.Context, post *model.Post) (*model.Post, error) {
+	result, err := s.mutate(ctx, walRecord{Op: walUpdatePost, Post: post})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.Post), nil
+}
+
+// DeletePost удаляет пост с комментариями и записывает удаление в журнал
+func (s *FileStorage) DeletePost(ctx context.Context, id uuid.UUID) error {
+	_, err := s.mutate(ctx, walRecord{Op: walDeletePost, ID: id})
+	return err
+}
+
+// TogglePostComments включает/выключает комментарии и записывает изменение в журнал
+func (s *FileStorage) TogglePostComments(ctx context.Context, id uuid.UUID, enabled bool) error {
+	_, err := s.mutate(ctx, walRecord{Op: walTogglePostComments, ID: id, Enabled: enabled})
+	return err
+}
+
+// Операции с комментариями
+
+// CreateComment создает комментарий и записывает его в журнал
+func (s *FileStorage) CreateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	result, err := s.mutate(ctx, walRecord{Op: walCreateComment, Comment: comment})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.Comment), nil
+}
+
+// DeleteComment удаляет комментарий с ответами и записывает удаление в журнал
+func (s *FileStorage) DeleteComment(ctx context.Context, id uuid.UUID) error {
+	_, err := s.mutate(ctx, walRecord{Op: walDeleteComment, ID: id})
+	return err
+}
+
+// SoftDeleteComment превращает комментарий в надгробие и записывает удаление в журнал
+func (s *FileStorage) SoftDeleteComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	result, err := s.mutate(ctx, walRecord{Op: walSoftDeleteComment, ID: id})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.Comment), nil
+}
+
+// EditComment заменяет текст комментария и записывает изменение в журнал
+func (s *FileStorage) EditComment(ctx context.Context, id uuid.UUID, content string) (*model.Comment, error) {
+	result, err := s.mutate(ctx, walRecord{Op: walEditComment, ID: id, Content: content})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.Comment), nil
+}
+
+// Операции с голосами и реакциями
+
+// SetVote устанавливает голос и записывает его в журнал
+func (s *FileStorage) SetVote(ctx context.Context, userID, targetID uuid.UUID, value model.VoteValue) (*model.VoteTally, error) {
+	result, err := s.mutate(ctx, walRecord{Op: walSetVote, UserID: userID, ID: targetID, Vote: value})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.VoteTally), nil
+}
+
+// AddReaction добавляет реакцию и записывает ее в журнал
+func (s *FileStorage) AddReaction(ctx context.Context, userID, commentID uuid.UUID, emoji string) error {
+	_, err := s.mutate(ctx, walRecord{Op: walAddReaction, UserID: userID, ID: commentID, Emoji: emoji})
+	return err
+}
+
+// RemoveReaction снимает реакцию и записывает изменение в журнал
+func (s *FileStorage) RemoveReaction(ctx context.Context, userID, commentID uuid.UUID, emoji string) error {
+	_, err := s.mutate(ctx, walRecord{Op: walRemoveReaction, UserID: userID, ID: commentID, Emoji: emoji})
+	return err
+}
+
+// Операции с пользователями
+
+// CreateUser создает пользователя и записывает его в журнал
+func (s *FileStorage) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	result, err := s.mutate(ctx, walRecord{Op: walCreateUser, User: user})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.User), nil
+}
+
+// Журнал
+
+// walRecord - запись журнала: операция с аргументами и значения, сгенерированные при ее выполнении
+type walRecord struct {
+	Seq     uint64          `json:"seq"`
+	Op      string          `json:"op"`
+	Post    *model.Post     `json:"post,omitempty"`
+	Comment *model.Comment  `json:"comment,omitempty"`
+	User    *model.User     `json:"user,omitempty"`
+	ID      uuid.UUID       `json:"id"`                // Пост, комментарий или цель голоса
+	UserID  uuid.UUID       `json:"userId"`            // Пользователь голоса или реакции
+	Enabled bool            `json:"enabled,omitempty"` // Флаг для toggle_post_comments
+	Content string          `json:"content,omitempty"` // Новый текст для edit_comment
+	Vote    model.VoteValue `json:"vote,omitempty"`
+	Emoji   string          `json:"emoji,omitempty"`
+	IDs     []uuid.UUID     `json:"ids,omitempty"`     // Сгенерированные ID по порядку
+	Times   []time.Time     `json:"times,omitempty"`   // Полученные значения времени по порядку
+	Records []walRecord     `json:"records,omitempty"` // Изменения транзакции для tx
+}
+
+// walGenerator выдает MemoryStorage ID и время.
+// При выполнении изменения запоминает выданные значения, при воспроизведении -
+// выдает значения из записи журнала, чтобы состояние совпало с исходным.
+type walGenerator struct {
+	replay bool
+	ids    []uuid.UUID
+	times  []time.Time
+}
+
+// reset начинает новое изменение; rec != nil - воспроизведение записи
+func (g *walGenerator) reset(rec *walRecord) {
+	g.replay = rec != nil
+	g.ids, g.times = nil, nil
+	if rec != nil {
+		g.ids, g.times = rec.IDs, rec.Times
+	}
+}
+
+func (g *walGenerator) newID() uuid.UUID {
+	if g.replay && len(g.ids) > 0 {
+		id := g.ids[0]
+		g.ids = g.ids[1:]
+		return id
+	}
+	id := uuid.New()
+	g.ids = append(g.ids, id)
+	return id
+}
+
+func (g *walGenerator) now() time.Time {
+	if g.replay && len(g.times) > 0 {
+		now := g.times[0]
+		g.times = g.times[1:]
+		return now
+	}
+	now := time.Now()
+	g.times = append(g.times, now)
+	return now
+}
+
+// mutate выполняет изменение и записывает его в журнал.
+// Если запись не удалась, изменение остается только в памяти, поэтому
+// дальнейшие изменения запрещаются до перезапуска.
+func (s *FileStorage) mutate(ctx context.Context, rec walRecord) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.txRecords != nil {
+		// Внутри WithTx журнал пишет фиксация транзакции
+		s.gen.reset(nil)
+		result, err := s.apply(ctx, rec)
+		if err != nil {
+			return nil, err
+		}
+		rec.IDs, rec.Times = s.gen.ids, s.gen.times
+		*s.txRecords = append(*s.txRecords, rec)
+		return result, nil
+	}
+
+	if s.wal == nil {
+		return nil, ErrConnectionFailed
+	}
+	if s.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, s.err)
+	}
+
+	s.gen.reset(nil)
+	result, err := s.apply(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+
+	rec.Seq = s.seq + 1
+	rec.IDs, rec.Times = s.gen.ids, s.gen.times
+	if err := s.append(rec); err != nil {
+		s.err = err
+		return nil, err
+	}
+
+	// Ошибка снимка не теряет данные: журнал остается, попытка повторится со следующей записью
+	if s.sinceSnapshot >= s.snapshotEvery {
+		_ = s.writeSnapshot()
+	}
+
+	return result, nil
+}
+
+// apply выполняет операцию записи журнала над MemoryStorage
+func (s *FileStorage) apply(ctx context.Context, rec walRecord) (any, error) {
+	switch rec.Op {
+	case walCreatePost:
+		return s.memoryStorage.CreatePost(ctx, rec.Post)
+	case walUpdatePost:
+		return s.memoryStorage.UpdatePost(ctx, rec.Post)
+	case walDeletePost:
+		return nil, s.memoryStorage.DeletePost(ctx, rec.ID)
+	case walTogglePostComments:
+		return nil, s.memoryStorage.TogglePostComments(ctx, rec.ID, rec.Enabled)
+	case walCreateComment:
+		return s.memoryStorage.CreateComment(ctx, rec.Comment)
+	case walDeleteComment:
+		return nil, s.memoryStorage.DeleteComment(ctx, rec.ID)
+	case walSoftDeleteComment:
+		return s.memoryStorage.SoftDeleteComment(ctx, rec.ID)
+	case walEditComment:
+		return s.memoryStorage.EditComment(ctx, rec.ID, rec.Content)
+	case walSetVote:
+		return s.memoryStorage.SetVote(ctx, rec.UserID, rec.ID, rec.Vote)
+	case walAddReaction:
+		return nil, s.memoryStorage.AddReaction(ctx, rec.UserID, rec.ID, rec.Emoji)
+	case walRemoveReaction:
+		return nil, s.memoryStorage.RemoveReaction(ctx, rec.UserID, rec.ID, rec.Emoji)
+	case walCreateUser:
+		return s.memoryStorage.CreateUser(ctx, rec.User)
+	case walTx:
+		// Записи транзакции появляются только при воспроизведении журнала
+		for i := range rec.Records {
+			s.gen.reset(&rec.Records[i])
+			if _, err := s.apply(ctx, rec.Records[i]); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrCorruptedWAL, rec.Op)
+	}
+}
+
+// append дописывает запись в журнал и сбрасывает его на диск
+func (s *FileStorage) append(rec walRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := s.wal.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write write-ahead log: %w", err)
+	}
+	if err := s.wal.Sync(); err != nil {
+		return fmt.Errorf("failed to sync write-ahead log: %w", err)
+	}
+
+	s.seq = rec.Seq
+	s.sinceSnapshot++
+	return nil
+}
+
+// replay воспроизводит записи журнала, которых нет в снимке.
+// Оборванная последняя запись (сбой во время записи) отрезается.
+func (s *FileStorage) replay() error {
+	reader := bufio.NewReader(s.wal)
+	ctx := context.Background()
+
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				// Запись без перевода строки не была дописана до конца
+				if err := s.wal.Truncate(offset); err != nil {
+					return fmt.Errorf("failed to truncate write-ahead log: %w", err)
+				}
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read write-ahead log: %w", err)
+		}
+
+		var rec walRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("%w: record at offset %d: %v", ErrCorruptedWAL, offset, err)
+		}
+		offset += int64(len(line))
+
+		// Записи до снимка уже учтены в нем
+		if rec.Seq <= s.seq {
+			continue
+		}
+		if rec.Seq != s.seq+1 {
+			return fmt.Errorf("%w: expected record %d, got %d", ErrCorruptedWAL, s.seq+1, rec.Seq)
+		}
+
+		s.gen.reset(&rec)
+		if _, err := s.apply(ctx, rec); err != nil {
+			return fmt.Errorf("%w: record %d (%s): %v", ErrCorruptedWAL, rec.Seq, rec.Op, err)
+		}
+		s.seq = rec.Seq
+		s.sinceSnapshot++
+	}
+}
+
+// Снимки
+
+// fileSnapshot - содержимое snapshot.json
+type fileSnapshot struct {
+	Seq   uint64         `json:"seq"` // Последняя запись журнала, учтенная в снимке
+	State memorySnapshot `json:"state"`
+}
+
+// loadSnapshot загружает снимок, если он есть
+func (s *FileStorage) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snapshot fileSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	s.memoryStorage.restore(snapshot.State)
+	s.seq = snapshot.Seq
+	return nil
+}
+
+// writeSnapshot атомарно заменяет снимок текущим состоянием и очищает журнал.
+// Должно вызываться под мьютексом.
+func (s *FileStorage) writeSnapshot() error {
+	data, err := json.Marshal(fileSnapshot{Seq: s.seq, State: s.memoryStorage.snapshot()})
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dir, snapshotFileName)
+	tmp := path + ".tmp"
+	if err := writeFileSync(tmp, data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	if err := syncDir(s.dir); err != nil {
+		return fmt.Errorf("failed to sync data directory: %w", err)
+	}
+
+	// Если журнал не очистится, его записи будут пропущены при загрузке по seq
+	if err := s.wal.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate write-ahead log: %w", err)
+	}
+	s.sinceSnapshot = 0
+	return nil
+}
+
+// writeFileSync записывает файл и сбрасывает его на диск
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// syncDir сбрасывает на диск содержимое каталога, чтобы переименование пережило сбой
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
+
+// memorySnapshot - полное состояние MemoryStorage
+type memorySnapshot struct {
+	Posts     []*model.Post           `json:"posts"`
+	Comments  []*model.Comment        `json:"comments"`
+	Revisions []model.CommentRevision `json:"revisions"` // Ревизии каждого комментария от старых к новым
+	Users     []*model.User           `json:"users"`
+	Votes     []snapshotVote          `json:"votes"`
+	Reactions []snapshotReaction      `json:"reactions"` // Реакции каждого комментария в порядке добавления
+}
+
+// snapshotVote - голос пользователя в снимке
+type snapshotVote struct {
+	TargetID uuid.UUID `json:"targetId"`
+	UserID   uuid.UUID `json:"userId"`
+	Value    int       `json:"value"`
+}
+
+// snapshotReaction - реакция пользователя в снимке
+type snapshotReaction struct {
+	CommentID uuid.UUID `json:"commentId"`
+	UserID    uuid.UUID `json:"userId"`
+	Emoji     string    `json:"emoji"`
+}
+
+// snapshot копирует состояние хранилища
+func (s *MemoryStorage) snapshot() memorySnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := memorySnapshot{
+		Posts:     make([]*model.Post, 0, len(s.posts)),
+		Comments:  make([]*model.Comment, 0, len(s.comments)),
+		Revisions: []model.CommentRevision{},
+		Users:     make([]*model.User, 0, len(s.users)),
+		Votes:     []snapshotVote{},
+		Reactions: []snapshotReaction{},
+	}
+	for _, post := range s.posts {
+		snapshot.Posts = append(snapshot.Posts, post)
+	}
+	for _, comment := range s.comments {
+		snapshot.Comments = append(snapshot.Comments, comment)
+	}
+	for _, revisions := range s.revisions {
+		snapshot.Revisions = append(snapshot.Revisions, revisions...)
+	}
+	for _, user := range s.users {
+		snapshot.Users = append(snapshot.Users, user)
+	}
+	for targetID, votes := range s.votes {
+		for userID, value := range votes {
+			snapshot.Votes = append(snapshot.Votes, snapshotVote{TargetID: targetID, UserID: userID, Value: value})
+		}
+	}
+	for commentID, reactions := range s.reactions {
+		for _, reaction := range reactions {
+			snapshot.Reactions = append(snapshot.Reactions, snapshotReaction{
+				CommentID: commentID,
+				UserID:    reaction.userID,
+				Emoji:     reaction.emoji,
+			})
+		}
+	}
+
+	return snapshot
+}
+
+// restore заменяет состояние хранилища снимком
+func (s *MemoryStorage) restore(snapshot memorySnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.posts = make(map[uuid.UUID]*model.Post, len(snapshot.Posts))
+	for _, post := range snapshot.Posts {
+		s.posts[post.ID] = post
+	}
+
+	s.comments = make(map[uuid.UUID]*model.Comment, len(snapshot.Comments))
+	for _, comment := range snapshot.Comments {
+		s.comments[comment.ID] = comment
+	}
+
+	s.revisions = make(map[uuid.UUID][]model.CommentRevision)
+	for _, revision := range snapshot.Revisions {
+		s.revisions[revision.CommentID] = append(s.revisions[revision.CommentID], revision)
+	}
+
+	s.users = make(map[uuid.UUID]*model.User, len(snapshot.Users))
+	s.usernames = make(map[string]uuid.UUID, len(snapshot.Users))
+	for _, user := range snapshot.Users {
+		s.users[user.ID] = user
+		s.usernames[user.Username] = user.ID
+	}
+
+	s.votes = make(map[uuid.UUID]map[uuid.UUID]int)
+	for _, vote := range snapshot.Votes {
+		if s.votes[vote.TargetID] == nil {
+			s.votes[vote.TargetID] = make(map[uuid.UUID]int)
+		}
+		s.votes[vote.TargetID][vote.UserID] = vote.Value
+	}
+
+	s.reactions = make(map[uuid.UUID][]commentReaction)
+	for _, reaction := range snapshot.Reactions {
+		s.reactions[reaction.CommentID] = append(s.reactions[reaction.CommentID], commentReaction{
+			userID: reaction.UserID,
+			emoji:  reaction.Emoji,
+		})
+	}
+}
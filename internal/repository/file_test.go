@@ -0,0 +1,371 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/repository"
+	"github.com/google/uuid"
+)
+
+// fileStorageState - все данные хранилища, доступные через интерфейс Storage
+type fileStorageState struct {
+	Posts     []*model.Post
+	Comments  map[uuid.UUID][]model.Comment
+	Revisions map[uuid.UUID][]model.CommentRevision
+	Reactions map[uuid.UUID][]model.Reaction
+	Users     []*model.User
+}
+
+// populateStorage выполняет над хранилищем все виды изменений
+func populateStorage(t *testing.T, storage repository.Storage) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	alice, err := storage.CreateUser(ctx, &model.User{Username: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, _ := storage.CreateUser(ctx, &model.User{Username: "bob"})
+
+	post, err := storage.CreatePost(ctx, &model.Post{AuthorID: &alice.ID, Title: "Post", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	removed, _ := storage.CreatePost(ctx, &model.Post{Title: "Removed", Content: "Content"})
+	locked, _ := storage.CreatePost(ctx, &model.Post{Title: "Locked", Content: "Content"})
+
+	root, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, AuthorID: &bob.ID, Content: "Root"})
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	reply, _ := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: &root.ID, Content: "Reply"})
+	gone, _ := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: &reply.ID, Content: "Gone"})
+	storage.CreateComment(ctx, &model.Comment{PostID: removed.ID, Content: "Removed with post"})
+
+	check := func(step string, err error) {
+		if err != nil {
+			t.Fatalf("Failed to %s: %v", step, err)
+		}
+	}
+
+	_, err = storage.UpdatePost(ctx, &model.Post{ID: post.ID, Title: "Updated", Content: "New", CommentsEnabled: true})
+	check("update post", err)
+	check("toggle comments", storage.TogglePostComments(ctx, locked.ID, false))
+	check("delete post", storage.DeletePost(ctx, removed.ID))
+	_, err = storage.EditComment(ctx, root.ID, "Root v2")
+	check("edit comment", err)
+	_, err = storage.EditComment(ctx, root.ID, "Root v3")
+	check("edit comment", err)
+	_, err = storage.SoftDeleteComment(ctx, reply.ID)
+	check("soft delete comment", err)
+	check("delete comment", storage.DeleteComment(ctx, gone.ID))
+	_, err = storage.SetVote(ctx, alice.ID, post.ID, model.VoteUp)
+	check("vote for post", err)
+	_, err = storage.SetVote(ctx, bob.ID, root.ID, model.VoteDown)
+	check("vote for comment", err)
+	check("add reaction", storage.AddReaction(ctx, alice.ID, root.ID, "🎉"))
+	check("add reaction", storage.AddReaction(ctx, bob.ID, root.ID, "👍"))
+	check("add reaction", storage.AddReaction(ctx, bob.ID, root.ID, "🎉"))
+	check("remove reaction", storage.RemoveReaction(ctx, alice.ID, root.ID, "🎉"))
+
+	return []string{alice.Username, bob.Username}
+}
+
+// readState читает все данные хранилища
+func readState(t *testing.T, storage repository.Storage, usernames []string) fileStorageState {
+	t.Helper()
+	ctx := context.Background()
+
+	state := fileStorageState{
+		Comments:  make(map[uuid.UUID][]model.Comment),
+		Revisions: make(map[uuid.UUID][]model.CommentRevision),
+	}
+
+	posts, err := storage.GetPosts(ctx, 100, 0)
+	if err != nil {
+		t.Fatalf("Failed to get posts: %v", err)
+	}
+	state.Posts = posts
+
+	var commentIDs []uuid.UUID
+	for _, post := range posts {
+		comments, err := storage.GetCommentsByPostID(ctx, post.ID)
+		if err != nil {
+			t.Fatalf("Failed to get comments: %v", err)
+		}
+		state.Comments[post.ID] = comments
+		for _, comment := range comments {
+			revisions, err := storage.GetCommentRevisions(ctx, comment.ID)
+			if err != nil {
+				t.Fatalf("Failed to get revisions: %v", err)
+			}
+			state.Revisions[comment.ID] = revisions
+			commentIDs = append(commentIDs, comment.ID)
+		}
+	}
+
+	state.Reactions, err = storage.GetReactionsByCommentIDs(ctx, commentIDs, uuid.Nil)
+	if err != nil {
+		t.Fatalf("Failed to get reactions: %v", err)
+	}
+
+	for _, username := range usernames {
+		user, err := storage.GetUserByUsername(ctx, username)
+		if err != nil {
+			t.Fatalf("Failed to get user %s: %v", username, err)
+		}
+		state.Users = append(state.Users, user)
+	}
+
+	return state
+}
+
+// TestFileStorage_Reopen тестирует восстановление данных из журнала и снимков
+func TestFileStorage_Reopen(t *testing.T) {
+	tests := []struct {
+		name          string
+		snapshotEvery int
+		close         bool // Закрыть хранилище перед повторным открытием (иначе - имитация сбоя)
+	}{
+		{"только журнал", 1000, false},
+		{"снимок при закрытии", 1000, true},
+		{"снимки и журнал", 4, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			storage, err := repository.NewFileStorage(dir, repository.FileOptions{SnapshotEvery: tt.snapshotEvery})
+			if err != nil {
+				t.Fatalf("Failed to open storage: %v", err)
+			}
+			defer storage.Close()
+
+			usernames := populateStorage(t, storage)
+			want := readState(t, storage, usernames)
+			if tt.close {
+				if err := storage.Close(); err != nil {
+					t.Fatalf("Failed to close storage: %v", err)
+				}
+			}
+
+			reopened, err := repository.NewFileStorage(dir, repository.FileOptions{SnapshotEvery: tt.snapshotEvery})
+			if err != nil {
+				t.Fatalf("Failed to reopen storage: %v", err)
+			}
+			defer reopened.Close()
+
+			if got := readState(t, reopened, usernames); !reflect.DeepEqual(got, want) {
+				t.Errorf("State after reopen differs:\ngot  %+v\nwant %+v", got, want)
+			}
+
+			// Восстановленное хранилище продолжает журнал
+			post, err := reopened.CreatePost(context.Background(), &model.Post{Title: "After reopen", Content: "Content"})
+			if err != nil {
+				t.Fatalf("Failed to create post after reopen: %v", err)
+			}
+			reopened.Close()
+
+			again, err := repository.NewFileStorage(dir, repository.FileOptions{SnapshotEvery: tt.snapshotEvery})
+			if err != nil {
+				t.Fatalf("Failed to reopen storage again: %v", err)
+			}
+			defer again.Close()
+			if _, err := again.GetPost(context.Background(), post.ID); err != nil {
+				t.Errorf("Post created after reopen was lost: %v", err)
+			}
+		})
+	}
+}
+
+// TestFileStorage_Snapshot тестирует сворачивание журнала в снимок
+func TestFileStorage_Snapshot(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := repository.NewFileStorage(dir, repository.FileOptions{SnapshotEvery: 2})
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := storage.CreatePost(ctx, &model.Post{Title: "Post", Content: "Content"}); err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "snapshot.json")); err != nil {
+		t.Fatalf("Expected snapshot to be written: %v", err)
+	}
+	wal, err := os.ReadFile(filepath.Join(dir, "wal.log"))
+	if err != nil {
+		t.Fatalf("Failed to read WAL: %v", err)
+	}
+	if lines := bytes.Count(wal, []byte("\n")); lines != 1 {
+		t.Errorf("Expected one WAL record after snapshot, got %d", lines)
+	}
+
+	if err := storage.Snapshot(); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "wal.log")); info.Size() != 0 {
+		t.Errorf("Expected empty WAL after snapshot, got %d bytes", info.Size())
+	}
+}
+
+// TestFileStorage_Transaction тестирует запись транзакции в журнал одной записью
+func TestFileStorage_Transaction(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := repository.NewFileStorage(dir, repository.FileOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	var post *model.Post
+	var comment *model.Comment
+	err = storage.WithTx(ctx, func(tx repository.Storage) error {
+		var err error
+		if post, err = tx.CreatePost(ctx, &model.Post{Title: "Post", Content: "Content"}); err != nil {
+			return err
+		}
+		comment, err = tx.CreateComment(ctx, &model.Comment{PostID: post.ID, Content: "Comment"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+
+	// Откаченная транзакция в журнал не попадает
+	errRollback := errors.New("rollback")
+	err = storage.WithTx(ctx, func(tx repository.Storage) error {
+		if err := tx.TogglePostComments(ctx, post.ID, false); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Expected fn error, got %v", err)
+	}
+
+	wal, err := os.ReadFile(filepath.Join(dir, "wal.log"))
+	if err != nil {
+		t.Fatalf("Failed to read WAL: %v", err)
+	}
+	if lines := bytes.Count(wal, []byte("\n")); lines != 1 {
+		t.Errorf("Expected one WAL record for transaction, got %d", lines)
+	}
+
+	// Повторное открытие без Close имитирует сбой: транзакция восстанавливается из журнала
+	reopened, err := repository.NewFileStorage(dir, repository.FileOptions{})
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer reopened.Close()
+
+	got, err := reopened.GetComment(ctx, comment.ID)
+	if err != nil {
+		t.Fatalf("Comment created in transaction was lost: %v", err)
+	}
+	if !got.CreatedAt.Equal(comment.CreatedAt) {
+		t.Errorf("Expected created_at %v, got %v", comment.CreatedAt, got.CreatedAt)
+	}
+	restored, err := reopened.GetPost(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("Post created in transaction was lost: %v", err)
+	}
+	if !restored.CommentsEnabled || restored.CommentCount != 1 {
+		t.Errorf("Expected enabled post with 1 comment, got %+v", restored)
+	}
+}
+
+// TestFileStorage_DamagedWAL тестирует обработку поврежденного журнала
+func TestFileStorage_DamagedWAL(t *testing.T) {
+	ctx := context.Background()
+
+	prepare := func(t *testing.T) (string, uuid.UUID) {
+		dir := t.TempDir()
+		storage, err := repository.NewFileStorage(dir, repository.FileOptions{})
+		if err != nil {
+			t.Fatalf("Failed to open storage: %v", err)
+		}
+		post, err := storage.CreatePost(ctx, &model.Post{Title: "Post", Content: "Content"})
+		if err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+		if err := storage.TogglePostComments(ctx, post.ID, false); err != nil {
+			t.Fatalf("Failed to toggle comments: %v", err)
+		}
+		return dir, post.ID
+	}
+
+	t.Run("оборванная запись", func(t *testing.T) {
+		dir, postID := prepare(t)
+		appendToFile(t, filepath.Join(dir, "wal.log"), `{"seq":3,"op":"create_po`)
+
+		storage, err := repository.NewFileStorage(dir, repository.FileOptions{})
+		if err != nil {
+			t.Fatalf("Expected torn record to be dropped, got %v", err)
+		}
+		defer storage.Close()
+
+		post, err := storage.GetPost(ctx, postID)
+		if err != nil || post.CommentsEnabled {
+			t.Errorf("Expected records before torn one to be replayed, got %+v, %v", post, err)
+		}
+		if _, err := storage.CreatePost(ctx, &model.Post{Title: "Next", Content: "Content"}); err != nil {
+			t.Errorf("Failed to write after recovery: %v", err)
+		}
+	})
+
+	t.Run("поврежденная запись", func(t *testing.T) {
+		dir, _ := prepare(t)
+		appendToFile(t, filepath.Join(dir, "wal.log"), "garbage\n")
+
+		if _, err := repository.NewFileStorage(dir, repository.FileOptions{}); !errors.Is(err, repository.ErrCorruptedWAL) {
+			t.Errorf("Expected ErrCorruptedWAL, got %v", err)
+		}
+	})
+}
+
+// TestFileStorage_Closed тестирует операции после закрытия
+func TestFileStorage_Closed(t *testing.T) {
+	storage, err := repository.NewFileStorage(t.TempDir(), repository.FileOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := storage.CreatePost(ctx, &model.Post{Title: "Post", Content: "Content"}); !errors.Is(err, repository.ErrConnectionFailed) {
+		t.Errorf("Expected ErrConnectionFailed, got %v", err)
+	}
+	if err := storage.HealthCheck(ctx); err == nil {
+		t.Error("Expected health check to fail after close")
+	}
+	if err := storage.Close(); err != nil {
+		t.Errorf("Second close should succeed, got %v", err)
+	}
+}
+
+func appendToFile(t *testing.T, path, data string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -22,19 +24,35 @@ import (
 // - Сортировка постов по времени создания (новые первыми)
 // - Каскадное удаление комментариев при удалении поста
 type MemoryStorage struct {
-	mu       sync.RWMutex                 // Мьютекс для thread-safe операций
-	posts    map[uuid.UUID]*model.Post    // Хранилище постов
-	comments map[uuid.UUID]*model.Comment // Хранилище комментариев
-	closed   bool                         // Флаг закрытия хранилища
+	mu        sync.RWMutex                          // Мьютекс для thread-safe операций
+	posts     map[uuid.UUID]*model.Post             // Хранилище постов
+	comments  map[uuid.UUID]*model.Comment          // Хранилище комментариев
+	revisions map[uuid.UUID][]model.CommentRevision // Ревизии комментариев: commentID -> версии от старых к новым
+	users     map[uuid.UUID]*model.User             // Хранилище пользователей
+	usernames map[string]uuid.UUID                  // Индекс уникальности имен: username -> userID
+	votes     map[uuid.UUID]map[uuid.UUID]int       // Голоса: targetID -> userID -> 1 или -1
+	reactions map[uuid.UUID][]commentReaction       // Реакции: commentID -> реакции в порядке добавления
+	closed    bool                                  // Флаг закрытия хранилища
+	undo      *memoryUndo                           // Журнал отката транзакции; nil вне транзакции
+
+	newID func() uuid.UUID // Генератор ID новых записей
+	now   func() time.Time // Источник текущего времени
 }
 
 // NewMemoryStorage создает новый экземпляр in-memory хранилища.
 // Инициализирует внутренние структуры данных и готов к использованию.
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		posts:    make(map[uuid.UUID]*model.Post),
-		comments: make(map[uuid.UUID]*model.Comment),
-		closed:   false,
+		posts:     make(map[uuid.UUID]*model.Post),
+		comments:  make(map[uuid.UUID]*model.Comment),
+		revisions: make(map[uuid.UUID][]model.CommentRevision),
+		users:     make(map[uuid.UUID]*model.User),
+		usernames: make(map[string]uuid.UUID),
+		votes:     make(map[uuid.UUID]map[uuid.UUID]int),
+		reactions: make(map[uuid.UUID][]commentReaction),
+		closed:    false,
+		newID:     uuid.New,
+		now:       time.Now,
 	}
 }
 
@@ -51,6 +69,11 @@ func (s *MemoryStorage) Close() error {
 	// Очищаем данные
 	s.posts = nil
 	s.comments = nil
+	s.revisions = nil
+	s.users = nil
+	s.usernames = nil
+	s.votes = nil
+	s.reactions = nil
 	s.closed = true
 
 	return nil
@@ -78,6 +101,196 @@ func (s *MemoryStorage) checkClosed() error {
 	return nil
 }
 
+// Транзакции
+
+// WithTx выполняет fn под эксклюзивной блокировкой хранилища.
+// fn изменяет данные на месте, а журнал отката хранит исходные версии
+// затронутых записей, поэтому ошибка fn откатывает все изменения.
+// Обращение к исходному хранилищу внутри fn заблокируется до завершения транзакции.
+func (s *MemoryStorage) WithTx(ctx context.Context, fn func(tx Storage) error) error {
+	return s.withTx(func(tx *MemoryStorage) error {
+		return fn(tx)
+	})
+}
+
+// withTx выполняет fn над представлением хранилища с журналом отката.
+// Если fn возвращает ошибку или паникует, затронутые записи восстанавливаются.
+// После возврата представление закрывается, и дальнейшие операции с ним возвращают ErrConnectionFailed.
+func (s *MemoryStorage) withTx(fn func(tx *MemoryStorage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkClosed(); err != nil {
+		return err
+	}
+
+	tx := s.txView()
+	committed := false
+	defer func() {
+		if !committed {
+			tx.undo.rollback(s)
+		}
+		tx.Close()
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	committed = true
+	return nil
+}
+
+// txView создает представление хранилища для транзакции: те же карты
+// с собственным мьютексом и журналом отката. Журнал вложенной транзакции
+// дублирует записи во внешний, чтобы ее изменения откатывались вместе с внешней.
+// Должно вызываться под мьютексом.
+func (s *MemoryStorage) txView() *MemoryStorage {
+	return &MemoryStorage{
+		posts:     s.posts,
+		comments:  s.comments,
+		revisions: s.revisions,
+		users:     s.users,
+		usernames: s.usernames,
+		votes:     s.votes,
+		reactions: s.reactions,
+		undo:      newMemoryUndo(s.undo),
+		newID:     s.newID,
+		now:       s.now,
+	}
+}
+
+// undoEntry - исходное значение ключа карты; ok = false, если ключа не было
+type undoEntry[V any] struct {
+	value V
+	ok    bool
+}
+
+// undoMap хранит исходные значения ключей одной карты, измененных в транзакции
+type undoMap[K comparable, V any] map[K]undoEntry[V]
+
+// save запоминает копию текущего значения key, если оно еще не сохранено
+func (u undoMap[K, V]) save(m map[K]V, key K, clone func(V) V) {
+	if _, saved := u[key]; saved {
+		return
+	}
+	value, ok := m[key]
+	if ok {
+		value = clone(value)
+	}
+	u[key] = undoEntry[V]{value: value, ok: ok}
+}
+
+// restore возвращает сохраненные значения в m
+func (u undoMap[K, V]) restore(m map[K]V) {
+	for key, entry := range u {
+		if entry.ok {
+			m[key] = entry.value
+		} else {
+			delete(m, key)
+		}
+	}
+}
+
+// memoryUndo - журнал отката транзакции MemoryStorage.
+// Копируются только записи, которые транзакция изменяет, а не все хранилище.
+type memoryUndo struct {
+	parent *memoryUndo // Журнал внешней транзакции
+
+	posts     undoMap[uuid.UUID, *model.Post]
+	comments  undoMap[uuid.UUID, *model.Comment]
+	revisions undoMap[uuid.UUID, []model.CommentRevision]
+	users     undoMap[uuid.UUID, *model.User]
+	usernames undoMap[string, uuid.UUID]
+	votes     undoMap[uuid.UUID, map[uuid.UUID]int]
+	reactions undoMap[uuid.UUID, []commentReaction]
+}
+
+func newMemoryUndo(parent *memoryUndo) *memoryUndo {
+	return &memoryUndo{
+		parent:    parent,
+		posts:     make(undoMap[uuid.UUID, *model.Post]),
+		comments:  make(undoMap[uuid.UUID, *model.Comment]),
+		revisions: make(undoMap[uuid.UUID, []model.CommentRevision]),
+		users:     make(undoMap[uuid.UUID, *model.User]),
+		usernames: make(undoMap[string, uuid.UUID]),
+		votes:     make(undoMap[uuid.UUID, map[uuid.UUID]int]),
+		reactions: make(undoMap[uuid.UUID, []commentReaction]),
+	}
+}
+
+// rollback восстанавливает в s исходные версии измененных записей
+func (u *memoryUndo) rollback(s *MemoryStorage) {
+	u.posts.restore(s.posts)
+	u.comments.restore(s.comments)
+	u.revisions.restore(s.revisions)
+	u.users.restore(s.users)
+	u.usernames.restore(s.usernames)
+	u.votes.restore(s.votes)
+	u.reactions.restore(s.reactions)
+}
+
+// cloneCommentPtr копирует комментарий для журнала отката: комментарии изменяются на месте
+func cloneCommentPtr(comment *model.Comment) *model.Comment {
+	copied := copyComment(comment)
+	return &copied
+}
+
+// keep возвращает значение без копирования: пользователи и имена не изменяются после создания
+func keep[V any](value V) V { return value }
+
+// savePost запоминает исходную версию поста перед изменением в транзакции.
+// Методы save* ничего не делают вне транзакции и должны вызываться под мьютексом.
+func (s *MemoryStorage) savePost(id uuid.UUID) {
+	for u := s.undo; u != nil; u = u.parent {
+		u.posts.save(s.posts, id, copyPost)
+	}
+}
+
+// saveComment запоминает исходную версию комментария перед изменением в транзакции
+func (s *MemoryStorage) saveComment(id uuid.UUID) {
+	for u := s.undo; u != nil; u = u.parent {
+		u.comments.save(s.comments, id, cloneCommentPtr)
+	}
+}
+
+// saveRevisions запоминает исходные ревизии комментария
+func (s *MemoryStorage) saveRevisions(id uuid.UUID) {
+	for u := s.undo; u != nil; u = u.parent {
+		u.revisions.save(s.revisions, id, slices.Clone[[]model.CommentRevision])
+	}
+}
+
+// saveVotes запоминает исходные голоса за пост или комментарий
+func (s *MemoryStorage) saveVotes(targetID uuid.UUID) {
+	for u := s.undo; u != nil; u = u.parent {
+		u.votes.save(s.votes, targetID, maps.Clone[map[uuid.UUID]int])
+	}
+}
+
+// saveReactions запоминает исходные реакции на комментарий
+func (s *MemoryStorage) saveReactions(commentID uuid.UUID) {
+	for u := s.undo; u != nil; u = u.parent {
+		u.reactions.save(s.reactions, commentID, slices.Clone[[]commentReaction])
+	}
+}
+
+// saveUser запоминает отсутствие пользователя и его имени перед созданием
+func (s *MemoryStorage) saveUser(id uuid.UUID, username string) {
+	for u := s.undo; u != nil; u = u.parent {
+		u.users.save(s.users, id, keep[*model.User])
+		u.usernames.save(s.usernames, username, keep[uuid.UUID])
+	}
+}
+
+// saveCommentData запоминает комментарий вместе с его ревизиями, голосами и реакциями перед удалением
+func (s *MemoryStorage) saveCommentData(id uuid.UUID) {
+	s.saveComment(id)
+	s.saveRevisions(id)
+	s.saveVotes(id)
+	s.saveReactions(id)
+}
+
 // Операции с постами
 
 // CreatePost создает новый пост в памяти.
@@ -97,9 +310,12 @@ func (s *MemoryStorage) CreatePost(ctx context.Context, post *model.Post) (*mode
 
 	// Создаем копию для безопасности
 	newPost := &model.Post{
+		ID:              post.ID,
+		AuthorID:        post.AuthorID,
 		Title:           post.Title,
 		Content:         post.Content,
 		CommentsEnabled: true, // По умолчанию комментарии включены
+		CreatedAt:       post.CreatedAt,
 	}
 
 	// Если в переданном посте уже установлен флаг, используем его
@@ -109,12 +325,12 @@ func (s *MemoryStorage) CreatePost(ctx context.Context, post *model.Post) (*mode
 
 	// Генерируем ID если не задан
 	if newPost.ID == uuid.Nil {
-		newPost.ID = uuid.New()
+		newPost.ID = s.newID()
 	}
 
 	// Устанавливаем время создания если не задано
 	if newPost.CreatedAt.IsZero() {
-		newPost.CreatedAt = time.Now().UTC()
+		newPost.CreatedAt = s.now().UTC()
 	}
 
 	// Валидируем бизнес-правила
@@ -122,22 +338,24 @@ func (s *MemoryStorage) CreatePost(ctx context.Context, post *model.Post) (*mode
 		return nil, ErrInvalidInput
 	}
 
+	// Проверяем, что автор существует
+	if newPost.AuthorID != nil {
+		if _, exists := s.users[*newPost.AuthorID]; !exists {
+			return nil, ErrUnknownAuthor
+		}
+	}
+
 	// Проверяем уникальность ID
 	if _, exists := s.posts[newPost.ID]; exists {
 		return nil, ErrDuplicate
 	}
 
 	// Сохраняем пост
+	s.savePost(newPost.ID)
 	s.posts[newPost.ID] = newPost
 
 	// Возвращаем копию
-	return &model.Post{
-		ID:              newPost.ID,
-		Title:           newPost.Title,
-		Content:         newPost.Content,
-		CommentsEnabled: newPost.CommentsEnabled,
-		CreatedAt:       newPost.CreatedAt,
-	}, nil
+	return copyPost(newPost), nil
 }
 
 // GetPost получает пост по ID из памяти.
@@ -155,13 +373,7 @@ func (s *MemoryStorage) GetPost(ctx context.Context, id uuid.UUID) (*model.Post,
 	}
 
 	// Возвращаем копию для безопасности
-	return &model.Post{
-		ID:              post.ID,
-		Title:           post.Title,
-		Content:         post.Content,
-		CommentsEnabled: post.CommentsEnabled,
-		CreatedAt:       post.CreatedAt,
-	}, nil
+	return copyPost(post), nil
 }
 
 // GetPosts получает список постов с пагинацией.
@@ -190,7 +402,7 @@ func (s *MemoryStorage) GetPosts(ctx context.Context, limit, offset int) ([]*mod
 
 	// Сортируем по времени создания (новые первыми)
 	sort.Slice(posts, func(i, j int) bool {
-		return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		return posts[j].Cursor().Less(posts[i].Cursor())
 	})
 
 	// Применяем пагинацию
@@ -208,13 +420,45 @@ func (s *MemoryStorage) GetPosts(ctx context.Context, limit, offset int) ([]*mod
 	result := make([]*model.Post, 0, end-start)
 	for i := start; i < end; i++ {
 		post := posts[i]
-		result = append(result, &model.Post{
-			ID:              post.ID,
-			Title:           post.Title,
-			Content:         post.Content,
-			CommentsEnabled: post.CommentsEnabled,
-			CreatedAt:       post.CreatedAt,
-		})
+		result = append(result, copyPost(post))
+	}
+
+	return result, nil
+}
+
+// GetPostsAfter получает страницу постов после заданной позиции (от новых к старым).
+func (s *MemoryStorage) GetPostsAfter(ctx context.Context, limit int, after *model.Cursor) ([]*model.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	// Собираем посты строго старше позиции after
+	posts := make([]*model.Post, 0, len(s.posts))
+	for _, post := range s.posts {
+		if after == nil || post.Cursor().Less(*after) {
+			posts = append(posts, post)
+		}
+	}
+
+	// Сортируем от новых к старым
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[j].Cursor().Less(posts[i].Cursor())
+	})
+
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+
+	result := make([]*model.Post, 0, len(posts))
+	for _, post := range posts {
+		result = append(result, copyPost(post))
 	}
 
 	return result, nil
@@ -244,25 +488,24 @@ func (s *MemoryStorage) UpdatePost(ctx context.Context, post *model.Post) (*mode
 		return nil, ErrInvalidInput
 	}
 
-	// Обновляем пост (сохраняем время создания)
+	// Обновляем пост (сохраняем автора и время создания)
 	updatedPost := &model.Post{
 		ID:              post.ID,
+		AuthorID:        existing.AuthorID, // Автор поста не меняется
 		Title:           post.Title,
 		Content:         post.Content,
 		CommentsEnabled: post.CommentsEnabled,
 		CreatedAt:       existing.CreatedAt, // Сохраняем оригинальное время
+		CommentCount:    existing.CommentCount,
+		Upvotes:         existing.Upvotes,
+		Downvotes:       existing.Downvotes,
 	}
 
+	s.savePost(post.ID)
 	s.posts[post.ID] = updatedPost
 
 	// Возвращаем копию
-	return &model.Post{
-		ID:              updatedPost.ID,
-		Title:           updatedPost.Title,
-		Content:         updatedPost.Content,
-		CommentsEnabled: updatedPost.CommentsEnabled,
-		CreatedAt:       updatedPost.CreatedAt,
-	}, nil
+	return copyPost(updatedPost), nil
 }
 
 // DeletePost удаляет пост и все связанные комментарии.
@@ -279,13 +522,20 @@ func (s *MemoryStorage) DeletePost(ctx context.Context, id uuid.UUID) error {
 		return ErrNotFound
 	}
 
-	// Удаляем пост
+	// Удаляем пост и голоса за него
+	s.savePost(id)
+	s.saveVotes(id)
 	delete(s.posts, id)
+	delete(s.votes, id)
 
 	// Удаляем все комментарии к посту (каскадное удаление)
 	for commentID, comment := range s.comments {
 		if comment.PostID == id {
+			s.saveCommentData(commentID)
 			delete(s.comments, commentID)
+			delete(s.revisions, commentID)
+			delete(s.votes, commentID)
+			delete(s.reactions, commentID)
 		}
 	}
 
@@ -308,6 +558,7 @@ func (s *MemoryStorage) TogglePostComments(ctx context.Context, id uuid.UUID, en
 	}
 
 	// Обновляем флаг комментариев
+	s.savePost(id)
 	post.CommentsEnabled = enabled
 
 	return nil
@@ -331,7 +582,7 @@ func (s *MemoryStorage) CreateComment(ctx context.Context, comment *model.Commen
 	// Проверяем, что пост существует
 	post, exists := s.posts[comment.PostID]
 	if !exists {
-		return nil, fmt.Errorf("post not found")
+		return nil, ErrNotFound
 	}
 
 	// Проверяем, что комментарии разрешены
@@ -343,29 +594,36 @@ func (s *MemoryStorage) CreateComment(ctx context.Context, comment *model.Commen
 	if comment.ParentID != nil {
 		parentComment, exists := s.comments[*comment.ParentID]
 		if !exists {
-			return nil, fmt.Errorf("parent comment not found")
+			return nil, ErrNotFound
 		}
 		// Проверяем, что родительский комментарий относится к тому же посту
 		if parentComment.PostID != comment.PostID {
 			return nil, fmt.Errorf("parent comment belongs to different post")
 		}
+		// На удаленный комментарий отвечать нельзя
+		if parentComment.IsDeleted() {
+			return nil, ErrCommentDeleted
+		}
 	}
 
 	// Создаем копию комментария
 	newComment := &model.Comment{
-		PostID:   comment.PostID,
-		ParentID: comment.ParentID,
-		Content:  comment.Content,
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		AuthorID:  comment.AuthorID,
+		ParentID:  comment.ParentID,
+		Content:   comment.Content,
+		CreatedAt: comment.CreatedAt,
 	}
 
 	// Генерируем ID если не задан
 	if newComment.ID == uuid.Nil {
-		newComment.ID = uuid.New()
+		newComment.ID = s.newID()
 	}
 
 	// Устанавливаем время создания если не задано
 	if newComment.CreatedAt.IsZero() {
-		newComment.CreatedAt = time.Now().UTC()
+		newComment.CreatedAt = s.now().UTC()
 	}
 
 	// Валидируем бизнес-правила
@@ -373,22 +631,26 @@ func (s *MemoryStorage) CreateComment(ctx context.Context, comment *model.Commen
 		return nil, ErrInvalidInput
 	}
 
+	// Проверяем, что автор существует
+	if newComment.AuthorID != nil {
+		if _, exists := s.users[*newComment.AuthorID]; !exists {
+			return nil, ErrUnknownAuthor
+		}
+	}
+
 	// Проверяем уникальность ID
 	if _, exists := s.comments[newComment.ID]; exists {
 		return nil, ErrDuplicate
 	}
 
-	// Сохраняем комментарий
+	// Сохраняем комментарий и обновляем счетчики
+	s.saveComment(newComment.ID)
 	s.comments[newComment.ID] = newComment
+	s.adjustCommentCounters(newComment, 1, 1)
 
 	// Возвращаем копию
-	return &model.Comment{
-		ID:        newComment.ID,
-		PostID:    newComment.PostID,
-		ParentID:  newComment.ParentID,
-		Content:   newComment.Content,
-		CreatedAt: newComment.CreatedAt,
-	}, nil
+	result := copyComment(newComment)
+	return &result, nil
 }
 
 // GetComment получает комментарий по ID.
@@ -406,13 +668,8 @@ func (s *MemoryStorage) GetComment(ctx context.Context, id uuid.UUID) (*model.Co
 	}
 
 	// Возвращаем копию
-	return &model.Comment{
-		ID:        comment.ID,
-		PostID:    comment.PostID,
-		ParentID:  comment.ParentID,
-		Content:   comment.Content,
-		CreatedAt: comment.CreatedAt,
-	}, nil
+	result := copyComment(comment)
+	return &result, nil
 }
 
 // GetCommentsByPostID получает все комментарии для поста.
@@ -427,34 +684,26 @@ func (s *MemoryStorage) GetCommentsByPostID(ctx context.Context, postID uuid.UUI
 
 	// Проверяем, что пост существует
 	if _, exists := s.posts[postID]; !exists {
-		return nil, fmt.Errorf("post not found")
+		return nil, ErrNotFound
 	}
 
 	// Собираем комментарии для поста
 	var comments []model.Comment
 	for _, comment := range s.comments {
 		if comment.PostID == postID {
-			comments = append(comments, model.Comment{
-				ID:        comment.ID,
-				PostID:    comment.PostID,
-				ParentID:  comment.ParentID,
-				Content:   comment.Content,
-				CreatedAt: comment.CreatedAt,
-			})
+			comments = append(comments, copyComment(comment))
 		}
 	}
 
 	// Сортируем по времени создания
-	sort.Slice(comments, func(i, j int) bool {
-		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
-	})
+	sortCommentsByCursor(comments)
 
 	return comments, nil
 }
 
 // GetCommentsByParentID получает дочерние комментарии с пагинацией
 // ПРОИЗВОДИТЕЛЬНОСТЬ: Решает N+1 проблему в GraphQL children резолвере
-func (s *MemoryStorage) GetCommentsByParentID(ctx context.Context, parentID uuid.UUID, limit, offset int) ([]model.Comment, error) {
+func (s *MemoryStorage) GetCommentsByParentID(ctx context.Context, parentID uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -473,21 +722,13 @@ func (s *MemoryStorage) GetCommentsByParentID(ctx context.Context, parentID uuid
 	// Собираем дочерние комментарии
 	var children []model.Comment
 	for _, comment := range s.comments {
-		if comment.ParentID != nil && *comment.ParentID == parentID {
-			children = append(children, model.Comment{
-				ID:        comment.ID,
-				PostID:    comment.PostID,
-				ParentID:  comment.ParentID,
-				Content:   comment.Content,
-				CreatedAt: comment.CreatedAt,
-			})
+		if comment.ParentID != nil && *comment.ParentID == parentID && s.isVisible(comment) {
+			children = append(children, copyComment(comment))
 		}
 	}
 
-	// Сортируем по времени создания
-	sort.Slice(children, func(i, j int) bool {
-		return children[i].CreatedAt.Before(children[j].CreatedAt)
-	})
+	// Сортируем в запрошенном порядке
+	sortComments(children, sort)
 
 	// Применяем пагинацию
 	start := offset
@@ -505,7 +746,7 @@ func (s *MemoryStorage) GetCommentsByParentID(ctx context.Context, parentID uuid
 
 // GetRootCommentsByPostID получает только корневые комментарии с пагинацией
 // ПРОИЗВОДИТЕЛЬНОСТЬ: Избегаем загрузки всех комментариев сразу
-func (s *MemoryStorage) GetRootCommentsByPostID(ctx context.Context, postID uuid.UUID, limit, offset int) ([]model.Comment, error) {
+func (s *MemoryStorage) GetRootCommentsByPostID(ctx context.Context, postID uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -515,7 +756,7 @@ func (s *MemoryStorage) GetRootCommentsByPostID(ctx context.Context, postID uuid
 
 	// Проверяем, что пост существует
 	if _, exists := s.posts[postID]; !exists {
-		return nil, fmt.Errorf("post not found")
+		return nil, ErrNotFound
 	}
 
 	// Валидируем параметры пагинации
@@ -529,21 +770,13 @@ func (s *MemoryStorage) GetRootCommentsByPostID(ctx context.Context, postID uuid
 	// Собираем только корневые комментарии для поста
 	var rootComments []model.Comment
 	for _, comment := range s.comments {
-		if comment.PostID == postID && comment.ParentID == nil {
-			rootComments = append(rootComments, model.Comment{
-				ID:        comment.ID,
-				PostID:    comment.PostID,
-				ParentID:  comment.ParentID,
-				Content:   comment.Content,
-				CreatedAt: comment.CreatedAt,
-			})
+		if comment.PostID == postID && comment.ParentID == nil && s.isVisible(comment) {
+			rootComments = append(rootComments, copyComment(comment))
 		}
 	}
 
-	// Сортируем по времени создания
-	sort.Slice(rootComments, func(i, j int) bool {
-		return rootComments[i].CreatedAt.Before(rootComments[j].CreatedAt)
-	})
+	// Сортируем в запрошенном порядке
+	sortComments(rootComments, sort)
 
 	// Применяем пагинацию
 	start := offset
@@ -559,6 +792,194 @@ func (s *MemoryStorage) GetRootCommentsByPostID(ctx context.Context, postID uuid
 	return rootComments[start:end], nil
 }
 
+// GetCommentsByParentIDs получает дочерние комментарии для нескольких родителей
+// с отдельным окном пагинации для каждого родителя.
+func (s *MemoryStorage) GetCommentsByParentIDs(ctx context.Context, parentIDs []uuid.UUID, sort model.CommentSort, limit, offset int) (map[uuid.UUID][]model.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[uuid.UUID]bool, len(parentIDs))
+	for _, id := range parentIDs {
+		wanted[id] = true
+	}
+
+	groups := make(map[uuid.UUID][]model.Comment)
+	for _, comment := range s.comments {
+		if comment.ParentID != nil && wanted[*comment.ParentID] && s.isVisible(comment) {
+			groups[*comment.ParentID] = append(groups[*comment.ParentID], copyComment(comment))
+		}
+	}
+
+	return pageCommentGroups(groups, sort, limit, offset), nil
+}
+
+// GetRootCommentsByPostIDs получает корневые комментарии для нескольких постов
+// с отдельным окном пагинации для каждого поста.
+func (s *MemoryStorage) GetRootCommentsByPostIDs(ctx context.Context, postIDs []uuid.UUID, sort model.CommentSort, limit, offset int) (map[uuid.UUID][]model.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[uuid.UUID]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+
+	groups := make(map[uuid.UUID][]model.Comment)
+	for _, comment := range s.comments {
+		if comment.ParentID == nil && wanted[comment.PostID] && s.isVisible(comment) {
+			groups[comment.PostID] = append(groups[comment.PostID], copyComment(comment))
+		}
+	}
+
+	return pageCommentGroups(groups, sort, limit, offset), nil
+}
+
+// pageCommentGroups сортирует каждую группу комментариев в порядке sort
+// и применяет к ней окно limit/offset. Пустые после пагинации группы удаляются.
+func pageCommentGroups(groups map[uuid.UUID][]model.Comment, sort model.CommentSort, limit, offset int) map[uuid.UUID][]model.Comment {
+	if limit <= 0 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	for id, comments := range groups {
+		if offset >= len(comments) {
+			delete(groups, id)
+			continue
+		}
+
+		sortComments(comments, sort)
+
+		end := offset + limit
+		if end > len(comments) {
+			end = len(comments)
+		}
+		groups[id] = comments[offset:end]
+	}
+
+	return groups
+}
+
+// GetCommentsByParentIDAfter получает страницу дочерних комментариев после заданной позиции.
+func (s *MemoryStorage) GetCommentsByParentIDAfter(ctx context.Context, parentID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	return s.commentsPageAfter(func(comment *model.Comment) bool {
+		return comment.ParentID != nil && *comment.ParentID == parentID
+	}, limit, after), nil
+}
+
+// GetRootCommentsAfter получает страницу корневых комментариев поста после заданной позиции.
+func (s *MemoryStorage) GetRootCommentsAfter(ctx context.Context, postID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	// Проверяем, что пост существует
+	if _, exists := s.posts[postID]; !exists {
+		return nil, ErrNotFound
+	}
+
+	return s.commentsPageAfter(func(comment *model.Comment) bool {
+		return comment.PostID == postID && comment.ParentID == nil
+	}, limit, after), nil
+}
+
+// GetCommentsByPostIDAfter получает неудаленные комментарии поста после заданной позиции
+func (s *MemoryStorage) GetCommentsByPostIDAfter(ctx context.Context, postID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	// Проверяем, что пост существует
+	if _, exists := s.posts[postID]; !exists {
+		return nil, ErrNotFound
+	}
+
+	return s.commentsPageAfter(func(comment *model.Comment) bool {
+		return comment.PostID == postID && !comment.IsDeleted()
+	}, limit, after), nil
+}
+
+// commentsPageAfter выбирает видимые комментарии, подходящие под match,
+// строго после позиции after, и возвращает первые limit из них.
+// Должно вызываться под мьютексом.
+func (s *MemoryStorage) commentsPageAfter(match func(*model.Comment) bool, limit int, after *model.Cursor) []model.Comment {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var page []model.Comment
+	for _, comment := range s.comments {
+		if !match(comment) || !s.isVisible(comment) {
+			continue
+		}
+		if after != nil && !after.Less(comment.Cursor()) {
+			continue
+		}
+		page = append(page, copyComment(comment))
+	}
+
+	sortCommentsByCursor(page)
+
+	if len(page) > limit {
+		page = page[:limit]
+	}
+	if page == nil {
+		page = []model.Comment{}
+	}
+
+	return page
+}
+
+// GetCommentAncestorIDs проходит по цепочке родителей комментария до корня.
+func (s *MemoryStorage) GetCommentAncestorIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	comment, exists := s.comments[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	ancestors := []uuid.UUID{}
+	for parentID := comment.ParentID; parentID != nil; {
+		ancestors = append(ancestors, *parentID)
+		parent, exists := s.comments[*parentID]
+		if !exists {
+			break
+		}
+		parentID = parent.ParentID
+	}
+
+	return ancestors, nil
+}
+
 // DeleteComment удаляет комментарий и все дочерние комментарии.
 func (s *MemoryStorage) DeleteComment(ctx context.Context, id uuid.UUID) error {
 	s.mu.Lock()
@@ -569,16 +990,149 @@ func (s *MemoryStorage) DeleteComment(ctx context.Context, id uuid.UUID) error {
 	}
 
 	// Проверяем, что комментарий существует
-	if _, exists := s.comments[id]; !exists {
+	comment, exists := s.comments[id]
+	if !exists {
 		return ErrNotFound
 	}
 
+	// Уменьшаем счетчики на все удаляемые живые комментарии
+	removed := comment.DescendantCount
+	replyDelta := 0
+	if !comment.IsDeleted() {
+		removed++
+		replyDelta = -1
+	}
+	s.adjustCommentCounters(comment, replyDelta, -removed)
+
 	// Рекурсивно удаляем комментарий и всех его потомков
 	s.deleteCommentRecursive(id)
 
 	return nil
 }
 
+// SoftDeleteComment превращает комментарий в надгробие, сохраняя ответы на него.
+func (s *MemoryStorage) SoftDeleteComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	comment, exists := s.comments[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	if !comment.IsDeleted() {
+		s.adjustCommentCounters(comment, -1, -1)
+	}
+	s.saveComment(id)
+	comment.MarkDeleted(s.now())
+
+	result := copyComment(comment)
+	return &result, nil
+}
+
+// EditComment заменяет текст комментария и сохраняет предыдущую версию в ревизиях.
+func (s *MemoryStorage) EditComment(ctx context.Context, id uuid.UUID, content string) (*model.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	comment, exists := s.comments[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	if comment.IsDeleted() {
+		return nil, ErrCommentDeleted
+	}
+
+	// Валидируем новый текст по правилам создания комментария
+	edited := model.Comment{Content: content}
+	if !edited.IsValidComment() {
+		return nil, ErrInvalidInput
+	}
+
+	now := s.now().UTC()
+
+	// Сохраняем заменяемый текст как ревизию
+	s.saveRevisions(id)
+	s.saveComment(id)
+	s.revisions[id] = append(s.revisions[id], model.CommentRevision{
+		ID:        s.newID(),
+		CommentID: id,
+		Content:   comment.Content,
+		CreatedAt: now,
+	})
+
+	comment.Content = content
+	comment.EditedAt = &now
+
+	result := copyComment(comment)
+	return &result, nil
+}
+
+// GetCommentRevisions получает предыдущие версии комментария от старых к новым.
+func (s *MemoryStorage) GetCommentRevisions(ctx context.Context, commentID uuid.UUID) ([]model.CommentRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if _, exists := s.comments[commentID]; !exists {
+		return nil, ErrNotFound
+	}
+
+	// Возвращаем копию, чтобы вызывающий код не мог изменить историю
+	revisions := make([]model.CommentRevision, len(s.revisions[commentID]))
+	copy(revisions, s.revisions[commentID])
+
+	return revisions, nil
+}
+
+// isVisible проверяет, должен ли комментарий попадать в выборки дерева.
+// Неудаленные комментарии видимы всегда, надгробия - только при наличии живых потомков,
+// которых считает DescendantCount.
+// Должно вызываться под мьютексом.
+func (s *MemoryStorage) isVisible(comment *model.Comment) bool {
+	return !comment.IsDeleted() || comment.DescendantCount > 0
+}
+
+// adjustCommentCounters изменяет счетчики при появлении или исчезновении живых комментариев:
+// CommentCount поста и DescendantCount всех предков comment меняются на delta,
+// ReplyCount непосредственного родителя - на replyDelta.
+// Должно вызываться под мьютексом.
+func (s *MemoryStorage) adjustCommentCounters(comment *model.Comment, replyDelta, delta int) {
+	if post, exists := s.posts[comment.PostID]; exists {
+		s.savePost(post.ID)
+		post.CommentCount += delta
+	}
+
+	if comment.ParentID != nil {
+		if parent, exists := s.comments[*comment.ParentID]; exists {
+			s.saveComment(parent.ID)
+			parent.ReplyCount += replyDelta
+		}
+	}
+
+	for ancestorID := comment.ParentID; ancestorID != nil; {
+		ancestor, exists := s.comments[*ancestorID]
+		if !exists {
+			break
+		}
+		s.saveComment(ancestor.ID)
+		ancestor.DescendantCount += delta
+		ancestorID = ancestor.ParentID
+	}
+}
+
 // deleteCommentRecursive рекурсивно удаляет комментарий и всех его потомков.
 // Должно вызываться под мьютексом.
 func (s *MemoryStorage) deleteCommentRecursive(id uuid.UUID) {
@@ -589,8 +1143,282 @@ func (s *MemoryStorage) deleteCommentRecursive(id uuid.UUID) {
 		}
 	}
 
-	// Затем удаляем сам комментарий
+	// Затем удаляем сам комментарий, его ревизии, голоса и реакции
+	s.saveCommentData(id)
 	delete(s.comments, id)
+	delete(s.revisions, id)
+	delete(s.votes, id)
+	delete(s.reactions, id)
+}
+
+// Операции с голосами
+
+// SetVote устанавливает, заменяет или снимает голос пользователя за пост или комментарий.
+func (s *MemoryStorage) SetVote(ctx context.Context, userID, targetID uuid.UUID, value model.VoteValue) (*model.VoteTally, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if !value.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
+	if _, exists := s.users[userID]; !exists {
+		return nil, ErrUnknownUser
+	}
+
+	// Находим счетчики цели: это либо пост, либо комментарий
+	tally := &model.VoteTally{TargetID: targetID}
+	var upvotes, downvotes *int
+	if post, exists := s.posts[targetID]; exists {
+		tally.PostID = post.ID
+		upvotes, downvotes = &post.Upvotes, &post.Downvotes
+	} else if comment, exists := s.comments[targetID]; exists {
+		if comment.IsDeleted() {
+			return nil, ErrCommentDeleted
+		}
+		tally.PostID = comment.PostID
+		upvotes, downvotes = &comment.Upvotes, &comment.Downvotes
+	} else {
+		return nil, ErrNotFound
+	}
+
+	// Отменяем предыдущий голос и учитываем новый
+	if tally.PostID == targetID {
+		s.savePost(targetID)
+	} else {
+		s.saveComment(targetID)
+	}
+	s.saveVotes(targetID)
+	switch s.votes[targetID][userID] {
+	case 1:
+		*upvotes--
+	case -1:
+		*downvotes--
+	}
+
+	switch next := value.Int(); next {
+	case 0:
+		delete(s.votes[targetID], userID)
+		if len(s.votes[targetID]) == 0 {
+			delete(s.votes, targetID)
+		}
+	default:
+		if s.votes[targetID] == nil {
+			s.votes[targetID] = make(map[uuid.UUID]int)
+		}
+		s.votes[targetID][userID] = next
+		if next > 0 {
+			*upvotes++
+		} else {
+			*downvotes++
+		}
+	}
+
+	tally.Upvotes, tally.Downvotes = *upvotes, *downvotes
+	return tally, nil
+}
+
+// Операции с реакциями
+
+// commentReaction - реакция одного пользователя на комментарий
+type commentReaction struct {
+	userID uuid.UUID
+	emoji  string
+}
+
+// AddReaction добавляет реакцию пользователя на комментарий, если ее еще нет.
+func (s *MemoryStorage) AddReaction(ctx context.Context, userID, commentID uuid.UUID, emoji string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkClosed(); err != nil {
+		return err
+	}
+
+	if !model.IsValidReactionEmoji(emoji) {
+		return ErrInvalidInput
+	}
+
+	comment, exists := s.comments[commentID]
+	if !exists {
+		return ErrNotFound
+	}
+	if comment.IsDeleted() {
+		return ErrCommentDeleted
+	}
+
+	if _, exists := s.users[userID]; !exists {
+		return ErrUnknownUser
+	}
+
+	reaction := commentReaction{userID: userID, emoji: emoji}
+	for _, existing := range s.reactions[commentID] {
+		if existing == reaction {
+			return nil
+		}
+	}
+	s.saveReactions(commentID)
+	s.reactions[commentID] = append(s.reactions[commentID], reaction)
+
+	return nil
+}
+
+// RemoveReaction снимает реакцию пользователя с комментария.
+func (s *MemoryStorage) RemoveReaction(ctx context.Context, userID, commentID uuid.UUID, emoji string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkClosed(); err != nil {
+		return err
+	}
+
+	if _, exists := s.comments[commentID]; !exists {
+		return ErrNotFound
+	}
+
+	reaction := commentReaction{userID: userID, emoji: emoji}
+	reactions := s.reactions[commentID]
+	s.saveReactions(commentID)
+	for i, existing := range reactions {
+		if existing == reaction {
+			// Сохраняем порядок добавления остальных реакций
+			s.reactions[commentID] = append(reactions[:i:i], reactions[i+1:]...)
+			break
+		}
+	}
+	if len(s.reactions[commentID]) == 0 {
+		delete(s.reactions, commentID)
+	}
+
+	return nil
+}
+
+// GetReactionsByCommentIDs собирает сводки реакций для нескольких комментариев.
+func (s *MemoryStorage) GetReactionsByCommentIDs(ctx context.Context, commentIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID][]model.Reaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID][]model.Reaction)
+	for _, commentID := range commentIDs {
+		reactions := s.reactions[commentID]
+		if len(reactions) == 0 {
+			continue
+		}
+
+		// Реакции хранятся в порядке добавления, поэтому первая встреча эмодзи задает его позицию
+		summary := []model.Reaction{}
+		positions := make(map[string]int)
+		for _, reaction := range reactions {
+			pos, exists := positions[reaction.emoji]
+			if !exists {
+				pos = len(summary)
+				positions[reaction.emoji] = pos
+				summary = append(summary, model.Reaction{Emoji: reaction.emoji})
+			}
+			summary[pos].Count++
+			if reaction.userID == viewerID {
+				summary[pos].ViewerReacted = true
+			}
+		}
+		result[commentID] = summary
+	}
+
+	return result, nil
+}
+
+// Операции с пользователями
+
+// CreateUser создает нового пользователя с уникальным именем.
+func (s *MemoryStorage) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, ErrInvalidInput
+	}
+
+	// Создаем копию для безопасности
+	newUser := &model.User{
+		ID:        user.ID,
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt,
+	}
+
+	// Устанавливаем ID и время, как User.Prepare, но через генераторы хранилища
+	if newUser.ID == uuid.Nil {
+		newUser.ID = s.newID()
+	}
+	if newUser.CreatedAt.IsZero() {
+		newUser.CreatedAt = s.now().UTC()
+	}
+
+	// Валидируем бизнес-правила
+	if !newUser.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
+	// Проверяем уникальность ID и имени
+	if _, exists := s.users[newUser.ID]; exists {
+		return nil, ErrDuplicate
+	}
+	if _, exists := s.usernames[newUser.Username]; exists {
+		return nil, ErrDuplicate
+	}
+
+	s.saveUser(newUser.ID, newUser.Username)
+	s.users[newUser.ID] = newUser
+	s.usernames[newUser.Username] = newUser.ID
+
+	result := *newUser
+	return &result, nil
+}
+
+// GetUser получает пользователя по ID.
+func (s *MemoryStorage) GetUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	user, exists := s.users[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	result := *user
+	return &result, nil
+}
+
+// GetUserByUsername получает пользователя по имени.
+func (s *MemoryStorage) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	id, exists := s.usernames[username]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	result := *s.users[id]
+	return &result, nil
 }
 
 // GetPostWithComments получает пост с комментариями (заглушка для совместимости).
@@ -612,17 +1440,15 @@ func (s *MemoryStorage) GetPostWithComments(ctx context.Context, postID uuid.UUI
 	var comments []model.Comment
 	for _, comment := range s.comments {
 		if comment.PostID == postID {
-			comments = append(comments, *comment)
+			comments = append(comments, copyComment(comment))
 		}
 	}
 
 	// Сортируем комментарии по времени создания
-	sort.Slice(comments, func(i, j int) bool {
-		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
-	})
+	sortCommentsByCursor(comments)
 
 	return &model.PostWithComments{
-		Post:     *post,
+		Post:     *copyPost(post),
 		Comments: comments,
 	}, nil
 }
@@ -637,20 +1463,21 @@ func (s *MemoryStorage) GetCommentTree(ctx context.Context, postID uuid.UUID) ([
 		return nil, err
 	}
 
-	// Получаем все комментарии для поста
-	comments, err := s.GetCommentsByPostID(ctx, postID)
-	if err != nil {
-		return nil, err
+	// Проверяем, что пост существует
+	if _, exists := s.posts[postID]; !exists {
+		return nil, ErrNotFound
 	}
 
 	// Создаем карту для быстрого поиска комментариев по ID
 	commentMap := make(map[uuid.UUID]model.Comment)
-	for _, comment := range comments {
-		commentMap[comment.ID] = comment
+	for _, comment := range s.comments {
+		if comment.PostID == postID {
+			commentMap[comment.ID] = copyComment(comment)
+		}
 	}
 
-	// Строим иерархию рекурсивно
-	return s.buildCommentTree(commentMap, nil), nil
+	// Строим иерархию рекурсивно и убираем ветки из одних надгробий
+	return model.PruneDeletedBranches(s.buildCommentTree(commentMap, nil)), nil
 }
 
 // buildCommentTree рекурсивно строит дерево комментариев.
@@ -673,9 +1500,9 @@ func (s *MemoryStorage) buildCommentTree(commentMap map[uuid.UUID]model.Comment,
 		}
 	}
 
-	// Сортируем по времени создания
+	// Сортируем по времени создания, как в GetCommentsByParentID
 	sort.Slice(children, func(i, j int) bool {
-		return children[i].Comment.CreatedAt.Before(children[j].Comment.CreatedAt)
+		return children[i].Comment.Cursor().Less(children[j].Comment.Cursor())
 	})
 
 	return children
@@ -685,3 +1512,52 @@ func (s *MemoryStorage) buildCommentTree(commentMap map[uuid.UUID]model.Comment,
 func (s *MemoryStorage) GetCommentHierarchy(ctx context.Context, postID uuid.UUID) ([]model.CommentTree, error) {
 	return s.GetCommentTree(ctx, postID)
 }
+
+// sortCommentsByCursor сортирует комментарии от старых к новым по (CreatedAt, ID)
+func sortCommentsByCursor(comments []model.Comment) {
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].Cursor().Less(comments[j].Cursor())
+	})
+}
+
+// sortComments сортирует комментарии в порядке order (пустое значение - от старых к новым)
+func sortComments(comments []model.Comment, order model.CommentSort) {
+	order = order.OrDefault()
+	sort.Slice(comments, func(i, j int) bool {
+		return order.Less(&comments[i], &comments[j])
+	})
+}
+
+// copyPost создает независимую копию поста для возврата наружу.
+// Должно вызываться под мьютексом.
+func copyPost(post *model.Post) *model.Post {
+	result := *post
+	if post.AuthorID != nil {
+		authorID := *post.AuthorID
+		result.AuthorID = &authorID
+	}
+	return &result
+}
+
+// copyComment создает независимую копию комментария для возврата наружу.
+// Должно вызываться под мьютексом.
+func copyComment(comment *model.Comment) model.Comment {
+	result := *comment
+	if comment.ParentID != nil {
+		parentID := *comment.ParentID
+		result.ParentID = &parentID
+	}
+	if comment.AuthorID != nil {
+		authorID := *comment.AuthorID
+		result.AuthorID = &authorID
+	}
+	if comment.EditedAt != nil {
+		editedAt := *comment.EditedAt
+		result.EditedAt = &editedAt
+	}
+	if comment.DeletedAt != nil {
+		deletedAt := *comment.DeletedAt
+		result.DeletedAt = &deletedAt
+	}
+	return result
+}
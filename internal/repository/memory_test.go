@@ -2,6 +2,7 @@ package repository_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -532,6 +533,682 @@ func TestMemoryStorage_DeleteComment(t *testing.T) {
 	}
 }
 
+// TestMemoryStorage_SoftDeleteComment тестирует мягкое удаление с сохранением ответов
+func TestMemoryStorage_SoftDeleteComment(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	createdPost, err := storage.CreatePost(ctx, &model.Post{
+		Title:   "Post for Soft Deletion",
+		Content: "Testing tombstones",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	rootComment, err := storage.CreateComment(ctx, &model.Comment{
+		PostID:  createdPost.ID,
+		Content: "Root comment",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create root comment: %v", err)
+	}
+
+	childComment, err := storage.CreateComment(ctx, &model.Comment{
+		PostID:   createdPost.ID,
+		ParentID: &rootComment.ID,
+		Content:  "Child comment",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create child comment: %v", err)
+	}
+
+	leafComment, err := storage.CreateComment(ctx, &model.Comment{
+		PostID:  createdPost.ID,
+		Content: "Root comment without replies",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create leaf comment: %v", err)
+	}
+
+	// Удаляем корневой комментарий с ответом
+	tombstone, err := storage.SoftDeleteComment(ctx, rootComment.ID)
+	if err != nil {
+		t.Fatalf("Failed to soft delete comment: %v", err)
+	}
+	if !tombstone.IsDeleted() {
+		t.Error("Expected comment to be marked as deleted")
+	}
+	if tombstone.Content != model.DeletedCommentContent {
+		t.Errorf("Expected content %q, got %q", model.DeletedCommentContent, tombstone.Content)
+	}
+
+	// Повторное удаление не меняет время удаления
+	again, err := storage.SoftDeleteComment(ctx, rootComment.ID)
+	if err != nil {
+		t.Fatalf("Failed to soft delete comment twice: %v", err)
+	}
+	if !again.DeletedAt.Equal(*tombstone.DeletedAt) {
+		t.Error("Expected DeletedAt to stay the same on repeated deletion")
+	}
+
+	// Надгробие с живым ответом остается среди корневых комментариев
+	roots, err := storage.GetRootCommentsByPostID(ctx, createdPost.ID, model.CommentSortOld, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to get root comments: %v", err)
+	}
+	if len(roots) != 2 {
+		t.Fatalf("Expected 2 root comments, got %d", len(roots))
+	}
+
+	children, err := storage.GetCommentsByParentID(ctx, rootComment.ID, model.CommentSortOld, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to get children: %v", err)
+	}
+	if len(children) != 1 || children[0].ID != childComment.ID {
+		t.Error("Expected reply to survive parent deletion")
+	}
+
+	// Удаленный комментарий без живых потомков исчезает из выборок
+	if _, err := storage.SoftDeleteComment(ctx, leafComment.ID); err != nil {
+		t.Fatalf("Failed to soft delete leaf comment: %v", err)
+	}
+	roots, err = storage.GetRootCommentsByPostID(ctx, createdPost.ID, model.CommentSortOld, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to get root comments: %v", err)
+	}
+	if len(roots) != 1 || roots[0].ID != rootComment.ID {
+		t.Errorf("Expected only tombstone with replies to remain, got %d comments", len(roots))
+	}
+
+	// После удаления единственного ответа вся ветка пропадает из дерева
+	if _, err := storage.SoftDeleteComment(ctx, childComment.ID); err != nil {
+		t.Fatalf("Failed to soft delete child comment: %v", err)
+	}
+	tree, err := storage.GetCommentTree(ctx, createdPost.ID)
+	if err != nil {
+		t.Fatalf("Failed to get comment tree: %v", err)
+	}
+	if len(tree) != 0 {
+		t.Errorf("Expected empty tree after deleting all comments, got %d roots", len(tree))
+	}
+
+	// Несуществующий комментарий
+	if _, err := storage.SoftDeleteComment(ctx, uuid.New()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestMemoryStorage_EditComment тестирует редактирование комментария с историей ревизий
+func TestMemoryStorage_EditComment(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	createdPost, err := storage.CreatePost(ctx, &model.Post{
+		Title:   "Post for Editing",
+		Content: "Testing revisions",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	comment, err := storage.CreateComment(ctx, &model.Comment{
+		PostID:  createdPost.ID,
+		Content: "First version",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	if comment.IsEdited() {
+		t.Error("New comment should not be marked as edited")
+	}
+
+	// Два редактирования подряд
+	if _, err := storage.EditComment(ctx, comment.ID, "Second version"); err != nil {
+		t.Fatalf("Failed to edit comment: %v", err)
+	}
+	edited, err := storage.EditComment(ctx, comment.ID, "Third version")
+	if err != nil {
+		t.Fatalf("Failed to edit comment: %v", err)
+	}
+	if edited.Content != "Third version" {
+		t.Errorf("Expected updated content, got %q", edited.Content)
+	}
+	if !edited.IsEdited() {
+		t.Error("Expected comment to be marked as edited")
+	}
+
+	// История содержит все предыдущие версии от старых к новым
+	revisions, err := storage.GetCommentRevisions(ctx, comment.ID)
+	if err != nil {
+		t.Fatalf("Failed to get revisions: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("Expected 2 revisions, got %d", len(revisions))
+	}
+	if revisions[0].Content != "First version" || revisions[1].Content != "Second version" {
+		t.Errorf("Unexpected revision order: %q, %q", revisions[0].Content, revisions[1].Content)
+	}
+
+	// Некорректный текст
+	if _, err := storage.EditComment(ctx, comment.ID, ""); !errors.Is(err, repository.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for empty content, got %v", err)
+	}
+
+	// Удаленный комментарий редактировать нельзя
+	if _, err := storage.SoftDeleteComment(ctx, comment.ID); err != nil {
+		t.Fatalf("Failed to soft delete comment: %v", err)
+	}
+	if _, err := storage.EditComment(ctx, comment.ID, "After deletion"); !errors.Is(err, repository.ErrCommentDeleted) {
+		t.Errorf("Expected ErrCommentDeleted, got %v", err)
+	}
+
+	// Несуществующий комментарий
+	if _, err := storage.EditComment(ctx, uuid.New(), "content"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestMemoryStorage_Users тестирует учетные записи и авторство
+func TestMemoryStorage_Users(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	user, err := storage.CreateUser(ctx, &model.User{Username: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if user.ID == uuid.Nil || user.CreatedAt.IsZero() {
+		t.Error("Expected user ID and CreatedAt to be set")
+	}
+
+	// Имя пользователя уникально
+	if _, err := storage.CreateUser(ctx, &model.User{Username: "alice"}); !errors.Is(err, repository.ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate, got %v", err)
+	}
+	if _, err := storage.CreateUser(ctx, &model.User{Username: "a"}); !errors.Is(err, repository.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for short username, got %v", err)
+	}
+
+	byName, err := storage.GetUserByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Failed to get user by username: %v", err)
+	}
+	if byName.ID != user.ID {
+		t.Error("GetUserByUsername returned wrong user")
+	}
+	if _, err := storage.GetUser(ctx, uuid.New()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// Автор сохраняется у поста и комментария
+	post, err := storage.CreatePost(ctx, &model.Post{
+		AuthorID: &user.ID,
+		Title:    "Authored Post",
+		Content:  "Content",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	if !post.IsAuthoredBy(user.ID) {
+		t.Error("Expected post to be authored by user")
+	}
+
+	comment, err := storage.CreateComment(ctx, &model.Comment{
+		PostID:   post.ID,
+		AuthorID: &user.ID,
+		Content:  "Authored comment",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	if !comment.IsAuthoredBy(user.ID) {
+		t.Error("Expected comment to be authored by user")
+	}
+
+	// Несуществующий автор
+	unknown := uuid.New()
+	if _, err := storage.CreatePost(ctx, &model.Post{AuthorID: &unknown, Title: "T", Content: "C"}); err == nil {
+		t.Error("Expected error for unknown author")
+	}
+}
+
+// TestMemoryStorage_KeysetPagination тестирует курсорную пагинацию комментариев
+func TestMemoryStorage_KeysetPagination(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Keyset", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	// Одинаковое время создания: порядок определяется ID
+	base := time.Now().UTC()
+	for i := 0; i < 4; i++ {
+		_, err := storage.CreateComment(ctx, &model.Comment{
+			PostID:    post.ID,
+			Content:   fmt.Sprintf("Comment %d", i),
+			CreatedAt: base.Add(time.Duration(i/2) * time.Second),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+	}
+
+	firstPage, err := storage.GetRootCommentsAfter(ctx, post.ID, 2, nil)
+	if err != nil {
+		t.Fatalf("Failed to get first page: %v", err)
+	}
+	if len(firstPage) != 2 {
+		t.Fatalf("Expected 2 comments, got %d", len(firstPage))
+	}
+
+	// Новый комментарий во время прокрутки не сдвигает следующую страницу
+	if _, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, Content: "Late comment"}); err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+
+	after := firstPage[1].Cursor()
+	secondPage, err := storage.GetRootCommentsAfter(ctx, post.ID, 2, &after)
+	if err != nil {
+		t.Fatalf("Failed to get second page: %v", err)
+	}
+	if len(secondPage) != 2 {
+		t.Fatalf("Expected 2 comments, got %d", len(secondPage))
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for _, comment := range append(firstPage, secondPage...) {
+		if seen[comment.ID] {
+			t.Errorf("Comment %s returned twice", comment.ID)
+		}
+		seen[comment.ID] = true
+	}
+	if !after.Less(secondPage[0].Cursor()) || !secondPage[0].Cursor().Less(secondPage[1].Cursor()) {
+		t.Error("Second page is not ordered after the first page")
+	}
+
+	// Посты: от новых к старым
+	newer, err := storage.CreatePost(ctx, &model.Post{Title: "Newer", Content: "C"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	posts, err := storage.GetPostsAfter(ctx, 10, nil)
+	if err != nil {
+		t.Fatalf("Failed to get posts: %v", err)
+	}
+	if len(posts) != 2 || posts[0].ID != newer.ID {
+		t.Fatalf("Expected newer post first, got %d posts", len(posts))
+	}
+	cursor := posts[0].Cursor()
+	rest, err := storage.GetPostsAfter(ctx, 10, &cursor)
+	if err != nil {
+		t.Fatalf("Failed to get posts after cursor: %v", err)
+	}
+	if len(rest) != 1 || rest[0].ID != post.ID {
+		t.Errorf("Expected only the older post after cursor, got %d posts", len(rest))
+	}
+}
+
+// TestMemoryStorage_CommentCounters тестирует счетчики при удалении ветки из середины дерева
+func TestMemoryStorage_CommentCounters(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Counters", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	// root -> child -> (grandchild1, grandchild2)
+	parentID := (*uuid.UUID)(nil)
+	var chain []uuid.UUID
+	for _, content := range []string{"Root", "Child", "Grandchild 1"} {
+		comment, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: parentID, Content: content})
+		if err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+		chain = append(chain, comment.ID)
+		id := comment.ID
+		parentID = &id
+	}
+	childID := chain[1]
+	if _, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: &childID, Content: "Grandchild 2"}); err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+
+	root, _ := storage.GetComment(ctx, chain[0])
+	child, _ := storage.GetComment(ctx, childID)
+	if root.ReplyCount != 1 || root.DescendantCount != 3 || child.ReplyCount != 2 || child.DescendantCount != 2 {
+		t.Fatalf("Unexpected counters: root %d/%d, child %d/%d",
+			root.ReplyCount, root.DescendantCount, child.ReplyCount, child.DescendantCount)
+	}
+
+	// Удаляем ветку child целиком: у корня не остается ответов
+	if err := storage.DeleteComment(ctx, childID); err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+
+	root, _ = storage.GetComment(ctx, chain[0])
+	if root.ReplyCount != 0 || root.DescendantCount != 0 {
+		t.Errorf("Expected root counters 0/0, got %d/%d", root.ReplyCount, root.DescendantCount)
+	}
+	updatedPost, _ := storage.GetPost(ctx, post.ID)
+	if updatedPost.CommentCount != 1 {
+		t.Errorf("Expected comment count 1, got %d", updatedPost.CommentCount)
+	}
+}
+
+// TestMemoryStorage_CommentAncestors тестирует получение цепочки предков комментария
+func TestMemoryStorage_CommentAncestors(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Ancestors", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	var chain []uuid.UUID
+	var parentID *uuid.UUID
+	for i := 0; i < 4; i++ {
+		comment, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: parentID, Content: fmt.Sprintf("Level %d", i)})
+		if err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+		chain = append(chain, comment.ID)
+		id := comment.ID
+		parentID = &id
+	}
+
+	ancestors, err := storage.GetCommentAncestorIDs(ctx, chain[3])
+	if err != nil {
+		t.Fatalf("Failed to get ancestors: %v", err)
+	}
+	if len(ancestors) != 3 || ancestors[0] != chain[2] || ancestors[1] != chain[1] || ancestors[2] != chain[0] {
+		t.Errorf("Expected ancestors from parent to root, got %v", ancestors)
+	}
+
+	if ancestors, err := storage.GetCommentAncestorIDs(ctx, chain[0]); err != nil || len(ancestors) != 0 {
+		t.Errorf("Expected no ancestors for root comment, got %v (err %v)", ancestors, err)
+	}
+	if _, err := storage.GetCommentAncestorIDs(ctx, uuid.New()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for unknown comment, got %v", err)
+	}
+}
+
+// TestMemoryStorage_Votes тестирует голосование за посты и комментарии
+func TestMemoryStorage_Votes(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	alice, err := storage.CreateUser(ctx, &model.User{Username: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, err := storage.CreateUser(ctx, &model.User{Username: "bob"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Votes", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	comment, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, Content: "Comment"})
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+
+	vote := func(userID, targetID uuid.UUID, value model.VoteValue) *model.VoteTally {
+		t.Helper()
+		tally, err := storage.SetVote(ctx, userID, targetID, value)
+		if err != nil {
+			t.Fatalf("Failed to vote: %v", err)
+		}
+		return tally
+	}
+
+	// Повторный голос не добавляет второй голос пользователя, а смена голоса переносит его
+	vote(alice.ID, comment.ID, model.VoteUp)
+	vote(alice.ID, comment.ID, model.VoteUp)
+	tally := vote(bob.ID, comment.ID, model.VoteUp)
+	if tally.Upvotes != 2 || tally.Downvotes != 0 || tally.PostID != post.ID {
+		t.Errorf("Expected 2/0 for post %s, got %d/%d for post %s", post.ID, tally.Upvotes, tally.Downvotes, tally.PostID)
+	}
+	tally = vote(bob.ID, comment.ID, model.VoteDown)
+	if tally.Upvotes != 1 || tally.Downvotes != 1 || tally.Score() != 0 {
+		t.Errorf("Expected 1/1 after switching vote, got %d/%d", tally.Upvotes, tally.Downvotes)
+	}
+	tally = vote(alice.ID, comment.ID, model.VoteNone)
+	if tally.Upvotes != 0 || tally.Downvotes != 1 {
+		t.Errorf("Expected 0/1 after removing vote, got %d/%d", tally.Upvotes, tally.Downvotes)
+	}
+
+	stored, _ := storage.GetComment(ctx, comment.ID)
+	if stored.Upvotes != 0 || stored.Downvotes != 1 || stored.Score() != -1 {
+		t.Errorf("Expected stored comment 0/1, got %d/%d", stored.Upvotes, stored.Downvotes)
+	}
+
+	// Голос за пост хранится отдельно от голосов за его комментарии
+	tally = vote(alice.ID, post.ID, model.VoteUp)
+	if tally.TargetID != post.ID || tally.PostID != post.ID || tally.Score() != 1 {
+		t.Errorf("Unexpected post tally %+v", tally)
+	}
+	if _, err := storage.UpdatePost(ctx, &model.Post{ID: post.ID, Title: "Updated", Content: "Content"}); err != nil {
+		t.Fatalf("Failed to update post: %v", err)
+	}
+	updatedPost, _ := storage.GetPost(ctx, post.ID)
+	if updatedPost.Upvotes != 1 {
+		t.Errorf("Expected update to keep post votes, got %d", updatedPost.Upvotes)
+	}
+
+	if _, err := storage.SetVote(ctx, alice.ID, uuid.New(), model.VoteUp); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for unknown target, got %v", err)
+	}
+	if _, err := storage.SetVote(ctx, alice.ID, post.ID, model.VoteValue("SIDEWAYS")); !errors.Is(err, repository.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for unknown value, got %v", err)
+	}
+	if _, err := storage.SetVote(ctx, uuid.New(), post.ID, model.VoteUp); err == nil {
+		t.Error("Expected error for unknown voter")
+	}
+
+	if _, err := storage.SoftDeleteComment(ctx, comment.ID); err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+	if _, err := storage.SetVote(ctx, alice.ID, comment.ID, model.VoteUp); !errors.Is(err, repository.ErrCommentDeleted) {
+		t.Errorf("Expected ErrCommentDeleted for deleted comment, got %v", err)
+	}
+}
+
+// TestMemoryStorage_Reactions тестирует реакции на комментарии и их пакетную загрузку
+func TestMemoryStorage_Reactions(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	alice, _ := storage.CreateUser(ctx, &model.User{Username: "alice"})
+	bob, _ := storage.CreateUser(ctx, &model.User{Username: "bob"})
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Reactions", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	first, _ := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, Content: "First"})
+	second, _ := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, Content: "Second"})
+	quiet, _ := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, Content: "No reactions"})
+
+	for _, r := range []struct {
+		userID    uuid.UUID
+		commentID uuid.UUID
+		emoji     string
+	}{
+		{alice.ID, first.ID, "🎉"},
+		{bob.ID, first.ID, "👍"},
+		{bob.ID, first.ID, "🎉"},
+		{bob.ID, first.ID, "🎉"}, // Повтор не увеличивает счетчик
+		{alice.ID, second.ID, "❤️"},
+	} {
+		if err := storage.AddReaction(ctx, r.userID, r.commentID, r.emoji); err != nil {
+			t.Fatalf("Failed to add reaction: %v", err)
+		}
+	}
+
+	reactions, err := storage.GetReactionsByCommentIDs(ctx, []uuid.UUID{first.ID, second.ID, quiet.ID}, alice.ID)
+	if err != nil {
+		t.Fatalf("Failed to get reactions: %v", err)
+	}
+	if _, exists := reactions[quiet.ID]; exists {
+		t.Error("Comment without reactions should not be in the result")
+	}
+
+	// Эмодзи идут в порядке первой реакции
+	got := reactions[first.ID]
+	if len(got) != 2 ||
+		got[0] != (model.Reaction{Emoji: "🎉", Count: 2, ViewerReacted: true}) ||
+		got[1] != (model.Reaction{Emoji: "👍", Count: 1, ViewerReacted: false}) {
+		t.Errorf("Unexpected reactions on first comment: %+v", got)
+	}
+	if got := reactions[second.ID]; len(got) != 1 || got[0].Count != 1 {
+		t.Errorf("Unexpected reactions on second comment: %+v", got)
+	}
+
+	// Снятие реакции, в том числе отсутствующей
+	if err := storage.RemoveReaction(ctx, alice.ID, first.ID, "🎉"); err != nil {
+		t.Fatalf("Failed to remove reaction: %v", err)
+	}
+	if err := storage.RemoveReaction(ctx, alice.ID, first.ID, "🎉"); err != nil {
+		t.Errorf("Removing a missing reaction should succeed, got %v", err)
+	}
+	reactions, _ = storage.GetReactionsByCommentIDs(ctx, []uuid.UUID{first.ID}, uuid.Nil)
+	if got := reactions[first.ID]; len(got) != 2 || got[0].Count != 1 || got[0].ViewerReacted {
+		t.Errorf("Unexpected reactions after removal: %+v", got)
+	}
+
+	if err := storage.AddReaction(ctx, alice.ID, uuid.New(), "👍"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for unknown comment, got %v", err)
+	}
+	if err := storage.AddReaction(ctx, alice.ID, first.ID, ""); !errors.Is(err, repository.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for empty emoji, got %v", err)
+	}
+	if _, err := storage.SoftDeleteComment(ctx, second.ID); err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+	if err := storage.AddReaction(ctx, bob.ID, second.ID, "👍"); !errors.Is(err, repository.ErrCommentDeleted) {
+		t.Errorf("Expected ErrCommentDeleted for deleted comment, got %v", err)
+	}
+}
+
+// TestMemoryStorage_BatchLoading тестирует загрузку комментариев сразу для нескольких родителей
+func TestMemoryStorage_BatchLoading(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Batch", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	emptyPost, err := storage.CreatePost(ctx, &model.Post{Title: "Empty", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	// Три корня с разным количеством ответов: 3, 1 и 0
+	base := time.Now().UTC()
+	var roots []uuid.UUID
+	for i := 0; i < 3; i++ {
+		root, err := storage.CreateComment(ctx, &model.Comment{
+			PostID:    post.ID,
+			Content:   fmt.Sprintf("Root %d", i),
+			CreatedAt: base.Add(time.Duration(i) * time.Second),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+		roots = append(roots, root.ID)
+
+		for j := 0; j < 3-i*2; j++ {
+			parentID := root.ID
+			_, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: &parentID, Content: fmt.Sprintf("Reply %d", j)})
+			if err != nil {
+				t.Fatalf("Failed to create reply: %v", err)
+			}
+		}
+	}
+
+	// Окно limit/offset применяется к каждому родителю отдельно
+	children, err := storage.GetCommentsByParentIDs(ctx, roots, model.CommentSortOld, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to get children: %v", err)
+	}
+	if len(children[roots[0]]) != 2 || len(children[roots[1]]) != 1 {
+		t.Errorf("Expected 2 and 1 replies, got %d and %d", len(children[roots[0]]), len(children[roots[1]]))
+	}
+	if _, ok := children[roots[2]]; ok {
+		t.Error("Parent without replies should not be in the result")
+	}
+
+	// Каждое окно совпадает с результатом одиночного запроса
+	for _, offset := range []int{0, 1, 2} {
+		batch, err := storage.GetCommentsByParentIDs(ctx, roots, model.CommentSortOld, 1, offset)
+		if err != nil {
+			t.Fatalf("Failed to get children: %v", err)
+		}
+		for _, root := range roots {
+			single, err := storage.GetCommentsByParentID(ctx, root, model.CommentSortOld, 1, offset)
+			if err != nil {
+				t.Fatalf("Failed to get children: %v", err)
+			}
+			if len(single) != len(batch[root]) || (len(single) == 1 && single[0].ID != batch[root][0].ID) {
+				t.Errorf("Batch window differs from single query for offset %d", offset)
+			}
+		}
+	}
+
+	rootComments, err := storage.GetRootCommentsByPostIDs(ctx, []uuid.UUID{post.ID, emptyPost.ID}, model.CommentSortOld, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to get root comments: %v", err)
+	}
+	if len(rootComments[post.ID]) != 3 || len(rootComments[emptyPost.ID]) != 0 {
+		t.Errorf("Expected 3 and 0 root comments, got %d and %d", len(rootComments[post.ID]), len(rootComments[emptyPost.ID]))
+	}
+	for i, comment := range rootComments[post.ID] {
+		if comment.ID != roots[i] {
+			t.Error("Root comments are not ordered by creation time")
+		}
+	}
+
+	// Порядок сортировки применяется и к пакетной загрузке
+	newest, err := storage.GetRootCommentsByPostIDs(ctx, []uuid.UUID{post.ID}, model.CommentSortNew, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to get root comments: %v", err)
+	}
+	single, err := storage.GetRootCommentsByPostID(ctx, post.ID, model.CommentSortNew, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to get root comments: %v", err)
+	}
+	if len(newest[post.ID]) != 2 || newest[post.ID][0].ID != roots[2] || single[0].ID != roots[2] || single[1].ID != newest[post.ID][1].ID {
+		t.Error("Expected newest root comments first")
+	}
+}
+
 // TestMemoryStorage_HealthCheck тестирует health check
 func TestMemoryStorage_HealthCheck(t *testing.T) {
 	storage := repository.NewMemoryStorage()
@@ -639,6 +1316,102 @@ func BenchmarkMemoryStorage_CreatePost(b *testing.B) {
 	}
 }
 
+func TestMemoryStorage_WithTxRollback(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	ctx := context.Background()
+
+	alice, err := storage.CreateUser(ctx, &model.User{Username: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Post", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	root, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, Content: "Root"})
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	reply, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: &root.ID, Content: "Reply"})
+	if err != nil {
+		t.Fatalf("Failed to create reply: %v", err)
+	}
+
+	// Изменения вложенной транзакции откатываются вместе с внешней
+	errRollback := errors.New("rollback")
+	err = storage.WithTx(ctx, func(tx repository.Storage) error {
+		err := tx.WithTx(ctx, func(nested repository.Storage) error {
+			if _, err := nested.EditComment(ctx, reply.ID, "Edited"); err != nil {
+				return err
+			}
+			_, err := nested.SetVote(ctx, alice.ID, root.ID, model.VoteUp)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := tx.SoftDeleteComment(ctx, reply.ID); err != nil {
+			return err
+		}
+		if err := tx.AddReaction(ctx, alice.ID, root.ID, "👍"); err != nil {
+			return err
+		}
+		if _, err := tx.CreateUser(ctx, &model.User{Username: "bob"}); err != nil {
+			return err
+		}
+		if err := tx.DeleteComment(ctx, root.ID); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Expected rollback error, got %v", err)
+	}
+
+	gotReply, err := storage.GetComment(ctx, reply.ID)
+	if err != nil {
+		t.Fatalf("Rolled back deletion removed reply: %v", err)
+	}
+	if gotReply.Content != "Reply" || gotReply.IsEdited() || gotReply.IsDeleted() {
+		t.Errorf("Expected reply unchanged, got %+v", gotReply)
+	}
+	if revisions, _ := storage.GetCommentRevisions(ctx, reply.ID); len(revisions) != 0 {
+		t.Errorf("Expected no revisions after rollback, got %d", len(revisions))
+	}
+	gotRoot, err := storage.GetComment(ctx, root.ID)
+	if err != nil {
+		t.Fatalf("Rolled back deletion removed root: %v", err)
+	}
+	if gotRoot.Upvotes != 0 || gotRoot.ReplyCount != 1 || gotRoot.DescendantCount != 1 {
+		t.Errorf("Expected root counters unchanged, got %+v", gotRoot)
+	}
+	if gotPost, _ := storage.GetPost(ctx, post.ID); gotPost.CommentCount != 2 {
+		t.Errorf("Expected comment count 2, got %d", gotPost.CommentCount)
+	}
+	reactions, err := storage.GetReactionsByCommentIDs(ctx, []uuid.UUID{root.ID}, alice.ID)
+	if err != nil || len(reactions[root.ID]) != 0 {
+		t.Errorf("Expected no reactions after rollback, got %v (err %v)", reactions, err)
+	}
+	if _, err := storage.GetUserByUsername(ctx, "bob"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected user created in rolled back transaction to be absent, got %v", err)
+	}
+
+	// Паника в fn тоже откатывает изменения
+	func() {
+		defer func() { _ = recover() }()
+		_ = storage.WithTx(ctx, func(tx repository.Storage) error {
+			if err := tx.TogglePostComments(ctx, post.ID, false); err != nil {
+				return err
+			}
+			panic("fn failed")
+		})
+	}()
+	if gotPost, _ := storage.GetPost(ctx, post.ID); !gotPost.CommentsEnabled {
+		t.Error("Expected comments to stay enabled after panic in transaction")
+	}
+}
+
 // BenchmarkMemoryStorage_GetPosts бенчмарк получения постов
 func BenchmarkMemoryStorage_GetPosts(b *testing.B) {
 	storage := repository.NewMemoryStorage()
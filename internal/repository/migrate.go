@@ -0,0 +1,389 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/NarthurN/CommentsSystem/migrations"
+)
+
+// Ошибки миграций
+var (
+	// ErrMigrationChecksum означает, что примененная миграция была изменена после применения
+	ErrMigrationChecksum = errors.New("migration checksum mismatch")
+
+	// ErrMigrationMissing означает, что в базе применена миграция, которой нет среди файлов
+	ErrMigrationMissing = errors.New("applied migration is missing")
+)
+
+// migrationLockKey - ключ advisory lock, под которым выполняются миграции.
+// Не дает нескольким экземплярам приложения применять миграции одновременно.
+const migrationLockKey int64 = 0x636f6d6d656e7473 // "comments"
+
+// migrationFileName - имя файла миграции: <номер>_<название>.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.sql$`)
+
+// Migration - версия схемы базы данных
+type Migration struct {
+	Version  int    // Номер миграции; миграции применяются по возрастанию
+	Name     string // Название из имени файла
+	Up       string // SQL применения
+	Down     string // SQL отката; пустой - откат не поддерживается
+	Checksum string // SHA-256 скрипта применения
+}
+
+// MigrationStatus - состояние миграции в базе данных
+type MigrationStatus struct {
+	Migration
+	Applied   bool       // Миграция применена
+	AppliedAt *time.Time // Время применения
+	Modified  bool       // Скрипт изменен после применения
+	Missing   bool       // Миграция применена, но ее файла нет
+}
+
+// LoadMigrations читает миграции из fsys: <номер>_<название>.sql в корне
+// и необязательные откаты down/<номер>_<название>.sql.
+// Возвращает миграции по возрастанию номера.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var result []Migration
+	versions := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+		if other, exists := versions[version]; exists {
+			return nil, fmt.Errorf("duplicate migration version %d: %q and %q", version, other, entry.Name())
+		}
+		versions[version] = entry.Name()
+
+		up, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", entry.Name(), err)
+		}
+		down, err := fs.ReadFile(fsys, path.Join("down", entry.Name()))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read down migration %q: %w", entry.Name(), err)
+		}
+
+		sum := sha256.Sum256(up)
+		result = append(result, Migration{
+			Version:  version,
+			Name:     match[2],
+			Up:       string(up),
+			Down:     string(down),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	// Откат без миграции применения - скорее всего опечатка в имени файла
+	downs, err := fs.ReadDir(fsys, "down")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read down migrations: %w", err)
+	}
+	for _, entry := range downs {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		if _, err := fs.Stat(fsys, entry.Name()); err != nil {
+			return nil, fmt.Errorf("down migration %q has no up migration", entry.Name())
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// Migrator применяет и откатывает миграции схемы PostgreSQL.
+//
+// Примененные миграции записываются в таблицу schema_migrations вместе с контрольной
+// суммой скрипта: если файл изменен после применения, Up и Down возвращают
+// ErrMigrationChecksum. Каждая миграция выполняется в отдельной транзакции,
+// а весь запуск - под advisory lock, поэтому экземпляры приложения, стартующие
+// одновременно, применяют миграции по очереди.
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+// NewMigrator создает Migrator для набора миграций
+func NewMigrator(db *pgxpool.Pool, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Migrator возвращает Migrator для миграций, встроенных в приложение
+func (s *PostgresStorage) Migrator() (*Migrator, error) {
+	list, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+	return NewMigrator(s.db, list), nil
+}
+
+// appliedMigration - запись таблицы schema_migrations
+type appliedMigration struct {
+	version   int
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Up применяет все непримененные миграции по возрастанию номера и возвращает их
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Down откатывает steps последних примененных миграций и возвращает их в порядке отката
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Status возвращает состояние всех миграций по возрастанию номера,
+// включая примененные миграции, файлов которых нет
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var result []MigrationStatus
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		known := make(map[int]bool, len(m.migrations))
+		for _, migration := range m.migrations {
+			known[migration.Version] = true
+			status := MigrationStatus{Migration: migration}
+			if record, ok := applied[migration.Version]; ok {
+				appliedAt := record.appliedAt
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+				status.Modified = record.checksum != migration.Checksum
+			}
+			result = append(result, status)
+		}
+
+		for version, record := range applied {
+			if known[version] {
+				continue
+			}
+			appliedAt := record.appliedAt
+			result = append(result, MigrationStatus{
+				Migration: Migration{Version: version, Name: record.name, Checksum: record.checksum},
+				Applied:   true,
+				AppliedAt: &appliedAt,
+				Missing:   true,
+			})
+		}
+		return nil
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, err
+}
+
+// withLock выполняет fn на выделенном соединении под advisory lock.
+// Таблица schema_migrations создается под той же блокировкой.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	// Блокировка принадлежит сессии: снимаем ее и при отмене ctx
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// applied читает примененные миграции
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var record appliedMigration
+		if err := rows.Scan(&record.version, &record.name, &record.checksum, &record.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[record.version] = record
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return applied, nil
+}
+
+// verify читает примененные миграции и проверяет, что их файлы есть и не изменены
+func (m *Migrator) verify(ctx context.Context, conn *pgxpool.Conn) (map[int]appliedMigration, error) {
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkApplied(applied, m.migrations); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// checkApplied проверяет, что для каждой примененной миграции есть неизмененный файл
+func checkApplied(applied map[int]appliedMigration, migrations []Migration) error {
+	known := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, record := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: %d_%s", ErrMigrationMissing, version, record.name)
+		}
+		if migration.Checksum != record.checksum {
+			return fmt.Errorf("%w: %d_%s", ErrMigrationChecksum, version, migration.Name)
+		}
+	}
+
+	return nil
+}
+
+// apply применяет миграцию и записывает ее в schema_migrations в одной транзакции
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, migration Migration) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, migration.Up); err != nil {
+		return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+		migration.Version, migration.Name, migration.Checksum)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	return nil
+}
+
+// revert откатывает миграцию и удаляет ее из schema_migrations в одной транзакции
+func (m *Migrator) revert(ctx context.Context, conn *pgxpool.Conn, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, migration.Down); err != nil {
+		return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+		return fmt.Errorf("failed to unrecord migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/NarthurN/CommentsSystem/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("миграции упорядочены по номеру", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"010_third.sql":      {Data: []byte("SELECT 3;")},
+			"002_second.sql":     {Data: []byte("SELECT 2;")},
+			"001_first.sql":      {Data: []byte("SELECT 1;")},
+			"down/001_first.sql": {Data: []byte("SELECT -1;")},
+			"README.md":          {Data: []byte("not a migration")},
+		}
+
+		list, err := LoadMigrations(fsys)
+		if err != nil {
+			t.Fatalf("LoadMigrations() error = %v", err)
+		}
+		if len(list) != 3 || list[0].Version != 1 || list[1].Version != 2 || list[2].Version != 10 {
+			t.Fatalf("Expected versions [1 2 10], got %+v", list)
+		}
+		if list[0].Name != "first" || list[0].Down != "SELECT -1;" || list[1].Down != "" {
+			t.Errorf("Unexpected migration contents: %+v", list[:2])
+		}
+		if list[0].Checksum == list[1].Checksum || len(list[0].Checksum) != 64 {
+			t.Errorf("Expected distinct SHA-256 checksums, got %q and %q", list[0].Checksum, list[1].Checksum)
+		}
+	})
+
+	errorCases := map[string]fstest.MapFS{
+		"некорректное имя": {
+			"first.sql": {Data: []byte("SELECT 1;")},
+		},
+		"повторяющийся номер": {
+			"001_first.sql": {Data: []byte("SELECT 1;")},
+			"1_again.sql":   {Data: []byte("SELECT 1;")},
+		},
+		"откат без миграции": {
+			"001_first.sql":       {Data: []byte("SELECT 1;")},
+			"down/002_second.sql": {Data: []byte("SELECT 2;")},
+		},
+	}
+	for name, fsys := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadMigrations(fsys); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+// TestEmbeddedMigrations проверяет миграции, встроенные в приложение
+func TestEmbeddedMigrations(t *testing.T) {
+	sqliteFS, err := fs.Sub(migrations.SQLite, "sqlite")
+	if err != nil {
+		t.Fatalf("fs.Sub() error = %v", err)
+	}
+
+	sets := []struct {
+		name string
+		fsys fs.FS
+	}{
+		{"PostgreSQL", migrations.FS},
+		{"SQLite", sqliteFS},
+	}
+
+	for _, set := range sets {
+		t.Run(set.name, func(t *testing.T) {
+			list, err := LoadMigrations(set.fsys)
+			if err != nil {
+				t.Fatalf("LoadMigrations() error = %v", err)
+			}
+			if len(list) == 0 {
+				t.Fatal("Expected embedded migrations")
+			}
+
+			for i, migration := range list {
+				if migration.Version != i+1 {
+					t.Errorf("Expected contiguous versions, got %d at position %d", migration.Version, i)
+				}
+				if migration.Down == "" {
+					t.Errorf("Migration %d_%s has no down script", migration.Version, migration.Name)
+				}
+			}
+		})
+	}
+}
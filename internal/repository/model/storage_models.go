@@ -10,11 +10,15 @@ import (
 // PostDB представляет модель поста в базе данных
 // Отражает структуру таблицы posts
 type PostDB struct {
-	ID              uuid.UUID `db:"id"`
-	Title           string    `db:"title"`
-	Content         string    `db:"content"`
-	CommentsEnabled bool      `db:"comments_enabled"`
-	CreatedAt       time.Time `db:"created_at"`
+	ID              uuid.UUID  `db:"id"`
+	AuthorID        *uuid.UUID `db:"author_id"`
+	Title           string     `db:"title"`
+	Content         string     `db:"content"`
+	CommentsEnabled bool       `db:"comments_enabled"`
+	CreatedAt       time.Time  `db:"created_at"`
+	CommentCount    int        `db:"comment_count"`
+	Upvotes         int        `db:"upvotes"`
+	Downvotes       int        `db:"downvotes"`
 }
 
 // CommentDB представляет модель комментария в базе данных
@@ -22,9 +26,34 @@ type PostDB struct {
 type CommentDB struct {
 	ID        uuid.UUID  `db:"id"`
 	PostID    uuid.UUID  `db:"post_id"`
+	AuthorID  *uuid.UUID `db:"author_id"`
 	ParentID  *uuid.UUID `db:"parent_id"`
 	Content   string     `db:"content"`
 	CreatedAt time.Time  `db:"created_at"`
+	EditedAt  *time.Time `db:"edited_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
+
+	ReplyCount      int `db:"reply_count"`
+	DescendantCount int `db:"descendant_count"`
+	Upvotes         int `db:"upvotes"`
+	Downvotes       int `db:"downvotes"`
+}
+
+// UserDB представляет модель пользователя в базе данных
+// Отражает структуру таблицы users
+type UserDB struct {
+	ID        uuid.UUID `db:"id"`
+	Username  string    `db:"username"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// CommentRevisionDB представляет предыдущую версию комментария в базе данных
+// Отражает структуру таблицы comment_revisions
+type CommentRevisionDB struct {
+	ID        uuid.UUID `db:"id"`
+	CommentID uuid.UUID `db:"comment_id"`
+	Content   string    `db:"content"`
+	CreatedAt time.Time `db:"created_at"`
 }
 
 // PostWithCommentsDB представляет пост с комментариями для JOIN запросов
@@ -33,9 +62,17 @@ type PostWithCommentsDB struct {
 	// Дополнительные поля для JOIN
 	CommentID        *uuid.UUID `db:"comment_id"`
 	CommentPostID    *uuid.UUID `db:"comment_post_id"`
+	CommentAuthorID  *uuid.UUID `db:"comment_author_id"`
 	CommentParentID  *uuid.UUID `db:"comment_parent_id"`
 	CommentContent   *string    `db:"comment_content"`
 	CommentCreatedAt *time.Time `db:"comment_created_at"`
+	CommentEditedAt  *time.Time `db:"comment_edited_at"`
+	CommentDeletedAt *time.Time `db:"comment_deleted_at"`
+
+	CommentReplyCount      *int `db:"comment_reply_count"`
+	CommentDescendantCount *int `db:"comment_descendant_count"`
+	CommentUpvotes         *int `db:"comment_upvotes"`
+	CommentDownvotes       *int `db:"comment_downvotes"`
 }
 
 // CommentTreeDB представляет результат рекурсивного CTE запроса
@@ -54,26 +91,36 @@ func (CommentDB) TableName() string {
 	return "comments"
 }
 
+// TableName возвращает имя таблицы для UserDB
+func (UserDB) TableName() string {
+	return "users"
+}
+
+// TableName возвращает имя таблицы для CommentRevisionDB
+func (CommentRevisionDB) TableName() string {
+	return "comment_revisions"
+}
+
 // Методы для работы с базой данных
 
 // GetSelectColumns возвращает список колонок для SELECT запроса постов
 func (PostDB) GetSelectColumns() []string {
-	return []string{"id", "title", "content", "comments_enabled", "created_at"}
+	return []string{"id", "author_id", "title", "content", "comments_enabled", "created_at"}
 }
 
 // GetSelectColumns возвращает список колонок для SELECT запроса комментариев
 func (CommentDB) GetSelectColumns() []string {
-	return []string{"id", "post_id", "parent_id", "content", "created_at"}
+	return []string{"id", "post_id", "author_id", "parent_id", "content", "created_at", "edited_at", "deleted_at"}
 }
 
 // GetInsertColumns возвращает список колонок для INSERT запроса постов
 func (PostDB) GetInsertColumns() []string {
-	return []string{"id", "title", "content", "comments_enabled", "created_at"}
+	return []string{"id", "author_id", "title", "content", "comments_enabled", "created_at"}
 }
 
 // GetInsertColumns возвращает список колонок для INSERT запроса комментариев
 func (CommentDB) GetInsertColumns() []string {
-	return []string{"id", "post_id", "parent_id", "content", "created_at"}
+	return []string{"id", "post_id", "author_id", "parent_id", "content", "created_at"}
 }
 
 // GetUpdateColumns возвращает список колонок для UPDATE запроса постов
@@ -111,10 +158,22 @@ func (c *CommentDB) Validate() error {
 	return nil
 }
 
+// Validate проверяет валидность модели пользователя на уровне БД
+func (u *UserDB) Validate() error {
+	if u.ID == uuid.Nil {
+		return ErrInvalidID
+	}
+	if len(u.Username) == 0 || len(u.Username) > 50 {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
 // Ошибки валидации репозитория
 var (
-	ErrInvalidID      = fmt.Errorf("invalid ID")
-	ErrInvalidTitle   = fmt.Errorf("invalid title")
-	ErrInvalidContent = fmt.Errorf("invalid content")
-	ErrInvalidPostID  = fmt.Errorf("invalid post ID")
+	ErrInvalidID       = fmt.Errorf("invalid ID")
+	ErrInvalidTitle    = fmt.Errorf("invalid title")
+	ErrInvalidContent  = fmt.Errorf("invalid content")
+	ErrInvalidPostID   = fmt.Errorf("invalid post ID")
+	ErrInvalidUsername = fmt.Errorf("invalid username")
 )
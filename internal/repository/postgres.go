@@ -2,10 +2,15 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/NarthurN/CommentsSystem/internal/model"
@@ -15,14 +20,65 @@ import (
 
 // PostgresStorage реализует интерфейс Storage для PostgreSQL
 type PostgresStorage struct {
-	db               *pgxpool.Pool
-	postConverter    *converter.PostConverter
-	commentConverter *converter.CommentConverter
-	treeConverter    *converter.TreeConverter
+	db                *pgxpool.Pool
+	tx                pgx.Tx // Транзакция WithTx; nil вне транзакции
+	outbox            bool   // Записывать события в outbox_events вместе с изменениями
+	postConverter     *converter.PostConverter
+	commentConverter  *converter.CommentConverter
+	treeConverter     *converter.TreeConverter
+	revisionConverter *converter.RevisionConverter
+	userConverter     *converter.UserConverter
+}
+
+var _ Outbox = (*PostgresStorage)(nil)
+
+// pgxQuerier - общие методы пула и транзакции, через которые PostgresStorage выполняет запросы
+type pgxQuerier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Коды ошибок PostgreSQL
+const (
+	pgUniqueViolation     = "23505" // Нарушение ограничения уникальности
+	pgForeignKeyViolation = "23503" // Нарушение внешнего ключа
+)
+
+// isUnknownAuthorError сообщает, что вставка нарушила внешний ключ author_id:
+// пользователь с токеном еще не создал учетную запись
+func isUnknownAuthorError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation &&
+		strings.HasSuffix(pgErr.ConstraintName, "_author_id_fkey")
+}
+
+// isUnknownUserError сообщает, что вставка голоса или реакции нарушила внешний ключ user_id:
+// пользователь с токеном еще не создал учетную запись
+func isUnknownUserError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation &&
+		strings.HasSuffix(pgErr.ConstraintName, "_user_id_fkey")
+}
+
+// PostgresOptions содержит необязательные параметры PostgresStorage
+type PostgresOptions struct {
+	// Outbox включает запись событий в outbox_events в транзакции изменения данных.
+	// События читает и доставляет relay через интерфейс Outbox.
+	Outbox bool
+
+	// Migrate применяет встроенные миграции схемы при создании хранилища
+	Migrate bool
 }
 
 // NewPostgresStorage создает новый экземпляр PostgresStorage
 func NewPostgresStorage(ctx context.Context, dsn string) (*PostgresStorage, error) {
+	return NewPostgresStorageWithOptions(ctx, dsn, PostgresOptions{})
+}
+
+// NewPostgresStorageWithOptions создает новый экземпляр PostgresStorage с параметрами opts
+func NewPostgresStorageWithOptions(ctx context.Context, dsn string, opts PostgresOptions) (*PostgresStorage, error) {
 	db, err := pgxpool.New(ctx, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
@@ -30,19 +86,40 @@ func NewPostgresStorage(ctx context.Context, dsn string) (*PostgresStorage, erro
 
 	// Проверяем соединение
 	if err := db.Ping(ctx); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &PostgresStorage{
-		db:               db,
-		postConverter:    converter.NewPostConverter(),
-		commentConverter: converter.NewCommentConverter(),
-		treeConverter:    converter.NewTreeConverter(),
-	}, nil
+	storage := &PostgresStorage{
+		db:                db,
+		outbox:            opts.Outbox,
+		postConverter:     converter.NewPostConverter(),
+		commentConverter:  converter.NewCommentConverter(),
+		treeConverter:     converter.NewTreeConverter(),
+		revisionConverter: converter.NewRevisionConverter(),
+		userConverter:     converter.NewUserConverter(),
+	}
+
+	if opts.Migrate {
+		migrator, err := storage.Migrator()
+		if err == nil {
+			_, err = migrator.Up(ctx)
+		}
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	return storage, nil
 }
 
-// Close закрывает соединение с базой данных
+// Close закрывает соединение с базой данных.
+// Для хранилища внутри WithTx ничего не делает: пул закрывает исходное хранилище.
 func (s *PostgresStorage) Close() error {
+	if s.tx != nil {
+		return nil
+	}
 	s.db.Close()
 	return nil
 }
@@ -52,10 +129,69 @@ func (s *PostgresStorage) HealthCheck(ctx context.Context) error {
 	return s.db.Ping(ctx)
 }
 
+// conn возвращает соединение для запросов: транзакцию WithTx или пул.
+// Транзакции методов внутри WithTx становятся точками сохранения.
+func (s *PostgresStorage) conn() pgxQuerier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// WithTx выполняет fn в транзакции PostgreSQL, а внутри другой транзакции - в точке сохранения
+func (s *PostgresStorage) WithTx(ctx context.Context, fn func(tx Storage) error) error {
+	tx, err := s.conn().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback(ctx)
+
+	txStorage := *s
+	txStorage.tx = tx
+	if err := fn(&txStorage); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	return nil
+}
+
 // Операции с постами
 
+// postColumns - список колонок поста для SELECT и RETURNING.
+// Порядок должен совпадать с порядком полей в scanPost.
+const postColumns = `id, author_id, title, content, comments_enabled, created_at, comment_count, upvotes, downvotes`
+
+// scanPost сканирует строку с колонками postColumns в модель репозитория
+func scanPost(row pgx.Row, postDB *repoModel.PostDB) error {
+	return row.Scan(
+		&postDB.ID,
+		&postDB.AuthorID,
+		&postDB.Title,
+		&postDB.Content,
+		&postDB.CommentsEnabled,
+		&postDB.CreatedAt,
+		&postDB.CommentCount,
+		&postDB.Upvotes,
+		&postDB.Downvotes,
+	)
+}
+
 // CreatePost создает новый пост
 func (s *PostgresStorage) CreatePost(ctx context.Context, post *model.Post) (*model.Post, error) {
+	if post == nil || !post.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
+	// Как в MemoryStorage: комментарии нового поста включены,
+	// флаг учитывается только у поста с заданными ID и временем создания
+	if post.ID == uuid.Nil || post.CreatedAt.IsZero() {
+		post.CommentsEnabled = true
+	}
+
 	// Генерируем ID и время создания если не заданы
 	if post.ID == uuid.Nil {
 		post.ID = uuid.New()
@@ -74,26 +210,23 @@ func (s *PostgresStorage) CreatePost(ctx context.Context, post *model.Post) (*mo
 
 	// Выполняем INSERT
 	query := `
-		INSERT INTO posts (id, title, content, comments_enabled, created_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, title, content, comments_enabled, created_at
-	`
+		INSERT INTO posts (id, author_id, title, content, comments_enabled, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + postColumns
 
 	var result repoModel.PostDB
-	err := s.db.QueryRow(ctx, query,
+	err := scanPost(s.conn().QueryRow(ctx, query,
 		postDB.ID,
+		postDB.AuthorID,
 		postDB.Title,
 		postDB.Content,
 		postDB.CommentsEnabled,
 		postDB.CreatedAt,
-	).Scan(
-		&result.ID,
-		&result.Title,
-		&result.Content,
-		&result.CommentsEnabled,
-		&result.CreatedAt,
-	)
+	), &result)
 
+	if isUnknownAuthorError(err) {
+		return nil, ErrUnknownAuthor
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create post: %w", err)
 	}
@@ -105,20 +238,17 @@ func (s *PostgresStorage) CreatePost(ctx context.Context, post *model.Post) (*mo
 // GetPost получает пост по ID
 func (s *PostgresStorage) GetPost(ctx context.Context, id uuid.UUID) (*model.Post, error) {
 	query := `
-		SELECT id, title, content, comments_enabled, created_at
+		SELECT ` + postColumns + `
 		FROM posts
 		WHERE id = $1
 	`
 
 	var postDB repoModel.PostDB
-	err := s.db.QueryRow(ctx, query, id).Scan(
-		&postDB.ID,
-		&postDB.Title,
-		&postDB.Content,
-		&postDB.CommentsEnabled,
-		&postDB.CreatedAt,
-	)
+	err := scanPost(s.conn().QueryRow(ctx, query, id), &postDB)
 
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
@@ -137,29 +267,60 @@ func (s *PostgresStorage) GetPosts(ctx context.Context, limit, offset int) ([]*m
 	}
 
 	query := `
-		SELECT id, title, content, comments_enabled, created_at
+		SELECT ` + postColumns + `
 		FROM posts
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
 		LIMIT $1 OFFSET $2
 	`
 
-	rows, err := s.db.Query(ctx, query, limit, offset)
+	rows, err := s.conn().Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	return s.collectPosts(rows)
+}
+
+// GetPostsAfter получает страницу постов после заданной позиции (от новых к старым).
+// Keyset условие использует индекс idx_posts_created_at.
+func (s *PostgresStorage) GetPostsAfter(ctx context.Context, limit int, after *model.Cursor) ([]*model.Post, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var rows pgx.Rows
+	var err error
+	if after == nil {
+		rows, err = s.conn().Query(ctx, `
+			SELECT `+postColumns+`
+			FROM posts
+			ORDER BY created_at DESC, id DESC
+			LIMIT $1
+		`, limit)
+	} else {
+		rows, err = s.conn().Query(ctx, `
+			SELECT `+postColumns+`
+			FROM posts
+			WHERE created_at <= $2 AND (created_at, id) < ($2, $3)
+			ORDER BY created_at DESC, id DESC
+			LIMIT $1
+		`, limit, after.CreatedAt, after.ID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get posts: %w", err)
 	}
+
+	return s.collectPosts(rows)
+}
+
+// collectPosts читает все строки выборки постов и конвертирует их в доменные модели
+func (s *PostgresStorage) collectPosts(rows pgx.Rows) ([]*model.Post, error) {
 	defer rows.Close()
 
 	var posts []*repoModel.PostDB
 	for rows.Next() {
 		var postDB repoModel.PostDB
-		err := rows.Scan(
-			&postDB.ID,
-			&postDB.Title,
-			&postDB.Content,
-			&postDB.CommentsEnabled,
-			&postDB.CreatedAt,
-		)
-		if err != nil {
+		if err := scanPost(rows, &postDB); err != nil {
 			return nil, fmt.Errorf("failed to scan post: %w", err)
 		}
 		posts = append(posts, &postDB)
@@ -173,31 +334,36 @@ func (s *PostgresStorage) GetPosts(ctx context.Context, limit, offset int) ([]*m
 	return s.postConverter.ToDomainModels(posts), nil
 }
 
-// UpdatePost обновляет пост
+// UpdatePost обновляет пост. Автор поста не меняется.
 func (s *PostgresStorage) UpdatePost(ctx context.Context, post *model.Post) (*model.Post, error) {
+	if post == nil || !post.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
 	postDB := s.postConverter.ToRepositoryModel(post)
 
+	// Валидируем модель репозитория по тем же правилам, что и при создании
+	if err := postDB.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
 	query := `
 		UPDATE posts
 		SET title = $2, content = $3, comments_enabled = $4
 		WHERE id = $1
-		RETURNING id, title, content, comments_enabled, created_at
-	`
+		RETURNING ` + postColumns
 
 	var result repoModel.PostDB
-	err := s.db.QueryRow(ctx, query,
+	err := scanPost(s.conn().QueryRow(ctx, query,
 		postDB.ID,
 		postDB.Title,
 		postDB.Content,
 		postDB.CommentsEnabled,
-	).Scan(
-		&result.ID,
-		&result.Title,
-		&result.Content,
-		&result.CommentsEnabled,
-		&result.CreatedAt,
-	)
+	), &result)
 
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update post: %w", err)
 	}
@@ -205,17 +371,56 @@ func (s *PostgresStorage) UpdatePost(ctx context.Context, post *model.Post) (*mo
 	return s.postConverter.ToDomainModel(&result), nil
 }
 
-// DeletePost удаляет пост
+// DeletePost удаляет пост; комментарии удаляются каскадно
 func (s *PostgresStorage) DeletePost(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM posts WHERE id = $1`
+	tx, err := s.conn().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback(ctx)
+
+	// Событию нужны удаляемые комментарии: relay завершит подписки на ответы к ним.
+	// Строка поста блокируется, поэтому новый комментарий не появится до удаления
+	var commentIDs []uuid.UUID
+	if s.outbox {
+		var locked uuid.UUID
+		err := tx.QueryRow(ctx, `SELECT id FROM posts WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get post: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, `SELECT id FROM comments WHERE post_id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to get post comments: %w", err)
+		}
+		commentIDs, err = pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		if err != nil {
+			return fmt.Errorf("failed to get post comments: %w", err)
+		}
+	}
 
-	result, err := s.db.Exec(ctx, query, id)
+	result, err := tx.Exec(ctx, `DELETE FROM posts WHERE id = $1`, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete post: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("post not found")
+		return ErrNotFound
+	}
+
+	if s.outbox {
+		event := model.PostDeletedEvent{PostID: id, CommentIDs: commentIDs}
+		if err := insertOutboxEvent(ctx, tx, model.OutboxPostDeleted, model.PostDeletedDedupKey(id), event); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
 	}
 
 	return nil
@@ -223,19 +428,37 @@ func (s *PostgresStorage) DeletePost(ctx context.Context, id uuid.UUID) error {
 
 // TogglePostComments включает/отключает комментарии для поста
 func (s *PostgresStorage) TogglePostComments(ctx context.Context, id uuid.UUID, enabled bool) error {
+	tx, err := s.conn().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback(ctx)
+
 	query := `
 		UPDATE posts
 		SET comments_enabled = $2
 		WHERE id = $1
 	`
 
-	result, err := s.db.Exec(ctx, query, id, enabled)
+	result, err := tx.Exec(ctx, query, id, enabled)
 	if err != nil {
 		return fmt.Errorf("failed to toggle post comments: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("post not found")
+		return ErrNotFound
+	}
+
+	if s.outbox {
+		event := model.CommentsToggledEvent{PostID: id, CommentsEnabled: enabled}
+		if err := insertOutboxEvent(ctx, tx, model.OutboxCommentsToggled, model.NewOutboxDedupKey(model.OutboxCommentsToggled, id), event); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
 	}
 
 	return nil
@@ -243,8 +466,85 @@ func (s *PostgresStorage) TogglePostComments(ctx context.Context, id uuid.UUID,
 
 // Comment operations
 
+// commentColumns - список колонок комментария для SELECT и RETURNING.
+// Порядок должен совпадать с порядком полей в scanComment.
+const commentColumns = `id, post_id, author_id, parent_id, content, created_at, edited_at, deleted_at,
+	reply_count, descendant_count, upvotes, downvotes`
+
+// liveDescendantCondition - условие видимости комментария c в выборках дерева:
+// неудаленный комментарий виден всегда, надгробие - только если у него есть живые потомки.
+// Живых потомков считает descendant_count, поэтому обход поддерева не нужен.
+const liveDescendantCondition = `(c.deleted_at IS NULL OR c.descendant_count > 0)`
+
+// commentOrderBy возвращает выражение ORDER BY (для алиаса c) для порядка сортировки комментариев.
+// Порядок совпадает с model.CommentSort.Less; пустое значение сортируется как OLD.
+// Колонки score и controversy генерируются из upvotes и downvotes (миграция 006).
+func commentOrderBy(sort model.CommentSort) string {
+	switch sort {
+	case model.CommentSortNew:
+		return `c.created_at DESC, c.id DESC`
+	case model.CommentSortTop:
+		return `c.score DESC, c.created_at ASC, c.id ASC`
+	case model.CommentSortControversial:
+		return `c.controversy DESC, c.created_at ASC, c.id ASC`
+	default:
+		return `c.created_at ASC, c.id ASC`
+	}
+}
+
+// scanComment сканирует строку с колонками commentColumns в модель репозитория
+func scanComment(row pgx.Row, commentDB *repoModel.CommentDB) error {
+	return row.Scan(
+		&commentDB.ID,
+		&commentDB.PostID,
+		&commentDB.AuthorID,
+		&commentDB.ParentID,
+		&commentDB.Content,
+		&commentDB.CreatedAt,
+		&commentDB.EditedAt,
+		&commentDB.DeletedAt,
+		&commentDB.ReplyCount,
+		&commentDB.DescendantCount,
+		&commentDB.Upvotes,
+		&commentDB.Downvotes,
+	)
+}
+
+// collectComments читает все строки выборки комментариев и конвертирует их в доменные модели
+func (s *PostgresStorage) collectComments(rows pgx.Rows) ([]model.Comment, error) {
+	defer rows.Close()
+
+	var comments []*repoModel.CommentDB
+	for rows.Next() {
+		var commentDB repoModel.CommentDB
+		if err := scanComment(rows, &commentDB); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, &commentDB)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	// Конвертируем в доменные модели
+	domainComments := s.commentConverter.ToDomainModels(comments)
+
+	// Конвертируем указатели в значения
+	result := make([]model.Comment, len(domainComments))
+	for i, comment := range domainComments {
+		result[i] = *comment
+	}
+
+	return result, nil
+}
+
 // CreateComment создает новый комментарий
 func (s *PostgresStorage) CreateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	if comment == nil || !comment.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
 	// Генерируем ID и время создания если не заданы
 	if comment.ID == uuid.Nil {
 		comment.ID = uuid.New()
@@ -261,53 +561,111 @@ func (s *PostgresStorage) CreateComment(ctx context.Context, comment *model.Comm
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
+	tx, err := s.conn().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback(ctx)
+
+	// Проверки MemoryStorage.CreateComment: внешние ключи не проверяют
+	// ни флаг комментариев поста, ни принадлежность родителя тому же посту, ни его удаление.
+	// Строки поста и родителя блокируются так же, как их блокирует обновление счетчиков ниже:
+	// флаг и deleted_at не меняются до конца транзакции, а блокировка не повышается
+	// и не ведет к взаимоблокировке двух ответов одному родителю.
+	var enabled bool
+	err = tx.QueryRow(ctx, `SELECT comments_enabled FROM posts WHERE id = $1 FOR NO KEY UPDATE`, commentDB.PostID).Scan(&enabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if !enabled {
+		return nil, fmt.Errorf("comments are disabled for this post")
+	}
+
+	if commentDB.ParentID != nil {
+		var (
+			parentPostID  uuid.UUID
+			parentDeleted bool
+		)
+		err = tx.QueryRow(ctx, `
+			SELECT post_id, deleted_at IS NOT NULL FROM comments WHERE id = $1 FOR NO KEY UPDATE
+		`, *commentDB.ParentID).Scan(&parentPostID, &parentDeleted)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent comment: %w", err)
+		}
+		if parentPostID != commentDB.PostID {
+			return nil, fmt.Errorf("parent comment belongs to different post")
+		}
+		if parentDeleted {
+			return nil, ErrCommentDeleted
+		}
+	}
+
 	// Выполняем INSERT
 	query := `
-		INSERT INTO comments (id, post_id, parent_id, content, created_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, post_id, parent_id, content, created_at
-	`
+		INSERT INTO comments (id, post_id, author_id, parent_id, content, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + commentColumns
 
 	var result repoModel.CommentDB
-	err := s.db.QueryRow(ctx, query,
+	err = scanComment(tx.QueryRow(ctx, query,
 		commentDB.ID,
 		commentDB.PostID,
+		commentDB.AuthorID,
 		commentDB.ParentID,
 		commentDB.Content,
 		commentDB.CreatedAt,
-	).Scan(
-		&result.ID,
-		&result.PostID,
-		&result.ParentID,
-		&result.Content,
-		&result.CreatedAt,
-	)
+	), &result)
 
+	if isUnknownAuthorError(err) {
+		return nil, ErrUnknownAuthor
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create comment: %w", err)
 	}
 
+	// Счетчики обновляются в той же транзакции, что и вставка
+	if err := adjustCommentCounters(ctx, tx, result.PostID, result.ParentID, 1, 1); err != nil {
+		return nil, err
+	}
+
 	// Конвертируем обратно в доменную модель
-	return s.commentConverter.ToDomainModel(&result), nil
+	created := s.commentConverter.ToDomainModel(&result)
+
+	// Событие фиксируется вместе с комментарием: сбой после Commit его не теряет
+	if s.outbox {
+		if err := insertOutboxEvent(ctx, tx, model.OutboxCommentAdded, model.CommentAddedDedupKey(created.ID), created); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+
+	return created, nil
 }
 
 // GetComment получает комментарий по ID
 func (s *PostgresStorage) GetComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
 	query := `
-		SELECT id, post_id, parent_id, content, created_at
+		SELECT ` + commentColumns + `
 		FROM comments
 		WHERE id = $1
 	`
 
 	var commentDB repoModel.CommentDB
-	err := s.db.QueryRow(ctx, query, id).Scan(
-		&commentDB.ID,
-		&commentDB.PostID,
-		&commentDB.ParentID,
-		&commentDB.Content,
-		&commentDB.CreatedAt,
-	)
+	err := scanComment(s.conn().QueryRow(ctx, query, id), &commentDB)
 
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get comment: %w", err)
 	}
@@ -318,146 +676,245 @@ func (s *PostgresStorage) GetComment(ctx context.Context, id uuid.UUID) (*model.
 // GetCommentsByPostID получает все комментарии для поста
 func (s *PostgresStorage) GetCommentsByPostID(ctx context.Context, postID uuid.UUID) ([]model.Comment, error) {
 	query := `
-		SELECT id, post_id, parent_id, content, created_at
+		SELECT ` + commentColumns + `
 		FROM comments
 		WHERE post_id = $1
-		ORDER BY created_at ASC
+		ORDER BY created_at ASC, id ASC
 	`
 
-	rows, err := s.db.Query(ctx, query, postID)
+	rows, err := s.conn().Query(ctx, query, postID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get comments: %w", err)
 	}
-	defer rows.Close()
-
-	var comments []*repoModel.CommentDB
-	for rows.Next() {
-		var commentDB repoModel.CommentDB
-		err := rows.Scan(
-			&commentDB.ID,
-			&commentDB.PostID,
-			&commentDB.ParentID,
-			&commentDB.Content,
-			&commentDB.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan comment: %w", err)
-		}
-		comments = append(comments, &commentDB)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration error: %w", err)
-	}
-
-	// Конвертируем в доменные модели
-	domainComments := s.commentConverter.ToDomainModels(comments)
 
-	// Конвертируем указатели в значения
-	result := make([]model.Comment, len(domainComments))
-	for i, comment := range domainComments {
-		result[i] = *comment
+	comments, err := s.collectComments(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	return comments, s.checkPostExists(ctx, postID, len(comments))
 }
 
 // GetRootCommentsByPostID получает только корневые комментарии с пагинацией
 // ПРОИЗВОДИТЕЛЬНОСТЬ: Избегаем загрузки всех комментариев сразу
-func (s *PostgresStorage) GetRootCommentsByPostID(ctx context.Context, postID uuid.UUID, limit, offset int) ([]model.Comment, error) {
+func (s *PostgresStorage) GetRootCommentsByPostID(ctx context.Context, postID uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
+	// Значения по умолчанию для пагинации
+	if limit <= 0 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
 	query := `
-		SELECT id, post_id, parent_id, content, created_at
-		FROM comments
-		WHERE post_id = $1 AND parent_id IS NULL
-		ORDER BY created_at ASC
+		SELECT ` + commentColumns + `
+		FROM comments c
+		WHERE c.post_id = $1 AND c.parent_id IS NULL
+		  AND ` + liveDescendantCondition + `
+		ORDER BY ` + commentOrderBy(sort) + `
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := s.db.Query(ctx, query, postID, limit, offset)
+	rows, err := s.conn().Query(ctx, query, postID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get root comments: %w", err)
 	}
-	defer rows.Close()
 
-	var comments []*repoModel.CommentDB
-	for rows.Next() {
-		var commentDB repoModel.CommentDB
-		err := rows.Scan(
-			&commentDB.ID,
-			&commentDB.PostID,
-			&commentDB.ParentID,
-			&commentDB.Content,
-			&commentDB.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan comment: %w", err)
-		}
-		comments = append(comments, &commentDB)
+	comments, err := s.collectComments(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration error: %w", err)
+	return comments, s.checkPostExists(ctx, postID, len(comments))
+}
+
+// GetCommentsByParentID получает дочерние комментарии с пагинацией
+// ПРОИЗВОДИТЕЛЬНОСТЬ: Решает N+1 проблему в GraphQL children резолвере
+func (s *PostgresStorage) GetCommentsByParentID(ctx context.Context, parentID uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
+	// Значения по умолчанию для пагинации
+	if limit <= 0 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
 	}
 
-	// Конвертируем в доменные модели
-	domainComments := s.commentConverter.ToDomainModels(comments)
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		WHERE c.parent_id = $1
+		  AND ` + liveDescendantCondition + `
+		ORDER BY ` + commentOrderBy(sort) + `
+		LIMIT $2 OFFSET $3
+	`
 
-	// Конвертируем указатели в значения
-	result := make([]model.Comment, len(domainComments))
-	for i, comment := range domainComments {
-		result[i] = *comment
+	rows, err := s.conn().Query(ctx, query, parentID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments by parent: %w", err)
 	}
 
-	return result, nil
+	return s.collectComments(rows)
 }
 
-// GetCommentsByParentID получает дочерние комментарии с пагинацией
-// ПРОИЗВОДИТЕЛЬНОСТЬ: Решает N+1 проблему в GraphQL children резолвере
-func (s *PostgresStorage) GetCommentsByParentID(ctx context.Context, parentID uuid.UUID, limit, offset int) ([]model.Comment, error) {
+// checkPostExists возвращает ErrNotFound, если выборка комментариев поста пуста и поста нет.
+// found - количество найденных строк: непустая выборка доказывает существование поста без запроса.
+func (s *PostgresStorage) checkPostExists(ctx context.Context, postID uuid.UUID, found int) error {
+	if found > 0 {
+		return nil
+	}
+
+	var exists bool
+	err := s.conn().QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1)`, postID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetCommentsByParentIDs получает дочерние комментарии нескольких родителей одним запросом.
+// ПРОИЗВОДИТЕЛЬНОСТЬ: Используется загрузчиками children резолвера вместо запроса на каждый комментарий
+func (s *PostgresStorage) GetCommentsByParentIDs(ctx context.Context, parentIDs []uuid.UUID, sort model.CommentSort, limit, offset int) (map[uuid.UUID][]model.Comment, error) {
+	comments, err := s.queryCommentsWindowed(ctx, `c.parent_id`, `c.parent_id = ANY($1)`, parentIDs, sort, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments by parents: %w", err)
+	}
+
+	groups := make(map[uuid.UUID][]model.Comment)
+	for _, comment := range comments {
+		groups[*comment.ParentID] = append(groups[*comment.ParentID], comment)
+	}
+
+	return groups, nil
+}
+
+// GetRootCommentsByPostIDs получает корневые комментарии нескольких постов одним запросом.
+func (s *PostgresStorage) GetRootCommentsByPostIDs(ctx context.Context, postIDs []uuid.UUID, sort model.CommentSort, limit, offset int) (map[uuid.UUID][]model.Comment, error) {
+	comments, err := s.queryCommentsWindowed(ctx, `c.post_id`, `c.post_id = ANY($1) AND c.parent_id IS NULL`, postIDs, sort, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get root comments: %w", err)
+	}
+
+	groups := make(map[uuid.UUID][]model.Comment)
+	for _, comment := range comments {
+		groups[comment.PostID] = append(groups[comment.PostID], comment)
+	}
+
+	return groups, nil
+}
+
+// queryCommentsWindowed выбирает видимые комментарии по условию scope (с параметром $1 = ids)
+// и нумерует их внутри каждой группы partition в порядке sort,
+// чтобы применить окно limit/offset к каждой группе отдельно.
+func (s *PostgresStorage) queryCommentsWindowed(ctx context.Context, partition, scope string, ids []uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
 	query := `
-		SELECT id, post_id, parent_id, content, created_at
-		FROM comments
-		WHERE parent_id = $1
-		ORDER BY created_at ASC
-		LIMIT $2 OFFSET $3
+		SELECT ` + commentColumns + `
+		FROM (
+			SELECT c.*, ROW_NUMBER() OVER (
+				PARTITION BY ` + partition + `
+				ORDER BY ` + commentOrderBy(sort) + `
+			) AS rn
+			FROM comments c
+			WHERE ` + scope + `
+			  AND ` + liveDescendantCondition + `
+		) windowed
+		WHERE rn > $3 AND rn <= $2 + $3
+		ORDER BY rn
 	`
 
-	rows, err := s.db.Query(ctx, query, parentID, limit, offset)
+	rows, err := s.conn().Query(ctx, query, ids, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.collectComments(rows)
+}
+
+// GetRootCommentsAfter получает страницу корневых комментариев поста после заданной позиции.
+// Keyset условие использует индекс idx_comments_post_root_created.
+func (s *PostgresStorage) GetRootCommentsAfter(ctx context.Context, postID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error) {
+	rows, err := s.queryCommentsAfter(ctx, `c.post_id = $1 AND c.parent_id IS NULL`, postID, limit, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get root comments: %w", err)
+	}
+
+	comments, err := s.collectComments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, s.checkPostExists(ctx, postID, len(comments))
+}
+
+// GetCommentsByParentIDAfter получает страницу дочерних комментариев после заданной позиции.
+// Keyset условие использует индекс idx_comments_parent_created.
+func (s *PostgresStorage) GetCommentsByParentIDAfter(ctx context.Context, parentID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error) {
+	rows, err := s.queryCommentsAfter(ctx, `c.parent_id = $1`, parentID, limit, after)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get comments by parent: %w", err)
 	}
-	defer rows.Close()
 
-	var comments []*repoModel.CommentDB
-	for rows.Next() {
-		var commentDB repoModel.CommentDB
-		err := rows.Scan(
-			&commentDB.ID,
-			&commentDB.PostID,
-			&commentDB.ParentID,
-			&commentDB.Content,
-			&commentDB.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan comment: %w", err)
-		}
-		comments = append(comments, &commentDB)
+	return s.collectComments(rows)
+}
+
+// GetCommentsByPostIDAfter получает неудаленные комментарии поста после заданной позиции.
+// Keyset условие использует индекс idx_comments_post_root_created по (post_id, created_at).
+func (s *PostgresStorage) GetCommentsByPostIDAfter(ctx context.Context, postID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error) {
+	rows, err := s.queryCommentsAfter(ctx, `c.post_id = $1 AND c.deleted_at IS NULL`, postID, limit, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments by post: %w", err)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration error: %w", err)
+	comments, err := s.collectComments(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	// Конвертируем в доменные модели
-	domainComments := s.commentConverter.ToDomainModels(comments)
+	return comments, s.checkPostExists(ctx, postID, len(comments))
+}
 
-	// Конвертируем указатели в значения
-	result := make([]model.Comment, len(domainComments))
-	for i, comment := range domainComments {
-		result[i] = *comment
+// queryCommentsAfter выполняет keyset выборку видимых комментариев по условию scope
+// (с параметром $1 = scopeID) от старых к новым, строго после позиции after.
+func (s *PostgresStorage) queryCommentsAfter(ctx context.Context, scope string, scopeID uuid.UUID, limit int, after *model.Cursor) (pgx.Rows, error) {
+	if limit <= 0 {
+		limit = 10
 	}
 
-	return result, nil
+	if after == nil {
+		return s.conn().Query(ctx, `
+			SELECT `+commentColumns+`
+			FROM comments c
+			WHERE `+scope+`
+			  AND `+liveDescendantCondition+`
+			ORDER BY c.created_at ASC, c.id ASC
+			LIMIT $2
+		`, scopeID, limit)
+	}
+
+	// Условие created_at >= $3 дублирует сравнение строк,
+	// чтобы планировщик использовал составной индекс с created_at как диапазон
+	return s.conn().Query(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		WHERE `+scope+`
+		  AND c.created_at >= $3 AND (c.created_at, c.id) > ($3, $4)
+		  AND `+liveDescendantCondition+`
+		ORDER BY c.created_at ASC, c.id ASC
+		LIMIT $2
+	`, scopeID, limit, after.CreatedAt, after.ID)
 }
 
 // GetCommentTree получает иерархическую структуру комментариев для поста
@@ -465,23 +922,24 @@ func (s *PostgresStorage) GetCommentTree(ctx context.Context, postID uuid.UUID)
 	query := `
 		WITH RECURSIVE comment_tree AS (
 			-- Базовый случай: корневые комментарии
-			SELECT id, post_id, parent_id, content, created_at, 0 as level
+			SELECT ` + commentColumns + `, 0 as level
 			FROM comments
 			WHERE post_id = $1 AND parent_id IS NULL
 
 			UNION ALL
 
 			-- Рекурсивная часть: дочерние комментарии
-			SELECT c.id, c.post_id, c.parent_id, c.content, c.created_at, ct.level + 1
+			SELECT c.id, c.post_id, c.author_id, c.parent_id, c.content, c.created_at, c.edited_at, c.deleted_at,
+				c.reply_count, c.descendant_count, c.upvotes, c.downvotes, ct.level + 1
 			FROM comments c
 			INNER JOIN comment_tree ct ON c.parent_id = ct.id
 		)
-		SELECT id, post_id, parent_id, content, created_at, level
+		SELECT ` + commentColumns + `, level
 		FROM comment_tree
-		ORDER BY level, created_at
+		ORDER BY level, created_at, id
 	`
 
-	rows, err := s.db.Query(ctx, query, postID)
+	rows, err := s.conn().Query(ctx, query, postID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get comment tree: %w", err)
 	}
@@ -493,9 +951,16 @@ func (s *PostgresStorage) GetCommentTree(ctx context.Context, postID uuid.UUID)
 		err := rows.Scan(
 			&commentDB.ID,
 			&commentDB.PostID,
+			&commentDB.AuthorID,
 			&commentDB.ParentID,
 			&commentDB.Content,
 			&commentDB.CreatedAt,
+			&commentDB.EditedAt,
+			&commentDB.DeletedAt,
+			&commentDB.ReplyCount,
+			&commentDB.DescendantCount,
+			&commentDB.Upvotes,
+			&commentDB.Downvotes,
 			&commentDB.Level,
 		)
 		if err != nil {
@@ -507,44 +972,609 @@ func (s *PostgresStorage) GetCommentTree(ctx context.Context, postID uuid.UUID)
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows iteration error: %w", err)
 	}
+	if err := s.checkPostExists(ctx, postID, len(comments)); err != nil {
+		return nil, err
+	}
 
-	// Строим дерево комментариев
-	return s.treeConverter.BuildCommentTree(comments), nil
+	// Строим дерево комментариев и убираем ветки из одних надгробий
+	return model.PruneDeletedBranches(s.treeConverter.BuildCommentTree(comments)), nil
 }
 
-// DeleteComment удаляет комментарий
+// GetCommentAncestorIDs получает ID предков комментария одним рекурсивным запросом
+func (s *PostgresStorage) GetCommentAncestorIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := s.conn().Query(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT parent_id, 1 AS depth FROM comments WHERE id = $1
+			UNION ALL
+			SELECT c.parent_id, a.depth + 1 FROM comments c
+			INNER JOIN ancestors a ON c.id = a.parent_id
+		)
+		SELECT parent_id FROM ancestors ORDER BY depth
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment ancestors: %w", err)
+	}
+	defer rows.Close()
+
+	// Первая строка есть всегда, если комментарий существует; у корня parent_id равен NULL
+	found := false
+	ancestors := []uuid.UUID{}
+	for rows.Next() {
+		found = true
+		var parentID *uuid.UUID
+		if err := rows.Scan(&parentID); err != nil {
+			return nil, fmt.Errorf("failed to scan comment ancestor: %w", err)
+		}
+		if parentID != nil {
+			ancestors = append(ancestors, *parentID)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	return ancestors, nil
+}
+
+// DeleteComment удаляет комментарий вместе с ответами (каскадно)
+// и уменьшает счетчики поста и предков на количество удаленных живых комментариев
 func (s *PostgresStorage) DeleteComment(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM comments WHERE id = $1`
+	tx, err := s.conn().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback(ctx)
 
-	result, err := s.db.Exec(ctx, query, id)
+	var current repoModel.CommentDB
+	err = scanComment(tx.QueryRow(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments
+		WHERE id = $1
+		FOR UPDATE
+	`, id), &current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
 	if err != nil {
+		return fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM comments WHERE id = $1`, id); err != nil {
 		return fmt.Errorf("failed to delete comment: %w", err)
 	}
 
+	// Живые потомки уже учтены в descendant_count удаляемого комментария
+	removed := current.DescendantCount
+	replyDelta := 0
+	if current.DeletedAt == nil {
+		removed++
+		replyDelta = -1
+	}
+	if err := adjustCommentCounters(ctx, tx, current.PostID, current.ParentID, replyDelta, -removed); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+
+	return nil
+}
+
+// SoftDeleteComment превращает комментарий в надгробие, сохраняя ответы на него.
+// Повторный вызов не меняет время удаления и счетчики.
+func (s *PostgresStorage) SoftDeleteComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	tx, err := s.conn().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback(ctx)
+
+	var wasDeleted bool
+	err = tx.QueryRow(ctx, `SELECT deleted_at IS NOT NULL FROM comments WHERE id = $1 FOR UPDATE`, id).Scan(&wasDeleted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	query := `
+		UPDATE comments
+		SET deleted_at = COALESCE(deleted_at, $2), content = $3
+		WHERE id = $1
+		RETURNING ` + commentColumns
+
+	var result repoModel.CommentDB
+	err = scanComment(tx.QueryRow(ctx, query, id, time.Now().UTC(), model.DeletedCommentContent), &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to soft delete comment: %w", err)
+	}
+
+	if !wasDeleted {
+		if err := adjustCommentCounters(ctx, tx, result.PostID, result.ParentID, -1, -1); err != nil {
+			return nil, err
+		}
+	}
+
+	deleted := s.commentConverter.ToDomainModel(&result)
+	if s.outbox {
+		if err := insertOutboxEvent(ctx, tx, model.OutboxCommentDeleted, model.CommentDeletedDedupKey(deleted.ID), deleted); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+
+	return deleted, nil
+}
+
+// adjustCommentCounters изменяет счетчики при появлении или исчезновении живых комментариев:
+// comment_count поста и descendant_count всех предков меняются на delta,
+// reply_count непосредственного родителя - на replyDelta.
+// Должно вызываться в транзакции, изменяющей сами комментарии.
+func adjustCommentCounters(ctx context.Context, tx pgx.Tx, postID uuid.UUID, parentID *uuid.UUID, replyDelta, delta int) error {
+	if delta != 0 {
+		_, err := tx.Exec(ctx, `UPDATE posts SET comment_count = comment_count + $2 WHERE id = $1`, postID, delta)
+		if err != nil {
+			return fmt.Errorf("failed to update post comment count: %w", err)
+		}
+	}
+
+	if parentID == nil {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM comments WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id FROM comments c
+			INNER JOIN ancestors a ON c.id = a.parent_id
+		)
+		UPDATE comments
+		SET descendant_count = descendant_count + $2,
+			reply_count = reply_count + CASE WHEN id = $1 THEN $3 ELSE 0 END
+		WHERE id IN (SELECT id FROM ancestors)
+	`, *parentID, delta, replyDelta)
+	if err != nil {
+		return fmt.Errorf("failed to update reply counts: %w", err)
+	}
+
+	return nil
+}
+
+// EditComment заменяет текст комментария и сохраняет предыдущую версию в ревизиях.
+// Чтение, запись ревизии и обновление выполняются в одной транзакции
+// с блокировкой строки, чтобы параллельные правки не теряли историю.
+func (s *PostgresStorage) EditComment(ctx context.Context, id uuid.UUID, content string) (*model.Comment, error) {
+	// Валидируем новый текст по правилам создания комментария
+	edited := model.Comment{Content: content}
+	if !edited.IsValidComment() {
+		return nil, ErrInvalidInput
+	}
+
+	tx, err := s.conn().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback(ctx)
+
+	var current repoModel.CommentDB
+	err = scanComment(tx.QueryRow(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments
+		WHERE id = $1
+		FOR UPDATE
+	`, id), &current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	if current.DeletedAt != nil {
+		return nil, ErrCommentDeleted
+	}
+
+	now := time.Now().UTC()
+
+	// Сохраняем заменяемый текст как ревизию
+	_, err = tx.Exec(ctx, `
+		INSERT INTO comment_revisions (id, comment_id, content, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, uuid.New(), id, current.Content, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save comment revision: %w", err)
+	}
+
+	var result repoModel.CommentDB
+	err = scanComment(tx.QueryRow(ctx, `
+		UPDATE comments
+		SET content = $2, edited_at = $3
+		WHERE id = $1
+		RETURNING `+commentColumns, id, content, now), &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to edit comment: %w", err)
+	}
+
+	updated := s.commentConverter.ToDomainModel(&result)
+	if s.outbox {
+		if err := insertOutboxEvent(ctx, tx, model.OutboxCommentEdited, model.NewOutboxDedupKey(model.OutboxCommentEdited, id), updated); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+
+	return updated, nil
+}
+
+// GetCommentRevisions получает предыдущие версии комментария от старых к новым
+func (s *PostgresStorage) GetCommentRevisions(ctx context.Context, commentID uuid.UUID) ([]model.CommentRevision, error) {
+	query := `
+		SELECT id, comment_id, content, created_at
+		FROM comment_revisions
+		WHERE comment_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := s.conn().Query(ctx, query, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []*repoModel.CommentRevisionDB
+	for rows.Next() {
+		var revisionDB repoModel.CommentRevisionDB
+		err := rows.Scan(
+			&revisionDB.ID,
+			&revisionDB.CommentID,
+			&revisionDB.Content,
+			&revisionDB.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment revision: %w", err)
+		}
+		revisions = append(revisions, &revisionDB)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	// Ревизий нет: различаем комментарий без редактирований и отсутствующий комментарий
+	if len(revisions) == 0 {
+		var exists bool
+		err := s.conn().QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1)`, commentID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get comment: %w", err)
+		}
+		if !exists {
+			return nil, ErrNotFound
+		}
+	}
+
+	return s.revisionConverter.ToDomainModels(revisions), nil
+}
+
+// Vote operations
+
+// SetVote устанавливает, заменяет или снимает голос пользователя за пост или комментарий.
+// Строка цели блокируется до конца транзакции, поэтому конкурентные голоса
+// за одну цель применяются последовательно и счетчики не расходятся с таблицей votes.
+func (s *PostgresStorage) SetVote(ctx context.Context, userID, targetID uuid.UUID, value model.VoteValue) (*model.VoteTally, error) {
+	if !value.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
+	tx, err := s.conn().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback(ctx)
+
+	// Определяем тип цели: таблицу со счетчиками и колонку ссылки в votes
+	tally := &model.VoteTally{TargetID: targetID}
+	table, column := "posts", "post_id"
+	err = tx.QueryRow(ctx, `SELECT id FROM posts WHERE id = $1 FOR UPDATE`, targetID).Scan(&tally.PostID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		table, column = "comments", "comment_id"
+
+		var deleted bool
+		err = tx.QueryRow(ctx, `SELECT post_id, deleted_at IS NOT NULL FROM comments WHERE id = $1 FOR UPDATE`, targetID).
+			Scan(&tally.PostID, &deleted)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		if err == nil && deleted {
+			return nil, ErrCommentDeleted
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vote target: %w", err)
+	}
+
+	var previous int
+	err = tx.QueryRow(ctx, `SELECT value FROM votes WHERE user_id = $1 AND `+column+` = $2`, userID, targetID).Scan(&previous)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get vote: %w", err)
+	}
+
+	next := value.Int()
+	if next == 0 {
+		_, err = tx.Exec(ctx, `DELETE FROM votes WHERE user_id = $1 AND `+column+` = $2`, userID, targetID)
+	} else {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO votes (user_id, `+column+`, value)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, `+column+`) WHERE `+column+` IS NOT NULL
+			DO UPDATE SET value = EXCLUDED.value
+		`, userID, targetID, next)
+	}
+	if isUnknownUserError(err) {
+		return nil, ErrUnknownUser
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save vote: %w", err)
+	}
+
+	upDelta, downDelta := voteDeltas(previous, next)
+	err = tx.QueryRow(ctx, `
+		UPDATE `+table+`
+		SET upvotes = upvotes + $2, downvotes = downvotes + $3
+		WHERE id = $1
+		RETURNING upvotes, downvotes
+	`, targetID, upDelta, downDelta).Scan(&tally.Upvotes, &tally.Downvotes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update vote counters: %w", err)
+	}
+
+	if s.outbox {
+		if err := insertOutboxEvent(ctx, tx, model.OutboxVoteTally, model.NewOutboxDedupKey(model.OutboxVoteTally, targetID), tally); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+
+	return tally, nil
+}
+
+// voteDeltas возвращает изменения счетчиков upvotes и downvotes
+// при замене голоса previous на next (значения 1, -1 или 0)
+func voteDeltas(previous, next int) (int, int) {
+	upDelta, downDelta := 0, 0
+	switch previous {
+	case 1:
+		upDelta--
+	case -1:
+		downDelta--
+	}
+	switch next {
+	case 1:
+		upDelta++
+	case -1:
+		downDelta++
+	}
+	return upDelta, downDelta
+}
+
+// Reaction operations
+
+// AddReaction добавляет реакцию пользователя на комментарий, если ее еще нет
+func (s *PostgresStorage) AddReaction(ctx context.Context, userID, commentID uuid.UUID, emoji string) error {
+	if !model.IsValidReactionEmoji(emoji) {
+		return ErrInvalidInput
+	}
+
+	var deleted bool
+	err := s.conn().QueryRow(ctx, `SELECT deleted_at IS NOT NULL FROM comments WHERE id = $1`, commentID).Scan(&deleted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get comment: %w", err)
+	}
+	if deleted {
+		return ErrCommentDeleted
+	}
+
+	_, err = s.conn().Exec(ctx, `
+		INSERT INTO comment_reactions (comment_id, user_id, emoji, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (comment_id, user_id, emoji) DO NOTHING
+	`, commentID, userID, emoji, time.Now().UTC())
+	if isUnknownUserError(err) {
+		return ErrUnknownUser
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add reaction: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveReaction снимает реакцию пользователя с комментария
+func (s *PostgresStorage) RemoveReaction(ctx context.Context, userID, commentID uuid.UUID, emoji string) error {
+	result, err := s.conn().Exec(ctx, `
+		DELETE FROM comment_reactions
+		WHERE comment_id = $1 AND user_id = $2 AND emoji = $3
+	`, commentID, userID, emoji)
+	if err != nil {
+		return fmt.Errorf("failed to remove reaction: %w", err)
+	}
+
+	// Реакции не было: различаем отсутствующую реакцию и отсутствующий комментарий
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("comment not found")
+		var exists bool
+		err := s.conn().QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1)`, commentID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to get comment: %w", err)
+		}
+		if !exists {
+			return ErrNotFound
+		}
 	}
 
 	return nil
 }
 
+// GetReactionsByCommentIDs получает сводки реакций для нескольких комментариев одним запросом
+// ПРОИЗВОДИТЕЛЬНОСТЬ: Используется загрузчиком Comment.reactions вместо запроса на каждый комментарий
+func (s *PostgresStorage) GetReactionsByCommentIDs(ctx context.Context, commentIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID][]model.Reaction, error) {
+	result := make(map[uuid.UUID][]model.Reaction)
+	if len(commentIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := s.conn().Query(ctx, `
+		SELECT comment_id, emoji, COUNT(*), BOOL_OR(user_id = $2)
+		FROM comment_reactions
+		WHERE comment_id = ANY($1)
+		GROUP BY comment_id, emoji
+		ORDER BY comment_id, MIN(created_at), emoji
+	`, commentIDs, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentID uuid.UUID
+		var reaction model.Reaction
+		if err := rows.Scan(&commentID, &reaction.Emoji, &reaction.Count, &reaction.ViewerReacted); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %w", err)
+		}
+		result[commentID] = append(result[commentID], reaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return result, nil
+}
+
+// User operations
+
+// CreateUser создает нового пользователя с уникальным именем
+func (s *PostgresStorage) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	if user == nil {
+		return nil, ErrInvalidInput
+	}
+
+	newUser := *user
+	newUser.Prepare()
+	if !newUser.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
+	userDB := s.userConverter.ToRepositoryModel(&newUser)
+	if err := userDB.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	query := `
+		INSERT INTO users (id, username, created_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, username, created_at
+	`
+
+	var result repoModel.UserDB
+	err := s.conn().QueryRow(ctx, query,
+		userDB.ID,
+		userDB.Username,
+		userDB.CreatedAt,
+	).Scan(
+		&result.ID,
+		&result.Username,
+		&result.CreatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return nil, ErrDuplicate
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return s.userConverter.ToDomainModel(&result), nil
+}
+
+// GetUser получает пользователя по ID
+func (s *PostgresStorage) GetUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	return s.getUserBy(ctx, "id", id)
+}
+
+// GetUserByUsername получает пользователя по имени
+func (s *PostgresStorage) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	return s.getUserBy(ctx, "username", username)
+}
+
+// getUserBy получает пользователя по значению уникальной колонки.
+// column подставляется в запрос напрямую и должен быть константой.
+func (s *PostgresStorage) getUserBy(ctx context.Context, column string, value any) (*model.User, error) {
+	query := `
+		SELECT id, username, created_at
+		FROM users
+		WHERE ` + column + ` = $1
+	`
+
+	var userDB repoModel.UserDB
+	err := s.conn().QueryRow(ctx, query, value).Scan(
+		&userDB.ID,
+		&userDB.Username,
+		&userDB.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return s.userConverter.ToDomainModel(&userDB), nil
+}
+
 // Complex operations
 
 // GetPostWithComments получает пост с комментариями
 func (s *PostgresStorage) GetPostWithComments(ctx context.Context, id uuid.UUID) (*model.PostWithComments, error) {
 	query := `
 		SELECT
-			p.id, p.title, p.content, p.comments_enabled, p.created_at,
-			c.id as comment_id, c.post_id as comment_post_id,
+			p.id, p.author_id, p.title, p.content, p.comments_enabled, p.created_at, p.comment_count,
+			p.upvotes, p.downvotes,
+			c.id as comment_id, c.post_id as comment_post_id, c.author_id as comment_author_id,
 			c.parent_id as comment_parent_id, c.content as comment_content,
-			c.created_at as comment_created_at
+			c.created_at as comment_created_at, c.edited_at as comment_edited_at,
+			c.deleted_at as comment_deleted_at, c.reply_count as comment_reply_count,
+			c.descendant_count as comment_descendant_count,
+			c.upvotes as comment_upvotes, c.downvotes as comment_downvotes
 		FROM posts p
 		LEFT JOIN comments c ON p.id = c.post_id
 		WHERE p.id = $1
-		ORDER BY c.created_at ASC
+		ORDER BY c.created_at ASC, c.id ASC
 	`
 
-	rows, err := s.db.Query(ctx, query, id)
+	rows, err := s.conn().Query(ctx, query, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get post with comments: %w", err)
 	}
@@ -555,15 +1585,26 @@ func (s *PostgresStorage) GetPostWithComments(ctx context.Context, id uuid.UUID)
 		var result repoModel.PostWithCommentsDB
 		err := rows.Scan(
 			&result.ID,
+			&result.AuthorID,
 			&result.Title,
 			&result.Content,
 			&result.CommentsEnabled,
 			&result.CreatedAt,
+			&result.CommentCount,
+			&result.Upvotes,
+			&result.Downvotes,
 			&result.CommentID,
 			&result.CommentPostID,
+			&result.CommentAuthorID,
 			&result.CommentParentID,
 			&result.CommentContent,
 			&result.CommentCreatedAt,
+			&result.CommentEditedAt,
+			&result.CommentDeletedAt,
+			&result.CommentReplyCount,
+			&result.CommentDescendantCount,
+			&result.CommentUpvotes,
+			&result.CommentDownvotes,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan post with comments: %w", err)
@@ -576,9 +1617,149 @@ func (s *PostgresStorage) GetPostWithComments(ctx context.Context, id uuid.UUID)
 	}
 
 	if len(results) == 0 {
-		return nil, fmt.Errorf("post not found")
+		return nil, ErrNotFound
 	}
 
 	// Конвертируем результат JOIN запроса в PostWithComments
 	return s.treeConverter.ToPostWithComments(results), nil
 }
+
+// Операции с outbox
+
+// insertOutboxEvent записывает событие в outbox в транзакции tx.
+// Событие с уже записанным ключом дедупликации не дублируется.
+func insertOutboxEvent(ctx context.Context, tx pgx.Tx, kind, dedupKey string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox event: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outbox_events (kind, dedup_key, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (dedup_key) DO NOTHING
+	`, kind, dedupKey, payload)
+	if err != nil {
+		return fmt.Errorf("failed to write outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimOutboxEvents захватывает события, не доставленные получателю.
+// Строка outbox_deliveries создается при первом захвате; если два экземпляра relay
+// захватывают событие одновременно, второй дождется первого и пропустит событие.
+func (s *PostgresStorage) ClaimOutboxEvents(ctx context.Context, consumer string, limit int, lease time.Duration) ([]model.OutboxEvent, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := s.conn().Query(ctx, `
+		WITH claimed AS (
+			INSERT INTO outbox_deliveries (event_id, consumer, locked_until)
+			SELECT e.id, $1::text, NOW() + $3 * INTERVAL '1 millisecond'
+			FROM outbox_events e
+			LEFT JOIN outbox_deliveries d ON d.event_id = e.id AND d.consumer = $1::text
+			WHERE e.delivered_at IS NULL
+			  AND (d.event_id IS NULL
+			       OR (d.delivered_at IS NULL AND (d.locked_until IS NULL OR d.locked_until < NOW())))
+			ORDER BY e.id
+			LIMIT $2
+			ON CONFLICT (event_id, consumer) DO UPDATE SET locked_until = EXCLUDED.locked_until
+			WHERE outbox_deliveries.delivered_at IS NULL
+			  AND (outbox_deliveries.locked_until IS NULL OR outbox_deliveries.locked_until < NOW())
+			RETURNING event_id, attempts
+		)
+		SELECT e.id, e.kind, e.dedup_key, e.payload, e.created_at, c.attempts
+		FROM claimed c
+		JOIN outbox_events e ON e.id = c.event_id
+		ORDER BY e.id
+	`, consumer, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []model.OutboxEvent{}
+	for rows.Next() {
+		var event model.OutboxEvent
+		if err := rows.Scan(&event.ID, &event.Kind, &event.DedupKey, &event.Payload, &event.CreatedAt, &event.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return events, nil
+}
+
+// MarkOutboxDelivered отмечает события доставленными получателю
+func (s *PostgresStorage) MarkOutboxDelivered(ctx context.Context, consumer string, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := s.conn().Exec(ctx, `
+		UPDATE outbox_deliveries SET delivered_at = NOW(), locked_until = NULL
+		WHERE consumer = $1 AND event_id = ANY($2)
+	`, consumer, ids)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox events delivered: %w", err)
+	}
+
+	return nil
+}
+
+// MarkOutboxFailed сохраняет ошибку доставки события получателю
+func (s *PostgresStorage) MarkOutboxFailed(ctx context.Context, consumer string, id int64, reason string) error {
+	_, err := s.conn().Exec(ctx, `
+		UPDATE outbox_deliveries SET attempts = attempts + 1, last_error = $3
+		WHERE consumer = $1 AND event_id = $2
+	`, consumer, id, reason)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event failed: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseOutboxEvents снимает захват недоставленных событий получателя
+func (s *PostgresStorage) ReleaseOutboxEvents(ctx context.Context, consumer string, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := s.conn().Exec(ctx, `
+		UPDATE outbox_deliveries SET locked_until = NULL
+		WHERE consumer = $1 AND event_id = ANY($2) AND delivered_at IS NULL
+	`, consumer, ids)
+	if err != nil {
+		return fmt.Errorf("failed to release outbox events: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeOutbox удаляет события, доставленные всем получателям (или до появления
+// учета по получателям), вместе с их строками outbox_deliveries
+func (s *PostgresStorage) PurgeOutbox(ctx context.Context, consumers []string, before time.Time) (int64, error) {
+	tag, err := s.conn().Exec(ctx, `
+		DELETE FROM outbox_events e
+		WHERE e.created_at < $2
+		  AND (e.delivered_at < $2 OR NOT EXISTS (
+			SELECT 1 FROM unnest($1::text[]) AS c(consumer)
+			WHERE NOT EXISTS (
+				SELECT 1 FROM outbox_deliveries d
+				WHERE d.event_id = e.id AND d.consumer = c.consumer AND d.delivered_at < $2
+			)
+		  ))
+	`, consumers, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge outbox events: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
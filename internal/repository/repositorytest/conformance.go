@@ -0,0 +1,1253 @@
+// Package repositorytest содержит общий набор тестов соответствия для реализаций repository.Storage.
+//
+// Набор фиксирует поведение, на которое опирается сервисный слой: порядок выборок,
+// границы пагинации, каскадное удаление, семантику ErrNotFound, форму дерева комментариев,
+// корректность счетчиков при конкурентных изменениях и откат транзакций WithTx.
+// Эталоном служит MemoryStorage; каждое хранилище, текущее или будущее, должно проходить RunConformance:
+//
+//	func TestMyStorage_Conformance(t *testing.T) {
+//		repositorytest.RunConformance(t, func(t *testing.T) repository.Storage {
+//			return newMyStorage(t)
+//		})
+//	}
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Factory создает пустое хранилище для одного теста.
+// Освобождение ресурсов хранилища регистрируется через t.Cleanup.
+type Factory func(t *testing.T) repository.Storage
+
+// RunConformance запускает набор тестов соответствия для хранилища.
+// Каждый подтест получает новое пустое хранилище от newStorage.
+func RunConformance(t *testing.T, newStorage Factory) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s repository.Storage)
+	}{
+		{"Posts", testPosts},
+		{"PostOrdering", testPostOrdering},
+		{"CommentOrdering", testCommentOrdering},
+		{"BatchLoading", testBatchLoading},
+		{"KeysetPagination", testKeysetPagination},
+		{"CommentTree", testCommentTree},
+		{"Cascade", testCascade},
+		{"NotFound", testNotFound},
+		{"UnknownAuthor", testUnknownAuthor},
+		{"UnknownUser", testUnknownUser},
+		{"EditAndSoftDelete", testEditAndSoftDelete},
+		{"Votes", testVotes},
+		{"Reactions", testReactions},
+		{"Users", testUsers},
+		{"PostWithComments", testPostWithComments},
+		{"Concurrency", testConcurrency},
+		{"Transactions", testTransactions},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newStorage(t))
+		})
+	}
+}
+
+// baseTime - начало отсчета времени создания в тестах.
+// Шаг в миллисекунду сохраняется всеми хранилищами без округления.
+var baseTime = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// at возвращает время создания с номером i
+func at(i int) time.Time {
+	return baseTime.Add(time.Duration(i) * time.Millisecond)
+}
+
+// createPost создает пост с заданным временем создания
+func createPost(t *testing.T, s repository.Storage, createdAt time.Time) *model.Post {
+	t.Helper()
+	post, err := s.CreatePost(context.Background(), &model.Post{
+		ID:              uuid.New(),
+		Title:           "Post",
+		Content:         "Content",
+		CommentsEnabled: true,
+		CreatedAt:       createdAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	return post
+}
+
+// createComment создает комментарий с заданным временем создания (nil parentID - корневой)
+func createComment(t *testing.T, s repository.Storage, postID uuid.UUID, parentID *uuid.UUID, createdAt time.Time) *model.Comment {
+	t.Helper()
+	comment, err := s.CreateComment(context.Background(), &model.Comment{
+		ID:        uuid.New(),
+		PostID:    postID,
+		ParentID:  parentID,
+		Content:   "Comment",
+		CreatedAt: createdAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	return comment
+}
+
+// createUser создает пользователя с уникальным именем
+func createUser(t *testing.T, s repository.Storage) *model.User {
+	t.Helper()
+	user, err := s.CreateUser(context.Background(), &model.User{Username: "user_" + uuid.NewString()[:8]})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return user
+}
+
+// getPost получает пост, завершая тест при ошибке
+func getPost(t *testing.T, s repository.Storage, id uuid.UUID) *model.Post {
+	t.Helper()
+	post, err := s.GetPost(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	return post
+}
+
+// getComment получает комментарий, завершая тест при ошибке
+func getComment(t *testing.T, s repository.Storage, id uuid.UUID) *model.Comment {
+	t.Helper()
+	comment, err := s.GetComment(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Failed to get comment: %v", err)
+	}
+	return comment
+}
+
+// postIDs возвращает ID постов в порядке выборки
+func postIDs(posts []*model.Post) []uuid.UUID {
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
+
+// commentIDs возвращает ID комментариев в порядке выборки
+func commentIDs(comments []model.Comment) []uuid.UUID {
+	ids := make([]uuid.UUID, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+	return ids
+}
+
+// window применяет к ids окно limit/offset
+func window(ids []uuid.UUID, limit, offset int) []uuid.UUID {
+	if offset >= len(ids) {
+		return []uuid.UUID{}
+	}
+	end := offset + limit
+	if end > len(ids) {
+		end = len(ids)
+	}
+	return ids[offset:end]
+}
+
+// equalIDs сравнивает списки ID с учетом порядка
+func equalIDs(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// expectIDs проверяет, что выборка вернула ровно want в указанном порядке
+func expectIDs(t *testing.T, what string, got, want []uuid.UUID) {
+	t.Helper()
+	if !equalIDs(got, want) {
+		t.Errorf("%s: expected %v, got %v", what, want, got)
+	}
+}
+
+// expectNotFound проверяет, что операция вернула ErrNotFound
+func expectNotFound(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("%s: expected ErrNotFound, got %v", what, err)
+	}
+}
+
+// testPosts проверяет создание, чтение, обновление и переключение комментариев поста
+func testPosts(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+
+	// Новый пост получает ID, время создания и включенные комментарии
+	created, err := s.CreatePost(ctx, &model.Post{Title: "Title", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	if created.ID == uuid.Nil || created.CreatedAt.IsZero() {
+		t.Errorf("Expected generated ID and created_at, got %s / %v", created.ID, created.CreatedAt)
+	}
+	if !created.CommentsEnabled {
+		t.Error("Expected comments to be enabled by default")
+	}
+
+	got := getPost(t, s, created.ID)
+	if got.Title != "Title" || got.Content != "Content" || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("Expected stored post %+v, got %+v", created, got)
+	}
+
+	// Заданные вызывающим кодом ID и время создания сохраняются
+	id := uuid.New()
+	explicit := createPost(t, s, at(1))
+	if !explicit.CreatedAt.Equal(at(1)) {
+		t.Errorf("Expected created_at %v, got %v", at(1), explicit.CreatedAt)
+	}
+	withID, err := s.CreatePost(ctx, &model.Post{ID: id, Title: "Title", Content: "Content", CommentsEnabled: true, CreatedAt: at(2)})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	if withID.ID != id {
+		t.Errorf("Expected ID %s, got %s", id, withID.ID)
+	}
+
+	// Обновление меняет текст
+	updated, err := s.UpdatePost(ctx, &model.Post{ID: created.ID, Title: "New title", Content: "New content", CommentsEnabled: true})
+	if err != nil {
+		t.Fatalf("Failed to update post: %v", err)
+	}
+	if updated.Title != "New title" || updated.Content != "New content" {
+		t.Errorf("Expected updated post, got %+v", updated)
+	}
+
+	// Выключенные комментарии запрещают новые комментарии
+	if err := s.TogglePostComments(ctx, created.ID, false); err != nil {
+		t.Fatalf("Failed to toggle comments: %v", err)
+	}
+	if getPost(t, s, created.ID).CommentsEnabled {
+		t.Error("Expected comments to be disabled")
+	}
+	if _, err := s.CreateComment(ctx, &model.Comment{PostID: created.ID, Content: "Comment"}); err == nil {
+		t.Error("Expected error for comment on post with disabled comments")
+	}
+
+	// Некорректные данные
+	invalid := []struct {
+		name string
+		call func() error
+	}{
+		{"CreatePost nil", func() error { _, err := s.CreatePost(ctx, nil); return err }},
+		{"CreatePost empty title", func() error {
+			_, err := s.CreatePost(ctx, &model.Post{Content: "Content"})
+			return err
+		}},
+		{"CreatePost empty content", func() error {
+			_, err := s.CreatePost(ctx, &model.Post{Title: "Title"})
+			return err
+		}},
+		{"UpdatePost nil", func() error { _, err := s.UpdatePost(ctx, nil); return err }},
+		{"UpdatePost empty title", func() error {
+			_, err := s.UpdatePost(ctx, &model.Post{ID: created.ID, Content: "Content"})
+			return err
+		}},
+	}
+	for _, tc := range invalid {
+		if err := tc.call(); !errors.Is(err, repository.ErrInvalidInput) {
+			t.Errorf("%s: expected ErrInvalidInput, got %v", tc.name, err)
+		}
+	}
+}
+
+// testPostOrdering проверяет порядок постов от новых к старым и границы пагинации
+func testPostOrdering(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+
+	// 12 постов: два последних делят время создания, порядок между ними задает ID
+	var posts []*model.Post
+	for i := 0; i < 10; i++ {
+		posts = append(posts, createPost(t, s, at(i)))
+	}
+	posts = append(posts, createPost(t, s, at(10)), createPost(t, s, at(10)))
+
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[j].Cursor().Less(posts[i].Cursor())
+	})
+	want := postIDs(posts)
+
+	all, err := s.GetPosts(ctx, 100, 0)
+	if err != nil {
+		t.Fatalf("Failed to get posts: %v", err)
+	}
+	expectIDs(t, "GetPosts order", postIDs(all), want)
+
+	cases := []struct {
+		name          string
+		limit, offset int
+		want          []uuid.UUID
+	}{
+		{"window", 3, 2, window(want, 3, 2)},
+		{"tail", 5, 10, window(want, 5, 10)},
+		{"offset past end", 5, 12, []uuid.UUID{}},
+		{"default limit", 0, 0, window(want, 10, 0)},
+		{"negative offset", 2, -1, window(want, 2, 0)},
+	}
+	for _, tc := range cases {
+		got, err := s.GetPosts(ctx, tc.limit, tc.offset)
+		if err != nil {
+			t.Fatalf("%s: failed to get posts: %v", tc.name, err)
+		}
+		expectIDs(t, "GetPosts "+tc.name, postIDs(got), tc.want)
+	}
+
+	// Keyset страницы вместе дают тот же порядок
+	var paged []uuid.UUID
+	var after *model.Cursor
+	for page := 0; ; page++ {
+		if page > len(want) {
+			t.Fatal("GetPostsAfter does not terminate")
+		}
+		got, err := s.GetPostsAfter(ctx, 5, after)
+		if err != nil {
+			t.Fatalf("Failed to get posts page: %v", err)
+		}
+		paged = append(paged, postIDs(got)...)
+		if len(got) < 5 {
+			break
+		}
+		cursor := got[len(got)-1].Cursor()
+		after = &cursor
+	}
+	expectIDs(t, "GetPostsAfter pages", paged, want)
+
+	first, err := s.GetPostsAfter(ctx, 0, nil)
+	if err != nil {
+		t.Fatalf("Failed to get posts page: %v", err)
+	}
+	expectIDs(t, "GetPostsAfter default limit", postIDs(first), window(want, 10, 0))
+}
+
+// testCommentOrdering проверяет порядки сортировки и пагинацию корневых и дочерних комментариев
+func testCommentOrdering(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+
+	users := []*model.User{createUser(t, s), createUser(t, s), createUser(t, s)}
+	vote := func(commentID uuid.UUID, values ...model.VoteValue) {
+		t.Helper()
+		for i, value := range values {
+			if _, err := s.SetVote(ctx, users[i].ID, commentID, value); err != nil {
+				t.Fatalf("Failed to vote: %v", err)
+			}
+		}
+	}
+
+	// Корни: b - самый популярный, d - самый спорный, a и d делят нулевой рейтинг
+	a := createComment(t, s, post.ID, nil, at(1))
+	b := createComment(t, s, post.ID, nil, at(2))
+	c := createComment(t, s, post.ID, nil, at(3))
+	d := createComment(t, s, post.ID, nil, at(4))
+	vote(b.ID, model.VoteUp, model.VoteUp, model.VoteUp)
+	vote(c.ID, model.VoteUp)
+	vote(d.ID, model.VoteUp, model.VoteDown)
+
+	roots := map[model.CommentSort][]uuid.UUID{
+		model.CommentSortOld:           {a.ID, b.ID, c.ID, d.ID},
+		model.CommentSortNew:           {d.ID, c.ID, b.ID, a.ID},
+		model.CommentSortTop:           {b.ID, c.ID, a.ID, d.ID},
+		model.CommentSortControversial: {d.ID, a.ID, b.ID, c.ID},
+		"":                             {a.ID, b.ID, c.ID, d.ID},
+	}
+	for order, want := range roots {
+		for _, page := range []struct{ limit, offset int }{{10, 0}, {2, 1}, {2, 3}, {2, 4}, {0, 0}, {3, -1}} {
+			got, err := s.GetRootCommentsByPostID(ctx, post.ID, order, page.limit, page.offset)
+			if err != nil {
+				t.Fatalf("Failed to get root comments: %v", err)
+			}
+			limit, offset := page.limit, page.offset
+			if limit <= 0 {
+				limit = 10
+			}
+			if offset < 0 {
+				offset = 0
+			}
+			expectIDs(t, fmt.Sprintf("GetRootCommentsByPostID %q limit=%d offset=%d", order, page.limit, page.offset),
+				commentIDs(got), window(want, limit, offset))
+		}
+	}
+
+	// Ответы сортируются так же; два ответа с одним временем упорядочены по ID
+	r1 := createComment(t, s, post.ID, &a.ID, at(5))
+	r2 := createComment(t, s, post.ID, &a.ID, at(6))
+	r3 := createComment(t, s, post.ID, &a.ID, at(6))
+	vote(r1.ID, model.VoteDown)
+	if r3.Cursor().Less(r2.Cursor()) {
+		r2, r3 = r3, r2
+	}
+
+	replies := map[model.CommentSort][]uuid.UUID{
+		model.CommentSortOld: {r1.ID, r2.ID, r3.ID},
+		model.CommentSortNew: {r3.ID, r2.ID, r1.ID},
+		model.CommentSortTop: {r2.ID, r3.ID, r1.ID},
+	}
+	for order, want := range replies {
+		got, err := s.GetCommentsByParentID(ctx, a.ID, order, 10, 0)
+		if err != nil {
+			t.Fatalf("Failed to get replies: %v", err)
+		}
+		expectIDs(t, fmt.Sprintf("GetCommentsByParentID %q", order), commentIDs(got), want)
+
+		got, err = s.GetCommentsByParentID(ctx, a.ID, order, 1, 1)
+		if err != nil {
+			t.Fatalf("Failed to get replies: %v", err)
+		}
+		expectIDs(t, fmt.Sprintf("GetCommentsByParentID %q window", order), commentIDs(got), want[1:2])
+	}
+
+	// Родитель без ответов - пустая выборка без ошибки
+	empty, err := s.GetCommentsByParentID(ctx, d.ID, model.CommentSortOld, 10, 0)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Expected no replies without error, got %v / %v", commentIDs(empty), err)
+	}
+
+	// Плоский список поста упорядочен от старых к новым
+	flat, err := s.GetCommentsByPostID(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("Failed to get comments: %v", err)
+	}
+	expectIDs(t, "GetCommentsByPostID", commentIDs(flat), []uuid.UUID{a.ID, b.ID, c.ID, d.ID, r1.ID, r2.ID, r3.ID})
+}
+
+// testBatchLoading проверяет пакетные выборки с отдельным окном для каждой группы
+func testBatchLoading(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+
+	first := createPost(t, s, at(0))
+	second := createPost(t, s, at(1))
+	empty := createPost(t, s, at(2))
+
+	firstRoots := []uuid.UUID{
+		createComment(t, s, first.ID, nil, at(3)).ID,
+		createComment(t, s, first.ID, nil, at(4)).ID,
+		createComment(t, s, first.ID, nil, at(5)).ID,
+	}
+	secondRoot := createComment(t, s, second.ID, nil, at(6))
+
+	replies := []uuid.UUID{
+		createComment(t, s, first.ID, &firstRoots[0], at(7)).ID,
+		createComment(t, s, first.ID, &firstRoots[0], at(8)).ID,
+	}
+	secondReply := createComment(t, s, second.ID, &secondRoot.ID, at(9))
+
+	roots, err := s.GetRootCommentsByPostIDs(ctx, []uuid.UUID{first.ID, second.ID, empty.ID}, model.CommentSortOld, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to batch load roots: %v", err)
+	}
+	if len(roots) != 2 {
+		t.Errorf("Expected groups only for posts with comments, got %d groups", len(roots))
+	}
+	expectIDs(t, "first post roots", commentIDs(roots[first.ID]), firstRoots[:2])
+	expectIDs(t, "second post roots", commentIDs(roots[second.ID]), []uuid.UUID{secondRoot.ID})
+
+	// Окно применяется к каждой группе: у второго поста после offset ничего не остается
+	roots, err = s.GetRootCommentsByPostIDs(ctx, []uuid.UUID{first.ID, second.ID}, model.CommentSortNew, 2, 1)
+	if err != nil {
+		t.Fatalf("Failed to batch load roots: %v", err)
+	}
+	if _, ok := roots[second.ID]; ok || len(roots) != 1 {
+		t.Errorf("Expected only first post group, got %d groups", len(roots))
+	}
+	expectIDs(t, "first post roots newest window", commentIDs(roots[first.ID]), []uuid.UUID{firstRoots[1], firstRoots[0]})
+
+	children, err := s.GetCommentsByParentIDs(ctx, []uuid.UUID{firstRoots[0], firstRoots[1], secondRoot.ID}, model.CommentSortOld, 0, -1)
+	if err != nil {
+		t.Fatalf("Failed to batch load replies: %v", err)
+	}
+	if len(children) != 2 {
+		t.Errorf("Expected groups only for parents with replies, got %d groups", len(children))
+	}
+	expectIDs(t, "first root replies", commentIDs(children[firstRoots[0]]), replies)
+	expectIDs(t, "second root replies", commentIDs(children[secondRoot.ID]), []uuid.UUID{secondReply.ID})
+
+	none, err := s.GetCommentsByParentIDs(ctx, nil, model.CommentSortOld, 10, 0)
+	if err != nil || len(none) != 0 {
+		t.Errorf("Expected empty result for no parents, got %v / %v", none, err)
+	}
+}
+
+// testKeysetPagination проверяет, что keyset страницы комментариев покрывают выборку без пропусков и повторов
+func testKeysetPagination(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+
+	// Пары комментариев с одинаковым временем проверяют сравнение по ID на границе страницы
+	var roots []*model.Comment
+	for i := 0; i < 7; i++ {
+		roots = append(roots, createComment(t, s, post.ID, nil, at(1+i/2)))
+	}
+	parent := roots[0]
+	var replies []*model.Comment
+	for i := 0; i < 5; i++ {
+		replies = append(replies, createComment(t, s, post.ID, &parent.ID, at(10+i/2)))
+	}
+
+	ordered := func(comments []*model.Comment) []uuid.UUID {
+		sorted := append([]*model.Comment(nil), comments...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].Cursor().Less(sorted[j].Cursor())
+		})
+		ids := make([]uuid.UUID, len(sorted))
+		for i, comment := range sorted {
+			ids[i] = comment.ID
+		}
+		return ids
+	}
+
+	collect := func(fetch func(after *model.Cursor) ([]model.Comment, error), total int) []uuid.UUID {
+		t.Helper()
+		var ids []uuid.UUID
+		var after *model.Cursor
+		for page := 0; ; page++ {
+			if page > total {
+				t.Fatal("Keyset pagination does not terminate")
+			}
+			got, err := fetch(after)
+			if err != nil {
+				t.Fatalf("Failed to get page: %v", err)
+			}
+			ids = append(ids, commentIDs(got)...)
+			if len(got) < 2 {
+				return ids
+			}
+			cursor := got[len(got)-1].Cursor()
+			after = &cursor
+		}
+	}
+
+	gotRoots := collect(func(after *model.Cursor) ([]model.Comment, error) {
+		return s.GetRootCommentsAfter(ctx, post.ID, 2, after)
+	}, len(roots))
+	expectIDs(t, "GetRootCommentsAfter pages", gotRoots, ordered(roots))
+
+	gotReplies := collect(func(after *model.Cursor) ([]model.Comment, error) {
+		return s.GetCommentsByParentIDAfter(ctx, parent.ID, 2, after)
+	}, len(replies))
+	expectIDs(t, "GetCommentsByParentIDAfter pages", gotReplies, ordered(replies))
+
+	first, err := s.GetRootCommentsAfter(ctx, post.ID, 0, nil)
+	if err != nil {
+		t.Fatalf("Failed to get page: %v", err)
+	}
+	expectIDs(t, "GetRootCommentsAfter default limit", commentIDs(first), ordered(roots))
+
+	// Позиция после последнего комментария дает пустую страницу
+	last := getComment(t, s, gotRoots[len(gotRoots)-1]).Cursor()
+	tail, err := s.GetRootCommentsAfter(ctx, post.ID, 2, &last)
+	if err != nil || len(tail) != 0 {
+		t.Errorf("Expected empty page after last comment, got %v / %v", commentIDs(tail), err)
+	}
+
+	// Комментарии поста всех уровней; удаленные пропускаются
+	all := append(append([]*model.Comment(nil), roots...), replies...)
+	gotAll := collect(func(after *model.Cursor) ([]model.Comment, error) {
+		return s.GetCommentsByPostIDAfter(ctx, post.ID, 2, after)
+	}, len(all))
+	expectIDs(t, "GetCommentsByPostIDAfter pages", gotAll, ordered(all))
+
+	if _, err := s.SoftDeleteComment(ctx, replies[0].ID); err != nil {
+		t.Fatalf("Failed to soft delete comment: %v", err)
+	}
+	since := roots[len(roots)-1].Cursor()
+	live, err := s.GetCommentsByPostIDAfter(ctx, post.ID, 10, &since)
+	if err != nil {
+		t.Fatalf("Failed to get comments: %v", err)
+	}
+	expectIDs(t, "GetCommentsByPostIDAfter without deleted", commentIDs(live), ordered(replies[1:]))
+}
+
+// testCommentTree проверяет форму дерева, надгробия и счетчики ответов
+func testCommentTree(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+
+	// root -> child -> grandchild, root -> sibling;
+	// tombstone (удален) -> survivor; dead (удален) -> deadChild (удален)
+	root := createComment(t, s, post.ID, nil, at(1))
+	child := createComment(t, s, post.ID, &root.ID, at(2))
+	grandchild := createComment(t, s, post.ID, &child.ID, at(3))
+	sibling := createComment(t, s, post.ID, &root.ID, at(4))
+	tombstone := createComment(t, s, post.ID, nil, at(5))
+	survivor := createComment(t, s, post.ID, &tombstone.ID, at(6))
+	dead := createComment(t, s, post.ID, nil, at(7))
+	deadChild := createComment(t, s, post.ID, &dead.ID, at(8))
+
+	for _, id := range []uuid.UUID{tombstone.ID, deadChild.ID, dead.ID} {
+		if _, err := s.SoftDeleteComment(ctx, id); err != nil {
+			t.Fatalf("Failed to soft delete comment: %v", err)
+		}
+	}
+
+	tree, err := s.GetCommentTree(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("Failed to get comment tree: %v", err)
+	}
+
+	type node struct {
+		id       uuid.UUID
+		children []node
+	}
+	want := []node{
+		{root.ID, []node{{child.ID, []node{{grandchild.ID, nil}}}, {sibling.ID, nil}}},
+		{tombstone.ID, []node{{survivor.ID, nil}}},
+	}
+	var check func(path string, got []model.CommentTree, want []node)
+	check = func(path string, got []model.CommentTree, want []node) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("%s: expected %d nodes, got %d", path, len(want), len(got))
+			return
+		}
+		for i := range want {
+			if got[i].ID != want[i].id {
+				t.Errorf("%s[%d]: expected %s, got %s", path, i, want[i].id, got[i].ID)
+				continue
+			}
+			check(fmt.Sprintf("%s[%d]", path, i), got[i].Children, want[i].children)
+		}
+	}
+	check("tree", tree, want)
+
+	if len(tree) == 2 {
+		if !tree[1].IsDeleted() || tree[1].Content != model.DeletedCommentContent {
+			t.Errorf("Expected tombstone with replaced content, got %+v", tree[1].Comment)
+		}
+		if tree[0].ReplyCount != 2 || tree[0].DescendantCount != 3 {
+			t.Errorf("Expected root counters 2/3, got %d/%d", tree[0].ReplyCount, tree[0].DescendantCount)
+		}
+	}
+
+	// Живые комментарии: root, child, grandchild, sibling, survivor
+	if got := getPost(t, s, post.ID).CommentCount; got != 5 {
+		t.Errorf("Expected 5 live comments on post, got %d", got)
+	}
+
+	// Выборки корней видят надгробие с живыми потомками, но не мертвую ветку
+	roots, err := s.GetRootCommentsByPostID(ctx, post.ID, model.CommentSortOld, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to get root comments: %v", err)
+	}
+	expectIDs(t, "visible roots", commentIDs(roots), []uuid.UUID{root.ID, tombstone.ID})
+
+	ancestors, err := s.GetCommentAncestorIDs(ctx, grandchild.ID)
+	if err != nil {
+		t.Fatalf("Failed to get ancestors: %v", err)
+	}
+	expectIDs(t, "grandchild ancestors", ancestors, []uuid.UUID{child.ID, root.ID})
+
+	ancestors, err = s.GetCommentAncestorIDs(ctx, root.ID)
+	if err != nil || ancestors == nil || len(ancestors) != 0 {
+		t.Errorf("Expected empty non-nil ancestors for root, got %v / %v", ancestors, err)
+	}
+
+	// Пост без комментариев дает пустое дерево без ошибки
+	other := createPost(t, s, at(9))
+	emptyTree, err := s.GetCommentTree(ctx, other.ID)
+	if err != nil || len(emptyTree) != 0 {
+		t.Errorf("Expected empty tree without error, got %d nodes / %v", len(emptyTree), err)
+	}
+}
+
+// testCascade проверяет каскадное удаление комментариев и постов вместе со связанными данными
+func testCascade(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+	user := createUser(t, s)
+
+	root := createComment(t, s, post.ID, nil, at(1))
+	child := createComment(t, s, post.ID, &root.ID, at(2))
+	grandchild := createComment(t, s, post.ID, &child.ID, at(3))
+	other := createComment(t, s, post.ID, nil, at(4))
+
+	if _, err := s.EditComment(ctx, child.ID, "Edited"); err != nil {
+		t.Fatalf("Failed to edit comment: %v", err)
+	}
+	if err := s.AddReaction(ctx, user.ID, grandchild.ID, "👍"); err != nil {
+		t.Fatalf("Failed to add reaction: %v", err)
+	}
+	if _, err := s.SetVote(ctx, user.ID, child.ID, model.VoteUp); err != nil {
+		t.Fatalf("Failed to vote: %v", err)
+	}
+
+	if err := s.DeleteComment(ctx, child.ID); err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+
+	for name, id := range map[string]uuid.UUID{"child": child.ID, "grandchild": grandchild.ID} {
+		_, err := s.GetComment(ctx, id)
+		expectNotFound(t, "GetComment deleted "+name, err)
+	}
+	_, err := s.GetCommentRevisions(ctx, child.ID)
+	expectNotFound(t, "GetCommentRevisions deleted comment", err)
+
+	reactions, err := s.GetReactionsByCommentIDs(ctx, []uuid.UUID{grandchild.ID}, user.ID)
+	if err != nil || len(reactions) != 0 {
+		t.Errorf("Expected reactions of deleted comment to be removed, got %v / %v", reactions, err)
+	}
+
+	if got := getComment(t, s, root.ID); got.ReplyCount != 0 || got.DescendantCount != 0 {
+		t.Errorf("Expected root counters 0/0 after delete, got %d/%d", got.ReplyCount, got.DescendantCount)
+	}
+	if got := getPost(t, s, post.ID).CommentCount; got != 2 {
+		t.Errorf("Expected 2 comments on post after delete, got %d", got)
+	}
+
+	// Удаление поста удаляет все его комментарии
+	if err := s.DeletePost(ctx, post.ID); err != nil {
+		t.Fatalf("Failed to delete post: %v", err)
+	}
+	_, err = s.GetPost(ctx, post.ID)
+	expectNotFound(t, "GetPost deleted", err)
+	for name, id := range map[string]uuid.UUID{"root": root.ID, "other": other.ID} {
+		_, err := s.GetComment(ctx, id)
+		expectNotFound(t, "GetComment of deleted post "+name, err)
+	}
+	_, err = s.GetCommentsByPostID(ctx, post.ID)
+	expectNotFound(t, "GetCommentsByPostID deleted post", err)
+
+	// Пользователь и его данные в других постах не затрагиваются
+	if _, err := s.GetUser(ctx, user.ID); err != nil {
+		t.Errorf("Expected user to survive post deletion, got %v", err)
+	}
+}
+
+// testNotFound проверяет, что операции над отсутствующими сущностями возвращают ErrNotFound
+func testNotFound(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+	user := createUser(t, s)
+	missing := uuid.New()
+
+	cases := []struct {
+		name string
+		call func() error
+	}{
+		{"GetPost", func() error { _, err := s.GetPost(ctx, missing); return err }},
+		{"UpdatePost", func() error {
+			_, err := s.UpdatePost(ctx, &model.Post{ID: missing, Title: "Title", Content: "Content"})
+			return err
+		}},
+		{"DeletePost", func() error { return s.DeletePost(ctx, missing) }},
+		{"TogglePostComments", func() error { return s.TogglePostComments(ctx, missing, false) }},
+		{"CreateComment on missing post", func() error {
+			_, err := s.CreateComment(ctx, &model.Comment{PostID: missing, Content: "Comment"})
+			return err
+		}},
+		{"CreateComment with missing parent", func() error {
+			_, err := s.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: &missing, Content: "Comment"})
+			return err
+		}},
+		{"GetComment", func() error { _, err := s.GetComment(ctx, missing); return err }},
+		{"GetCommentsByPostID", func() error { _, err := s.GetCommentsByPostID(ctx, missing); return err }},
+		{"GetRootCommentsByPostID", func() error {
+			_, err := s.GetRootCommentsByPostID(ctx, missing, model.CommentSortOld, 10, 0)
+			return err
+		}},
+		{"GetRootCommentsAfter", func() error { _, err := s.GetRootCommentsAfter(ctx, missing, 10, nil); return err }},
+		{"GetCommentsByPostIDAfter", func() error { _, err := s.GetCommentsByPostIDAfter(ctx, missing, 10, nil); return err }},
+		{"GetCommentTree", func() error { _, err := s.GetCommentTree(ctx, missing); return err }},
+		{"GetCommentAncestorIDs", func() error { _, err := s.GetCommentAncestorIDs(ctx, missing); return err }},
+		{"DeleteComment", func() error { return s.DeleteComment(ctx, missing) }},
+		{"SoftDeleteComment", func() error { _, err := s.SoftDeleteComment(ctx, missing); return err }},
+		{"EditComment", func() error { _, err := s.EditComment(ctx, missing, "Edited"); return err }},
+		{"GetCommentRevisions", func() error { _, err := s.GetCommentRevisions(ctx, missing); return err }},
+		{"SetVote", func() error { _, err := s.SetVote(ctx, user.ID, missing, model.VoteUp); return err }},
+		{"AddReaction", func() error { return s.AddReaction(ctx, user.ID, missing, "👍") }},
+		{"RemoveReaction", func() error { return s.RemoveReaction(ctx, user.ID, missing, "👍") }},
+		{"GetUser", func() error { _, err := s.GetUser(ctx, missing); return err }},
+		{"GetUserByUsername", func() error { _, err := s.GetUserByUsername(ctx, "missing_user"); return err }},
+		{"GetPostWithComments", func() error { _, err := s.GetPostWithComments(ctx, missing); return err }},
+	}
+	for _, tc := range cases {
+		expectNotFound(t, tc.name, tc.call())
+	}
+
+	// Существующий пост без комментариев - пустые выборки без ошибки
+	comments, err := s.GetCommentsByPostID(ctx, post.ID)
+	if err != nil || len(comments) != 0 {
+		t.Errorf("Expected no comments without error, got %v / %v", commentIDs(comments), err)
+	}
+	roots, err := s.GetRootCommentsByPostID(ctx, post.ID, model.CommentSortOld, 10, 0)
+	if err != nil || len(roots) != 0 {
+		t.Errorf("Expected no root comments without error, got %v / %v", commentIDs(roots), err)
+	}
+	page, err := s.GetRootCommentsAfter(ctx, post.ID, 10, nil)
+	if err != nil || len(page) != 0 {
+		t.Errorf("Expected empty root page without error, got %v / %v", commentIDs(page), err)
+	}
+}
+
+// testUnknownAuthor проверяет, что автор без учетной записи дает ErrUnknownAuthor во всех хранилищах
+func testUnknownAuthor(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+	missing := uuid.New()
+
+	if _, err := s.CreatePost(ctx, &model.Post{AuthorID: &missing, Title: "Title", Content: "Content"}); !errors.Is(err, repository.ErrUnknownAuthor) {
+		t.Errorf("CreatePost: expected ErrUnknownAuthor, got %v", err)
+	}
+	if _, err := s.CreateComment(ctx, &model.Comment{PostID: post.ID, AuthorID: &missing, Content: "Comment"}); !errors.Is(err, repository.ErrUnknownAuthor) {
+		t.Errorf("CreateComment: expected ErrUnknownAuthor, got %v", err)
+	}
+
+	// Неудачная вставка не меняет счетчики поста
+	got, err := s.GetPost(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	if got.CommentCount != 0 {
+		t.Errorf("Expected comment count 0, got %d", got.CommentCount)
+	}
+}
+
+// testUnknownUser проверяет, что голос или реакция пользователя без учетной записи
+// дает ErrUnknownUser во всех хранилищах
+func testUnknownUser(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+	comment := createComment(t, s, post.ID, nil, at(1))
+	missing := uuid.New()
+
+	if _, err := s.SetVote(ctx, missing, post.ID, model.VoteUp); !errors.Is(err, repository.ErrUnknownUser) {
+		t.Errorf("SetVote: expected ErrUnknownUser, got %v", err)
+	}
+	if err := s.AddReaction(ctx, missing, comment.ID, "👍"); !errors.Is(err, repository.ErrUnknownUser) {
+		t.Errorf("AddReaction: expected ErrUnknownUser, got %v", err)
+	}
+
+	// Неудачный голос не меняет счетчики поста
+	got, err := s.GetPost(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	if got.Upvotes != 0 {
+		t.Errorf("Expected 0 upvotes, got %d", got.Upvotes)
+	}
+}
+
+// testEditAndSoftDelete проверяет редактирование с ревизиями и мягкое удаление
+func testEditAndSoftDelete(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+	root := createComment(t, s, post.ID, nil, at(1))
+	reply := createComment(t, s, post.ID, &root.ID, at(2))
+
+	revisions, err := s.GetCommentRevisions(ctx, reply.ID)
+	if err != nil || revisions == nil || len(revisions) != 0 {
+		t.Errorf("Expected empty non-nil revisions for unedited comment, got %v / %v", revisions, err)
+	}
+
+	if _, err := s.EditComment(ctx, reply.ID, "Second"); err != nil {
+		t.Fatalf("Failed to edit comment: %v", err)
+	}
+	edited, err := s.EditComment(ctx, reply.ID, "Third")
+	if err != nil {
+		t.Fatalf("Failed to edit comment: %v", err)
+	}
+	if edited.Content != "Third" || edited.EditedAt == nil {
+		t.Errorf("Expected edited content with edited_at, got %+v", edited)
+	}
+
+	revisions, err = s.GetCommentRevisions(ctx, reply.ID)
+	if err != nil {
+		t.Fatalf("Failed to get revisions: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Content != "Comment" || revisions[1].Content != "Second" {
+		t.Errorf("Expected revisions [Comment Second], got %+v", revisions)
+	}
+
+	if _, err := s.EditComment(ctx, reply.ID, ""); !errors.Is(err, repository.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for empty content, got %v", err)
+	}
+
+	// Повторное мягкое удаление возвращает то же надгробие и не меняет счетчики
+	deleted, err := s.SoftDeleteComment(ctx, reply.ID)
+	if err != nil {
+		t.Fatalf("Failed to soft delete comment: %v", err)
+	}
+	if !deleted.IsDeleted() || deleted.Content != model.DeletedCommentContent {
+		t.Errorf("Expected tombstone, got %+v", deleted)
+	}
+	again, err := s.SoftDeleteComment(ctx, reply.ID)
+	if err != nil {
+		t.Fatalf("Failed to soft delete comment twice: %v", err)
+	}
+	if again.DeletedAt == nil || !again.DeletedAt.Equal(*deleted.DeletedAt) {
+		t.Errorf("Expected deleted_at to be preserved, got %v and %v", deleted.DeletedAt, again.DeletedAt)
+	}
+
+	if got := getComment(t, s, root.ID); got.ReplyCount != 0 || got.DescendantCount != 0 {
+		t.Errorf("Expected root counters 0/0 after soft delete, got %d/%d", got.ReplyCount, got.DescendantCount)
+	}
+	if got := getPost(t, s, post.ID).CommentCount; got != 1 {
+		t.Errorf("Expected 1 live comment on post, got %d", got)
+	}
+
+	if _, err := s.EditComment(ctx, reply.ID, "Fourth"); !errors.Is(err, repository.ErrCommentDeleted) {
+		t.Errorf("Expected ErrCommentDeleted for edit of deleted comment, got %v", err)
+	}
+	if _, err := s.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: &reply.ID, Content: "Reply"}); !errors.Is(err, repository.ErrCommentDeleted) {
+		t.Errorf("Expected ErrCommentDeleted for reply to deleted comment, got %v", err)
+	}
+
+	// Надгробие без живых потомков не попадает в выборку ответов
+	children, err := s.GetCommentsByParentID(ctx, root.ID, model.CommentSortOld, 10, 0)
+	if err != nil || len(children) != 0 {
+		t.Errorf("Expected deleted leaf to be hidden, got %v / %v", commentIDs(children), err)
+	}
+}
+
+// testVotes проверяет замену и снятие голосов и итоги голосования
+func testVotes(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+	comment := createComment(t, s, post.ID, nil, at(1))
+	alice, bob := createUser(t, s), createUser(t, s)
+
+	// Повторный голос ничего не меняет, новый заменяет предыдущий, VoteNone снимает голос
+	steps := []struct {
+		user     uuid.UUID
+		value    model.VoteValue
+		up, down int
+	}{
+		{alice.ID, model.VoteUp, 1, 0},
+		{alice.ID, model.VoteUp, 1, 0},
+		{bob.ID, model.VoteDown, 1, 1},
+		{alice.ID, model.VoteDown, 0, 2},
+		{alice.ID, model.VoteNone, 0, 1},
+		{alice.ID, model.VoteNone, 0, 1},
+	}
+	for i, step := range steps {
+		tally, err := s.SetVote(ctx, step.user, post.ID, step.value)
+		if err != nil {
+			t.Fatalf("Step %d: failed to vote: %v", i, err)
+		}
+		if tally.TargetID != post.ID || tally.PostID != post.ID || tally.Upvotes != step.up || tally.Downvotes != step.down {
+			t.Errorf("Step %d: expected %d/%d, got %+v", i, step.up, step.down, tally)
+		}
+	}
+	if got := getPost(t, s, post.ID); got.Upvotes != 0 || got.Downvotes != 1 {
+		t.Errorf("Expected stored post votes 0/1, got %d/%d", got.Upvotes, got.Downvotes)
+	}
+
+	tally, err := s.SetVote(ctx, alice.ID, comment.ID, model.VoteUp)
+	if err != nil {
+		t.Fatalf("Failed to vote for comment: %v", err)
+	}
+	if tally.PostID != post.ID || tally.Upvotes != 1 {
+		t.Errorf("Expected comment tally 1/0 for post %s, got %+v", post.ID, tally)
+	}
+	if got := getComment(t, s, comment.ID); got.Upvotes != 1 || got.Downvotes != 0 {
+		t.Errorf("Expected stored comment votes 1/0, got %d/%d", got.Upvotes, got.Downvotes)
+	}
+
+	if _, err := s.SetVote(ctx, alice.ID, post.ID, model.VoteValue("SIDEWAYS")); !errors.Is(err, repository.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for unknown vote, got %v", err)
+	}
+
+	if _, err := s.SoftDeleteComment(ctx, comment.ID); err != nil {
+		t.Fatalf("Failed to soft delete comment: %v", err)
+	}
+	if _, err := s.SetVote(ctx, bob.ID, comment.ID, model.VoteUp); !errors.Is(err, repository.ErrCommentDeleted) {
+		t.Errorf("Expected ErrCommentDeleted for vote on deleted comment, got %v", err)
+	}
+}
+
+// testReactions проверяет сводки реакций, идемпотентность и порядок эмодзи
+func testReactions(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+	comment := createComment(t, s, post.ID, nil, at(1))
+	quiet := createComment(t, s, post.ID, nil, at(2))
+	alice, bob := createUser(t, s), createUser(t, s)
+
+	add := func(user uuid.UUID, emoji string) {
+		t.Helper()
+		if err := s.AddReaction(ctx, user, comment.ID, emoji); err != nil {
+			t.Fatalf("Failed to add reaction: %v", err)
+		}
+		// Порядок эмодзи определяется временем первой реакции
+		time.Sleep(2 * time.Millisecond)
+	}
+	add(alice.ID, "👍")
+	add(bob.ID, "❤️")
+	add(bob.ID, "👍")
+	add(alice.ID, "👍")
+
+	summary, err := s.GetReactionsByCommentIDs(ctx, []uuid.UUID{comment.ID, quiet.ID}, alice.ID)
+	if err != nil {
+		t.Fatalf("Failed to get reactions: %v", err)
+	}
+	if _, ok := summary[quiet.ID]; ok {
+		t.Error("Expected no group for comment without reactions")
+	}
+	want := []model.Reaction{{Emoji: "👍", Count: 2, ViewerReacted: true}, {Emoji: "❤️", Count: 1, ViewerReacted: false}}
+	if got := summary[comment.ID]; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected reactions %+v, got %+v", want, got)
+	}
+
+	// Снятие реакции идемпотентно
+	for i := 0; i < 2; i++ {
+		if err := s.RemoveReaction(ctx, alice.ID, comment.ID, "👍"); err != nil {
+			t.Fatalf("Failed to remove reaction: %v", err)
+		}
+	}
+	summary, err = s.GetReactionsByCommentIDs(ctx, []uuid.UUID{comment.ID}, alice.ID)
+	if err != nil {
+		t.Fatalf("Failed to get reactions: %v", err)
+	}
+	var thumbs model.Reaction
+	for _, reaction := range summary[comment.ID] {
+		if reaction.Emoji == "👍" {
+			thumbs = reaction
+		}
+	}
+	if thumbs.Count != 1 || thumbs.ViewerReacted {
+		t.Errorf("Expected 👍 count 1 without viewer, got %+v", summary[comment.ID])
+	}
+
+	anonymous, err := s.GetReactionsByCommentIDs(ctx, []uuid.UUID{comment.ID}, uuid.Nil)
+	if err != nil {
+		t.Fatalf("Failed to get reactions: %v", err)
+	}
+	for _, reaction := range anonymous[comment.ID] {
+		if reaction.ViewerReacted {
+			t.Errorf("Expected no viewer reactions for anonymous viewer, got %+v", reaction)
+		}
+	}
+
+	if err := s.AddReaction(ctx, alice.ID, comment.ID, ""); !errors.Is(err, repository.ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for empty emoji, got %v", err)
+	}
+
+	if _, err := s.SoftDeleteComment(ctx, quiet.ID); err != nil {
+		t.Fatalf("Failed to soft delete comment: %v", err)
+	}
+	if err := s.AddReaction(ctx, alice.ID, quiet.ID, "👍"); !errors.Is(err, repository.ErrCommentDeleted) {
+		t.Errorf("Expected ErrCommentDeleted for reaction on deleted comment, got %v", err)
+	}
+}
+
+// testUsers проверяет создание пользователей и уникальность имен
+func testUsers(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+
+	user, err := s.CreateUser(ctx, &model.User{Username: "conformance_user"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if user.ID == uuid.Nil || user.CreatedAt.IsZero() {
+		t.Errorf("Expected generated ID and created_at, got %+v", user)
+	}
+
+	byID, err := s.GetUser(ctx, user.ID)
+	if err != nil || byID.Username != user.Username {
+		t.Errorf("Expected user %q by ID, got %+v / %v", user.Username, byID, err)
+	}
+	byName, err := s.GetUserByUsername(ctx, user.Username)
+	if err != nil || byName.ID != user.ID {
+		t.Errorf("Expected user %s by name, got %+v / %v", user.ID, byName, err)
+	}
+
+	if _, err := s.CreateUser(ctx, &model.User{Username: "conformance_user"}); !errors.Is(err, repository.ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for taken username, got %v", err)
+	}
+	for _, invalid := range []*model.User{nil, {Username: ""}, {Username: "a b"}} {
+		if _, err := s.CreateUser(ctx, invalid); !errors.Is(err, repository.ErrInvalidInput) {
+			t.Errorf("Expected ErrInvalidInput for %+v, got %v", invalid, err)
+		}
+	}
+}
+
+// testPostWithComments проверяет выборку поста вместе с плоским списком комментариев
+func testPostWithComments(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+	root := createComment(t, s, post.ID, nil, at(1))
+	reply := createComment(t, s, post.ID, &root.ID, at(2))
+	second := createComment(t, s, post.ID, nil, at(3))
+
+	result, err := s.GetPostWithComments(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("Failed to get post with comments: %v", err)
+	}
+	if result.ID != post.ID || result.CommentCount != 3 {
+		t.Errorf("Expected post %s with 3 comments, got %+v", post.ID, result.Post)
+	}
+	expectIDs(t, "GetPostWithComments comments", commentIDs(result.Comments), []uuid.UUID{root.ID, reply.ID, second.ID})
+
+	bare := createPost(t, s, at(4))
+	result, err = s.GetPostWithComments(ctx, bare.ID)
+	if err != nil {
+		t.Fatalf("Failed to get post without comments: %v", err)
+	}
+	if result.ID != bare.ID || len(result.Comments) != 0 {
+		t.Errorf("Expected post %s without comments, got %+v", bare.ID, result)
+	}
+}
+
+// testConcurrency проверяет счетчики при конкурентных ответах и голосах
+func testConcurrency(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+	root := createComment(t, s, post.ID, nil, at(1))
+
+	const workers = 20
+
+	users := make([]*model.User, workers)
+	for i := range users {
+		users[i] = createUser(t, s)
+	}
+
+	errs := make(chan error, 2*workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: &root.ID, Content: fmt.Sprintf("Reply %d", i)})
+			errs <- err
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.SetVote(ctx, users[i].ID, root.ID, model.VoteUp)
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Concurrent operation failed: %v", err)
+		}
+	}
+
+	got := getComment(t, s, root.ID)
+	if got.ReplyCount != workers || got.DescendantCount != workers {
+		t.Errorf("Expected root counters %d/%d, got %d/%d", workers, workers, got.ReplyCount, got.DescendantCount)
+	}
+	if got.Upvotes != workers {
+		t.Errorf("Expected %d upvotes, got %d", workers, got.Upvotes)
+	}
+	if count := getPost(t, s, post.ID).CommentCount; count != workers+1 {
+		t.Errorf("Expected %d comments on post, got %d", workers+1, count)
+	}
+
+	replies, err := s.GetCommentsByParentID(ctx, root.ID, model.CommentSortOld, 100, 0)
+	if err != nil {
+		t.Fatalf("Failed to get replies: %v", err)
+	}
+	if len(replies) != workers {
+		t.Errorf("Expected %d replies, got %d", workers, len(replies))
+	}
+}
+
+// testTransactions проверяет фиксацию, откат и вложенные транзакции WithTx
+func testTransactions(t *testing.T, s repository.Storage) {
+	ctx := context.Background()
+	post := createPost(t, s, at(0))
+
+	// Фиксация: изменения видны внутри транзакции и после нее
+	var committed *model.Comment
+	err := s.WithTx(ctx, func(tx repository.Storage) error {
+		comment, err := tx.CreateComment(ctx, &model.Comment{ID: uuid.New(), PostID: post.ID, Content: "Committed", CreatedAt: at(1)})
+		if err != nil {
+			return err
+		}
+		if got, err := tx.GetPost(ctx, post.ID); err != nil || got.CommentCount != 1 {
+			return fmt.Errorf("expected own comment to be counted inside transaction, got %+v, %v", got, err)
+		}
+		committed = comment
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit transaction: %v", err)
+	}
+	if got := getComment(t, s, committed.ID); got.Content != "Committed" {
+		t.Errorf("Expected committed comment, got %+v", got)
+	}
+
+	// Откат: ошибка fn возвращается без изменений, и ни одно изменение не сохраняется
+	errRollback := errors.New("rollback")
+	rolledBackPost := uuid.New()
+	err = s.WithTx(ctx, func(tx repository.Storage) error {
+		if _, err := tx.CreatePost(ctx, &model.Post{ID: rolledBackPost, Title: "Post", Content: "Content", CreatedAt: at(2)}); err != nil {
+			return err
+		}
+		if _, err := tx.CreateComment(ctx, &model.Comment{ID: uuid.New(), PostID: post.ID, Content: "Rolled back", CreatedAt: at(3)}); err != nil {
+			return err
+		}
+		if err := tx.TogglePostComments(ctx, post.ID, false); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Expected fn error from WithTx, got %v", err)
+	}
+	_, err = s.GetPost(ctx, rolledBackPost)
+	expectNotFound(t, "post created in rolled back transaction", err)
+	if got := getPost(t, s, post.ID); !got.CommentsEnabled || got.CommentCount != 1 {
+		t.Errorf("Expected post unchanged by rolled back transaction, got %+v", got)
+	}
+
+	// Вложенная транзакция откатывается отдельно и фиксируется вместе с внешней
+	outerPost, innerPost, failedPost := uuid.New(), uuid.New(), uuid.New()
+	err = s.WithTx(ctx, func(tx repository.Storage) error {
+		if _, err := tx.CreatePost(ctx, &model.Post{ID: outerPost, Title: "Outer", Content: "Content", CreatedAt: at(4)}); err != nil {
+			return err
+		}
+		err := tx.WithTx(ctx, func(nested repository.Storage) error {
+			_, err := nested.CreatePost(ctx, &model.Post{ID: innerPost, Title: "Inner", Content: "Content", CreatedAt: at(5)})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		err = tx.WithTx(ctx, func(nested repository.Storage) error {
+			if _, err := nested.CreatePost(ctx, &model.Post{ID: failedPost, Title: "Failed", Content: "Content", CreatedAt: at(6)}); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			return fmt.Errorf("expected nested fn error, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit outer transaction: %v", err)
+	}
+	getPost(t, s, outerPost)
+	getPost(t, s, innerPost)
+	_, err = s.GetPost(ctx, failedPost)
+	expectNotFound(t, "post created in rolled back nested transaction", err)
+}
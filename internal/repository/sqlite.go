@@ -0,0 +1,1528 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/repository/converter"
+	repoModel "github.com/NarthurN/CommentsSystem/internal/repository/model"
+	"github.com/NarthurN/CommentsSystem/migrations"
+)
+
+// SQLiteStorage реализует интерфейс Storage для SQLite.
+//
+// Использует драйвер modernc.org/sqlite на чистом Go, поэтому сборка не требует cgo.
+// Схема создается встроенными миграциями migrations/sqlite при открытии базы.
+// Запросы повторяют PostgresStorage; вместо блокировок строк FOR UPDATE
+// изменяющие транзакции начинаются с BEGIN IMMEDIATE и выполняются по очереди.
+type SQLiteStorage struct {
+	db                *sql.DB
+	tx                *sql.Tx // Транзакция WithTx; nil вне транзакции
+	postConverter     *converter.PostConverter
+	commentConverter  *converter.CommentConverter
+	treeConverter     *converter.TreeConverter
+	revisionConverter *converter.RevisionConverter
+	userConverter     *converter.UserConverter
+}
+
+var _ Storage = (*SQLiteStorage)(nil)
+
+// sqliteQuerier - общие методы базы и транзакции, через которые SQLiteStorage выполняет запросы
+type sqliteQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqliteTx - транзакция метода SQLiteStorage: *sql.Tx или точка сохранения внутри WithTx
+type sqliteTx interface {
+	sqliteQuerier
+	Commit() error
+	Rollback() error
+}
+
+// sqliteTimeFormat - формат хранения времени в SQLite.
+// Время хранится текстом, поэтому формат фиксированной ширины в UTC
+// нужен, чтобы строки сортировались и сравнивались в хронологическом порядке.
+const sqliteTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// sqliteTime форматирует время для записи в SQLite
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// NewSQLiteStorage открывает базу SQLite в файле path, создавая каталог при необходимости,
+// и применяет к ней миграции
+func NewSQLiteStorage(ctx context.Context, path string) (*SQLiteStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	// Внешние ключи в SQLite выключены по умолчанию; WAL позволяет читать во время записи,
+	// а busy_timeout ждет освобождения блокировки записи вместо немедленной ошибки
+	dsn := path + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Проверяем соединение
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	if err := migrateSQLite(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return &SQLiteStorage{
+		db:                db,
+		postConverter:     converter.NewPostConverter(),
+		commentConverter:  converter.NewCommentConverter(),
+		treeConverter:     converter.NewTreeConverter(),
+		revisionConverter: converter.NewRevisionConverter(),
+		userConverter:     converter.NewUserConverter(),
+	}, nil
+}
+
+// migrateSQLite применяет непримененные миграции migrations/sqlite.
+// Все миграции выполняются в одной транзакции: SQLite поддерживает DDL в транзакциях,
+// а BEGIN IMMEDIATE не дает двум процессам применять миграции одновременно.
+func migrateSQLite(ctx context.Context, db *sql.DB) error {
+	fsys, err := fs.Sub(migrations.SQLite, "sqlite")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+	list, err := LoadMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var record appliedMigration
+		if err := rows.Scan(&record.version, &record.name, &record.checksum, &record.appliedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[record.version] = record
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if err := checkApplied(applied, list); err != nil {
+		return err
+	}
+
+	for _, migration := range list {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?1, ?2, ?3, ?4)`,
+			migration.Version, migration.Name, migration.Checksum, sqliteTime(time.Now()))
+		if err != nil {
+			return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	return nil
+}
+
+// Close закрывает соединение с базой данных.
+// Для хранилища внутри WithTx ничего не делает: базу закрывает исходное хранилище.
+func (s *SQLiteStorage) Close() error {
+	if s.tx != nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// HealthCheck проверяет состояние подключения к базе данных
+func (s *SQLiteStorage) HealthCheck(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// conn возвращает соединение для запросов: транзакцию WithTx или базу
+func (s *SQLiteStorage) conn() sqliteQuerier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// begin начинает транзакцию метода, а внутри WithTx - точку сохранения
+func (s *SQLiteStorage) begin(ctx context.Context) (sqliteTx, error) {
+	if s.tx == nil {
+		return s.db.BeginTx(ctx, nil)
+	}
+	if _, err := s.tx.ExecContext(ctx, `SAVEPOINT `+sqliteSavepointName); err != nil {
+		return nil, err
+	}
+	return &sqliteSavepoint{Tx: s.tx, ctx: ctx}, nil
+}
+
+// WithTx выполняет fn в транзакции SQLite, а внутри другой транзакции - в точке сохранения.
+// Транзакция начинается с BEGIN IMMEDIATE, поэтому проверки внутри fn
+// не устаревают до фиксации: остальные изменения ждут ее завершения.
+func (s *SQLiteStorage) WithTx(ctx context.Context, fn func(tx Storage) error) error {
+	txStorage := *s
+	var tx sqliteTx
+	if s.tx == nil {
+		sqlTx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+		}
+		txStorage.tx, tx = sqlTx, sqlTx
+	} else {
+		savepoint, err := s.begin(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+		}
+		tx = savepoint
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback()
+
+	if err := fn(&txStorage); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	return nil
+}
+
+// sqliteSavepointName - имя точек сохранения. Вложенные точки с одинаковым именем
+// допустимы: RELEASE и ROLLBACK TO относятся к последней из них.
+const sqliteSavepointName = "storage_tx"
+
+// sqliteSavepoint - вложенная транзакция внутри WithTx
+type sqliteSavepoint struct {
+	*sql.Tx
+	ctx  context.Context
+	done bool
+}
+
+// Commit фиксирует изменения точки сохранения во внешней транзакции
+func (sp *sqliteSavepoint) Commit() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	_, err := sp.ExecContext(sp.ctx, `RELEASE `+sqliteSavepointName)
+	return err
+}
+
+// Rollback отменяет изменения после точки сохранения, не затрагивая внешнюю транзакцию
+func (sp *sqliteSavepoint) Rollback() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	if _, err := sp.ExecContext(sp.ctx, `ROLLBACK TO `+sqliteSavepointName); err != nil {
+		return err
+	}
+	_, err := sp.ExecContext(sp.ctx, `RELEASE `+sqliteSavepointName)
+	return err
+}
+
+// idsJSON кодирует список ID в JSON массив для условия IN (SELECT value FROM json_each(?)),
+// которое заменяет = ANY($1) из PostgreSQL
+func idsJSON(ids []uuid.UUID) (string, error) {
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode ids: %w", err)
+	}
+	return string(data), nil
+}
+
+// isSQLiteForeignKeyError сообщает, что запрос нарушил внешний ключ.
+// В отличие от PostgreSQL, SQLite не называет нарушенное ограничение.
+func isSQLiteForeignKeyError(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+}
+
+// Операции с постами
+
+// CreatePost создает новый пост
+func (s *SQLiteStorage) CreatePost(ctx context.Context, post *model.Post) (*model.Post, error) {
+	if post == nil || !post.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
+	// Как в MemoryStorage: комментарии нового поста включены,
+	// флаг учитывается только у поста с заданными ID и временем создания
+	if post.ID == uuid.Nil || post.CreatedAt.IsZero() {
+		post.CommentsEnabled = true
+	}
+
+	// Генерируем ID и время создания если не заданы
+	if post.ID == uuid.Nil {
+		post.ID = uuid.New()
+	}
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = time.Now()
+	}
+
+	// Конвертируем в модель репозитория
+	postDB := s.postConverter.ToRepositoryModel(post)
+
+	// Валидируем модель репозитория
+	if err := postDB.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	query := `
+		INSERT INTO posts (id, author_id, title, content, comments_enabled, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+		RETURNING ` + postColumns
+
+	var result repoModel.PostDB
+	err := scanPost(s.conn().QueryRowContext(ctx, query,
+		postDB.ID,
+		postDB.AuthorID,
+		postDB.Title,
+		postDB.Content,
+		postDB.CommentsEnabled,
+		sqliteTime(postDB.CreatedAt),
+	), &result)
+
+	// Единственный внешний ключ постов - author_id
+	if isSQLiteForeignKeyError(err) {
+		return nil, ErrUnknownAuthor
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create post: %w", err)
+	}
+
+	return s.postConverter.ToDomainModel(&result), nil
+}
+
+// GetPost получает пост по ID
+func (s *SQLiteStorage) GetPost(ctx context.Context, id uuid.UUID) (*model.Post, error) {
+	query := `
+		SELECT ` + postColumns + `
+		FROM posts
+		WHERE id = ?1
+	`
+
+	var postDB repoModel.PostDB
+	err := scanPost(s.conn().QueryRowContext(ctx, query, id), &postDB)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+
+	return s.postConverter.ToDomainModel(&postDB), nil
+}
+
+// GetPosts получает список постов с пагинацией
+func (s *SQLiteStorage) GetPosts(ctx context.Context, limit, offset int) ([]*model.Post, error) {
+	// Значения по умолчанию для пагинации
+	if limit <= 0 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT `+postColumns+`
+		FROM posts
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?1 OFFSET ?2
+	`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	return s.collectPosts(rows)
+}
+
+// GetPostsAfter получает страницу постов после заданной позиции (от новых к старым)
+func (s *SQLiteStorage) GetPostsAfter(ctx context.Context, limit int, after *model.Cursor) ([]*model.Post, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var rows *sql.Rows
+	var err error
+	if after == nil {
+		rows, err = s.conn().QueryContext(ctx, `
+			SELECT `+postColumns+`
+			FROM posts
+			ORDER BY created_at DESC, id DESC
+			LIMIT ?1
+		`, limit)
+	} else {
+		rows, err = s.conn().QueryContext(ctx, `
+			SELECT `+postColumns+`
+			FROM posts
+			WHERE created_at <= ?2 AND (created_at, id) < (?2, ?3)
+			ORDER BY created_at DESC, id DESC
+			LIMIT ?1
+		`, limit, sqliteTime(after.CreatedAt), after.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	return s.collectPosts(rows)
+}
+
+// collectPosts читает все строки выборки постов и конвертирует их в доменные модели
+func (s *SQLiteStorage) collectPosts(rows *sql.Rows) ([]*model.Post, error) {
+	defer rows.Close()
+
+	var posts []*repoModel.PostDB
+	for rows.Next() {
+		var postDB repoModel.PostDB
+		if err := scanPost(rows, &postDB); err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, &postDB)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return s.postConverter.ToDomainModels(posts), nil
+}
+
+// UpdatePost обновляет пост. Автор поста не меняется.
+func (s *SQLiteStorage) UpdatePost(ctx context.Context, post *model.Post) (*model.Post, error) {
+	if post == nil || !post.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
+	postDB := s.postConverter.ToRepositoryModel(post)
+
+	// Валидируем модель репозитория по тем же правилам, что и при создании
+	if err := postDB.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	query := `
+		UPDATE posts
+		SET title = ?2, content = ?3, comments_enabled = ?4
+		WHERE id = ?1
+		RETURNING ` + postColumns
+
+	var result repoModel.PostDB
+	err := scanPost(s.conn().QueryRowContext(ctx, query,
+		postDB.ID,
+		postDB.Title,
+		postDB.Content,
+		postDB.CommentsEnabled,
+	), &result)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+
+	return s.postConverter.ToDomainModel(&result), nil
+}
+
+// DeletePost удаляет пост
+func (s *SQLiteStorage) DeletePost(ctx context.Context, id uuid.UUID) error {
+	result, err := s.conn().ExecContext(ctx, `DELETE FROM posts WHERE id = ?1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// TogglePostComments включает/отключает комментарии для поста
+func (s *SQLiteStorage) TogglePostComments(ctx context.Context, id uuid.UUID, enabled bool) error {
+	result, err := s.conn().ExecContext(ctx, `UPDATE posts SET comments_enabled = ?2 WHERE id = ?1`, id, enabled)
+	if err != nil {
+		return fmt.Errorf("failed to toggle post comments: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Comment operations
+
+// collectComments читает все строки выборки комментариев и конвертирует их в доменные модели
+func (s *SQLiteStorage) collectComments(rows *sql.Rows) ([]model.Comment, error) {
+	defer rows.Close()
+
+	var comments []*repoModel.CommentDB
+	for rows.Next() {
+		var commentDB repoModel.CommentDB
+		if err := scanComment(rows, &commentDB); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, &commentDB)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	domainComments := s.commentConverter.ToDomainModels(comments)
+
+	// Конвертируем указатели в значения
+	result := make([]model.Comment, len(domainComments))
+	for i, comment := range domainComments {
+		result[i] = *comment
+	}
+
+	return result, nil
+}
+
+// CreateComment создает новый комментарий
+func (s *SQLiteStorage) CreateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	if comment == nil || !comment.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
+	// Генерируем ID и время создания если не заданы
+	if comment.ID == uuid.Nil {
+		comment.ID = uuid.New()
+	}
+	if comment.CreatedAt.IsZero() {
+		comment.CreatedAt = time.Now()
+	}
+
+	// Конвертируем в модель репозитория
+	commentDB := s.commentConverter.ToRepositoryModel(comment)
+
+	// Валидируем модель репозитория
+	if err := commentDB.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback()
+
+	// Проверки MemoryStorage.CreateComment: внешние ключи не проверяют
+	// ни флаг комментариев поста, ни принадлежность родителя тому же посту, ни его удаление.
+	// Транзакция начинается с BEGIN IMMEDIATE, поэтому проверенные строки не меняются до вставки.
+	var enabled bool
+	err = tx.QueryRowContext(ctx, `SELECT comments_enabled FROM posts WHERE id = ?1`, commentDB.PostID).Scan(&enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if !enabled {
+		return nil, fmt.Errorf("comments are disabled for this post")
+	}
+
+	if commentDB.ParentID != nil {
+		var (
+			parentPostID  uuid.UUID
+			parentDeleted bool
+		)
+		err = tx.QueryRowContext(ctx, `
+			SELECT post_id, deleted_at IS NOT NULL FROM comments WHERE id = ?1
+		`, *commentDB.ParentID).Scan(&parentPostID, &parentDeleted)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent comment: %w", err)
+		}
+		if parentPostID != commentDB.PostID {
+			return nil, fmt.Errorf("parent comment belongs to different post")
+		}
+		if parentDeleted {
+			return nil, ErrCommentDeleted
+		}
+	}
+
+	query := `
+		INSERT INTO comments (id, post_id, author_id, parent_id, content, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+		RETURNING ` + commentColumns
+
+	var result repoModel.CommentDB
+	err = scanComment(tx.QueryRowContext(ctx, query,
+		commentDB.ID,
+		commentDB.PostID,
+		commentDB.AuthorID,
+		commentDB.ParentID,
+		commentDB.Content,
+		sqliteTime(commentDB.CreatedAt),
+	), &result)
+
+	// Пост и родитель проверены выше в той же транзакции: остается только author_id
+	if isSQLiteForeignKeyError(err) {
+		return nil, ErrUnknownAuthor
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	// Счетчики обновляются в той же транзакции, что и вставка
+	if err := adjustSQLiteCommentCounters(ctx, tx, result.PostID, result.ParentID, 1, 1); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+
+	return s.commentConverter.ToDomainModel(&result), nil
+}
+
+// GetComment получает комментарий по ID
+func (s *SQLiteStorage) GetComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments
+		WHERE id = ?1
+	`
+
+	var commentDB repoModel.CommentDB
+	err := scanComment(s.conn().QueryRowContext(ctx, query, id), &commentDB)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	return s.commentConverter.ToDomainModel(&commentDB), nil
+}
+
+// GetCommentsByPostID получает все комментарии для поста
+func (s *SQLiteStorage) GetCommentsByPostID(ctx context.Context, postID uuid.UUID) ([]model.Comment, error) {
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments
+		WHERE post_id = ?1
+		ORDER BY created_at ASC, id ASC
+	`, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	comments, err := s.collectComments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, s.checkPostExists(ctx, postID, len(comments))
+}
+
+// GetRootCommentsByPostID получает только корневые комментарии с пагинацией
+func (s *SQLiteStorage) GetRootCommentsByPostID(ctx context.Context, postID uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
+	// Значения по умолчанию для пагинации
+	if limit <= 0 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		WHERE c.post_id = ?1 AND c.parent_id IS NULL
+		  AND `+liveDescendantCondition+`
+		ORDER BY `+commentOrderBy(sort)+`
+		LIMIT ?2 OFFSET ?3
+	`, postID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get root comments: %w", err)
+	}
+
+	comments, err := s.collectComments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, s.checkPostExists(ctx, postID, len(comments))
+}
+
+// GetCommentsByParentID получает дочерние комментарии с пагинацией
+func (s *SQLiteStorage) GetCommentsByParentID(ctx context.Context, parentID uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
+	// Значения по умолчанию для пагинации
+	if limit <= 0 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		WHERE c.parent_id = ?1
+		  AND `+liveDescendantCondition+`
+		ORDER BY `+commentOrderBy(sort)+`
+		LIMIT ?2 OFFSET ?3
+	`, parentID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments by parent: %w", err)
+	}
+
+	return s.collectComments(rows)
+}
+
+// checkPostExists возвращает ErrNotFound, если выборка комментариев поста пуста и поста нет.
+// found - количество найденных строк: непустая выборка доказывает существование поста без запроса.
+func (s *SQLiteStorage) checkPostExists(ctx context.Context, postID uuid.UUID, found int) error {
+	if found > 0 {
+		return nil
+	}
+
+	var exists bool
+	err := s.conn().QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM posts WHERE id = ?1)`, postID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetCommentsByParentIDs получает дочерние комментарии нескольких родителей одним запросом
+func (s *SQLiteStorage) GetCommentsByParentIDs(ctx context.Context, parentIDs []uuid.UUID, sort model.CommentSort, limit, offset int) (map[uuid.UUID][]model.Comment, error) {
+	comments, err := s.queryCommentsWindowed(ctx, `c.parent_id`, `c.parent_id IN (SELECT value FROM json_each(?1))`, parentIDs, sort, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments by parents: %w", err)
+	}
+
+	groups := make(map[uuid.UUID][]model.Comment)
+	for _, comment := range comments {
+		groups[*comment.ParentID] = append(groups[*comment.ParentID], comment)
+	}
+
+	return groups, nil
+}
+
+// GetRootCommentsByPostIDs получает корневые комментарии нескольких постов одним запросом
+func (s *SQLiteStorage) GetRootCommentsByPostIDs(ctx context.Context, postIDs []uuid.UUID, sort model.CommentSort, limit, offset int) (map[uuid.UUID][]model.Comment, error) {
+	comments, err := s.queryCommentsWindowed(ctx, `c.post_id`, `c.post_id IN (SELECT value FROM json_each(?1)) AND c.parent_id IS NULL`, postIDs, sort, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get root comments: %w", err)
+	}
+
+	groups := make(map[uuid.UUID][]model.Comment)
+	for _, comment := range comments {
+		groups[comment.PostID] = append(groups[comment.PostID], comment)
+	}
+
+	return groups, nil
+}
+
+// queryCommentsWindowed выбирает видимые комментарии по условию scope (с параметром ?1 = ids)
+// и нумерует их внутри каждой группы partition в порядке sort,
+// чтобы применить окно limit/offset к каждой группе отдельно.
+func (s *SQLiteStorage) queryCommentsWindowed(ctx context.Context, partition, scope string, ids []uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	encoded, err := idsJSON(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM (
+			SELECT c.*, ROW_NUMBER() OVER (
+				PARTITION BY `+partition+`
+				ORDER BY `+commentOrderBy(sort)+`
+			) AS rn
+			FROM comments c
+			WHERE `+scope+`
+			  AND `+liveDescendantCondition+`
+		) windowed
+		WHERE rn > ?3 AND rn <= ?2 + ?3
+		ORDER BY rn
+	`, encoded, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.collectComments(rows)
+}
+
+// GetRootCommentsAfter получает страницу корневых комментариев поста после заданной позиции
+func (s *SQLiteStorage) GetRootCommentsAfter(ctx context.Context, postID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error) {
+	rows, err := s.queryCommentsAfter(ctx, `c.post_id = ?1 AND c.parent_id IS NULL`, postID, limit, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get root comments: %w", err)
+	}
+
+	comments, err := s.collectComments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, s.checkPostExists(ctx, postID, len(comments))
+}
+
+// GetCommentsByParentIDAfter получает страницу дочерних комментариев после заданной позиции
+func (s *SQLiteStorage) GetCommentsByParentIDAfter(ctx context.Context, parentID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error) {
+	rows, err := s.queryCommentsAfter(ctx, `c.parent_id = ?1`, parentID, limit, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments by parent: %w", err)
+	}
+
+	return s.collectComments(rows)
+}
+
+// GetCommentsByPostIDAfter получает неудаленные комментарии поста после заданной позиции
+func (s *SQLiteStorage) GetCommentsByPostIDAfter(ctx context.Context, postID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error) {
+	rows, err := s.queryCommentsAfter(ctx, `c.post_id = ?1 AND c.deleted_at IS NULL`, postID, limit, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments by post: %w", err)
+	}
+
+	comments, err := s.collectComments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, s.checkPostExists(ctx, postID, len(comments))
+}
+
+// queryCommentsAfter выполняет keyset выборку видимых комментариев по условию scope
+// (с параметром ?1 = scopeID) от старых к новым, строго после позиции after.
+func (s *SQLiteStorage) queryCommentsAfter(ctx context.Context, scope string, scopeID uuid.UUID, limit int, after *model.Cursor) (*sql.Rows, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	if after == nil {
+		return s.conn().QueryContext(ctx, `
+			SELECT `+commentColumns+`
+			FROM comments c
+			WHERE `+scope+`
+			  AND `+liveDescendantCondition+`
+			ORDER BY c.created_at ASC, c.id ASC
+			LIMIT ?2
+		`, scopeID, limit)
+	}
+
+	return s.conn().QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		WHERE `+scope+`
+		  AND c.created_at >= ?3 AND (c.created_at, c.id) > (?3, ?4)
+		  AND `+liveDescendantCondition+`
+		ORDER BY c.created_at ASC, c.id ASC
+		LIMIT ?2
+	`, scopeID, limit, sqliteTime(after.CreatedAt), after.ID)
+}
+
+// GetCommentTree получает иерархическую структуру комментариев для поста.
+// Рекурсивный запрос совпадает с PostgresStorage.GetCommentTree, но обходит только
+// id и уровни: колонки читаются соединением с comments, чтобы драйвер видел их объявленные
+// типы и сканировал время в time.Time.
+func (s *SQLiteStorage) GetCommentTree(ctx context.Context, postID uuid.UUID) ([]model.CommentTree, error) {
+	rows, err := s.conn().QueryContext(ctx, `
+		WITH RECURSIVE comment_tree AS (
+			-- Базовый случай: корневые комментарии
+			SELECT id, 0 AS level
+			FROM comments
+			WHERE post_id = ?1 AND parent_id IS NULL
+
+			UNION ALL
+
+			-- Рекурсивная часть: дочерние комментарии
+			SELECT c.id, ct.level + 1
+			FROM comments c
+			INNER JOIN comment_tree ct ON c.parent_id = ct.id
+		)
+		SELECT c.id, c.post_id, c.author_id, c.parent_id, c.content, c.created_at, c.edited_at, c.deleted_at,
+			c.reply_count, c.descendant_count, c.upvotes, c.downvotes, ct.level
+		FROM comment_tree ct
+		INNER JOIN comments c ON c.id = ct.id
+		ORDER BY ct.level, c.created_at, c.id
+	`, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment tree: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []*repoModel.CommentTreeDB
+	for rows.Next() {
+		var commentDB repoModel.CommentTreeDB
+		err := rows.Scan(
+			&commentDB.ID,
+			&commentDB.PostID,
+			&commentDB.AuthorID,
+			&commentDB.ParentID,
+			&commentDB.Content,
+			&commentDB.CreatedAt,
+			&commentDB.EditedAt,
+			&commentDB.DeletedAt,
+			&commentDB.ReplyCount,
+			&commentDB.DescendantCount,
+			&commentDB.Upvotes,
+			&commentDB.Downvotes,
+			&commentDB.Level,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment tree: %w", err)
+		}
+		comments = append(comments, &commentDB)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	if err := s.checkPostExists(ctx, postID, len(comments)); err != nil {
+		return nil, err
+	}
+
+	// Строим дерево комментариев и убираем ветки из одних надгробий
+	return model.PruneDeletedBranches(s.treeConverter.BuildCommentTree(comments)), nil
+}
+
+// GetCommentAncestorIDs получает ID предков комментария одним рекурсивным запросом
+func (s *SQLiteStorage) GetCommentAncestorIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := s.conn().QueryContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT parent_id, 1 AS depth FROM comments WHERE id = ?1
+			UNION ALL
+			SELECT c.parent_id, a.depth + 1 FROM comments c
+			INNER JOIN ancestors a ON c.id = a.parent_id
+		)
+		SELECT parent_id FROM ancestors ORDER BY depth
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment ancestors: %w", err)
+	}
+	defer rows.Close()
+
+	// Первая строка есть всегда, если комментарий существует; у корня parent_id равен NULL
+	found := false
+	ancestors := []uuid.UUID{}
+	for rows.Next() {
+		found = true
+		var parentID *uuid.UUID
+		if err := rows.Scan(&parentID); err != nil {
+			return nil, fmt.Errorf("failed to scan comment ancestor: %w", err)
+		}
+		if parentID != nil {
+			ancestors = append(ancestors, *parentID)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	return ancestors, nil
+}
+
+// DeleteComment удаляет комментарий вместе с ответами (каскадно)
+// и уменьшает счетчики поста и предков на количество удаленных живых комментариев
+func (s *SQLiteStorage) DeleteComment(ctx context.Context, id uuid.UUID) error {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback()
+
+	var current repoModel.CommentDB
+	err = scanComment(tx.QueryRowContext(ctx, `SELECT `+commentColumns+` FROM comments WHERE id = ?1`, id), &current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM comments WHERE id = ?1`, id); err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+
+	// Живые потомки уже учтены в descendant_count удаляемого комментария
+	removed := current.DescendantCount
+	replyDelta := 0
+	if current.DeletedAt == nil {
+		removed++
+		replyDelta = -1
+	}
+	if err := adjustSQLiteCommentCounters(ctx, tx, current.PostID, current.ParentID, replyDelta, -removed); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+
+	return nil
+}
+
+// SoftDeleteComment превращает комментарий в надгробие, сохраняя ответы на него.
+// Повторный вызов не меняет время удаления и счетчики.
+func (s *SQLiteStorage) SoftDeleteComment(ctx context.Context, id uuid.UUID) (*model.Comment, error) {
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback()
+
+	var wasDeleted bool
+	err = tx.QueryRowContext(ctx, `SELECT deleted_at IS NOT NULL FROM comments WHERE id = ?1`, id).Scan(&wasDeleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	var result repoModel.CommentDB
+	err = scanComment(tx.QueryRowContext(ctx, `
+		UPDATE comments
+		SET deleted_at = COALESCE(deleted_at, ?2), content = ?3
+		WHERE id = ?1
+		RETURNING `+commentColumns, id, sqliteTime(time.Now()), model.DeletedCommentContent), &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to soft delete comment: %w", err)
+	}
+
+	if !wasDeleted {
+		if err := adjustSQLiteCommentCounters(ctx, tx, result.PostID, result.ParentID, -1, -1); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+
+	return s.commentConverter.ToDomainModel(&result), nil
+}
+
+// adjustSQLiteCommentCounters - аналог adjustCommentCounters для транзакции SQLite
+func adjustSQLiteCommentCounters(ctx context.Context, tx sqliteQuerier, postID uuid.UUID, parentID *uuid.UUID, replyDelta, delta int) error {
+	if delta != 0 {
+		_, err := tx.ExecContext(ctx, `UPDATE posts SET comment_count = comment_count + ?2 WHERE id = ?1`, postID, delta)
+		if err != nil {
+			return fmt.Errorf("failed to update post comment count: %w", err)
+		}
+	}
+
+	if parentID == nil {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM comments WHERE id = ?1
+			UNION ALL
+			SELECT c.id, c.parent_id FROM comments c
+			INNER JOIN ancestors a ON c.id = a.parent_id
+		)
+		UPDATE comments
+		SET descendant_count = descendant_count + ?2,
+			reply_count = reply_count + CASE WHEN id = ?1 THEN ?3 ELSE 0 END
+		WHERE id IN (SELECT id FROM ancestors)
+	`, *parentID, delta, replyDelta)
+	if err != nil {
+		return fmt.Errorf("failed to update reply counts: %w", err)
+	}
+
+	return nil
+}
+
+// EditComment заменяет текст комментария и сохраняет предыдущую версию в ревизиях
+func (s *SQLiteStorage) EditComment(ctx context.Context, id uuid.UUID, content string) (*model.Comment, error) {
+	// Валидируем новый текст по правилам создания комментария
+	edited := model.Comment{Content: content}
+	if !edited.IsValidComment() {
+		return nil, ErrInvalidInput
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback()
+
+	var current repoModel.CommentDB
+	err = scanComment(tx.QueryRowContext(ctx, `SELECT `+commentColumns+` FROM comments WHERE id = ?1`, id), &current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	if current.DeletedAt != nil {
+		return nil, ErrCommentDeleted
+	}
+
+	now := sqliteTime(time.Now())
+
+	// Сохраняем заменяемый текст как ревизию
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO comment_revisions (id, comment_id, content, created_at)
+		VALUES (?1, ?2, ?3, ?4)
+	`, uuid.New(), id, current.Content, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save comment revision: %w", err)
+	}
+
+	var result repoModel.CommentDB
+	err = scanComment(tx.QueryRowContext(ctx, `
+		UPDATE comments
+		SET content = ?2, edited_at = ?3
+		WHERE id = ?1
+		RETURNING `+commentColumns, id, content, now), &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to edit comment: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+
+	return s.commentConverter.ToDomainModel(&result), nil
+}
+
+// GetCommentRevisions получает предыдущие версии комментария от старых к новым
+func (s *SQLiteStorage) GetCommentRevisions(ctx context.Context, commentID uuid.UUID) ([]model.CommentRevision, error) {
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT id, comment_id, content, created_at
+		FROM comment_revisions
+		WHERE comment_id = ?1
+		ORDER BY created_at ASC, id ASC
+	`, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []*repoModel.CommentRevisionDB
+	for rows.Next() {
+		var revisionDB repoModel.CommentRevisionDB
+		err := rows.Scan(
+			&revisionDB.ID,
+			&revisionDB.CommentID,
+			&revisionDB.Content,
+			&revisionDB.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment revision: %w", err)
+		}
+		revisions = append(revisions, &revisionDB)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	// Ревизий нет: различаем комментарий без редактирований и отсутствующий комментарий
+	if len(revisions) == 0 {
+		var exists bool
+		err := s.conn().QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id = ?1)`, commentID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get comment: %w", err)
+		}
+		if !exists {
+			return nil, ErrNotFound
+		}
+	}
+
+	return s.revisionConverter.ToDomainModels(revisions), nil
+}
+
+// Vote operations
+
+// SetVote устанавливает, заменяет или снимает голос пользователя за пост или комментарий.
+// Транзакция начинается с блокировки записи, поэтому голоса применяются последовательно
+// и счетчики не расходятся с таблицей votes.
+func (s *SQLiteStorage) SetVote(ctx context.Context, userID, targetID uuid.UUID, value model.VoteValue) (*model.VoteTally, error) {
+	if !value.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback()
+
+	// Определяем тип цели: таблицу со счетчиками и колонку ссылки в votes
+	tally := &model.VoteTally{TargetID: targetID}
+	table, column := "posts", "post_id"
+	err = tx.QueryRowContext(ctx, `SELECT id FROM posts WHERE id = ?1`, targetID).Scan(&tally.PostID)
+	if errors.Is(err, sql.ErrNoRows) {
+		table, column = "comments", "comment_id"
+
+		var deleted bool
+		err = tx.QueryRowContext(ctx, `SELECT post_id, deleted_at IS NOT NULL FROM comments WHERE id = ?1`, targetID).
+			Scan(&tally.PostID, &deleted)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		if err == nil && deleted {
+			return nil, ErrCommentDeleted
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vote target: %w", err)
+	}
+
+	var previous int
+	err = tx.QueryRowContext(ctx, `SELECT value FROM votes WHERE user_id = ?1 AND `+column+` = ?2`, userID, targetID).Scan(&previous)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get vote: %w", err)
+	}
+
+	next := value.Int()
+	if next == 0 {
+		_, err = tx.ExecContext(ctx, `DELETE FROM votes WHERE user_id = ?1 AND `+column+` = ?2`, userID, targetID)
+	} else {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO votes (user_id, `+column+`, value)
+			VALUES (?1, ?2, ?3)
+			ON CONFLICT (user_id, `+column+`) WHERE `+column+` IS NOT NULL
+			DO UPDATE SET value = excluded.value
+		`, userID, targetID, next)
+	}
+	// Цель проверена выше в той же транзакции: остается только user_id
+	if isSQLiteForeignKeyError(err) {
+		return nil, ErrUnknownUser
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save vote: %w", err)
+	}
+
+	upDelta, downDelta := voteDeltas(previous, next)
+	err = tx.QueryRowContext(ctx, `
+		UPDATE `+table+`
+		SET upvotes = upvotes + ?2, downvotes = downvotes + ?3
+		WHERE id = ?1
+		RETURNING upvotes, downvotes
+	`, targetID, upDelta, downDelta).Scan(&tally.Upvotes, &tally.Downvotes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update vote counters: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+
+	return tally, nil
+}
+
+// Reaction operations
+
+// AddReaction добавляет реакцию пользователя на комментарий, если ее еще нет
+func (s *SQLiteStorage) AddReaction(ctx context.Context, userID, commentID uuid.UUID, emoji string) error {
+	if !model.IsValidReactionEmoji(emoji) {
+		return ErrInvalidInput
+	}
+
+	var deleted bool
+	err := s.conn().QueryRowContext(ctx, `SELECT deleted_at IS NOT NULL FROM comments WHERE id = ?1`, commentID).Scan(&deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get comment: %w", err)
+	}
+	if deleted {
+		return ErrCommentDeleted
+	}
+
+	_, err = s.conn().ExecContext(ctx, `
+		INSERT INTO comment_reactions (comment_id, user_id, emoji, created_at)
+		VALUES (?1, ?2, ?3, ?4)
+		ON CONFLICT (comment_id, user_id, emoji) DO NOTHING
+	`, commentID, userID, emoji, sqliteTime(time.Now()))
+	// Комментарий проверен выше: остается user_id
+	if isSQLiteForeignKeyError(err) {
+		return ErrUnknownUser
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add reaction: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveReaction снимает реакцию пользователя с комментария
+func (s *SQLiteStorage) RemoveReaction(ctx context.Context, userID, commentID uuid.UUID, emoji string) error {
+	result, err := s.conn().ExecContext(ctx, `
+		DELETE FROM comment_reactions
+		WHERE comment_id = ?1 AND user_id = ?2 AND emoji = ?3
+	`, commentID, userID, emoji)
+	if err != nil {
+		return fmt.Errorf("failed to remove reaction: %w", err)
+	}
+
+	// Реакции не было: различаем отсутствующую реакцию и отсутствующий комментарий
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		var exists bool
+		err := s.conn().QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id = ?1)`, commentID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to get comment: %w", err)
+		}
+		if !exists {
+			return ErrNotFound
+		}
+	}
+
+	return nil
+}
+
+// GetReactionsByCommentIDs получает сводки реакций для нескольких комментариев одним запросом
+func (s *SQLiteStorage) GetReactionsByCommentIDs(ctx context.Context, commentIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID][]model.Reaction, error) {
+	result := make(map[uuid.UUID][]model.Reaction)
+	if len(commentIDs) == 0 {
+		return result, nil
+	}
+
+	encoded, err := idsJSON(commentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT comment_id, emoji, COUNT(*), MAX(user_id = ?2)
+		FROM comment_reactions
+		WHERE comment_id IN (SELECT value FROM json_each(?1))
+		GROUP BY comment_id, emoji
+		ORDER BY comment_id, MIN(created_at), emoji
+	`, encoded, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentID uuid.UUID
+		var reaction model.Reaction
+		if err := rows.Scan(&commentID, &reaction.Emoji, &reaction.Count, &reaction.ViewerReacted); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %w", err)
+		}
+		result[commentID] = append(result[commentID], reaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return result, nil
+}
+
+// User operations
+
+// CreateUser создает нового пользователя с уникальным именем
+func (s *SQLiteStorage) CreateUser(ctx context.Context, user *model.User) (*model.User, error) {
+	if user == nil {
+		return nil, ErrInvalidInput
+	}
+
+	newUser := *user
+	newUser.Prepare()
+	if !newUser.IsValid() {
+		return nil, ErrInvalidInput
+	}
+
+	userDB := s.userConverter.ToRepositoryModel(&newUser)
+	if err := userDB.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	var result repoModel.UserDB
+	err := s.conn().QueryRowContext(ctx, `
+		INSERT INTO users (id, username, created_at)
+		VALUES (?1, ?2, ?3)
+		RETURNING id, username, created_at
+	`, userDB.ID, userDB.Username, sqliteTime(userDB.CreatedAt)).Scan(
+		&result.ID,
+		&result.Username,
+		&result.CreatedAt,
+	)
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return nil, ErrDuplicate
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return s.userConverter.ToDomainModel(&result), nil
+}
+
+// GetUser получает пользователя по ID
+func (s *SQLiteStorage) GetUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	return s.getUserBy(ctx, "id", id)
+}
+
+// GetUserByUsername получает пользователя по имени
+func (s *SQLiteStorage) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	return s.getUserBy(ctx, "username", username)
+}
+
+// getUserBy получает пользователя по значению уникальной колонки.
+// column подставляется в запрос напрямую и должен быть константой.
+func (s *SQLiteStorage) getUserBy(ctx context.Context, column string, value any) (*model.User, error) {
+	var userDB repoModel.UserDB
+	err := s.conn().QueryRowContext(ctx, `SELECT id, username, created_at FROM users WHERE `+column+` = ?1`, value).Scan(
+		&userDB.ID,
+		&userDB.Username,
+		&userDB.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return s.userConverter.ToDomainModel(&userDB), nil
+}
+
+// Complex operations
+
+// GetPostWithComments получает пост с комментариями
+func (s *SQLiteStorage) GetPostWithComments(ctx context.Context, id uuid.UUID) (*model.PostWithComments, error) {
+	rows, err := s.conn().QueryContext(ctx, `
+		SELECT
+			p.id, p.author_id, p.title, p.content, p.comments_enabled, p.created_at, p.comment_count,
+			p.upvotes, p.downvotes,
+			c.id, c.post_id, c.author_id, c.parent_id, c.content, c.created_at, c.edited_at,
+			c.deleted_at, c.reply_count, c.descendant_count, c.upvotes, c.downvotes
+		FROM posts p
+		LEFT JOIN comments c ON p.id = c.post_id
+		WHERE p.id = ?1
+		ORDER BY c.created_at ASC, c.id ASC
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post with comments: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*repoModel.PostWithCommentsDB
+	for rows.Next() {
+		var result repoModel.PostWithCommentsDB
+		err := rows.Scan(
+			&result.ID,
+			&result.AuthorID,
+			&result.Title,
+			&result.Content,
+			&result.CommentsEnabled,
+			&result.CreatedAt,
+			&result.CommentCount,
+			&result.Upvotes,
+			&result.Downvotes,
+			&result.CommentID,
+			&result.CommentPostID,
+			&result.CommentAuthorID,
+			&result.CommentParentID,
+			&result.CommentContent,
+			&result.CommentCreatedAt,
+			&result.CommentEditedAt,
+			&result.CommentDeletedAt,
+			&result.CommentReplyCount,
+			&result.CommentDescendantCount,
+			&result.CommentUpvotes,
+			&result.CommentDownvotes,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post with comments: %w", err)
+		}
+		results = append(results, &result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return s.treeConverter.ToPostWithComments(results), nil
+}
@@ -0,0 +1,200 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/repository"
+	"github.com/google/uuid"
+)
+
+// openSQLite открывает SQLiteStorage во временном каталоге теста
+func openSQLite(t *testing.T) (*repository.SQLiteStorage, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "comments.db")
+	storage, err := repository.NewSQLiteStorage(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage, path
+}
+
+// TestSQLiteStorage_Reopen тестирует сохранность данных и повторное применение миграций
+func TestSQLiteStorage_Reopen(t *testing.T) {
+	storage, path := openSQLite(t)
+
+	usernames := populateStorage(t, storage)
+	want := readState(t, storage, usernames)
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	reopened, err := repository.NewSQLiteStorage(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer reopened.Close()
+
+	if got := readState(t, reopened, usernames); !reflect.DeepEqual(got, want) {
+		t.Errorf("State after reopen differs:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+// TestSQLiteStorage_CommentTree тестирует рекурсивную выборку дерева и счетчики
+func TestSQLiteStorage_CommentTree(t *testing.T) {
+	storage, _ := openSQLite(t)
+	ctx := context.Background()
+
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Post", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	create := func(parentID *uuid.UUID, content string) *model.Comment {
+		t.Helper()
+		comment, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: parentID, Content: content})
+		if err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+		return comment
+	}
+
+	root := create(nil, "Root")
+	reply := create(&root.ID, "Reply")
+	nested := create(&reply.ID, "Nested")
+	create(&root.ID, "Second reply")
+	create(nil, "Second root")
+
+	tree, err := storage.GetCommentTree(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("Failed to get comment tree: %v", err)
+	}
+	if len(tree) != 2 || tree[0].Comment.ID != root.ID {
+		t.Fatalf("Expected 2 roots starting with %s, got %+v", root.ID, tree)
+	}
+	if len(tree[0].Children) != 2 || tree[0].Children[0].Comment.ID != reply.ID {
+		t.Fatalf("Expected root replies in creation order, got %+v", tree[0].Children)
+	}
+	if got := tree[0].Children[0].Children; len(got) != 1 || got[0].Comment.ID != nested.ID {
+		t.Errorf("Expected nested comment under reply, got %+v", got)
+	}
+	if tree[0].Comment.ReplyCount != 2 || tree[0].Comment.DescendantCount != 3 {
+		t.Errorf("Expected root counters 2/3, got %d/%d", tree[0].Comment.ReplyCount, tree[0].Comment.DescendantCount)
+	}
+
+	ancestors, err := storage.GetCommentAncestorIDs(ctx, nested.ID)
+	if err != nil {
+		t.Fatalf("Failed to get ancestors: %v", err)
+	}
+	if !reflect.DeepEqual(ancestors, []uuid.UUID{reply.ID, root.ID}) {
+		t.Errorf("Expected ancestors [reply root], got %v", ancestors)
+	}
+
+	// Удаление ветки уменьшает счетчики поста и предков
+	if err := storage.DeleteComment(ctx, reply.ID); err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+	updated, err := storage.GetComment(ctx, root.ID)
+	if err != nil {
+		t.Fatalf("Failed to get comment: %v", err)
+	}
+	if updated.ReplyCount != 1 || updated.DescendantCount != 1 {
+		t.Errorf("Expected root counters 1/1 after delete, got %d/%d", updated.ReplyCount, updated.DescendantCount)
+	}
+	if p, _ := storage.GetPost(ctx, post.ID); p.CommentCount != 3 {
+		t.Errorf("Expected 3 comments on post, got %d", p.CommentCount)
+	}
+	if _, err := storage.GetComment(ctx, nested.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected nested comment to be deleted with branch, got %v", err)
+	}
+}
+
+// TestSQLiteStorage_TimeOrdering тестирует порядок и keyset пагинацию по времени,
+// которое SQLite хранит текстом
+func TestSQLiteStorage_TimeOrdering(t *testing.T) {
+	storage, _ := openSQLite(t)
+	ctx := context.Background()
+
+	// Времена, чьи текстовые представления без фиксированной ширины сортируются неверно
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	offsets := []time.Duration{0, 500 * time.Millisecond, time.Second, time.Second + time.Nanosecond}
+
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Post", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	var want []uuid.UUID
+	for i := len(offsets) - 1; i >= 0; i-- {
+		comment, err := storage.CreateComment(ctx, &model.Comment{
+			PostID:    post.ID,
+			Content:   "Comment",
+			CreatedAt: base.Add(offsets[i]),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+		want = append([]uuid.UUID{comment.ID}, want...)
+	}
+
+	var got []uuid.UUID
+	var after *model.Cursor
+	for {
+		page, err := storage.GetRootCommentsAfter(ctx, post.ID, 3, after)
+		if err != nil {
+			t.Fatalf("Failed to get page: %v", err)
+		}
+		for _, comment := range page {
+			got = append(got, comment.ID)
+		}
+		if len(page) < 3 {
+			break
+		}
+		last := page[len(page)-1]
+		after = &model.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected comments from old to new %v, got %v", want, got)
+	}
+
+	comment, err := storage.GetComment(ctx, want[3])
+	if err != nil {
+		t.Fatalf("Failed to get comment: %v", err)
+	}
+	if !comment.CreatedAt.Equal(base.Add(offsets[3])) {
+		t.Errorf("Expected created_at %v, got %v", base.Add(offsets[3]), comment.CreatedAt)
+	}
+}
+
+// TestSQLiteStorage_Errors тестирует ошибки хранилища
+func TestSQLiteStorage_Errors(t *testing.T) {
+	storage, _ := openSQLite(t)
+	ctx := context.Background()
+
+	if _, err := storage.CreateUser(ctx, &model.User{Username: "alice"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := storage.CreateUser(ctx, &model.User{Username: "alice"}); !errors.Is(err, repository.ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate, got %v", err)
+	}
+	if _, err := storage.GetUserByUsername(ctx, "bob"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing user, got %v", err)
+	}
+
+	if _, err := storage.CreateComment(ctx, &model.Comment{PostID: uuid.New(), Content: "Orphan"}); err == nil {
+		t.Error("Expected error for comment on missing post")
+	}
+	if _, err := storage.SetVote(ctx, uuid.New(), uuid.New(), model.VoteUp); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing vote target, got %v", err)
+	}
+	if err := storage.AddReaction(ctx, uuid.New(), uuid.New(), "👍"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for reaction on missing comment, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/NarthurN/CommentsSystem/internal/model"
 	"github.com/google/uuid"
@@ -27,6 +28,15 @@ var (
 
 	// ErrTransactionFailed indicates that database transaction failed
 	ErrTransactionFailed = errors.New("database transaction failed")
+
+	// ErrCommentDeleted indicates that the operation is not allowed on a deleted comment
+	ErrCommentDeleted = errors.New("comment is deleted")
+
+	// ErrUnknownAuthor indicates that the author of a new post or comment has no user record
+	ErrUnknownAuthor = errors.New("author not found")
+
+	// ErrUnknownUser indicates that the user who votes or reacts has no user record
+	ErrUnknownUser = errors.New("user not found")
 )
 
 // Storage представляет интерфейс для работы с хранилищем данных.
@@ -40,6 +50,9 @@ type Storage interface {
 
 	// CreatePost создает новый пост в хранилище.
 	// Возвращает созданный пост с заполненным ID и временем создания.
+	// Заданные ID и время создания сохраняются; комментарии нового поста включены.
+	// Возвращает ErrInvalidInput для некорректного поста
+	// и ErrUnknownAuthor, если автора нет среди пользователей.
 	CreatePost(ctx context.Context, post *model.Post) (*model.Post, error)
 
 	// GetPost получает пост по ID.
@@ -51,21 +64,32 @@ type Storage interface {
 	// offset - количество пропускаемых постов
 	GetPosts(ctx context.Context, limit, offset int) ([]*model.Post, error)
 
+	// GetPostsAfter получает страницу постов для keyset пагинации.
+	// Посты упорядочены от новых к старым по (created_at, id);
+	// after - позиция последнего поста предыдущей страницы (nil для первой страницы).
+	GetPostsAfter(ctx context.Context, limit int, after *model.Cursor) ([]*model.Post, error)
+
 	// UpdatePost обновляет существующий пост.
-	// Возвращает ErrNotFound если пост не найден.
+	// Возвращает ErrNotFound если пост не найден и ErrInvalidInput для некорректного поста.
 	UpdatePost(ctx context.Context, post *model.Post) (*model.Post, error)
 
 	// DeletePost удаляет пост по ID.
 	// Также удаляет все связанные комментарии.
+	// Возвращает ErrNotFound если пост не найден.
 	DeletePost(ctx context.Context, id uuid.UUID) error
 
 	// TogglePostComments включает/выключает возможность комментирования поста.
+	// Возвращает ErrNotFound если пост не найден.
 	TogglePostComments(ctx context.Context, id uuid.UUID, enabled bool) error
 
 	// Comment operations
 
 	// CreateComment создает новый комментарий.
 	// Возвращает созданный комментарий с заполненным ID и временем создания.
+	// Возвращает ErrNotFound если нет поста или родительского комментария,
+	// ErrCommentDeleted если родительский комментарий удален,
+	// ErrInvalidInput для некорректного комментария
+	// и ErrUnknownAuthor, если автора нет среди пользователей.
 	CreateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error)
 
 	// GetComment получает комментарий по ID.
@@ -73,30 +97,145 @@ type Storage interface {
 	GetComment(ctx context.Context, id uuid.UUID) (*model.Comment, error)
 
 	// GetCommentsByPostID получает все комментарии для поста.
-	// Возвращает плоский список комментариев, отсортированный по времени создания.
+	// Возвращает плоский список комментариев, отсортированный по (created_at, id).
+	// Возвращает ErrNotFound если пост не найден.
 	GetCommentsByPostID(ctx context.Context, postID uuid.UUID) ([]model.Comment, error)
 
 	// GetCommentsByParentID получает дочерние комментарии для родительского комментария с пагинацией.
 	// Решает N+1 проблему при получении иерархии комментариев в GraphQL.
-	GetCommentsByParentID(ctx context.Context, parentID uuid.UUID, limit, offset int) ([]model.Comment, error)
+	// Комментарии упорядочены по sort (пустое значение - model.CommentSortOld).
+	// Удаленные комментарии возвращаются как надгробия, только если у них есть неудаленные потомки.
+	GetCommentsByParentID(ctx context.Context, parentID uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error)
+
+	// GetCommentsByParentIDAfter получает страницу дочерних комментариев для keyset пагинации.
+	// Комментарии упорядочены от старых к новым по (created_at, id);
+	// after - позиция последнего комментария предыдущей страницы (nil для первой страницы).
+	GetCommentsByParentIDAfter(ctx context.Context, parentID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error)
 
 	// GetRootCommentsByPostID получает только корневые комментарии для поста с пагинацией.
 	// Избегает загрузки всех комментариев сразу для лучшей производительности.
-	GetRootCommentsByPostID(ctx context.Context, postID uuid.UUID, limit, offset int) ([]model.Comment, error)
+	// Порядок задается sort, как в GetCommentsByParentID.
+	// Возвращает ErrNotFound если пост не найден.
+	// Удаленные комментарии возвращаются как надгробия, только если у них есть неудаленные потомки.
+	GetRootCommentsByPostID(ctx context.Context, postID uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error)
+
+	// GetCommentsByParentIDs получает дочерние комментарии сразу для нескольких родителей одним запросом.
+	// Окно limit/offset применяется к каждому родителю отдельно, порядок - как в GetCommentsByParentID.
+	// Родители без видимых ответов в результат не попадают.
+	GetCommentsByParentIDs(ctx context.Context, parentIDs []uuid.UUID, sort model.CommentSort, limit, offset int) (map[uuid.UUID][]model.Comment, error)
+
+	// GetRootCommentsByPostIDs получает корневые комментарии сразу для нескольких постов одним запросом.
+	// Окно limit/offset применяется к каждому посту отдельно, порядок - как в GetRootCommentsByPostID.
+	// Посты без видимых комментариев в результат не попадают.
+	GetRootCommentsByPostIDs(ctx context.Context, postIDs []uuid.UUID, sort model.CommentSort, limit, offset int) (map[uuid.UUID][]model.Comment, error)
+
+	// GetRootCommentsAfter получает страницу корневых комментариев поста для keyset пагинации.
+	// Порядок и смысл after такие же, как в GetCommentsByParentIDAfter.
+	// Возвращает ErrNotFound если пост не найден.
+	GetRootCommentsAfter(ctx context.Context, postID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error)
+
+	// GetCommentsByPostIDAfter получает неудаленные комментарии поста всех уровней,
+	// созданные строго после позиции after (nil - с начала), не больше limit.
+	// Порядок такой же, как в GetCommentsByParentIDAfter; надгробия не возвращаются.
+	// Возвращает ErrNotFound если пост не найден.
+	GetCommentsByPostIDAfter(ctx context.Context, postID uuid.UUID, limit int, after *model.Cursor) ([]model.Comment, error)
 
 	// GetCommentTree получает иерархическое дерево комментариев для поста.
 	// Возвращает структурированное дерево с вложенными комментариями.
+	// Ветки, состоящие только из удаленных комментариев, в дерево не попадают.
+	// Возвращает ErrNotFound если пост не найден.
 	GetCommentTree(ctx context.Context, postID uuid.UUID) ([]model.CommentTree, error)
 
+	// GetCommentAncestorIDs получает ID всех предков комментария от непосредственного родителя к корню.
+	// Для корневого комментария возвращает пустой список.
+	// Возвращает ErrNotFound если комментарий не найден.
+	GetCommentAncestorIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error)
+
 	// DeleteComment удаляет комментарий и все его дочерние комментарии.
+	// Возвращает ErrNotFound если комментарий не найден.
 	DeleteComment(ctx context.Context, id uuid.UUID) error
 
+	// SoftDeleteComment помечает комментарий удаленным, сохраняя все ответы на него.
+	// Текст заменяется на model.DeletedCommentContent, время удаления фиксируется.
+	// Повторное удаление возвращает существующее надгробие.
+	// Возвращает ErrNotFound если комментарий не найден.
+	SoftDeleteComment(ctx context.Context, id uuid.UUID) (*model.Comment, error)
+
+	// EditComment заменяет текст комментария, сохраняя предыдущий текст как ревизию.
+	// Устанавливает время редактирования.
+	// Возвращает ErrNotFound если комментарий не найден,
+	// ErrCommentDeleted если комментарий удален и ErrInvalidInput для некорректного текста.
+	EditComment(ctx context.Context, id uuid.UUID, content string) (*model.Comment, error)
+
+	// GetCommentRevisions получает предыдущие версии комментария от старых к новым.
+	// Для комментария без редактирований возвращает пустой список.
+	// Возвращает ErrNotFound если комментарий не найден.
+	GetCommentRevisions(ctx context.Context, commentID uuid.UUID) ([]model.CommentRevision, error)
+
+	// Vote operations
+
+	// SetVote устанавливает голос пользователя userID за пост или комментарий targetID.
+	// У пользователя может быть только один голос за цель: новый голос заменяет
+	// предыдущий, model.VoteNone снимает голос.
+	// Возвращает итог голосования цели после изменения.
+	// Возвращает ErrNotFound если цели нет, ErrCommentDeleted если комментарий удален,
+	// ErrUnknownUser если пользователя нет среди пользователей
+	// и ErrInvalidInput для неизвестного значения голоса.
+	SetVote(ctx context.Context, userID, targetID uuid.UUID, value model.VoteValue) (*model.VoteTally, error)
+
+	// Reaction operations
+
+	// AddReaction добавляет реакцию emoji пользователя userID на комментарий.
+	// Повторное добавление той же реакции ничего не меняет.
+	// Возвращает ErrNotFound если комментарий не найден, ErrCommentDeleted если он удален,
+	// ErrUnknownUser если пользователя нет среди пользователей
+	// и ErrInvalidInput для некорректного эмодзи.
+	AddReaction(ctx context.Context, userID, commentID uuid.UUID, emoji string) error
+
+	// RemoveReaction снимает реакцию emoji пользователя userID с комментария.
+	// Снятие отсутствующей реакции ничего не меняет.
+	// Возвращает ErrNotFound если комментарий не найден.
+	RemoveReaction(ctx context.Context, userID, commentID uuid.UUID, emoji string) error
+
+	// GetReactionsByCommentIDs получает сводки реакций сразу для нескольких комментариев одним запросом.
+	// Реакции каждого комментария упорядочены по времени первой реакции эмодзи.
+	// viewerID - пользователь, для которого заполняется ViewerReacted (uuid.Nil для анонимного).
+	// Комментарии без реакций в результат не попадают.
+	GetReactionsByCommentIDs(ctx context.Context, commentIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID][]model.Reaction, error)
+
+	// User operations
+
+	// CreateUser создает нового пользователя.
+	// Возвращает ErrDuplicate если имя пользователя уже занято
+	// и ErrInvalidInput для некорректного имени.
+	CreateUser(ctx context.Context, user *model.User) (*model.User, error)
+
+	// GetUser получает пользователя по ID.
+	// Возвращает ErrNotFound если пользователь не найден.
+	GetUser(ctx context.Context, id uuid.UUID) (*model.User, error)
+
+	// GetUserByUsername получает пользователя по имени.
+	// Возвращает ErrNotFound если пользователь не найден.
+	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
+
 	// Complex operations
 
 	// GetPostWithComments получает пост со всеми его комментариями.
 	// Оптимизированный запрос для получения полной информации о посте.
+	// Возвращает ErrNotFound если пост не найден.
 	GetPostWithComments(ctx context.Context, id uuid.UUID) (*model.PostWithComments, error)
 
+	// Transactions
+
+	// WithTx выполняет fn в одной транзакции.
+	// Внутри fn вместо исходного хранилища нужно использовать tx. tx нельзя
+	// использовать после возврата из fn и из нескольких горутин одновременно.
+	// Ошибка fn откатывает изменения и возвращается без изменений, ошибка
+	// начала или фиксации транзакции оборачивается в ErrTransactionFailed.
+	// Вызов WithTx у tx выполняет fn во вложенной транзакции, которая
+	// откатывается отдельно и фиксируется вместе с внешней.
+	WithTx(ctx context.Context, fn func(tx Storage) error) error
+
 	// Health and lifecycle management
 
 	// HealthCheck проверяет состояние соединения с хранилищем.
@@ -107,6 +246,36 @@ type Storage interface {
 	Close() error
 }
 
+// Outbox предоставляет доступ к событиям, записанным хранилищем в одной транзакции
+// с изменением данных (transactional outbox). Реализуется хранилищами, которые
+// гарантируют доставку событий после сбоя; события читает и доставляет фоновый relay.
+//
+// Доставка учитывается отдельно для каждого получателя (consumer): захваты, попытки
+// и отметки о доставке одного получателя не влияют на других. Несколько экземпляров relay
+// могут работать одновременно: событие захватывается для получателя на время lease
+// и достается другому экземпляру, только если не было доставлено за это время.
+type Outbox interface {
+	// ClaimOutboxEvents захватывает для получателя до limit недоставленных ему событий
+	// на время lease и возвращает их в порядке записи. Захваченные другим экземпляром
+	// события пропускаются. Attempts событий - неудачные попытки доставки этому получателю.
+	ClaimOutboxEvents(ctx context.Context, consumer string, limit int, lease time.Duration) ([]model.OutboxEvent, error)
+
+	// MarkOutboxDelivered отмечает события доставленными получателю
+	MarkOutboxDelivered(ctx context.Context, consumer string, ids []int64) error
+
+	// MarkOutboxFailed увеличивает счетчик попыток доставки события получателю и сохраняет ошибку.
+	// Событие остается захваченным до конца lease, поэтому повтор происходит не раньше.
+	MarkOutboxFailed(ctx context.Context, consumer string, id int64, reason string) error
+
+	// ReleaseOutboxEvents снимает захват событий, которые relay не стал доставлять получателю,
+	// чтобы их можно было захватить снова, не дожидаясь конца lease
+	ReleaseOutboxEvents(ctx context.Context, consumer string, ids []int64) error
+
+	// PurgeOutbox удаляет события, доставленные всем получателям consumers раньше before,
+	// и возвращает их количество
+	PurgeOutbox(ctx context.Context, consumers []string, before time.Time) (int64, error)
+}
+
 // PostRepository специализированный интерфейс для работы с постами.
 // Предоставляет более специфичные методы для работы с постами.
 type PostRepository interface {
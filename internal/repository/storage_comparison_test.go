@@ -2,6 +2,7 @@ package repository_test
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -10,7 +11,7 @@ import (
 	"github.com/google/uuid"
 )
 
-// TestStorageComparison демонстрирует идентичное поведение PostgreSQL и In-Memory хранилищ
+// TestStorageComparison демонстрирует идентичное поведение PostgreSQL, SQLite и In-Memory хранилищ
 func TestStorageComparison(t *testing.T) {
 	ctx := context.Background()
 
@@ -28,11 +29,18 @@ func TestStorageComparison(t *testing.T) {
 		defer postgresStorage.Close()
 	}
 
+	sqliteStorage, err := repository.NewSQLiteStorage(ctx, filepath.Join(t.TempDir(), "comments.db"))
+	if err != nil {
+		t.Fatalf("Failed to open SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
 	storages := []struct {
 		name    string
 		storage repository.Storage
 	}{
 		{"Memory", memoryStorage},
+		{"SQLite", sqliteStorage},
 	}
 
 	if postgresStorage != nil {
@@ -144,6 +152,27 @@ func testStorageBehavior(t *testing.T, storage repository.Storage) {
 		t.Error("Expected no great-grandchildren")
 	}
 
+	// Тест 4.1: Счетчики комментариев
+	assertCommentCount(t, storage, createdPost.ID, 3)
+	if rootNode.Comment.ReplyCount != 1 || rootNode.Comment.DescendantCount != 2 {
+		t.Errorf("Expected root counters 1/2, got %d/%d", rootNode.Comment.ReplyCount, rootNode.Comment.DescendantCount)
+	}
+
+	// Надгробие не учитывается, повторное удаление не меняет счетчики
+	for i := 0; i < 2; i++ {
+		if _, err := storage.SoftDeleteComment(ctx, grandchildNode.Comment.ID); err != nil {
+			t.Fatalf("Failed to soft delete comment: %v", err)
+		}
+	}
+	assertCommentCount(t, storage, createdPost.ID, 2)
+	updatedChild, err := storage.GetComment(ctx, childComment.ID)
+	if err != nil {
+		t.Fatalf("Failed to get comment: %v", err)
+	}
+	if updatedChild.ReplyCount != 0 || updatedChild.DescendantCount != 0 {
+		t.Errorf("Expected child counters 0/0, got %d/%d", updatedChild.ReplyCount, updatedChild.DescendantCount)
+	}
+
 	// Тест 5: Отключение комментариев
 	err = storage.TogglePostComments(ctx, createdPost.ID, false)
 	if err != nil {
@@ -178,6 +207,7 @@ func testStorageBehavior(t *testing.T, storage repository.Storage) {
 	if len(remainingComments) != 0 {
 		t.Errorf("Expected 0 comments after cascade delete, got %d", len(remainingComments))
 	}
+	assertCommentCount(t, storage, createdPost.ID, 0)
 
 	// Тест 7: Health check
 	err = storage.HealthCheck(ctx)
@@ -188,6 +218,19 @@ func testStorageBehavior(t *testing.T, storage repository.Storage) {
 	t.Logf("✅ Storage '%T' passed all compatibility tests", storage)
 }
 
+// assertCommentCount проверяет счетчик комментариев поста
+func assertCommentCount(t *testing.T, storage repository.Storage, postID uuid.UUID, want int) {
+	t.Helper()
+
+	post, err := storage.GetPost(context.Background(), postID)
+	if err != nil {
+		t.Fatalf("Failed to get post: %v", err)
+	}
+	if post.CommentCount != want {
+		t.Errorf("Expected comment count %d, got %d", want, post.CommentCount)
+	}
+}
+
 // BenchmarkStorageComparison сравнивает производительность разных типов хранилища
 func BenchmarkStorageComparison(b *testing.B) {
 	ctx := context.Background()
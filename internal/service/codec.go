@@ -0,0 +1,113 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/service/generated"
+	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
+)
+
+// Виды событий pub/sub в сериализованном виде
+const (
+	eventPostAdded       = "post_added"
+	eventCommentAdded    = "comment_added"
+	eventCommentEdited   = "comment_edited"
+	eventCommentDeleted  = "comment_deleted"
+	eventCommentsToggled = "comments_toggled"
+	eventPostDeleted     = "post_deleted"
+	eventScoreChanged    = "score_changed"
+	eventReplyAdded      = "reply_added"
+)
+
+// EventCodec сериализует события, которые резолверы публикуют в pub/sub,
+// для передачи между экземплярами приложения (pubsub.Redis, pubsub.Postgres).
+// Восстанавливает те же типы, что ожидают подписки.
+type EventCodec struct{}
+
+var _ pubsub.Codec = EventCodec{}
+
+// wireEvent - событие pub/sub в сериализованном виде
+type wireEvent struct {
+	Kind            string           `json:"kind"`
+	Post            *model.Post      `json:"post,omitempty"`
+	Comment         *model.Comment   `json:"comment,omitempty"`
+	Tally           *model.VoteTally `json:"tally,omitempty"`
+	PostID          string           `json:"postId,omitempty"`
+	CommentsEnabled bool             `json:"commentsEnabled,omitempty"`
+	Direct          bool             `json:"direct,omitempty"` // Для reply_added: прямой ответ на комментарий топика
+}
+
+// Marshal сериализует событие; для неизвестного типа данных возвращает ошибку
+func (EventCodec) Marshal(data interface{}) ([]byte, error) {
+	var event wireEvent
+
+	switch v := data.(type) {
+	case *model.Post:
+		event = wireEvent{Kind: eventPostAdded, Post: v}
+	case *generated.CommentAdded:
+		event = wireEvent{Kind: eventCommentAdded, Comment: v.Comment}
+	case *generated.CommentEdited:
+		event = wireEvent{Kind: eventCommentEdited, Comment: v.Comment}
+	case *generated.CommentDeleted:
+		event = wireEvent{Kind: eventCommentDeleted, Comment: v.Comment}
+	case *generated.CommentsToggled:
+		event = wireEvent{Kind: eventCommentsToggled, PostID: v.PostID, CommentsEnabled: v.CommentsEnabled}
+	case *generated.PostDeleted:
+		event = wireEvent{Kind: eventPostDeleted, PostID: v.PostID}
+	case *model.VoteTally:
+		event = wireEvent{Kind: eventScoreChanged, Tally: v}
+	case *replyEvent:
+		event = wireEvent{Kind: eventReplyAdded, Comment: v.comment, Direct: v.direct}
+	default:
+		return nil, fmt.Errorf("unsupported event type %T", data)
+	}
+
+	return json.Marshal(event)
+}
+
+// Unmarshal восстанавливает событие, сериализованное Marshal
+func (EventCodec) Unmarshal(raw []byte) (interface{}, error) {
+	var event wireEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return nil, err
+	}
+
+	switch event.Kind {
+	case eventPostAdded:
+		if event.Post != nil {
+			return event.Post, nil
+		}
+	case eventCommentAdded:
+		if event.Comment != nil {
+			return &generated.CommentAdded{Comment: event.Comment}, nil
+		}
+	case eventCommentEdited:
+		if event.Comment != nil {
+			return &generated.CommentEdited{Comment: event.Comment}, nil
+		}
+	case eventCommentDeleted:
+		if event.Comment != nil {
+			return &generated.CommentDeleted{Comment: event.Comment}, nil
+		}
+	case eventCommentsToggled:
+		return &generated.CommentsToggled{PostID: event.PostID, CommentsEnabled: event.CommentsEnabled}, nil
+	case eventPostDeleted:
+		if event.PostID != "" {
+			return &generated.PostDeleted{PostID: event.PostID}, nil
+		}
+	case eventScoreChanged:
+		if event.Tally != nil {
+			return event.Tally, nil
+		}
+	case eventReplyAdded:
+		if event.Comment != nil {
+			return &replyEvent{comment: event.Comment, direct: event.Direct}, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown event kind %q", event.Kind)
+	}
+
+	return nil, fmt.Errorf("event %q has no payload", event.Kind)
+}
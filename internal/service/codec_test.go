@@ -0,0 +1,70 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/service/generated"
+	"github.com/google/uuid"
+)
+
+func TestEventCodec_RoundTrip(t *testing.T) {
+	authorID := uuid.New()
+	parentID := uuid.New()
+	editedAt := time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)
+	post := &model.Post{
+		ID:              uuid.New(),
+		AuthorID:        &authorID,
+		Title:           "Post",
+		Content:         "Content",
+		CommentsEnabled: true,
+		CreatedAt:       time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		CommentCount:    3,
+		Upvotes:         2,
+	}
+	comment := &model.Comment{
+		ID:        uuid.New(),
+		PostID:    post.ID,
+		AuthorID:  &authorID,
+		ParentID:  &parentID,
+		Content:   "Comment",
+		CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC),
+		EditedAt:  &editedAt,
+		Downvotes: 1,
+	}
+
+	events := []interface{}{
+		post,
+		&generated.CommentAdded{Comment: comment},
+		&generated.CommentEdited{Comment: comment},
+		&generated.CommentDeleted{Comment: comment},
+		&generated.CommentsToggled{PostID: post.ID.String(), CommentsEnabled: false},
+		&generated.PostDeleted{PostID: post.ID.String()},
+		&model.VoteTally{TargetID: comment.ID, PostID: post.ID, Upvotes: 4, Downvotes: 1},
+		&replyEvent{comment: comment, direct: true},
+	}
+
+	codec := EventCodec{}
+	for _, event := range events {
+		raw, err := codec.Marshal(event)
+		if err != nil {
+			t.Fatalf("Marshal(%T) error = %v", event, err)
+		}
+		decoded, err := codec.Unmarshal(raw)
+		if err != nil {
+			t.Fatalf("Unmarshal(%T) error = %v", event, err)
+		}
+		if !reflect.DeepEqual(decoded, event) {
+			t.Errorf("Event changed after round trip: %#v != %#v", decoded, event)
+		}
+	}
+
+	if _, err := codec.Marshal("unknown"); err == nil {
+		t.Error("Expected error for unsupported event type")
+	}
+	if _, err := codec.Unmarshal([]byte(`{"kind":"unknown"}`)); err == nil {
+		t.Error("Expected error for unknown event kind")
+	}
+}
@@ -2,9 +2,106 @@
 
 package generated
 
+import (
+	"github.com/NarthurN/CommentsSystem/internal/model"
+)
+
+type PostEvent interface {
+	IsPostEvent()
+}
+
+type CommentAdded struct {
+	Cursor  string         `json:"cursor"`
+	Comment *model.Comment `json:"comment"`
+}
+
+func (CommentAdded) IsPostEvent() {}
+
+type CommentConnection struct {
+	Edges    []*CommentEdge `json:"edges"`
+	PageInfo *PageInfo      `json:"pageInfo"`
+}
+
+type CommentDeleted struct {
+	Cursor  string         `json:"cursor"`
+	Comment *model.Comment `json:"comment"`
+}
+
+func (CommentDeleted) IsPostEvent() {}
+
+type CommentEdge struct {
+	Cursor string         `json:"cursor"`
+	Node   *model.Comment `json:"node"`
+}
+
+type CommentEdited struct {
+	Cursor  string         `json:"cursor"`
+	Comment *model.Comment `json:"comment"`
+}
+
+func (CommentEdited) IsPostEvent() {}
+
+type CommentThread struct {
+	Nodes      []*CommentThreadNode `json:"nodes"`
+	TotalCount int                  `json:"totalCount"`
+	HasMore    bool                 `json:"hasMore"`
+	MoreCount  int                  `json:"moreCount"`
+}
+
+type CommentThreadNode struct {
+	Comment          *model.Comment       `json:"comment"`
+	Depth            int                  `json:"depth"`
+	Replies          []*CommentThreadNode `json:"replies"`
+	ReplyCount       int                  `json:"replyCount"`
+	DescendantCount  int                  `json:"descendantCount"`
+	HasMoreReplies   bool                 `json:"hasMoreReplies"`
+	MoreRepliesCount int                  `json:"moreRepliesCount"`
+}
+
+type CommentsToggled struct {
+	Cursor          string `json:"cursor"`
+	PostID          string `json:"postId"`
+	CommentsEnabled bool   `json:"commentsEnabled"`
+}
+
+func (CommentsToggled) IsPostEvent() {}
+
+type EventsMissed struct {
+	Count int `json:"count"`
+}
+
+func (EventsMissed) IsPostEvent() {}
+
 type Mutation struct {
 }
 
+type PageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor,omitempty"`
+}
+
+type PostConnection struct {
+	Edges    []*PostEdge `json:"edges"`
+	PageInfo *PageInfo   `json:"pageInfo"`
+}
+
+type PostDeleted struct {
+	Cursor string `json:"cursor"`
+	PostID string `json:"postId"`
+}
+
+func (PostDeleted) IsPostEvent() {}
+
+type PostEdge struct {
+	Cursor string      `json:"cursor"`
+	Node   *model.Post `json:"node"`
+}
+
+type PostFilter struct {
+	CommentsEnabled *bool   `json:"commentsEnabled,omitempty"`
+	AuthorID        *string `json:"authorId,omitempty"`
+}
+
 type Query struct {
 }
 
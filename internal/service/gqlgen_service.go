@@ -3,16 +3,20 @@ package service
 import (
 	"context"
 	"net/http"
+	"strings"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/NarthurN/CommentsSystem/internal/auth"
 	"github.com/NarthurN/CommentsSystem/internal/config"
 	"github.com/NarthurN/CommentsSystem/internal/repository"
 	"github.com/NarthurN/CommentsSystem/internal/service/generated"
 	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
 	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/ast"
 )
 
 // GQLGenService представляет сервис с использованием gqlgen.
@@ -23,17 +27,20 @@ import (
 // - WebSocket транспорт для real-time подписок
 // - Настраиваемые CORS политики
 // - Health check для мониторинга
+// - JWT аутентификация для HTTP запросов и WebSocket подписок
+// - Пакетная загрузка комментариев для вложенных полей
 type GQLGenService struct {
 	storage  repository.Storage // Интерфейс для работы с данными
-	pubsub   *pubsub.PubSub     // Система pub/sub для подписок
+	pubsub   pubsub.PubSub      // Система pub/sub для подписок
 	resolver *Resolver          // GraphQL резолверы
 	server   *handler.Server    // GraphQL сервер
 	config   *config.Config     // Конфигурация приложения
+	verifier *auth.Verifier     // Проверка JWT токенов (nil - аутентификация отключена)
 }
 
 // NewGQLGenService создает новый экземпляр сервиса с gqlgen и конфигурацией по умолчанию.
 // Использует стандартные настройки для WebSocket и CORS.
-func NewGQLGenService(storage repository.Storage, ps *pubsub.PubSub) *GQLGenService {
+func NewGQLGenService(storage repository.Storage, ps pubsub.PubSub) *GQLGenService {
 	// Создаем временную конфигурацию для обратной совместимости
 	cfg := &config.Config{
 		KeepAlivePing:       config.DefaultKeepAlivePing,
@@ -59,8 +66,20 @@ func NewGQLGenService(storage repository.Storage, ps *pubsub.PubSub) *GQLGenServ
 //   - WebSocket транспорт с настраиваемыми параметрами
 //   - CORS политики на основе конфигурации
 //   - GraphQL интроспекцию (опционально)
-func NewGQLGenServiceWithConfig(storage repository.Storage, ps *pubsub.PubSub, cfg *config.Config) *GQLGenService {
+//   - JWT аутентификацию, если в конфигурации заданы ключи
+func NewGQLGenServiceWithConfig(storage repository.Storage, ps pubsub.PubSub, cfg *config.Config) *GQLGenService {
 	resolver := NewResolver(storage, ps)
+	if len(cfg.ReactionEmoji) > 0 {
+		resolver.reactionEmoji = cfg.ReactionEmoji
+	}
+
+	s := &GQLGenService{
+		storage:  storage,
+		pubsub:   ps,
+		resolver: resolver,
+		config:   cfg,
+		verifier: auth.NewVerifier([]byte(cfg.JWTSecret), cfg.JWTPublicKey, cfg.JWTIssuer),
+	}
 
 	// Создаем GraphQL сервер с сгенерированной схемой
 	srv := handler.New(generated.NewExecutableSchema(generated.Config{
@@ -83,6 +102,7 @@ func NewGQLGenServiceWithConfig(storage repository.Storage, ps *pubsub.PubSub, c
 			WriteBufferSize: 1024,
 		},
 		KeepAlivePingInterval: cfg.KeepAlivePing,
+		InitFunc:              s.websocketInit,
 	})
 
 	// Добавляем расширения на основе конфигурации
@@ -90,13 +110,86 @@ func NewGQLGenServiceWithConfig(storage repository.Storage, ps *pubsub.PubSub, c
 		srv.Use(extension.Introspection{})
 	}
 
-	return &GQLGenService{
-		storage:  storage,
-		pubsub:   ps,
-		resolver: resolver,
-		server:   srv,
-		config:   cfg,
+	// Анонимным пользователям доступно только чтение
+	srv.AroundOperations(s.requireAuthForMutations)
+
+	// Загрузчики объединяют запросы комментариев соседних объектов
+	srv.AroundOperations(s.attachLoaders)
+
+	s.server = srv
+	return s
+}
+
+// Authenticate проверяет значение заголовка Authorization ("Bearer <token>")
+// и возвращает контекст с ID пользователя и его ролью.
+//
+// Если аутентификация отключена, учетных записей нет и каждый вызывающий
+// считается модератором: иначе посты и комментарии без автора нельзя было бы изменить.
+// Без токена запрос остается анонимным, если это разрешено конфигурацией,
+// иначе возвращается auth.ErrUnauthenticated. Некорректный токен
+// всегда отклоняется с auth.ErrInvalidToken.
+func (s *GQLGenService) Authenticate(ctx context.Context, authorization string) (context.Context, error) {
+	if s.verifier == nil {
+		return auth.WithModerator(ctx), nil
+	}
+
+	if strings.TrimSpace(authorization) == "" {
+		// Пользователь мог быть аутентифицирован раньше (заголовок при WebSocket upgrade)
+		if _, ok := auth.UserIDFromContext(ctx); ok || s.config.AuthAllowAnonymous {
+			return ctx, nil
+		}
+		return nil, auth.ErrUnauthenticated
 	}
+
+	token := auth.BearerToken(authorization)
+	if token == "" {
+		return nil, auth.ErrInvalidToken
+	}
+
+	identity, err := s.verifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return identity.Context(ctx), nil
+}
+
+// websocketInit аутентифицирует WebSocket соединение по payload сообщения connection_init.
+// Токен передается в поле "Authorization" в виде "Bearer <token>" или без префикса.
+func (s *GQLGenService) websocketInit(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+	authorization := payload.Authorization()
+	if authorization != "" && auth.BearerToken(authorization) == "" {
+		authorization = "Bearer " + authorization
+	}
+
+	ctx, err := s.Authenticate(ctx, authorization)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ctx, nil, nil
+}
+
+// requireAuthForMutations отклоняет мутации анонимных пользователей,
+// если аутентификация включена. Запросы и подписки проходят без изменений.
+func (s *GQLGenService) requireAuthForMutations(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	if s.verifier == nil {
+		return next(ctx)
+	}
+
+	opCtx := graphql.GetOperationContext(ctx)
+	if opCtx.Operation != nil && opCtx.Operation.Operation == ast.Mutation {
+		if _, err := auth.RequireUserID(ctx); err != nil {
+			return graphql.OneShot(graphql.ErrorResponse(ctx, "%s", err.Error()))
+		}
+	}
+
+	return next(ctx)
+}
+
+// attachLoaders создает загрузчики комментариев для каждой операции
+func (s *GQLGenService) attachLoaders(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	return next(withLoaders(ctx, newLoaders(s.storage)))
 }
 
 // GetHandler возвращает HTTP обработчик для GraphQL эндпоинта.
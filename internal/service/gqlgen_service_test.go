@@ -2,11 +2,17 @@ package service
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/NarthurN/CommentsSystem/internal/auth"
 	"github.com/NarthurN/CommentsSystem/internal/config"
 	"github.com/NarthurN/CommentsSystem/internal/repository"
 	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 func TestNewGQLGenService(t *testing.T) {
@@ -134,3 +140,71 @@ type mockHealthCheckStorage struct {
 func (m *mockHealthCheckStorage) HealthCheck(ctx context.Context) error {
 	return nil
 }
+
+func TestGQLGenService_Authenticate(t *testing.T) {
+	secret := "test-secret"
+	userID := uuid.New()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   userID.String(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	newService := func(allowAnonymous bool) *GQLGenService {
+		return NewGQLGenServiceWithConfig(&mockStorage{}, pubsub.New(), &config.Config{
+			JWTSecret:          secret,
+			AuthAllowAnonymous: allowAnonymous,
+		})
+	}
+
+	t.Run("валидный токен", func(t *testing.T) {
+		ctx, err := newService(true).Authenticate(context.Background(), "Bearer "+token)
+		if err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+		if got, ok := auth.UserIDFromContext(ctx); !ok || got != userID {
+			t.Errorf("Expected user %v in context, got %v", userID, got)
+		}
+	})
+
+	t.Run("некорректный токен", func(t *testing.T) {
+		if _, err := newService(true).Authenticate(context.Background(), "Bearer garbage"); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("Expected ErrInvalidToken, got %v", err)
+		}
+		if _, err := newService(true).Authenticate(context.Background(), "Basic abc"); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("Expected ErrInvalidToken for non-bearer scheme, got %v", err)
+		}
+	})
+
+	t.Run("анонимный запрос", func(t *testing.T) {
+		if _, err := newService(true).Authenticate(context.Background(), ""); err != nil {
+			t.Errorf("Anonymous request should be allowed: %v", err)
+		}
+		if _, err := newService(false).Authenticate(context.Background(), ""); !errors.Is(err, auth.ErrUnauthenticated) {
+			t.Errorf("Expected ErrUnauthenticated, got %v", err)
+		}
+	})
+
+	t.Run("токен в connection_init", func(t *testing.T) {
+		svc := newService(false)
+		ctx, _, err := svc.websocketInit(context.Background(), transport.InitPayload{"Authorization": token})
+		if err != nil {
+			t.Fatalf("websocketInit() error = %v", err)
+		}
+		if got, _ := auth.UserIDFromContext(ctx); got != userID {
+			t.Errorf("Expected user %v in websocket context, got %v", userID, got)
+		}
+		if _, _, err := svc.websocketInit(context.Background(), transport.InitPayload{}); !errors.Is(err, auth.ErrUnauthenticated) {
+			t.Errorf("Expected ErrUnauthenticated without token, got %v", err)
+		}
+	})
+
+	t.Run("аутентификация отключена", func(t *testing.T) {
+		svc := NewGQLGenService(&mockStorage{}, pubsub.New())
+		if _, err := svc.Authenticate(context.Background(), "Bearer garbage"); err != nil {
+			t.Errorf("Tokens should be ignored when auth is disabled: %v", err)
+		}
+	})
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/NarthurN/CommentsSystem/internal/auth"
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	// loaderWait - сколько загрузчик ждет соседние запросы перед походом в хранилище
+	loaderWait = 2 * time.Millisecond
+
+	// loaderMaxBatch - максимальное количество ID в одном запросе к хранилищу
+	loaderMaxBatch = 200
+)
+
+// batchFetchFunc загружает значения сразу для нескольких владельцев
+// (родительских комментариев, постов, комментариев) с общими параметрами запроса.
+type batchFetchFunc[W comparable, V any] func(ctx context.Context, ids []uuid.UUID, params W) (map[uuid.UUID]V, error)
+
+// batch - набор ожидающих загрузок с одинаковыми параметрами запроса
+type batch[V any] struct {
+	ids    []uuid.UUID
+	done   chan struct{}
+	result map[uuid.UUID]V
+	err    error
+}
+
+// batchLoader объединяет загрузки, запрошенные резолверами соседних объектов
+// (gqlgen резолвит элементы списка параллельно), в один запрос к хранилищу.
+// В один запрос объединяются только загрузки с одинаковыми параметрами W.
+//
+// Загрузчик не кэширует результаты: он живет в течение операции,
+// а подписки и мутации внутри одной операции должны видеть свежие данные.
+type batchLoader[W comparable, V any] struct {
+	fetch   batchFetchFunc[W, V]
+	mu      sync.Mutex
+	pending map[W]*batch[V]
+}
+
+// newBatchLoader создает загрузчик поверх функции пакетной загрузки
+func newBatchLoader[W comparable, V any](fetch batchFetchFunc[W, V]) *batchLoader[W, V] {
+	return &batchLoader[W, V]{
+		fetch:   fetch,
+		pending: make(map[W]*batch[V]),
+	}
+}
+
+// Load возвращает значение владельца id или нулевое значение, если у владельца ничего нет.
+// Блокируется до выполнения пакетного запроса, в который попал id.
+func (l *batchLoader[W, V]) Load(ctx context.Context, id uuid.UUID, params W) (V, error) {
+	l.mu.Lock()
+	b, ok := l.pending[params]
+	if !ok {
+		b = &batch[V]{done: make(chan struct{})}
+		l.pending[params] = b
+		time.AfterFunc(loaderWait, func() { l.dispatch(ctx, params, b) })
+	}
+	b.ids = append(b.ids, id)
+	if len(b.ids) >= loaderMaxBatch {
+		// Пакет заполнен: отправляем сразу, следующие загрузки начнут новый
+		delete(l.pending, params)
+		go l.run(ctx, params, b)
+	}
+	l.mu.Unlock()
+
+	var zero V
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+
+	if b.err != nil {
+		return zero, b.err
+	}
+	return b.result[id], nil
+}
+
+// dispatch отправляет пакет по истечении ожидания, если он не был отправлен раньше как заполненный
+func (l *batchLoader[W, V]) dispatch(ctx context.Context, params W, b *batch[V]) {
+	l.mu.Lock()
+	if l.pending[params] != b {
+		l.mu.Unlock()
+		return
+	}
+	delete(l.pending, params)
+	l.mu.Unlock()
+
+	l.run(ctx, params, b)
+}
+
+// run выполняет пакетный запрос и будит все ожидающие загрузки
+func (l *batchLoader[W, V]) run(ctx context.Context, params W, b *batch[V]) {
+	b.result, b.err = l.fetch(ctx, b.ids, params)
+	close(b.done)
+}
+
+// commentsFetchFunc загружает комментарии сразу для нескольких владельцев
+// (родительских комментариев или постов) с окном limit/offset на каждого владельца.
+type commentsFetchFunc func(ctx context.Context, ids []uuid.UUID, sort model.CommentSort, limit, offset int) (map[uuid.UUID][]model.Comment, error)
+
+// commentsWindow - порядок сортировки и окно пагинации.
+// В один запрос объединяются только загрузки с одинаковым окном.
+type commentsWindow struct {
+	sort   model.CommentSort
+	limit  int
+	offset int
+}
+
+// commentsLoader объединяет загрузки комментариев соседних объектов в один запрос к хранилищу
+type commentsLoader struct {
+	loader *batchLoader[commentsWindow, []model.Comment]
+}
+
+// newCommentsLoader создает загрузчик поверх функции пакетной загрузки комментариев
+func newCommentsLoader(fetch commentsFetchFunc) *commentsLoader {
+	return &commentsLoader{
+		loader: newBatchLoader(func(ctx context.Context, ids []uuid.UUID, window commentsWindow) (map[uuid.UUID][]model.Comment, error) {
+			return fetch(ctx, ids, window.sort, window.limit, window.offset)
+		}),
+	}
+}
+
+// Load возвращает комментарии владельца id в порядке sort в окне limit/offset
+func (l *commentsLoader) Load(ctx context.Context, id uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
+	comments, err := l.loader.Load(ctx, id, commentsWindow{sort: sort, limit: limit, offset: offset})
+	if err != nil {
+		return nil, err
+	}
+	if comments == nil {
+		comments = []model.Comment{}
+	}
+	return comments, nil
+}
+
+// loaders - набор загрузчиков одной GraphQL операции
+type loaders struct {
+	children     *commentsLoader                           // Ответы на комментарии (Comment.children)
+	rootComments *commentsLoader                           // Корневые комментарии постов (Post.comments)
+	reactions    *batchLoader[uuid.UUID, []model.Reaction] // Реакции на комментарии по ID зрителя (Comment.reactions)
+}
+
+// newLoaders создает загрузчики для одной операции
+func newLoaders(storage repository.Storage) *loaders {
+	return &loaders{
+		children:     newCommentsLoader(storage.GetCommentsByParentIDs),
+		rootComments: newCommentsLoader(storage.GetRootCommentsByPostIDs),
+		reactions:    newBatchLoader(storage.GetReactionsByCommentIDs),
+	}
+}
+
+// loadersKey - ключ контекста для загрузчиков операции
+type loadersKey struct{}
+
+// withLoaders возвращает контекст с загрузчиками операции
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+// loadersFromContext возвращает загрузчики операции или nil,
+// если резолвер вызван вне GraphQL сервера (например, в тестах)
+func loadersFromContext(ctx context.Context) *loaders {
+	l, _ := ctx.Value(loadersKey{}).(*loaders)
+	return l
+}
+
+// childComments загружает ответы на комментарий, объединяя запросы соседних комментариев
+func (r *Resolver) childComments(ctx context.Context, parentID uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
+	if l := loadersFromContext(ctx); l != nil {
+		return l.children.Load(ctx, parentID, sort, limit, offset)
+	}
+	return r.storage.GetCommentsByParentID(ctx, parentID, sort, limit, offset)
+}
+
+// rootComments загружает корневые комментарии поста, объединяя запросы соседних постов
+func (r *Resolver) rootComments(ctx context.Context, postID uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
+	if l := loadersFromContext(ctx); l != nil {
+		return l.rootComments.Load(ctx, postID, sort, limit, offset)
+	}
+	return r.storage.GetRootCommentsByPostID(ctx, postID, sort, limit, offset)
+}
+
+// commentReactions загружает реакции на комментарий для текущего пользователя,
+// объединяя запросы соседних комментариев
+func (r *Resolver) commentReactions(ctx context.Context, commentID uuid.UUID) ([]model.Reaction, error) {
+	viewerID, _ := auth.UserIDFromContext(ctx)
+
+	var reactions []model.Reaction
+	var err error
+	if l := loadersFromContext(ctx); l != nil {
+		reactions, err = l.reactions.Load(ctx, commentID, viewerID)
+	} else {
+		var byComment map[uuid.UUID][]model.Reaction
+		byComment, err = r.storage.GetReactionsByCommentIDs(ctx, []uuid.UUID{commentID}, viewerID)
+		reactions = byComment[commentID]
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if reactions == nil {
+		reactions = []model.Reaction{}
+	}
+	return reactions, nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/repository"
+	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
+	"github.com/google/uuid"
+)
+
+// countingStorage считает обращения к хранилищу за комментариями
+type countingStorage struct {
+	*repository.MemoryStorage
+	singleCalls   atomic.Int32
+	batchCalls    atomic.Int32
+	reactionCalls atomic.Int32
+}
+
+func (s *countingStorage) GetCommentsByParentID(ctx context.Context, parentID uuid.UUID, sort model.CommentSort, limit, offset int) ([]model.Comment, error) {
+	s.singleCalls.Add(1)
+	return s.MemoryStorage.GetCommentsByParentID(ctx, parentID, sort, limit, offset)
+}
+
+func (s *countingStorage) GetCommentsByParentIDs(ctx context.Context, parentIDs []uuid.UUID, sort model.CommentSort, limit, offset int) (map[uuid.UUID][]model.Comment, error) {
+	s.batchCalls.Add(1)
+	return s.MemoryStorage.GetCommentsByParentIDs(ctx, parentIDs, sort, limit, offset)
+}
+
+func (s *countingStorage) GetReactionsByCommentIDs(ctx context.Context, commentIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID][]model.Reaction, error) {
+	s.reactionCalls.Add(1)
+	return s.MemoryStorage.GetReactionsByCommentIDs(ctx, commentIDs, viewerID)
+}
+
+func TestGQLGenService_BatchesChildren(t *testing.T) {
+	storage := &countingStorage{MemoryStorage: repository.NewMemoryStorage()}
+	defer storage.Close()
+	ctx := context.Background()
+
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Title", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		root, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, Content: "Root"})
+		if err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+		parentID := root.ID
+		if _, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, ParentID: &parentID, Content: "Reply"}); err != nil {
+			t.Fatalf("Failed to create reply: %v", err)
+		}
+	}
+
+	service := NewGQLGenService(storage, pubsub.New())
+	query := `{"query":"{ post(id: \"` + post.ID.String() + `\") { comments(limit: 50) { id children(limit: 5) { id } } } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(query))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	service.GetHandler().ServeHTTP(rec, req)
+
+	var resp struct {
+		Data struct {
+			Post struct {
+				Comments []struct {
+					Children []struct{ ID string }
+				}
+			}
+		}
+		Errors []any
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", resp.Errors)
+	}
+	if len(resp.Data.Post.Comments) != 20 {
+		t.Fatalf("Expected 20 comments, got %d", len(resp.Data.Post.Comments))
+	}
+	for _, comment := range resp.Data.Post.Comments {
+		if len(comment.Children) != 1 {
+			t.Fatalf("Expected 1 reply per comment, got %d", len(comment.Children))
+		}
+	}
+
+	if got := storage.singleCalls.Load(); got != 0 {
+		t.Errorf("Expected no per-comment queries, got %d", got)
+	}
+	if got := storage.batchCalls.Load(); got != 1 {
+		t.Errorf("Expected 1 batch query, got %d", got)
+	}
+}
+
+func TestCommentsLoader_SeparatesWindows(t *testing.T) {
+	var calls atomic.Int32
+	loader := newCommentsLoader(func(ctx context.Context, ids []uuid.UUID, sort model.CommentSort, limit, offset int) (map[uuid.UUID][]model.Comment, error) {
+		calls.Add(1)
+		result := make(map[uuid.UUID][]model.Comment)
+		for _, id := range ids {
+			result[id] = []model.Comment{{ID: uuid.New(), PostID: id, Content: strings.Repeat("x", limit+offset)}}
+		}
+		return result, nil
+	})
+
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	results := make([][]model.Comment, len(ids))
+	done := make(chan struct{})
+	for i, id := range ids {
+		go func(i int, id uuid.UUID) {
+			defer func() { done <- struct{}{} }()
+			// Разные окна не должны смешиваться в одном запросе
+			comments, err := loader.Load(context.Background(), id, model.CommentSortOld, 1+i%2, 0)
+			if err != nil {
+				t.Errorf("Load() error = %v", err)
+			}
+			results[i] = comments
+		}(i, id)
+	}
+	for range ids {
+		<-done
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected 2 batch queries (one per window), got %d", got)
+	}
+	for i, comments := range results {
+		if len(comments) != 1 || comments[0].PostID != ids[i] || len(comments[0].Content) != 1+i%2 {
+			t.Errorf("Unexpected result for id %d: %+v", i, comments)
+		}
+	}
+}
+
+func TestGQLGenService_BatchesReactions(t *testing.T) {
+	storage := &countingStorage{MemoryStorage: repository.NewMemoryStorage()}
+	defer storage.Close()
+	ctx := context.Background()
+
+	user, err := storage.CreateUser(ctx, &model.User{Username: "reactor"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Title", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		comment, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, Content: "Root"})
+		if err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+		if err := storage.AddReaction(ctx, user.ID, comment.ID, "👍"); err != nil {
+			t.Fatalf("Failed to add reaction: %v", err)
+		}
+	}
+
+	service := NewGQLGenService(storage, pubsub.New())
+	query := `{"query":"{ post(id: \"` + post.ID.String() + `\") { comments(limit: 50) { id reactions { emoji count viewerReacted } } } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(query))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	service.GetHandler().ServeHTTP(rec, req)
+
+	var resp struct {
+		Data struct {
+			Post struct {
+				Comments []struct {
+					Reactions []model.Reaction
+				}
+			}
+		}
+		Errors []any
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", resp.Errors)
+	}
+	if len(resp.Data.Post.Comments) != 20 {
+		t.Fatalf("Expected 20 comments, got %d", len(resp.Data.Post.Comments))
+	}
+	for _, comment := range resp.Data.Post.Comments {
+		if len(comment.Reactions) != 1 || comment.Reactions[0] != (model.Reaction{Emoji: "👍", Count: 1}) {
+			t.Fatalf("Unexpected reactions %+v", comment.Reactions)
+		}
+	}
+
+	if got := storage.reactionCalls.Load(); got != 1 {
+		t.Errorf("Expected 1 batch reactions query, got %d", got)
+	}
+}
@@ -0,0 +1,429 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/repository"
+	"github.com/NarthurN/CommentsSystem/internal/service/generated"
+)
+
+// Значения OutboxRelayConfig по умолчанию
+const (
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxBatchSize    = 100
+	defaultOutboxLease        = 30 * time.Second
+	defaultWebhookTimeout     = 5 * time.Second
+
+	// outboxPurgeInterval - как часто relay удаляет доставленные события
+	outboxPurgeInterval = time.Minute
+
+	// outboxDedupCapacity - сколько последних доставок relay помнит, чтобы не повторять их
+	outboxDedupCapacity = 10000
+)
+
+// OutboxRelayConfig содержит параметры доставки событий outbox
+type OutboxRelayConfig struct {
+	PollInterval   time.Duration // Период опроса outbox
+	BatchSize      int           // Сколько событий захватывается за раз
+	Lease          time.Duration // На сколько событие захватывается; после неудачи - пауза до повтора
+	Retention      time.Duration // Сколько хранятся доставленные события; 0 - не удаляются
+	WebhookURLs    []string      // Вебхуки, получающие события POST запросом
+	WebhookTimeout time.Duration // Таймаут доставки одного события вебхуку
+	HTTPClient     *http.Client  // Клиент для вебхуков; nil - http.Client с WebhookTimeout
+	OnError        func(error)   // Вызывается при ошибках доставки; nil - ошибки игнорируются
+}
+
+// outboxSink - получатель событий outbox
+type outboxSink interface {
+	// name однозначно называет получателя: под этим именем хранилище учитывает его доставки
+	name() string
+	// deliver доставляет событие; ошибка означает, что доставку нужно повторить
+	deliver(ctx context.Context, event model.OutboxEvent) error
+}
+
+// OutboxRelay доставляет события outbox в pub/sub и вебхуки.
+//
+// Каждый получатель обслуживается своим циклом со своими захватами, попытками и паузой
+// после ошибок: недоступный вебхук не задерживает публикацию подписчикам и другие вебхуки.
+// Доставка происходит как минимум один раз: событие отмечается доставленным получателю
+// только после успешной доставки, а после сбоя relay или ошибки получателя повторяется.
+// Повторы в пределах процесса отсекаются по DedupKey; вебхуки получают DedupKey в заголовке
+// Idempotency-Key и отбрасывают повторы, пришедшие после перезапуска.
+// Каждый получатель получает события в порядке записи, пока нет повторов.
+type OutboxRelay struct {
+	outbox    repository.Outbox
+	consumers []*outboxConsumer
+	cfg       OutboxRelayConfig
+}
+
+// outboxConsumer - состояние доставки событий одному получателю
+type outboxConsumer struct {
+	sink      outboxSink
+	delivered *dedupSet     // Последние доставки получателю в пределах процесса
+	backoff   time.Duration // Пауза перед следующей попыткой после ошибки; 0 - ошибок не было
+	wake      chan struct{}
+}
+
+// NewOutboxRelay создает relay для событий outbox хранилища сервиса.
+// После вызова мутации не публикуют события комментариев сами: их доставляет relay,
+// который нужно запустить методом Run. Хранилище должно записывать события
+// (repository.PostgresOptions.Outbox), иначе возвращается ошибка.
+func (s *GQLGenService) NewOutboxRelay(cfg OutboxRelayConfig) (*OutboxRelay, error) {
+	outbox, ok := s.storage.(repository.Outbox)
+	if !ok {
+		return nil, fmt.Errorf("storage %T does not support outbox", s.storage)
+	}
+
+	relay := newOutboxRelay(outbox, s.resolver, cfg)
+	s.resolver.outboxRelay = relay
+	return relay, nil
+}
+
+// newOutboxRelay создает relay для событий outbox.
+// Подписчики получают события через resolver так же, как при публикации из мутаций.
+func newOutboxRelay(outbox repository.Outbox, resolver *Resolver, cfg OutboxRelayConfig) *OutboxRelay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultOutboxPollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultOutboxBatchSize
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = defaultOutboxLease
+	}
+	if cfg.WebhookTimeout <= 0 {
+		cfg.WebhookTimeout = defaultWebhookTimeout
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: cfg.WebhookTimeout}
+	}
+
+	sinks := []outboxSink{pubsubSink{resolver: resolver}}
+	for _, url := range cfg.WebhookURLs {
+		sinks = append(sinks, webhookSink{url: url, client: cfg.HTTPClient})
+	}
+
+	consumers := make([]*outboxConsumer, len(sinks))
+	for i, sink := range sinks {
+		consumers[i] = &outboxConsumer{
+			sink:      sink,
+			delivered: newDedupSet(outboxDedupCapacity),
+			wake:      make(chan struct{}, 1),
+		}
+	}
+
+	return &OutboxRelay{
+		outbox:    outbox,
+		consumers: consumers,
+		cfg:       cfg,
+	}
+}
+
+// Wake просит relay проверить outbox, не дожидаясь очередного опроса.
+// Получатели, ожидающие повтора после ошибки, не будятся.
+func (r *OutboxRelay) Wake() {
+	for _, consumer := range r.consumers {
+		select {
+		case consumer.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run доставляет события, пока не отменен ctx.
+// Возвращает управление после остановки циклов всех получателей.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, consumer := range r.consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.runConsumer(ctx, consumer)
+		}()
+	}
+
+	r.runPurge(ctx)
+	wg.Wait()
+}
+
+// runConsumer доставляет события одному получателю, пока не отменен ctx
+func (r *OutboxRelay) runConsumer(ctx context.Context, consumer *outboxConsumer) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.flushConsumer(ctx, consumer)
+
+		if consumer.backoff > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(consumer.backoff):
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-consumer.wake:
+		}
+	}
+}
+
+// runPurge удаляет события, доставленные всем получателям, раз в outboxPurgeInterval
+func (r *OutboxRelay) runPurge(ctx context.Context) {
+	if r.cfg.Retention <= 0 {
+		<-ctx.Done()
+		return
+	}
+
+	names := make([]string, len(r.consumers))
+	for i, consumer := range r.consumers {
+		names[i] = consumer.sink.name()
+	}
+
+	ticker := time.NewTicker(outboxPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.outbox.PurgeOutbox(ctx, names, time.Now().Add(-r.cfg.Retention)); err != nil && ctx.Err() == nil {
+			r.fail(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush доставляет всем получателям события, доступные для захвата.
+// Не вызывается одновременно с Run.
+func (r *OutboxRelay) Flush(ctx context.Context) {
+	for _, consumer := range r.consumers {
+		r.flushConsumer(ctx, consumer)
+	}
+}
+
+// flushConsumer доставляет получателю события, доступные для захвата.
+// После первой ошибки доставка останавливается, чтобы не нарушать порядок и не ждать
+// таймаута на каждом событии: неудачное событие остается захваченным до конца lease,
+// с остальных захват снимается, а следующая попытка откладывается с нарастающей паузой.
+func (r *OutboxRelay) flushConsumer(ctx context.Context, consumer *outboxConsumer) {
+	name := consumer.sink.name()
+	for ctx.Err() == nil {
+		events, err := r.outbox.ClaimOutboxEvents(ctx, name, r.cfg.BatchSize, r.cfg.Lease)
+		if err != nil {
+			r.fail(err)
+			return
+		}
+
+		delivered := make([]int64, 0, len(events))
+		failed := -1
+		for i, event := range events {
+			if err := r.deliver(ctx, consumer, event); err != nil {
+				r.fail(fmt.Errorf("failed to deliver outbox event %s to %s: %w", event.DedupKey, name, err))
+				if err := r.outbox.MarkOutboxFailed(ctx, name, event.ID, err.Error()); err != nil {
+					r.fail(err)
+				}
+				failed = i
+				break
+			}
+			delivered = append(delivered, event.ID)
+		}
+
+		// Если отметка не сохранится, события доставятся повторно после lease
+		if err := r.outbox.MarkOutboxDelivered(ctx, name, delivered); err != nil {
+			r.fail(err)
+			return
+		}
+
+		if failed >= 0 {
+			skipped := make([]int64, 0, len(events)-failed-1)
+			for _, event := range events[failed+1:] {
+				skipped = append(skipped, event.ID)
+			}
+			if err := r.outbox.ReleaseOutboxEvents(ctx, name, skipped); err != nil {
+				r.fail(err)
+			}
+			consumer.backoff = min(max(consumer.backoff*2, r.cfg.PollInterval), r.cfg.Lease)
+			return
+		}
+		consumer.backoff = 0
+
+		if len(events) < r.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// deliver доставляет событие получателю, если он еще его не получил
+func (r *OutboxRelay) deliver(ctx context.Context, consumer *outboxConsumer, event model.OutboxEvent) error {
+	if consumer.delivered.contains(event.DedupKey) {
+		return nil
+	}
+	if err := consumer.sink.deliver(ctx, event); err != nil {
+		return err
+	}
+	consumer.delivered.add(event.DedupKey)
+	return nil
+}
+
+// fail передает ошибку обработчику OnError
+func (r *OutboxRelay) fail(err error) {
+	if r.cfg.OnError != nil {
+		r.cfg.OnError(err)
+	}
+}
+
+// pubsubSink публикует события outbox подписчикам GraphQL.
+// Если брокер не принял сообщение, доставка возвращает ошибку и relay повторяет ее:
+// в отличие от мутаций без outbox, сообщение не доставляется только локальным подписчикам,
+// иначе после повтора они получили бы его дважды.
+type pubsubSink struct {
+	resolver *Resolver
+}
+
+func (pubsubSink) name() string { return "pubsub" }
+
+func (s pubsubSink) deliver(ctx context.Context, event model.OutboxEvent) error {
+	switch event.Kind {
+	case model.OutboxCommentAdded:
+		var comment model.Comment
+		if err := json.Unmarshal(event.Payload, &comment); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		if err := s.resolver.pubsub.PublishErr(commentsTopic(comment.PostID), &generated.CommentAdded{Comment: &comment}); err != nil {
+			return err
+		}
+		return s.resolver.publishReply(ctx, &comment, s.resolver.pubsub.PublishErr)
+	case model.OutboxCommentEdited:
+		var comment model.Comment
+		if err := json.Unmarshal(event.Payload, &comment); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return s.resolver.pubsub.PublishErr(commentsTopic(comment.PostID), &generated.CommentEdited{Comment: &comment})
+	case model.OutboxCommentDeleted:
+		var comment model.Comment
+		if err := json.Unmarshal(event.Payload, &comment); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return s.resolver.pubsub.PublishErr(commentsTopic(comment.PostID), &generated.CommentDeleted{Comment: &comment})
+	case model.OutboxCommentsToggled:
+		var toggled model.CommentsToggledEvent
+		if err := json.Unmarshal(event.Payload, &toggled); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return s.resolver.pubsub.PublishErr(commentsTopic(toggled.PostID), &generated.CommentsToggled{
+			PostID:          toggled.PostID.String(),
+			CommentsEnabled: toggled.CommentsEnabled,
+		})
+	case model.OutboxVoteTally:
+		var tally model.VoteTally
+		if err := json.Unmarshal(event.Payload, &tally); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return s.resolver.pubsub.PublishErr(scoresTopic(tally.PostID), &tally)
+	case model.OutboxPostDeleted:
+		var deleted model.PostDeletedEvent
+		if err := json.Unmarshal(event.Payload, &deleted); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return s.resolver.publishPostDeleted(deleted.PostID, deleted.CommentIDs, s.resolver.pubsub.PublishErr)
+	default:
+		return fmt.Errorf("unknown event kind %q", event.Kind)
+	}
+}
+
+// webhookEvent - тело запроса вебхука
+type webhookEvent struct {
+	ID        string          `json:"id"`        // Ключ дедупликации события
+	Kind      string          `json:"kind"`      // Вид события
+	CreatedAt time.Time       `json:"createdAt"` // Время записи события
+	Data      json.RawMessage `json:"data"`      // Данные события; формат зависит от Kind
+}
+
+// webhookSink отправляет события outbox POST запросом на url
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s webhookSink) name() string { return "webhook " + s.url }
+
+func (s webhookSink) deliver(ctx context.Context, event model.OutboxEvent) error {
+	body, err := json.Marshal(webhookEvent{
+		ID:        event.DedupKey,
+		Kind:      event.Kind,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", event.DedupKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	// Тело дочитывается, чтобы соединение вернулось в пул keep-alive
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// dedupSet хранит последние capacity ключей; самые старые вытесняются
+type dedupSet struct {
+	keys  map[string]struct{}
+	order []string
+	next  int
+}
+
+// newDedupSet создает пустое множество ключей
+func newDedupSet(capacity int) *dedupSet {
+	return &dedupSet{
+		keys:  make(map[string]struct{}, capacity),
+		order: make([]string, capacity),
+	}
+}
+
+// contains сообщает, есть ли ключ в множестве
+func (d *dedupSet) contains(key string) bool {
+	_, ok := d.keys[key]
+	return ok
+}
+
+// add добавляет ключ, вытесняя самый старый при заполнении
+func (d *dedupSet) add(key string) {
+	if d.contains(key) {
+		return
+	}
+	if old := d.order[d.next]; old != "" {
+		delete(d.keys, old)
+	}
+	d.order[d.next] = key
+	d.keys[key] = struct{}{}
+	d.next = (d.next + 1) % len(d.order)
+}
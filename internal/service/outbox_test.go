@@ -0,0 +1,461 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/CommentsSystem/internal/auth"
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/repository"
+	"github.com/NarthurN/CommentsSystem/internal/service/generated"
+	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
+	"github.com/google/uuid"
+)
+
+// outboxStorage - хранилище в памяти, записывающее события в outbox, как PostgresStorage
+type outboxStorage struct {
+	*repository.MemoryStorage
+
+	mu        sync.Mutex
+	events    []model.OutboxEvent
+	delivered map[string]map[int64]bool // Доставки по получателям
+	failures  map[string]map[int64]int  // Неудачные попытки по получателям
+}
+
+func newOutboxStorage() *outboxStorage {
+	return &outboxStorage{
+		MemoryStorage: repository.NewMemoryStorage(),
+		delivered:     make(map[string]map[int64]bool),
+		failures:      make(map[string]map[int64]int),
+	}
+}
+
+func (s *outboxStorage) CreateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	created, err := s.MemoryStorage.CreateComment(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(model.OutboxCommentAdded, model.CommentAddedDedupKey(created.ID), created)
+	return created, nil
+}
+
+func (s *outboxStorage) EditComment(ctx context.Context, id uuid.UUID, content string) (*model.Comment, error) {
+	edited, err := s.MemoryStorage.EditComment(ctx, id, content)
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(model.OutboxCommentEdited, model.NewOutboxDedupKey(model.OutboxCommentEdited, id), edited)
+	return edited, nil
+}
+
+// record записывает событие в outbox
+func (s *outboxStorage) record(kind, dedupKey string, data any) {
+	payload, _ := json.Marshal(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, model.OutboxEvent{
+		ID:        int64(len(s.events) + 1),
+		Kind:      kind,
+		DedupKey:  dedupKey,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	})
+}
+
+// WithTx выполняет fn над самим хранилищем, чтобы CreateComment внутри транзакции записывал события
+func (s *outboxStorage) WithTx(ctx context.Context, fn func(tx repository.Storage) error) error {
+	return fn(s)
+}
+
+// ClaimOutboxEvents возвращает все недоставленные получателю события: lease в тестах не нужен
+func (s *outboxStorage) ClaimOutboxEvents(ctx context.Context, consumer string, limit int, lease time.Duration) ([]model.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []model.OutboxEvent
+	for _, event := range s.events {
+		if !s.delivered[consumer][event.ID] && len(pending) < limit {
+			pending = append(pending, event)
+		}
+	}
+	return pending, nil
+}
+
+func (s *outboxStorage) MarkOutboxDelivered(ctx context.Context, consumer string, ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.delivered[consumer] == nil {
+		s.delivered[consumer] = make(map[int64]bool)
+	}
+	for _, id := range ids {
+		s.delivered[consumer][id] = true
+	}
+	return nil
+}
+
+func (s *outboxStorage) MarkOutboxFailed(ctx context.Context, consumer string, id int64, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures[consumer] == nil {
+		s.failures[consumer] = make(map[int64]int)
+	}
+	s.failures[consumer][id]++
+	return nil
+}
+
+func (s *outboxStorage) ReleaseOutboxEvents(ctx context.Context, consumer string, ids []int64) error {
+	return nil
+}
+
+func (s *outboxStorage) PurgeOutbox(ctx context.Context, consumers []string, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// isDelivered сообщает, доставлено ли событие получателю
+func (s *outboxStorage) isDelivered(consumer string, id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.delivered[consumer][id]
+}
+
+// failureCount возвращает количество неудачных попыток доставки события получателю
+func (s *outboxStorage) failureCount(consumer string, id int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.failures[consumer][id]
+}
+
+func TestOutboxRelay_DeliversComments(t *testing.T) {
+	storage := newOutboxStorage()
+	defer storage.Close()
+
+	var (
+		mu       sync.Mutex
+		keys     []string
+		failNext = true
+	)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Первая доставка вебхуку не удается
+		if failNext {
+			failNext = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event webhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil || event.Kind != model.OutboxCommentAdded {
+			t.Errorf("Unexpected webhook body: %+v, %v", event, err)
+		}
+		if r.Header.Get("Idempotency-Key") != event.ID {
+			t.Errorf("Expected Idempotency-Key %q, got %q", event.ID, r.Header.Get("Idempotency-Key"))
+		}
+		keys = append(keys, event.ID)
+	}))
+	defer webhook.Close()
+
+	svc := NewGQLGenService(storage, pubsub.New())
+	relay, err := svc.NewOutboxRelay(OutboxRelayConfig{WebhookURLs: []string{webhook.URL}})
+	if err != nil {
+		t.Fatalf("Failed to create outbox relay: %v", err)
+	}
+	resolver := svc.resolver
+	ctx := context.Background()
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Post", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := resolver.Subscription().PostEvents(subCtx, post.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	comment, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "Comment")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+
+	// До запуска relay мутация ничего не публикует
+	select {
+	case event := <-events:
+		t.Fatalf("Unexpected event before relay: %#v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Вебхук не принял событие: оно остается в outbox, но подписчики уже получили его
+	relay.Flush(ctx)
+	select {
+	case event := <-events:
+		added, ok := event.(*generated.CommentAdded)
+		if !ok || added.Comment.ID != comment.ID {
+			t.Errorf("Expected CommentAdded for %s, got %#v", comment.ID, event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Comment was not published by relay")
+	}
+	webhookConsumer := webhookSink{url: webhook.URL}.name()
+	if !storage.isDelivered("pubsub", 1) {
+		t.Error("Expected event to be delivered to pubsub despite webhook failure")
+	}
+	if storage.failureCount(webhookConsumer, 1) != 1 || storage.isDelivered(webhookConsumer, 1) {
+		t.Fatal("Expected failed webhook delivery to be recorded")
+	}
+
+	// Повтор доставляет событие вебхуку, не публикуя его подписчикам второй раз
+	relay.Flush(ctx)
+	if !storage.isDelivered(webhookConsumer, 1) {
+		t.Error("Expected event to be marked delivered to webhook")
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Event was published twice: %#v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 1 || keys[0] != model.CommentAddedDedupKey(comment.ID) {
+		t.Errorf("Expected one webhook delivery with dedup key, got %v", keys)
+	}
+}
+
+// TestOutboxRelay_PublishesEventsInOrder проверяет, что с relay правка комментария
+// публикуется через outbox и не опережает создание комментария
+func TestOutboxRelay_PublishesEventsInOrder(t *testing.T) {
+	storage := newOutboxStorage()
+	defer storage.Close()
+
+	svc := NewGQLGenService(storage, pubsub.New())
+	relay, err := svc.NewOutboxRelay(OutboxRelayConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create outbox relay: %v", err)
+	}
+	resolver := svc.resolver
+	ctx := auth.WithModerator(context.Background()) // Как при отключенной аутентификации
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Post", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := resolver.Subscription().PostEvents(subCtx, post.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	comment, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "Comment")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	if _, err := resolver.Mutation().EditComment(ctx, comment.ID.String(), "Edited"); err != nil {
+		t.Fatalf("Failed to edit comment: %v", err)
+	}
+
+	// Мутация правки тоже ничего не публикует сама
+	select {
+	case event := <-events:
+		t.Fatalf("Unexpected event before relay: %#v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	relay.Flush(ctx)
+	for _, expected := range []string{"CommentAdded", "CommentEdited"} {
+		select {
+		case event := <-events:
+			var got string
+			switch event.(type) {
+			case *generated.CommentAdded:
+				got = "CommentAdded"
+			case *generated.CommentEdited:
+				got = "CommentEdited"
+			}
+			if got != expected {
+				t.Fatalf("Expected %s, got %#v", expected, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s was not published by relay", expected)
+		}
+	}
+}
+
+// TestOutboxRelay_SlowWebhookDoesNotDelayPubsub проверяет, что зависший вебхук
+// не задерживает публикацию событий подписчикам
+func TestOutboxRelay_SlowWebhookDoesNotDelayPubsub(t *testing.T) {
+	storage := newOutboxStorage()
+	defer storage.Close()
+
+	release := make(chan struct{})
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer webhook.Close()
+	defer close(release)
+
+	svc := NewGQLGenService(storage, pubsub.New())
+	relay, err := svc.NewOutboxRelay(OutboxRelayConfig{
+		PollInterval:   10 * time.Millisecond,
+		WebhookURLs:    []string{webhook.URL},
+		WebhookTimeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create outbox relay: %v", err)
+	}
+	resolver := svc.resolver
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Post", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	events, err := resolver.Subscription().PostEvents(ctx, post.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	for i := range 2 {
+		if _, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "Comment"); err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+		select {
+		case event := <-events:
+			if _, ok := event.(*generated.CommentAdded); !ok {
+				t.Errorf("Expected CommentAdded, got %#v", event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Comment %d was not published while webhook is hanging", i+1)
+		}
+	}
+}
+
+// failingPubSub - pub/sub в памяти, брокер которого не принимает первую публикацию через PublishErr
+type failingPubSub struct {
+	*pubsub.Memory
+	mu       sync.Mutex
+	failNext bool
+}
+
+func (ps *failingPubSub) PublishErr(topic string, data interface{}) error {
+	ps.mu.Lock()
+	fail := ps.failNext
+	ps.failNext = false
+	ps.mu.Unlock()
+
+	if fail {
+		return errors.New("broker unavailable")
+	}
+	return ps.Memory.PublishErr(topic, data)
+}
+
+func TestOutboxRelay_RetriesFailedPublish(t *testing.T) {
+	storage := newOutboxStorage()
+	defer storage.Close()
+
+	ps := &failingPubSub{Memory: pubsub.New(), failNext: true}
+	svc := NewGQLGenService(storage, ps)
+	relay, err := svc.NewOutboxRelay(OutboxRelayConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create outbox relay: %v", err)
+	}
+	resolver := svc.resolver
+	ctx := context.Background()
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Post", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := resolver.Subscription().PostEvents(subCtx, post.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	comment, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "Comment")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+
+	// Брокер не принял событие: оно остается в outbox и не доставляется даже локально
+	relay.Flush(ctx)
+	if storage.isDelivered("pubsub", 1) || storage.failureCount("pubsub", 1) != 1 {
+		t.Fatal("Expected failed publish to be recorded as a failed delivery")
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("Unexpected event after failed publish: %#v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Повтор публикует событие один раз
+	relay.Flush(ctx)
+	if !storage.isDelivered("pubsub", 1) {
+		t.Error("Expected event to be delivered on retry")
+	}
+	select {
+	case event := <-events:
+		added, ok := event.(*generated.CommentAdded)
+		if !ok || added.Comment.ID != comment.ID {
+			t.Errorf("Expected CommentAdded for %s, got %#v", comment.ID, event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Comment was not published on retry")
+	}
+}
+
+func TestGQLGenService_NewOutboxRelay_Unsupported(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+
+	svc := NewGQLGenService(storage, pubsub.New())
+	if _, err := svc.NewOutboxRelay(OutboxRelayConfig{}); err == nil {
+		t.Error("Expected error for storage without outbox")
+	}
+}
+
+func TestDedupSet(t *testing.T) {
+	set := newDedupSet(2)
+	set.add("a")
+	set.add("b")
+	set.add("a")
+	set.add("c")
+
+	if set.contains("a") {
+		t.Error("Expected oldest key to be evicted")
+	}
+	if !set.contains("b") || !set.contains("c") {
+		t.Error("Expected recent keys to be kept")
+	}
+}
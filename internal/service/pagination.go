@@ -0,0 +1,116 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/service/generated"
+	"github.com/google/uuid"
+)
+
+// Ограничения keyset пагинации
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// errInvalidCursor возвращается для курсора, который не был выдан сервером
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor кодирует позицию элемента в непрозрачный для клиента курсор
+func encodeCursor(c model.Cursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor разбирает курсор, выданный encodeCursor.
+// Для пустого курсора возвращает nil: запрашивается первая страница.
+func decodeCursor(cursor *string) (*model.Cursor, error) {
+	if cursor == nil || *cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(*cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	createdAtStr, idStr, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, errInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return &model.Cursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// pageSize проверяет аргумент first и возвращает размер страницы
+func pageSize(first *int) (int, error) {
+	if first == nil {
+		return defaultPageSize, nil
+	}
+	if *first <= 0 || *first > maxPageSize {
+		return 0, errors.New("first must be between 1 and 100")
+	}
+	return *first, nil
+}
+
+// newPostConnection строит connection из страницы постов.
+// posts содержит до size+1 элементов: лишний элемент означает, что есть следующая страница.
+func newPostConnection(posts []*model.Post, size int) *generated.PostConnection {
+	hasNextPage := len(posts) > size
+	if hasNextPage {
+		posts = posts[:size]
+	}
+
+	edges := make([]*generated.PostEdge, len(posts))
+	for i, post := range posts {
+		edges[i] = &generated.PostEdge{
+			Cursor: encodeCursor(post.Cursor()),
+			Node:   post,
+		}
+	}
+
+	pageInfo := &generated.PageInfo{HasNextPage: hasNextPage}
+	if len(edges) > 0 {
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &generated.PostConnection{Edges: edges, PageInfo: pageInfo}
+}
+
+// newCommentConnection строит connection из страницы комментариев.
+// comments содержит до size+1 элементов: лишний элемент означает, что есть следующая страница.
+func newCommentConnection(comments []model.Comment, size int) *generated.CommentConnection {
+	hasNextPage := len(comments) > size
+	if hasNextPage {
+		comments = comments[:size]
+	}
+
+	edges := make([]*generated.CommentEdge, len(comments))
+	for i := range comments {
+		edges[i] = &generated.CommentEdge{
+			Cursor: encodeCursor(comments[i].Cursor()),
+			Node:   &comments[i],
+		}
+	}
+
+	pageInfo := &generated.PageInfo{HasNextPage: hasNextPage}
+	if len(edges) > 0 {
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &generated.CommentConnection{Edges: edges, PageInfo: pageInfo}
+}
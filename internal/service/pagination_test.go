@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/repository"
+	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	original := model.Cursor{
+		CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC),
+		ID:        uuid.New(),
+	}
+	encoded := encodeCursor(original)
+
+	decoded, err := decodeCursor(&encoded)
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	if !decoded.CreatedAt.Equal(original.CreatedAt) || decoded.ID != original.ID {
+		t.Errorf("Cursor changed after round trip: %+v != %+v", decoded, original)
+	}
+
+	if cursor, err := decodeCursor(nil); cursor != nil || err != nil {
+		t.Error("Expected nil cursor for first page")
+	}
+
+	for _, bad := range []string{"garbage", encodeCursor(original)[:5], "bm90LWEtY3Vyc29y"} {
+		if _, err := decodeCursor(&bad); err == nil {
+			t.Errorf("Expected error for invalid cursor %q", bad)
+		}
+	}
+}
+
+func TestQueryResolver_PostsConnection(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := resolver.Mutation().CreatePost(ctx, "Title", "Content"); err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+	}
+
+	first := 2
+	page, err := resolver.Query().PostsConnection(ctx, &first, nil)
+	if err != nil {
+		t.Fatalf("Failed to get first page: %v", err)
+	}
+	if len(page.Edges) != 2 || !page.PageInfo.HasNextPage || page.PageInfo.EndCursor == nil {
+		t.Fatalf("Unexpected first page: %d edges, pageInfo %+v", len(page.Edges), page.PageInfo)
+	}
+
+	next, err := resolver.Query().PostsConnection(ctx, &first, page.PageInfo.EndCursor)
+	if err != nil {
+		t.Fatalf("Failed to get second page: %v", err)
+	}
+	if len(next.Edges) != 1 || next.PageInfo.HasNextPage {
+		t.Errorf("Unexpected second page: %d edges, hasNextPage %v", len(next.Edges), next.PageInfo.HasNextPage)
+	}
+	for _, edge := range page.Edges {
+		if edge.Node.ID == next.Edges[0].Node.ID {
+			t.Error("Post repeated across pages")
+		}
+	}
+
+	tooMany := 101
+	if _, err := resolver.Query().PostsConnection(ctx, &tooMany, nil); err == nil {
+		t.Error("Expected error for first > 100")
+	}
+}
+
+func TestPostResolver_CommentsConnection(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := context.Background()
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Title", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	root, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "Root")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	parentID := root.ID.String()
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), &parentID, "Reply"); err != nil {
+			t.Fatalf("Failed to create reply: %v", err)
+		}
+	}
+
+	comments, err := resolver.Post().CommentsConnection(ctx, post, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to get comments: %v", err)
+	}
+	if len(comments.Edges) != 1 || comments.PageInfo.HasNextPage {
+		t.Errorf("Expected single root comment, got %d", len(comments.Edges))
+	}
+
+	first := 1
+	children, err := resolver.Comment().ChildrenConnection(ctx, root, &first, nil)
+	if err != nil {
+		t.Fatalf("Failed to get children: %v", err)
+	}
+	if len(children.Edges) != 1 || !children.PageInfo.HasNextPage {
+		t.Errorf("Expected first child page with next page, got %d edges", len(children.Edges))
+	}
+
+	rest, err := resolver.Comment().ChildrenConnection(ctx, root, &first, children.PageInfo.EndCursor)
+	if err != nil {
+		t.Fatalf("Failed to get next children page: %v", err)
+	}
+	if len(rest.Edges) != 1 || rest.PageInfo.HasNextPage || rest.Edges[0].Node.ID == children.Edges[0].Node.ID {
+		t.Error("Unexpected second children page")
+	}
+}
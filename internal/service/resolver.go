@@ -1,8 +1,19 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/NarthurN/CommentsSystem/internal/auth"
+	"github.com/NarthurN/CommentsSystem/internal/config"
+	"github.com/NarthurN/CommentsSystem/internal/model"
 	"github.com/NarthurN/CommentsSystem/internal/repository"
+	"github.com/NarthurN/CommentsSystem/internal/service/generated"
 	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
+	"github.com/google/uuid"
 )
 
 // This file will not be regenerated automatically.
@@ -10,14 +21,177 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	storage repository.Storage
-	pubsub  *pubsub.PubSub
+	storage       repository.Storage
+	pubsub        pubsub.PubSub
+	reactionEmoji []string     // Разрешенные эмодзи реакций на комментарии
+	outboxRelay   *OutboxRelay // Доставляет события комментариев из outbox; nil - мутации публикуют сами
 }
 
-// NewResolver создает новый экземпляр Resolver с зависимостями
-func NewResolver(storage repository.Storage, ps *pubsub.PubSub) *Resolver {
+// NewResolver создает новый экземпляр Resolver с зависимостями.
+// Разрешенные реакции берутся из config.DefaultReactionEmoji.
+func NewResolver(storage repository.Storage, ps pubsub.PubSub) *Resolver {
 	return &Resolver{
-		storage: storage,
-		pubsub:  ps,
+		storage:       storage,
+		pubsub:        ps,
+		reactionEmoji: strings.Split(config.DefaultReactionEmoji, ","),
+	}
+}
+
+// outboxDelivers сообщает, что события комментариев доставляет outbox relay, и будит его.
+// Хранилище записывает событие в outbox вместе с изменением, а relay публикует события
+// в порядке записи: если бы мутации публиковали часть событий сами, правка или удаление
+// могли бы дойти до подписчиков раньше создания комментария.
+func (r *Resolver) outboxDelivers() bool {
+	if r.outboxRelay == nil {
+		return false
+	}
+	r.outboxRelay.Wake()
+	return true
+}
+
+// publishFunc публикует сообщение в топик pub/sub
+type publishFunc func(topic string, data interface{}) error
+
+// publishBestEffort публикует сообщение без ошибки: если брокер недоступен,
+// сообщение получают хотя бы подписчики этого экземпляра.
+// Так публикуют мутации, события которых не повторяет outbox relay.
+func (r *Resolver) publishBestEffort(topic string, data interface{}) error {
+	r.pubsub.Publish(topic, data)
+	return nil
+}
+
+// publishPostDeleted сообщает подписчикам об удалении поста и завершает подписки
+// на пост и комментарии commentIDs, удаленные вместе с ним.
+// Если событие удаления не опубликовано, подписки остаются: иначе подписчики
+// отключились бы, не узнав причины, а повторная публикация их уже не застала бы.
+func (r *Resolver) publishPostDeleted(postID uuid.UUID, commentIDs []uuid.UUID, publish publishFunc) error {
+	// Подписчики postEvents получают явное событие удаления, чтобы отличить его
+	// от остановки сервера или отключения из-за переполнения
+	if err := publish(commentsTopic(postID), &generated.PostDeleted{PostID: postID.String()}); err != nil {
+		return err
 	}
+
+	// Пост удален, новых событий не будет
+	r.pubsub.CloseTopic(commentsTopic(postID))
+	r.pubsub.CloseTopic(scoresTopic(postID))
+	for _, commentID := range commentIDs {
+		r.pubsub.CloseTopic(repliesTopic(commentID))
+	}
+	return nil
+}
+
+// postsTopic - топик pub/sub с новыми постами
+const postsTopic = "posts"
+
+// commentsTopic возвращает имя топика pub/sub с событиями комментариев поста.
+// В топик публикуются события generated.PostEvent: новые, отредактированные
+// и удаленные комментарии, а также включение и отключение комментариев.
+func commentsTopic(postID uuid.UUID) string {
+	return fmt.Sprintf("post:%s:comments", postID.String())
+}
+
+// scoresTopic возвращает имя топика pub/sub с изменениями рейтинга поста и его комментариев
+func scoresTopic(postID uuid.UUID) string {
+	return fmt.Sprintf("post:%s:scores", postID.String())
+}
+
+// checkReaction проверяет, что эмодзи входит в список разрешенных реакций
+func (r *Resolver) checkReaction(emoji string) error {
+	if !slices.Contains(r.reactionEmoji, emoji) {
+		return fmt.Errorf("invalid reaction: %q is not an allowed emoji", emoji)
+	}
+	return nil
+}
+
+// repliesTopic возвращает имя топика pub/sub с новыми ответами в ветке комментария
+func repliesTopic(commentID uuid.UUID) string {
+	return fmt.Sprintf("comment:%s:replies", commentID.String())
+}
+
+// sortOrder возвращает порядок сортировки комментариев из аргумента sort
+func sortOrder(sort *model.CommentSort) model.CommentSort {
+	if sort == nil {
+		return model.CommentSortOld
+	}
+	return sort.OrDefault()
+}
+
+// callerAuthorID возвращает ID вызывающего пользователя для записи в поле автора.
+// Для анонимного запроса возвращает nil: контент создается без владельца.
+func callerAuthorID(ctx context.Context) *uuid.UUID {
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return &userID
+}
+
+// errCannotReplyToDeleted - ответ на удаленный комментарий
+var errCannotReplyToDeleted = errors.New("cannot reply to a deleted comment")
+
+// authorError поясняет repository.ErrUnknownAuthor и repository.ErrUnknownUser: токен валиден,
+// но пользователь еще не создал учетную запись через createUser.
+// Остальные ошибки возвращаются без изменений.
+func authorError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrUnknownAuthor):
+		return fmt.Errorf("%w: create an account with createUser first", repository.ErrUnknownAuthor)
+	case errors.Is(err, repository.ErrUnknownUser):
+		return fmt.Errorf("%w: create an account with createUser first", repository.ErrUnknownUser)
+	}
+	return err
+}
+
+// resolveAuthor загружает автора поста или комментария.
+// Возвращает nil, если автора нет или его учетная запись удалена.
+func (r *Resolver) resolveAuthor(ctx context.Context, authorID *uuid.UUID) (*model.User, error) {
+	if authorID == nil {
+		return nil, nil
+	}
+
+	user, err := r.storage.GetUser(ctx, *authorID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get author: %w", err)
+	}
+
+	return user, nil
+}
+
+// subscribablePost проверяет, что пост для подписки существует, и возвращает его ID
+func (r *Resolver) subscribablePost(ctx context.Context, postID string) (uuid.UUID, error) {
+	id, err := uuid.Parse(postID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid post id: %w", err)
+	}
+
+	if _, err := r.storage.GetPost(ctx, id); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get post: %w", err)
+	}
+
+	return id, nil
+}
+
+// authorizeCommentRemoval проверяет право удалить комментарий:
+// его может удалить автор комментария или автор поста (модерация своей ветки).
+// Пост читается через storage, чтобы проверка шла в транзакции удаления.
+func (r *Resolver) authorizeCommentRemoval(ctx context.Context, storage repository.Storage, comment *model.Comment) error {
+	err := auth.AuthorizeAuthor(ctx, comment.AuthorID)
+	if !errors.Is(err, auth.ErrForbidden) {
+		return err
+	}
+
+	post, postErr := storage.GetPost(ctx, comment.PostID)
+	if postErr != nil {
+		return fmt.Errorf("failed to get post: %w", postErr)
+	}
+
+	userID, _ := auth.UserIDFromContext(ctx)
+	if post.IsAuthoredBy(userID) {
+		return nil
+	}
+
+	return err
 }
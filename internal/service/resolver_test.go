@@ -1,10 +1,18 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/NarthurN/CommentsSystem/internal/auth"
+	"github.com/NarthurN/CommentsSystem/internal/model"
 	"github.com/NarthurN/CommentsSystem/internal/repository"
+	"github.com/NarthurN/CommentsSystem/internal/service/generated"
 	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
+	"github.com/google/uuid"
 )
 
 // Мок для Storage интерфейса
@@ -30,3 +38,827 @@ func TestNewResolver(t *testing.T) {
 		t.Error("PubSub not properly set in resolver")
 	}
 }
+
+func TestMutationResolver_UpdatePost(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := auth.WithModerator(context.Background()) // Как при отключенной аутентификации
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Original", "Original content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	updated, err := resolver.Mutation().UpdatePost(ctx, post.ID.String(), "Updated", "Updated content")
+	if err != nil {
+		t.Fatalf("Failed to update post: %v", err)
+	}
+	if updated.Title != "Updated" || updated.Content != "Updated content" {
+		t.Errorf("Post not updated: %+v", updated)
+	}
+	if !updated.CreatedAt.Equal(post.CreatedAt) {
+		t.Error("CreatedAt should not change on update")
+	}
+
+	// Та же валидация, что и при создании
+	if _, err := resolver.Mutation().UpdatePost(ctx, post.ID.String(), "", "content"); err == nil {
+		t.Error("Expected error for empty title")
+	}
+	if _, err := resolver.Mutation().UpdatePost(ctx, post.ID.String(), "title", strings.Repeat("a", 10001)); err == nil {
+		t.Error("Expected error for too long content")
+	}
+	if _, err := resolver.Mutation().UpdatePost(ctx, "not-a-uuid", "title", "content"); err == nil {
+		t.Error("Expected error for invalid id")
+	}
+}
+
+func TestMutationResolver_DeletePost(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := auth.WithModerator(context.Background()) // Как при отключенной аутентификации
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Title", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, err := resolver.Subscription().CommentAdded(subCtx, post.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	events, err := resolver.Subscription().PostEvents(subCtx, post.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to post events: %v", err)
+	}
+
+	deleted, err := resolver.Mutation().DeletePost(ctx, post.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to delete post: %v", err)
+	}
+	if !deleted {
+		t.Error("Expected DeletePost to return true")
+	}
+
+	// Подписчики commentAdded узнают об удалении по закрытию канала
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("Subscription channel should be closed after post deletion")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Subscription was not closed after post deletion")
+	}
+
+	// Подписчики postEvents получают PostDeleted перед закрытием канала
+	select {
+	case event := <-events:
+		if got, ok := event.(*generated.PostDeleted); !ok || got.PostID != post.ID.String() || got.Cursor == "" {
+			t.Errorf("Expected PostDeleted, got %#v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("PostDeleted was not published")
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Post events channel should be closed after PostDeleted")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Post events subscription was not closed after post deletion")
+	}
+
+	if _, err := resolver.Query().Post(ctx, post.ID.String()); err == nil {
+		t.Error("Expected error when fetching deleted post")
+	}
+	if _, err := resolver.Mutation().DeletePost(ctx, post.ID.String()); err == nil {
+		t.Error("Expected error when deleting post twice")
+	}
+}
+
+func TestMutationResolver_DeleteComment(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := auth.WithModerator(context.Background()) // Как при отключенной аутентификации
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Title", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	postID := post.ID.String()
+
+	root, err := resolver.Mutation().CreateComment(ctx, postID, nil, "Root")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	rootID := root.ID.String()
+	if _, err := resolver.Mutation().CreateComment(ctx, postID, &rootID, "Reply"); err != nil {
+		t.Fatalf("Failed to create reply: %v", err)
+	}
+
+	deleted, err := resolver.Mutation().DeleteComment(ctx, rootID)
+	if err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+	if !deleted.IsDeleted() || deleted.Content != model.DeletedCommentContent {
+		t.Errorf("Expected tombstone, got %+v", deleted)
+	}
+
+	deletedAt, err := resolver.Comment().DeletedAt(ctx, deleted)
+	if err != nil || deletedAt == nil {
+		t.Errorf("Expected deletedAt to be set, got %v (%v)", deletedAt, err)
+	}
+
+	// Ответ сохраняется под надгробием
+	children, err := resolver.Comment().Children(ctx, deleted, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to get children: %v", err)
+	}
+	if len(children) != 1 {
+		t.Errorf("Expected reply to be kept, got %d children", len(children))
+	}
+
+	// Отвечать на удаленный комментарий нельзя
+	if _, err := resolver.Mutation().CreateComment(ctx, postID, &rootID, "Late reply"); err == nil {
+		t.Error("Expected error when replying to deleted comment")
+	}
+}
+
+func TestMutationResolver_EditComment(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := auth.WithModerator(context.Background()) // Как при отключенной аутентификации
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Title", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	comment, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "Original")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+
+	edited, err := resolver.Mutation().EditComment(ctx, comment.ID.String(), "Edited")
+	if err != nil {
+		t.Fatalf("Failed to edit comment: %v", err)
+	}
+
+	editedAt, err := resolver.Comment().EditedAt(ctx, edited)
+	if err != nil || editedAt == nil {
+		t.Errorf("Expected editedAt to be set, got %v (%v)", editedAt, err)
+	}
+
+	revisions, err := resolver.Comment().Revisions(ctx, edited)
+	if err != nil {
+		t.Fatalf("Failed to get revisions: %v", err)
+	}
+	if len(revisions) != 1 || revisions[0].Content != "Original" {
+		t.Errorf("Expected original text in revisions, got %+v", revisions)
+	}
+
+	if _, err := resolver.Mutation().EditComment(ctx, comment.ID.String(), strings.Repeat("a", 2001)); err == nil {
+		t.Error("Expected error for too long content")
+	}
+
+	// История удаленного комментария скрыта
+	deleted, err := resolver.Mutation().DeleteComment(ctx, comment.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+	revisions, err = resolver.Comment().Revisions(ctx, deleted)
+	if err != nil {
+		t.Fatalf("Failed to get revisions: %v", err)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("Expected no revisions for deleted comment, got %d", len(revisions))
+	}
+}
+
+func TestMutationResolver_Ownership(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := context.Background()
+
+	alice, err := resolver.Mutation().CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, err := resolver.Mutation().CreateUser(ctx, "bob")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := resolver.Mutation().CreateUser(ctx, "alice"); err == nil {
+		t.Error("Expected error for duplicate username")
+	}
+
+	aliceCtx := auth.WithUserID(ctx, alice.ID)
+	bobCtx := auth.WithUserID(ctx, bob.ID)
+
+	post, err := resolver.Mutation().CreatePost(aliceCtx, "Alice's post", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	author, err := resolver.Post().Author(ctx, post)
+	if err != nil || author == nil || author.ID != alice.ID {
+		t.Fatalf("Expected post author alice, got %v (err %v)", author, err)
+	}
+
+	// Токен пользователя без учетной записи не дает создавать контент
+	strangerCtx := auth.WithUserID(ctx, uuid.New())
+	if _, err := resolver.Mutation().CreatePost(strangerCtx, "Stranger", "Content"); !errors.Is(err, repository.ErrUnknownAuthor) {
+		t.Errorf("Expected ErrUnknownAuthor for post, got %v", err)
+	}
+	if _, err := resolver.Mutation().CreateComment(strangerCtx, post.ID.String(), nil, "Comment"); !errors.Is(err, repository.ErrUnknownAuthor) {
+		t.Errorf("Expected ErrUnknownAuthor for comment, got %v", err)
+	}
+	if _, err := resolver.Mutation().Vote(strangerCtx, post.ID.String(), model.VoteUp); !errors.Is(err, repository.ErrUnknownUser) {
+		t.Errorf("Expected ErrUnknownUser for vote, got %v", err)
+	}
+
+	// Управлять комментариями поста может только его автор
+	if _, err := resolver.Mutation().ToggleComments(bobCtx, post.ID.String(), false); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden for non-author, got %v", err)
+	}
+	if _, err := resolver.Mutation().ToggleComments(ctx, post.ID.String(), false); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated for anonymous caller, got %v", err)
+	}
+	if _, err := resolver.Mutation().UpdatePost(bobCtx, post.ID.String(), "Hijacked", "Content"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden on update, got %v", err)
+	}
+	if _, err := resolver.Mutation().DeletePost(bobCtx, post.ID.String()); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden on delete, got %v", err)
+	}
+	if _, err := resolver.Mutation().ToggleComments(aliceCtx, post.ID.String(), true); err != nil {
+		t.Errorf("Author should be able to toggle comments: %v", err)
+	}
+
+	// Комментарий bob: редактирует только bob, удалить может bob или автор поста
+	comment, err := resolver.Mutation().CreateComment(bobCtx, post.ID.String(), nil, "Bob's comment")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	if _, err := resolver.Mutation().EditComment(aliceCtx, comment.ID.String(), "Edited by alice"); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden when editing someone else's comment, got %v", err)
+	}
+	if _, err := resolver.Mutation().EditComment(bobCtx, comment.ID.String(), "Edited by bob"); err != nil {
+		t.Errorf("Author should be able to edit comment: %v", err)
+	}
+
+	carol, err := resolver.Mutation().CreateUser(ctx, "carol")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := resolver.Mutation().DeleteComment(auth.WithUserID(ctx, carol.ID), comment.ID.String()); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden for unrelated user, got %v", err)
+	}
+	if _, err := resolver.Mutation().DeleteComment(aliceCtx, comment.ID.String()); err != nil {
+		t.Errorf("Post author should be able to delete comments on their post: %v", err)
+	}
+
+	// Контент без автора может изменять только модератор
+	legacy, err := resolver.Mutation().CreatePost(ctx, "Anonymous", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	if legacy.AuthorID != nil {
+		t.Error("Anonymous post should have no author")
+	}
+	if _, err := resolver.Mutation().ToggleComments(bobCtx, legacy.ID.String(), false); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden for ownerless post, got %v", err)
+	}
+	if _, err := resolver.Mutation().ToggleComments(ctx, legacy.ID.String(), false); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated for anonymous caller, got %v", err)
+	}
+	if _, err := resolver.Mutation().ToggleComments(auth.WithModerator(bobCtx), legacy.ID.String(), false); err != nil {
+		t.Errorf("Moderator should modify ownerless post: %v", err)
+	}
+	if _, err := resolver.Mutation().UpdatePost(auth.WithModerator(bobCtx), post.ID.String(), "Moderated", "Content"); err != nil {
+		t.Errorf("Moderator should modify someone else's post: %v", err)
+	}
+
+	user, err := resolver.Query().User(ctx, alice.ID.String())
+	if err != nil || user == nil || user.Username != "alice" {
+		t.Errorf("Expected to find alice, got %v (err %v)", user, err)
+	}
+}
+
+func TestMutationResolver_Vote(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := context.Background()
+
+	alice, err := resolver.Mutation().CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	aliceCtx := auth.WithUserID(ctx, alice.ID)
+
+	post, err := resolver.Mutation().CreatePost(aliceCtx, "Post", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	comment, err := resolver.Mutation().CreateComment(aliceCtx, post.ID.String(), nil, "Comment")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+
+	// Анонимные голоса не принимаются
+	if _, err := resolver.Mutation().Vote(ctx, comment.ID.String(), model.VoteUp); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated for anonymous vote, got %v", err)
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, err := resolver.Subscription().ScoreChanged(subCtx, post.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	tally, err := resolver.Mutation().Vote(aliceCtx, comment.ID.String(), model.VoteDown)
+	if err != nil {
+		t.Fatalf("Failed to vote: %v", err)
+	}
+	if tally.Score() != -1 {
+		t.Errorf("Expected score -1, got %d", tally.Score())
+	}
+
+	// Новый рейтинг приходит подписчикам поста
+	select {
+	case update := <-ch:
+		if update.TargetID != comment.ID || update.Downvotes != 1 {
+			t.Errorf("Unexpected score update %+v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Score update was not published")
+	}
+}
+
+func TestMutationResolver_Reactions(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := context.Background()
+
+	alice, err := resolver.Mutation().CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	aliceCtx := auth.WithUserID(ctx, alice.ID)
+
+	post, err := resolver.Mutation().CreatePost(aliceCtx, "Post", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	comment, err := resolver.Mutation().CreateComment(aliceCtx, post.ID.String(), nil, "Comment")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+
+	if _, err := resolver.Mutation().AddReaction(ctx, comment.ID.String(), "👍"); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("Expected ErrUnauthenticated for anonymous reaction, got %v", err)
+	}
+	if _, err := resolver.Mutation().AddReaction(aliceCtx, comment.ID.String(), "🦄"); err == nil {
+		t.Error("Expected error for emoji outside the allow-list")
+	}
+
+	if _, err := resolver.Mutation().AddReaction(aliceCtx, comment.ID.String(), "👍"); err != nil {
+		t.Fatalf("Failed to add reaction: %v", err)
+	}
+	reactions, err := resolver.Comment().Reactions(aliceCtx, comment)
+	if err != nil {
+		t.Fatalf("Failed to get reactions: %v", err)
+	}
+	if len(reactions) != 1 || reactions[0].Count != 1 || !reactions[0].ViewerReacted {
+		t.Errorf("Unexpected reactions %+v", reactions)
+	}
+
+	if _, err := resolver.Mutation().RemoveReaction(aliceCtx, comment.ID.String(), "👍"); err != nil {
+		t.Fatalf("Failed to remove reaction: %v", err)
+	}
+	reactions, _ = resolver.Comment().Reactions(ctx, comment)
+	if len(reactions) != 0 {
+		t.Errorf("Expected no reactions after removal, got %+v", reactions)
+	}
+}
+
+func TestSubscriptionResolver_PostEvents(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := context.Background()
+
+	alice, err := resolver.Mutation().CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	aliceCtx := auth.WithUserID(ctx, alice.ID)
+
+	post, err := resolver.Mutation().CreatePost(aliceCtx, "Post", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := resolver.Subscription().PostEvents(subCtx, post.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to post events: %v", err)
+	}
+	added, err := resolver.Subscription().CommentAdded(subCtx, post.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to new comments: %v", err)
+	}
+
+	comment, err := resolver.Mutation().CreateComment(aliceCtx, post.ID.String(), nil, "Comment")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	if _, err := resolver.Mutation().EditComment(aliceCtx, comment.ID.String(), "Edited"); err != nil {
+		t.Fatalf("Failed to edit comment: %v", err)
+	}
+	if _, err := resolver.Mutation().DeleteComment(aliceCtx, comment.ID.String()); err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+	if _, err := resolver.Mutation().ToggleComments(aliceCtx, post.ID.String(), false); err != nil {
+		t.Fatalf("Failed to toggle comments: %v", err)
+	}
+
+	next := func() generated.PostEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("Post event was not published")
+			return nil
+		}
+	}
+
+	if event, ok := next().(*generated.CommentAdded); !ok || event.Comment.ID != comment.ID {
+		t.Errorf("Expected CommentAdded, got %#v", event)
+	}
+	if event, ok := next().(*generated.CommentEdited); !ok || event.Comment.Content != "Edited" {
+		t.Errorf("Expected CommentEdited, got %#v", event)
+	}
+	if event, ok := next().(*generated.CommentDeleted); !ok || !event.Comment.IsDeleted() {
+		t.Errorf("Expected CommentDeleted, got %#v", event)
+	}
+	if event, ok := next().(*generated.CommentsToggled); !ok || event.CommentsEnabled || event.PostID != post.ID.String() {
+		t.Errorf("Expected CommentsToggled, got %#v", event)
+	}
+
+	// commentAdded получает только новые комментарии
+	select {
+	case got := <-added:
+		if got.ID != comment.ID {
+			t.Errorf("Expected new comment %s, got %s", comment.ID, got.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("New comment was not published")
+	}
+	select {
+	case got := <-added:
+		t.Errorf("Unexpected commentAdded message %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscriptionResolver_Resume(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := auth.WithModerator(context.Background()) // Как при отключенной аутентификации
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Post", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	// Первое подключение получает одно событие и обрывается
+	subCtx, cancel := context.WithCancel(ctx)
+	events, err := resolver.Subscription().PostEvents(subCtx, post.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to post events: %v", err)
+	}
+	first, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "First")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	var lastEvent *generated.CommentAdded
+	select {
+	case event := <-events:
+		lastEvent, _ = event.(*generated.CommentAdded)
+	case <-time.After(time.Second):
+		t.Fatal("Post event was not published")
+	}
+	if lastEvent == nil || lastEvent.Cursor == "" {
+		t.Fatalf("Expected CommentAdded with cursor, got %#v", lastEvent)
+	}
+	cancel()
+
+	// Пока клиент отключен
+	second, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "Second")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	gone, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "Gone")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	if _, err := resolver.Mutation().DeleteComment(ctx, gone.ID.String()); err != nil {
+		t.Fatalf("Failed to delete comment: %v", err)
+	}
+	if _, err := resolver.Mutation().ToggleComments(ctx, post.ID.String(), false); err != nil {
+		t.Fatalf("Failed to toggle comments: %v", err)
+	}
+
+	subCtx, cancel = context.WithCancel(ctx)
+	defer cancel()
+
+	events, err = resolver.Subscription().PostEvents(subCtx, post.ID.String(), &lastEvent.Cursor)
+	if err != nil {
+		t.Fatalf("Failed to resume post events: %v", err)
+	}
+	firstCursor, err := resolver.Comment().Cursor(ctx, first)
+	if err != nil {
+		t.Fatalf("Failed to get comment cursor: %v", err)
+	}
+	added, err := resolver.Subscription().CommentAdded(subCtx, post.ID.String(), &firstCursor)
+	if err != nil {
+		t.Fatalf("Failed to resume new comments: %v", err)
+	}
+
+	next := func() generated.PostEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("Missed post event was not replayed")
+			return nil
+		}
+	}
+	if event, ok := next().(*generated.CommentAdded); !ok || event.Comment.ID != second.ID || event.Cursor == lastEvent.Cursor {
+		t.Errorf("Expected replayed CommentAdded for second comment, got %#v", event)
+	}
+	if event, ok := next().(*generated.CommentAdded); !ok || event.Comment.ID != gone.ID {
+		t.Errorf("Expected replayed CommentAdded for deleted comment, got %#v", event)
+	}
+	if event, ok := next().(*generated.CommentDeleted); !ok || event.Comment.ID != gone.ID {
+		t.Errorf("Expected replayed CommentDeleted, got %#v", event)
+	}
+	if event, ok := next().(*generated.CommentsToggled); !ok || event.CommentsEnabled {
+		t.Errorf("Expected replayed CommentsToggled, got %#v", event)
+	}
+
+	// commentAdded доставляет пропущенные комментарии из хранилища без удаленных, затем живые без повторов
+	select {
+	case got := <-added:
+		if got.ID != second.ID {
+			t.Errorf("Expected replayed comment %s, got %s", second.ID, got.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Missed comment was not replayed")
+	}
+	if _, err := resolver.Mutation().ToggleComments(ctx, post.ID.String(), true); err != nil {
+		t.Fatalf("Failed to toggle comments: %v", err)
+	}
+	third, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "Third")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	select {
+	case got := <-added:
+		if got.ID != third.ID {
+			t.Errorf("Expected live comment %s, got %s", third.ID, got.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Live comment was not delivered after replay")
+	}
+
+	// Курсоры, которые сервер не выдавал или события по которым уже недоступны
+	invalid := "not-a-cursor"
+	if _, err := resolver.Subscription().PostEvents(subCtx, post.ID.String(), &invalid); err == nil {
+		t.Error("Expected error for invalid event cursor")
+	}
+	if _, err := resolver.Subscription().CommentAdded(subCtx, post.ID.String(), &invalid); err == nil {
+		t.Error("Expected error for invalid comment cursor")
+	}
+	unknown := encodeEventCursor(1000)
+	if _, err := resolver.Subscription().PostEvents(subCtx, post.ID.String(), &unknown); err == nil {
+		t.Error("Expected error for unavailable events")
+	}
+}
+
+func TestSubscriptionResolver_SlowSubscriber(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.NewWithConfig(1))
+	ctx := context.Background()
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Post", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := resolver.Subscription().PostEvents(subCtx, post.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to post events: %v", err)
+	}
+	added, err := resolver.Subscription().CommentAdded(subCtx, post.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to new comments: %v", err)
+	}
+
+	// Клиенты не читают события, и часть из них не помещается в буфер
+	const published = 5
+	for i := 0; i < published; i++ {
+		if _, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "Comment"); err != nil {
+			t.Fatalf("Failed to create comment: %v", err)
+		}
+	}
+
+	// Каждое событие либо доставлено, либо учтено в EventsMissed
+	delivered, missed := 0, 0
+	for delivered+missed < published {
+		select {
+		case event := <-events:
+			switch event := event.(type) {
+			case *generated.CommentAdded:
+				delivered++
+			case *generated.EventsMissed:
+				missed += event.Count
+			default:
+				t.Fatalf("Unexpected event %#v", event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %d events accounted for, got %d delivered and %d missed", published, delivered, missed)
+		}
+	}
+	if missed == 0 {
+		t.Error("Expected EventsMissed signal for the slow subscriber")
+	}
+
+	// commentAdded не может сообщить о потерях в потоке комментариев и завершается
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-added:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Expected commentAdded subscription to end after losing comments")
+		}
+	}
+}
+
+func TestSubscriptionResolver_ReplyAdded(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := auth.WithModerator(context.Background()) // Как при отключенной аутентификации
+
+	post, err := resolver.Mutation().CreatePost(ctx, "Post", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	root, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), nil, "Root")
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	includeDescendants := true
+	direct, err := resolver.Subscription().ReplyAdded(subCtx, root.ID.String(), nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to direct replies: %v", err)
+	}
+	all, err := resolver.Subscription().ReplyAdded(subCtx, root.ID.String(), &includeDescendants)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to all replies: %v", err)
+	}
+
+	rootID := root.ID.String()
+	reply, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), &rootID, "Reply")
+	if err != nil {
+		t.Fatalf("Failed to create reply: %v", err)
+	}
+	replyID := reply.ID.String()
+	nested, err := resolver.Mutation().CreateComment(ctx, post.ID.String(), &replyID, "Nested reply")
+	if err != nil {
+		t.Fatalf("Failed to create nested reply: %v", err)
+	}
+
+	receive := func(ch <-chan *model.Comment) *model.Comment {
+		t.Helper()
+		select {
+		case comment := <-ch:
+			return comment
+		case <-time.After(time.Second):
+			t.Fatal("Reply was not published")
+			return nil
+		}
+	}
+
+	if got := receive(direct); got.ID != reply.ID {
+		t.Errorf("Expected direct reply %s, got %s", reply.ID, got.ID)
+	}
+	if got := receive(all); got.ID != reply.ID {
+		t.Errorf("Expected direct reply %s, got %s", reply.ID, got.ID)
+	}
+	if got := receive(all); got.ID != nested.ID {
+		t.Errorf("Expected nested reply %s, got %s", nested.ID, got.ID)
+	}
+
+	// Без includeDescendants ответы глубже первого уровня не приходят
+	select {
+	case got := <-direct:
+		t.Errorf("Unexpected reply %s for direct-only subscription", got.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Удаление поста завершает подписки на его комментарии
+	if _, err := resolver.Mutation().DeletePost(ctx, post.ID.String()); err != nil {
+		t.Fatalf("Failed to delete post: %v", err)
+	}
+	select {
+	case _, ok := <-direct:
+		if ok {
+			t.Error("Expected subscription channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Subscription was not closed after post deletion")
+	}
+}
+
+func TestSubscriptionResolver_PostAdded(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := context.Background()
+
+	alice, err := resolver.Mutation().CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	authorID := alice.ID.String()
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	all, err := resolver.Subscription().PostAdded(subCtx, nil)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to all posts: %v", err)
+	}
+	byAlice, err := resolver.Subscription().PostAdded(subCtx, &generated.PostFilter{AuthorID: &authorID})
+	if err != nil {
+		t.Fatalf("Failed to subscribe to alice's posts: %v", err)
+	}
+	invalid := "not-a-uuid"
+	if _, err := resolver.Subscription().PostAdded(subCtx, &generated.PostFilter{AuthorID: &invalid}); err == nil {
+		t.Error("Expected error for invalid author id")
+	}
+
+	anonymous, err := resolver.Mutation().CreatePost(ctx, "Anonymous", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	alicePost, err := resolver.Mutation().CreatePost(auth.WithUserID(ctx, alice.ID), "Alice", "Content")
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	receive := func(ch <-chan *model.Post) *model.Post {
+		t.Helper()
+		select {
+		case post := <-ch:
+			return post
+		case <-time.After(time.Second):
+			t.Fatal("Post was not published")
+			return nil
+		}
+	}
+
+	if got := receive(all); got.ID != anonymous.ID {
+		t.Errorf("Expected post %s, got %s", anonymous.ID, got.ID)
+	}
+	if got := receive(all); got.ID != alicePost.ID {
+		t.Errorf("Expected post %s, got %s", alicePost.ID, got.ID)
+	}
+
+	// Фильтр по автору отбрасывает анонимный пост на сервере
+	if got := receive(byAlice); got.ID != alicePost.ID {
+		t.Errorf("Expected only alice's post %s, got %s", alicePost.ID, got.ID)
+	}
+}
@@ -9,8 +9,11 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/NarthurN/CommentsSystem/internal/auth"
 	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/repository"
 	"github.com/NarthurN/CommentsSystem/internal/service/generated"
+	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
 	"github.com/google/uuid"
 )
 
@@ -19,6 +22,11 @@ func (r *commentResolver) ID(ctx context.Context, obj *model.Comment) (string, e
 	return obj.ID.String(), nil
 }
 
+// Author возвращает автора комментария (null для анонимных и удаленных учетных записей)
+func (r *commentResolver) Author(ctx context.Context, obj *model.Comment) (*model.User, error) {
+	return r.resolveAuthor(ctx, obj.AuthorID)
+}
+
 // ParentID возвращает строковое представление ID родительского комментария
 func (r *commentResolver) ParentID(ctx context.Context, obj *model.Comment) (*string, error) {
 	if obj.ParentID == nil {
@@ -33,9 +41,71 @@ func (r *commentResolver) CreatedAt(ctx context.Context, obj *model.Comment) (st
 	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
 }
 
+// EditedAt возвращает время последнего редактирования в формате ISO 8601 (null если не редактировался)
+func (r *commentResolver) EditedAt(ctx context.Context, obj *model.Comment) (*string, error) {
+	if obj.EditedAt == nil {
+		return nil, nil
+	}
+	editedAtStr := obj.EditedAt.Format("2006-01-02T15:04:05Z07:00")
+	return &editedAtStr, nil
+}
+
+// DeletedAt возвращает время удаления комментария в формате ISO 8601 (null для активных)
+func (r *commentResolver) DeletedAt(ctx context.Context, obj *model.Comment) (*string, error) {
+	if obj.DeletedAt == nil {
+		return nil, nil
+	}
+	deletedAtStr := obj.DeletedAt.Format("2006-01-02T15:04:05Z07:00")
+	return &deletedAtStr, nil
+}
+
+// Revisions возвращает предыдущие версии комментария от старых к новым.
+// У удаленного комментария история скрыта, чтобы не раскрывать удаленный текст.
+func (r *commentResolver) Revisions(ctx context.Context, obj *model.Comment) ([]*model.CommentRevision, error) {
+	if obj.IsDeleted() {
+		return []*model.CommentRevision{}, nil
+	}
+
+	revisions, err := r.storage.GetCommentRevisions(ctx, obj.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment revisions: %w", err)
+	}
+
+	// Конвертируем []model.CommentRevision в []*model.CommentRevision
+	result := make([]*model.CommentRevision, len(revisions))
+	for i := range revisions {
+		result[i] = &revisions[i]
+	}
+
+	return result, nil
+}
+
+// Reactions возвращает сводку реакций на комментарий для текущего пользователя
+// Реакции соседних комментариев загружаются одним запросом через загрузчик операции
+func (r *commentResolver) Reactions(ctx context.Context, obj *model.Comment) ([]*model.Reaction, error) {
+	reactions, err := r.commentReactions(ctx, obj.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reactions: %w", err)
+	}
+
+	// Конвертируем []model.Reaction в []*model.Reaction
+	result := make([]*model.Reaction, len(reactions))
+	for i := range reactions {
+		result[i] = &reactions[i]
+	}
+
+	return result, nil
+}
+
+// Cursor возвращает курсор комментария для аргумента since подписки commentAdded
+// и аргумента after постраничных списков комментариев
+func (r *commentResolver) Cursor(ctx context.Context, obj *model.Comment) (string, error) {
+	return encodeCursor(obj.Cursor()), nil
+}
+
 // Children возвращает дочерние комментарии для данного комментария
-// ИСПРАВЛЕНИЕ: Устраняем N+1 проблему через кэширование и пагинацию
-func (r *commentResolver) Children(ctx context.Context, obj *model.Comment, limit *int, offset *int) ([]*model.Comment, error) {
+// Ответы соседних комментариев загружаются одним запросом через загрузчик операции
+func (r *commentResolver) Children(ctx context.Context, obj *model.Comment, limit *int, offset *int, sort *model.CommentSort) ([]*model.Comment, error) {
 	// Валидируем параметры пагинации
 	limitVal := 10
 	offsetVal := 0
@@ -53,8 +123,8 @@ func (r *commentResolver) Children(ctx context.Context, obj *model.Comment, limi
 		offsetVal = *offset
 	}
 
-	// Получаем дочерние комментарии через репозиторий
-	children, err := r.storage.GetCommentsByParentID(ctx, obj.ID, limitVal, offsetVal)
+	// Получаем дочерние комментарии через загрузчик
+	children, err := r.childComments(ctx, obj.ID, sortOrder(sort), limitVal, offsetVal)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get child comments: %w", err)
 	}
@@ -68,45 +138,193 @@ func (r *commentResolver) Children(ctx context.Context, obj *model.Comment, limi
 	return result, nil
 }
 
+// ChildrenConnection возвращает страницу дочерних комментариев с курсорной пагинацией
+func (r *commentResolver) ChildrenConnection(ctx context.Context, obj *model.Comment, first *int, after *string) (*generated.CommentConnection, error) {
+	size, err := pageSize(first)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := decodeCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	// Запрашиваем на один элемент больше, чтобы узнать о следующей странице
+	children, err := r.storage.GetCommentsByParentIDAfter(ctx, obj.ID, size+1, cursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get child comments: %w", err)
+	}
+
+	return newCommentConnection(children, size), nil
+}
+
+// ID возвращает строковое представление ID ревизии
+func (r *commentRevisionResolver) ID(ctx context.Context, obj *model.CommentRevision) (string, error) {
+	return obj.ID.String(), nil
+}
+
+// CreatedAt возвращает время редактирования, заменившего эту версию, в формате ISO 8601
+func (r *commentRevisionResolver) CreatedAt(ctx context.Context, obj *model.CommentRevision) (string, error) {
+	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// CreateUser регистрирует нового пользователя с уникальным именем
+func (r *mutationResolver) CreateUser(ctx context.Context, username string) (*model.User, error) {
+	// Аутентифицированный пользователь регистрирует учетную запись
+	// под ID из своего токена, чтобы авторство совпадало с identity
+	user := &model.User{Username: username}
+	if userID, ok := auth.UserIDFromContext(ctx); ok {
+		user.ID = userID
+	}
+
+	// Валидация имени пользователя
+	if !user.IsValid() {
+		return nil, errors.New("username must be 3-50 characters: letters, digits, '_', '.', '-'")
+	}
+
+	createdUser, err := r.storage.CreateUser(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return createdUser, nil
+}
+
 // CreatePost создает новый пост в системе
 func (r *mutationResolver) CreatePost(ctx context.Context, title string, content string) (*model.Post, error) {
 	post := &model.Post{
-		Title:   title,
-		Content: content,
+		AuthorID: callerAuthorID(ctx),
+		Title:    title,
+		Content:  content,
 	}
 
 	createdPost, err := r.storage.CreatePost(ctx, post)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create post: %w", err)
+		return nil, authorError(fmt.Errorf("failed to create post: %w", err))
 	}
 
+	// Уведомляем ленту новых постов
+	r.pubsub.Publish(postsTopic, createdPost)
+
 	return createdPost, nil
 }
 
-// CreateComment создает новый комментарий к посту
-func (r *mutationResolver) CreateComment(ctx context.Context, postID string, parentID *string, content string) (*model.Comment, error) {
-	postUUID, err := uuid.Parse(postID)
+// UpdatePost изменяет заголовок и содержимое существующего поста
+func (r *mutationResolver) UpdatePost(ctx context.Context, id string, title string, content string) (*model.Post, error) {
+	postUUID, err := uuid.Parse(id)
 	if err != nil {
 		return nil, fmt.Errorf("invalid post id: %w", err)
 	}
 
-	// Валидация длины комментария
-	if len(content) > 2000 {
-		return nil, errors.New("comment content must not exceed 2000 characters")
+	// Проверка автора и изменение выполняются в одной транзакции
+	var updatedPost *model.Post
+	err = r.storage.WithTx(ctx, func(tx repository.Storage) error {
+		existing, err := tx.GetPost(ctx, postUUID)
+		if err != nil {
+			return fmt.Errorf("failed to get post: %w", err)
+		}
+
+		if existing == nil {
+			return errors.New("post not found")
+		}
+
+		// Изменять пост может только его автор
+		if err := auth.AuthorizeAuthor(ctx, existing.AuthorID); err != nil {
+			return err
+		}
+
+		// Сохраняем автора, флаг комментариев и время создания, меняем только текст
+		post := &model.Post{
+			ID:              existing.ID,
+			AuthorID:        existing.AuthorID,
+			Title:           title,
+			Content:         content,
+			CommentsEnabled: existing.CommentsEnabled,
+			CreatedAt:       existing.CreatedAt,
+		}
+
+		// Валидация по тем же правилам, что и при создании
+		if !post.IsValidTitle() {
+			return errors.New("post title must be between 1 and 255 characters")
+		}
+		if !post.IsValidContent() {
+			return errors.New("post content must be between 1 and 10000 characters")
+		}
+
+		updatedPost, err = tx.UpdatePost(ctx, post)
+		if err != nil {
+			return fmt.Errorf("failed to update post: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Проверяем, разрешены ли комментарии для поста
-	post, err := r.storage.GetPost(ctx, postUUID)
+	return updatedPost, nil
+}
+
+// DeletePost удаляет пост вместе со всеми комментариями
+func (r *mutationResolver) DeletePost(ctx context.Context, id string) (bool, error) {
+	postUUID, err := uuid.Parse(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get post: %w", err)
+		return false, fmt.Errorf("invalid post id: %w", err)
 	}
 
-	if post == nil {
-		return nil, errors.New("post not found")
+	// Проверка автора и удаление выполняются в одной транзакции
+	var comments []model.Comment
+	err = r.storage.WithTx(ctx, func(tx repository.Storage) error {
+		post, err := tx.GetPost(ctx, postUUID)
+		if err != nil {
+			return fmt.Errorf("failed to get post: %w", err)
+		}
+
+		// Удалять пост может только его автор
+		if err := auth.AuthorizeAuthor(ctx, post.AuthorID); err != nil {
+			return err
+		}
+
+		// Комментарии удаляются вместе с постом: запоминаем их, чтобы завершить подписки replyAdded.
+		// С outbox событие удаления запишет хранилище, а подписки завершит relay
+		if r.outboxRelay == nil {
+			comments, err = tx.GetCommentsByPostID(ctx, postUUID)
+			if err != nil {
+				return fmt.Errorf("failed to get comments: %w", err)
+			}
+		}
+
+		if err := tx.DeletePost(ctx, postUUID); err != nil {
+			return fmt.Errorf("failed to delete post: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if r.outboxDelivers() {
+		return true, nil
 	}
 
-	if !post.CommentsEnabled {
-		return nil, errors.New("comments are disabled for this post")
+	commentIDs := make([]uuid.UUID, len(comments))
+	for i, comment := range comments {
+		commentIDs[i] = comment.ID
+	}
+	r.publishPostDeleted(postUUID, commentIDs, r.publishBestEffort)
+
+	return true, nil
+}
+
+// CreateComment создает новый комментарий к посту
+func (r *mutationResolver) CreateComment(ctx context.Context, postID string, parentID *string, content string) (*model.Comment, error) {
+	postUUID, err := uuid.Parse(postID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid post id: %w", err)
+	}
+
+	// Валидация длины комментария
+	if len(content) > 2000 {
+		return nil, errors.New("comment content must not exceed 2000 characters")
 	}
 
 	// Обрабатываем parentId
@@ -121,22 +339,146 @@ func (r *mutationResolver) CreateComment(ctx context.Context, postID string, par
 
 	comment := &model.Comment{
 		PostID:   postUUID,
+		AuthorID: callerAuthorID(ctx),
 		ParentID: parentUUID,
 		Content:  content,
 	}
 
-	createdComment, err := r.storage.CreateComment(ctx, comment)
+	// Окончательные проверки выполняет Storage.CreateComment: флаг комментариев
+	// и удаление родителя проверяются там под блокировкой строк, поэтому одновременное
+	// выключение комментариев или удаление родителя не проскочит до вставки.
+	// Проверки здесь дают понятные ошибки в обычном случае
+	var createdComment *model.Comment
+	err = r.storage.WithTx(ctx, func(tx repository.Storage) error {
+		// Проверяем, разрешены ли комментарии для поста
+		post, err := tx.GetPost(ctx, postUUID)
+		if err != nil {
+			return fmt.Errorf("failed to get post: %w", err)
+		}
+
+		if post == nil {
+			return errors.New("post not found")
+		}
+
+		if !post.CommentsEnabled {
+			return errors.New("comments are disabled for this post")
+		}
+
+		// На удаленный комментарий отвечать нельзя
+		if parentUUID != nil {
+			parent, err := tx.GetComment(ctx, *parentUUID)
+			if err != nil {
+				return fmt.Errorf("failed to get parent comment: %w", err)
+			}
+			if parent.IsDeleted() {
+				return errCannotReplyToDeleted
+			}
+		}
+
+		createdComment, err = tx.CreateComment(ctx, comment)
+		if errors.Is(err, repository.ErrCommentDeleted) {
+			return errCannotReplyToDeleted
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create comment: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create comment: %w", err)
+		return nil, authorError(err)
 	}
 
 	// Публикуем событие о новом комментарии для подписчиков
-	topicName := fmt.Sprintf("post:%s:comments", postUUID.String())
-	r.pubsub.Publish(topicName, createdComment)
+	if !r.outboxDelivers() {
+		r.pubsub.Publish(commentsTopic(postUUID), &generated.CommentAdded{Comment: createdComment})
+		r.publishReply(ctx, createdComment, r.publishBestEffort)
+	}
 
 	return createdComment, nil
 }
 
+// EditComment изменяет текст комментария, сохраняя предыдущую версию в истории
+func (r *mutationResolver) EditComment(ctx context.Context, id string, content string) (*model.Comment, error) {
+	commentUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comment id: %w", err)
+	}
+
+	// Валидация по тем же правилам, что и при создании
+	if len(content) == 0 {
+		return nil, errors.New("comment content must not be empty")
+	}
+	if len(content) > 2000 {
+		return nil, errors.New("comment content must not exceed 2000 characters")
+	}
+
+	// Проверка автора и правка выполняются в одной транзакции
+	var editedComment *model.Comment
+	err = r.storage.WithTx(ctx, func(tx repository.Storage) error {
+		existing, err := tx.GetComment(ctx, commentUUID)
+		if err != nil {
+			return fmt.Errorf("failed to get comment: %w", err)
+		}
+
+		// Редактировать комментарий может только его автор
+		if err := auth.AuthorizeAuthor(ctx, existing.AuthorID); err != nil {
+			return err
+		}
+
+		editedComment, err = tx.EditComment(ctx, commentUUID, content)
+		if err != nil {
+			return fmt.Errorf("failed to edit comment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.outboxDelivers() {
+		r.pubsub.Publish(commentsTopic(editedComment.PostID), &generated.CommentEdited{Comment: editedComment})
+	}
+
+	return editedComment, nil
+}
+
+// DeleteComment мягко удаляет комментарий, оставляя в дереве надгробие с ответами
+func (r *mutationResolver) DeleteComment(ctx context.Context, id string) (*model.Comment, error) {
+	commentUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comment id: %w", err)
+	}
+
+	// Проверка прав и удаление выполняются в одной транзакции
+	var deletedComment *model.Comment
+	err = r.storage.WithTx(ctx, func(tx repository.Storage) error {
+		existing, err := tx.GetComment(ctx, commentUUID)
+		if err != nil {
+			return fmt.Errorf("failed to get comment: %w", err)
+		}
+
+		// Удалить комментарий может его автор или автор поста
+		if err := r.authorizeCommentRemoval(ctx, tx, existing); err != nil {
+			return err
+		}
+
+		deletedComment, err = tx.SoftDeleteComment(ctx, commentUUID)
+		if err != nil {
+			return fmt.Errorf("failed to delete comment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.outboxDelivers() {
+		r.pubsub.Publish(commentsTopic(deletedComment.PostID), &generated.CommentDeleted{Comment: deletedComment})
+	}
+
+	return deletedComment, nil
+}
+
 // ToggleComments включает или отключает комментарии для поста
 func (r *mutationResolver) ToggleComments(ctx context.Context, postID string, enable bool) (*model.Post, error) {
 	postUUID, err := uuid.Parse(postID)
@@ -144,21 +486,127 @@ func (r *mutationResolver) ToggleComments(ctx context.Context, postID string, en
 		return nil, fmt.Errorf("invalid post id: %w", err)
 	}
 
-	err = r.storage.TogglePostComments(ctx, postUUID, enable)
+	// Проверка автора и переключение выполняются в одной транзакции
+	var updatedPost *model.Post
+	err = r.storage.WithTx(ctx, func(tx repository.Storage) error {
+		post, err := tx.GetPost(ctx, postUUID)
+		if err != nil {
+			return fmt.Errorf("failed to get post: %w", err)
+		}
+
+		// Управлять комментариями может только автор поста
+		if err := auth.AuthorizeAuthor(ctx, post.AuthorID); err != nil {
+			return err
+		}
+
+		if err := tx.TogglePostComments(ctx, postUUID, enable); err != nil {
+			return fmt.Errorf("failed to update post: %w", err)
+		}
+
+		updatedPost, err = tx.GetPost(ctx, postUUID)
+		if err != nil {
+			return fmt.Errorf("failed to get updated post: %w", err)
+		}
+
+		if updatedPost == nil {
+			return errors.New("post not found")
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update post: %w", err)
+		return nil, err
 	}
 
-	updatedPost, err := r.storage.GetPost(ctx, postUUID)
+	// Открытые обсуждения сразу блокируют или разблокируют форму ответа
+	if !r.outboxDelivers() {
+		r.pubsub.Publish(commentsTopic(postUUID), &generated.CommentsToggled{
+			PostID:          postUUID.String(),
+			CommentsEnabled: updatedPost.CommentsEnabled,
+		})
+	}
+
+	return updatedPost, nil
+}
+
+// Vote устанавливает, заменяет или снимает голос вызывающего пользователя
+// за пост или комментарий и публикует новый рейтинг подписчикам поста
+func (r *mutationResolver) Vote(ctx context.Context, targetID string, value model.VoteValue) (*model.VoteTally, error) {
+	// Голосовать могут только аутентифицированные пользователи
+	userID, err := auth.RequireUserID(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get updated post: %w", err)
+		return nil, err
 	}
 
-	if updatedPost == nil {
-		return nil, errors.New("post not found")
+	targetUUID, err := uuid.Parse(targetID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target id: %w", err)
 	}
 
-	return updatedPost, nil
+	tally, err := r.storage.SetVote(ctx, userID, targetUUID, value)
+	if err != nil {
+		return nil, authorError(fmt.Errorf("failed to vote: %w", err))
+	}
+
+	// Публикуем новый рейтинг для открытых обсуждений поста
+	if !r.outboxDelivers() {
+		r.pubsub.Publish(scoresTopic(tally.PostID), tally)
+	}
+
+	return tally, nil
+}
+
+// AddReaction добавляет реакцию вызывающего пользователя на комментарий
+func (r *mutationResolver) AddReaction(ctx context.Context, commentID string, emoji string) (*model.Comment, error) {
+	// Реакции ставят только аутентифицированные пользователи
+	userID, err := auth.RequireUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	commentUUID, err := uuid.Parse(commentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comment id: %w", err)
+	}
+
+	if err := r.checkReaction(emoji); err != nil {
+		return nil, err
+	}
+
+	if err := r.storage.AddReaction(ctx, userID, commentUUID, emoji); err != nil {
+		return nil, authorError(fmt.Errorf("failed to add reaction: %w", err))
+	}
+
+	comment, err := r.storage.GetComment(ctx, commentUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	return comment, nil
+}
+
+// RemoveReaction снимает реакцию вызывающего пользователя с комментария
+func (r *mutationResolver) RemoveReaction(ctx context.Context, commentID string, emoji string) (*model.Comment, error) {
+	userID, err := auth.RequireUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	commentUUID, err := uuid.Parse(commentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comment id: %w", err)
+	}
+
+	// Эмодзи не проверяется по списку: реакцию, исключенную из конфигурации, все равно можно снять
+	if err := r.storage.RemoveReaction(ctx, userID, commentUUID, emoji); err != nil {
+		return nil, fmt.Errorf("failed to remove reaction: %w", err)
+	}
+
+	comment, err := r.storage.GetComment(ctx, commentUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	return comment, nil
 }
 
 // ID возвращает строковое представление ID поста
@@ -166,6 +614,11 @@ func (r *postResolver) ID(ctx context.Context, obj *model.Post) (string, error)
 	return obj.ID.String(), nil
 }
 
+// Author возвращает автора поста (null для анонимных и удаленных учетных записей)
+func (r *postResolver) Author(ctx context.Context, obj *model.Post) (*model.User, error) {
+	return r.resolveAuthor(ctx, obj.AuthorID)
+}
+
 // CreatedAt возвращает время создания поста в формате ISO 8601
 func (r *postResolver) CreatedAt(ctx context.Context, obj *model.Post) (string, error) {
 	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
@@ -173,7 +626,7 @@ func (r *postResolver) CreatedAt(ctx context.Context, obj *model.Post) (string,
 
 // Comments возвращает все комментарии для данного поста
 // ИСПРАВЛЕНИЕ: Добавляем пагинацию для избежания загрузки тысяч комментариев
-func (r *postResolver) Comments(ctx context.Context, obj *model.Post, limit *int, offset *int) ([]*model.Comment, error) {
+func (r *postResolver) Comments(ctx context.Context, obj *model.Post, limit *int, offset *int, sort *model.CommentSort) ([]*model.Comment, error) {
 	// Валидируем параметры пагинации
 	limitVal := 10
 	offsetVal := 0
@@ -192,7 +645,7 @@ func (r *postResolver) Comments(ctx context.Context, obj *model.Post, limit *int
 	}
 
 	// Получаем только корневые комментарии с пагинацией
-	comments, err := r.storage.GetRootCommentsByPostID(ctx, obj.ID, limitVal, offsetVal)
+	comments, err := r.rootComments(ctx, obj.ID, sortOrder(sort), limitVal, offsetVal)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get comments: %w", err)
 	}
@@ -206,6 +659,42 @@ func (r *postResolver) Comments(ctx context.Context, obj *model.Post, limit *int
 	return result, nil
 }
 
+// CommentsConnection возвращает страницу корневых комментариев с курсорной пагинацией
+func (r *postResolver) CommentsConnection(ctx context.Context, obj *model.Post, first *int, after *string) (*generated.CommentConnection, error) {
+	size, err := pageSize(first)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := decodeCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	// Запрашиваем на один элемент больше, чтобы узнать о следующей странице
+	comments, err := r.storage.GetRootCommentsAfter(ctx, obj.ID, size+1, cursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	return newCommentConnection(comments, size), nil
+}
+
+// CommentTree возвращает дерево комментариев поста, обрезанное до maxDepth уровней и maxNodes узлов.
+// Дерево загружается из хранилища одним вызовом.
+func (r *postResolver) CommentTree(ctx context.Context, obj *model.Post, maxDepth *int, maxNodes *int) (*generated.CommentThread, error) {
+	depth, nodes, err := threadLimits(maxDepth, maxNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	trees, err := r.storage.GetCommentTree(ctx, obj.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment tree: %w", err)
+	}
+
+	return buildThread(trees, depth, nodes), nil
+}
+
 // Posts возвращает список постов с поддержкой пагинации
 func (r *queryResolver) Posts(ctx context.Context, limit *int, offset *int) ([]*model.Post, error) {
 	limitVal := 10
@@ -226,6 +715,26 @@ func (r *queryResolver) Posts(ctx context.Context, limit *int, offset *int) ([]*
 	return posts, nil
 }
 
+// PostsConnection возвращает страницу постов (от новых к старым) с курсорной пагинацией
+func (r *queryResolver) PostsConnection(ctx context.Context, first *int, after *string) (*generated.PostConnection, error) {
+	size, err := pageSize(first)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := decodeCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	// Запрашиваем на один элемент больше, чтобы узнать о следующей странице
+	posts, err := r.storage.GetPostsAfter(ctx, size+1, cursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	return newPostConnection(posts, size), nil
+}
+
 // Post возвращает конкретный пост по его ID
 func (r *queryResolver) Post(ctx context.Context, id string) (*model.Post, error) {
 	postUUID, err := uuid.Parse(id)
@@ -241,62 +750,208 @@ func (r *queryResolver) Post(ctx context.Context, id string) (*model.Post, error
 	return post, nil
 }
 
-// CommentAdded создает подписку на новые комментарии к посту
-func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string) (<-chan *model.Comment, error) {
-	// Проверяем, что пост существует
-	id, err := uuid.Parse(postID)
+// User возвращает пользователя по его ID (null, если пользователь не найден)
+func (r *queryResolver) User(ctx context.Context, id string) (*model.User, error) {
+	userUUID, err := uuid.Parse(id)
 	if err != nil {
-		return nil, fmt.Errorf("invalid post id: %w", err)
+		return nil, fmt.Errorf("invalid user id: %w", err)
 	}
 
-	post, err := r.storage.GetPost(ctx, id)
+	user, err := r.storage.GetUser(ctx, userUUID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get post: %w", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
-	if post == nil {
-		return nil, errors.New("post not found")
-	}
-
-	// Создаем уникальный ID подписчика
-	subscriberID := uuid.New().String()
-
-	// Подписываемся на топик комментариев поста
-	topicName := fmt.Sprintf("post:%s:comments", postID)
-	subscriber := r.pubsub.Subscribe(topicName, subscriberID)
-
-	// Создаем канал для GraphQL подписки
-	ch := make(chan *model.Comment)
-
-	// Запускаем горутину для обработки сообщений
-	go func() {
-		defer close(ch)
-		defer r.pubsub.Unsubscribe(topicName, subscriberID)
-
-		for {
-			select {
-			case msg, ok := <-subscriber.Channel:
-				if !ok {
-					return
-				}
-				if comment, ok := msg.Data.(*model.Comment); ok {
-					select {
-					case ch <- comment:
-					case <-ctx.Done():
-						return
-					}
-				}
-			case <-ctx.Done():
-				return
+
+	return user, nil
+}
+
+// AllowedReactions возвращает список разрешенных эмодзи реакций
+func (r *queryResolver) AllowedReactions(ctx context.Context) ([]string, error) {
+	return r.reactionEmoji, nil
+}
+
+// PostAdded подписывает на новые посты.
+// Фильтр проверяется на сервере, поэтому клиент получает только подходящие посты.
+func (r *subscriptionResolver) PostAdded(ctx context.Context, filter *generated.PostFilter) (<-chan *model.Post, error) {
+	matcher, err := newPostMatcher(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := subscribeTopic(ctx, r.pubsub, postsTopic, func(msg pubsub.Message) (*model.Post, bool) {
+		post, ok := msg.Data.(*model.Post)
+		if !ok || !matcher.Match(post) {
+			return nil, false
+		}
+		return post, true
+	}, nil)
+
+	return ch, nil
+}
+
+// CommentAdded создает подписку на новые комментарии к посту.
+// С курсором since (Comment.cursor последнего полученного комментария) подписка сначала
+// доставляет комментарии, созданные после него, а затем переходит к живым событиям.
+// Если pub/sub потерял комментарии медленного клиента, подписка завершается:
+// клиент переподключается с since и получает их из хранилища.
+func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string, since *string) (<-chan *model.Comment, error) {
+	id, err := r.subscribablePost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := decodeCursor(since)
+	if err != nil {
+		return nil, err
+	}
+
+	topic := commentsTopic(id)
+
+	// Из событий поста пересылаем только новые комментарии
+	if after == nil {
+		ch := subscribeTopic(ctx, r.pubsub, topic, func(msg pubsub.Message) (*model.Comment, bool) {
+			event, ok := msg.Data.(*generated.CommentAdded)
+			if !ok {
+				return nil, false
 			}
+			return event.Comment, true
+		}, nil)
+		return ch, nil
+	}
+
+	// Подписываемся до чтения хранилища, чтобы не потерять комментарии, созданные во время чтения
+	subscriber := r.pubsub.Subscribe(topic, uuid.New().String())
+
+	missed, err := r.missedComments(ctx, id, *after)
+	if err != nil {
+		r.pubsub.Unsubscribe(topic, subscriber.ID)
+		return nil, err
+	}
+
+	// Комментарий, созданный во время чтения, может прийти и из хранилища, и из топика
+	replayed := make(map[uuid.UUID]bool, len(missed))
+	for _, comment := range missed {
+		replayed[comment.ID] = true
+	}
+
+	ch := forwardTopic(ctx, r.pubsub, topic, subscriber, missed, func(msg pubsub.Message) (*model.Comment, bool) {
+		event, ok := msg.Data.(*generated.CommentAdded)
+		if !ok || replayed[event.Comment.ID] {
+			return nil, false
 		}
-	}()
+		return event.Comment, true
+	}, nil)
 
 	return ch, nil
 }
 
+// PostEvents подписывает на все события обсуждения поста: новые, отредактированные
+// и удаленные комментарии, включение и отключение комментариев.
+// С курсором since (cursor последнего полученного события) подписка сначала доставляет
+// пропущенные события из истории топика, а затем переходит к живым событиям.
+// О событиях, потерянных из-за переполнения канала, клиент узнает из EventsMissed.
+func (r *subscriptionResolver) PostEvents(ctx context.Context, postID string, since *string) (<-chan generated.PostEvent, error) {
+	id, err := r.subscribablePost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	seq, resume, err := decodeEventCursor(since)
+	if err != nil {
+		return nil, err
+	}
+
+	topic := commentsTopic(id)
+	if !resume {
+		return subscribeTopic(ctx, r.pubsub, topic, postEventFromMessage, postEventsMissed), nil
+	}
+
+	subscriber, messages, complete := r.pubsub.SubscribeSince(topic, uuid.New().String(), seq)
+	if !complete {
+		r.pubsub.Unsubscribe(topic, subscriber.ID)
+		return nil, errEventsUnavailable
+	}
+
+	missed := make([]generated.PostEvent, 0, len(messages))
+	for _, msg := range messages {
+		if event, ok := postEventFromMessage(msg); ok {
+			missed = append(missed, event)
+		}
+	}
+
+	return forwardTopic(ctx, r.pubsub, topic, subscriber, missed, postEventFromMessage, postEventsMissed), nil
+}
+
+// ReplyAdded подписывает на ответы на комментарий.
+// С includeDescendants подписка получает также ответы на любом уровне ветки комментария.
+func (r *subscriptionResolver) ReplyAdded(ctx context.Context, commentID string, includeDescendants *bool) (<-chan *model.Comment, error) {
+	id, err := uuid.Parse(commentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comment id: %w", err)
+	}
+
+	if _, err := r.storage.GetComment(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	descendants := includeDescendants != nil && *includeDescendants
+	ch := subscribeTopic(ctx, r.pubsub, repliesTopic(id), func(msg pubsub.Message) (*model.Comment, bool) {
+		event, ok := msg.Data.(*replyEvent)
+		if !ok || (!event.direct && !descendants) {
+			return nil, false
+		}
+		return event.comment, true
+	}, nil)
+
+	return ch, nil
+}
+
+// ScoreChanged подписывает на изменения рейтинга поста и его комментариев
+func (r *subscriptionResolver) ScoreChanged(ctx context.Context, postID string) (<-chan *model.VoteTally, error) {
+	id, err := r.subscribablePost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := subscribeTopic(ctx, r.pubsub, scoresTopic(id), func(msg pubsub.Message) (*model.VoteTally, bool) {
+		tally, ok := msg.Data.(*model.VoteTally)
+		return tally, ok
+	}, nil)
+
+	return ch, nil
+}
+
+// ID возвращает строковое представление ID пользователя
+func (r *userResolver) ID(ctx context.Context, obj *model.User) (string, error) {
+	return obj.ID.String(), nil
+}
+
+// CreatedAt возвращает время регистрации пользователя в формате ISO 8601
+func (r *userResolver) CreatedAt(ctx context.Context, obj *model.User) (string, error) {
+	return obj.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), nil
+}
+
+// TargetID возвращает строковое представление ID поста или комментария
+func (r *voteTallyResolver) TargetID(ctx context.Context, obj *model.VoteTally) (string, error) {
+	return obj.TargetID.String(), nil
+}
+
+// PostID возвращает строковое представление ID поста цели
+func (r *voteTallyResolver) PostID(ctx context.Context, obj *model.VoteTally) (string, error) {
+	return obj.PostID.String(), nil
+}
+
 // Comment returns generated.CommentResolver implementation.
 func (r *Resolver) Comment() generated.CommentResolver { return &commentResolver{r} }
 
+// CommentRevision returns generated.CommentRevisionResolver implementation.
+func (r *Resolver) CommentRevision() generated.CommentRevisionResolver {
+	return &commentRevisionResolver{r}
+}
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
@@ -309,8 +964,17 @@ func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 // Subscription returns generated.SubscriptionResolver implementation.
 func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
 
+// User returns generated.UserResolver implementation.
+func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
+
+// VoteTally returns generated.VoteTallyResolver implementation.
+func (r *Resolver) VoteTally() generated.VoteTallyResolver { return &voteTallyResolver{r} }
+
 type commentResolver struct{ *Resolver }
+type commentRevisionResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type postResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
+type voteTallyResolver struct{ *Resolver }
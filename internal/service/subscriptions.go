@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/service/generated"
+	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
+	"github.com/google/uuid"
+)
+
+// maxReplayComments - сколько пропущенных комментариев можно доставить при переподключении
+// подписки commentAdded; при большем разрыве клиенту дешевле перечитать пост целиком.
+const maxReplayComments = 1000
+
+// errEventsUnavailable возвращается, если часть событий после курсора since уже не хранится
+var errEventsUnavailable = errors.New("events since cursor are no longer available, refetch the post")
+
+// droppedFunc строит значение подписки, сообщающее клиенту о count потерянных сообщениях
+type droppedFunc[T any] func(count int) T
+
+// subscribeTopic подписывается на топик pub/sub и пересылает в канал GraphQL подписки
+// сообщения, которые convert смог преобразовать в T; остальные сообщения топика пропускаются.
+//
+// Канал закрывается, когда клиент завершает подписку (отменяется ctx)
+// или топик закрывается издателем (например, при удалении поста).
+// Если pub/sub потерял сообщения медленного подписчика, клиент получает значение dropped;
+// для подписок без такого значения (dropped == nil) канал закрывается, и клиент
+// переподключается, перечитав состояние.
+func subscribeTopic[T any](ctx context.Context, ps pubsub.PubSub, topic string, convert func(msg pubsub.Message) (T, bool), dropped droppedFunc[T]) <-chan T {
+	// Создаем уникальный ID подписчика
+	subscriber := ps.Subscribe(topic, uuid.New().String())
+
+	return forwardTopic(ctx, ps, topic, subscriber, nil, convert, dropped)
+}
+
+// forwardTopic пересылает в канал GraphQL подписки сначала пропущенные клиентом значения missed,
+// затем живые сообщения уже созданного подписчика топика. Подписчик отписывается,
+// когда канал закрывается.
+func forwardTopic[T any](ctx context.Context, ps pubsub.PubSub, topic string, subscriber *pubsub.Subscriber, missed []T, convert func(msg pubsub.Message) (T, bool), dropped droppedFunc[T]) <-chan T {
+	ch := make(chan T)
+
+	go func() {
+		defer close(ch)
+		defer ps.Unsubscribe(topic, subscriber.ID)
+
+		// reported - сколько потерянных сообщений уже сообщено клиенту.
+		// reportDropped возвращает false, если подписку нужно завершить.
+		var reported uint64
+		reportDropped := func() bool {
+			total := subscriber.Dropped()
+			if total == reported {
+				return true
+			}
+			count := total - reported
+			reported = total
+			if dropped == nil {
+				return false
+			}
+			select {
+			case ch <- dropped(int(count)):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, value := range missed {
+			select {
+			case ch <- value:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for {
+			select {
+			case msg, ok := <-subscriber.Channel:
+				if !ok {
+					// Отключенный из-за переполнения подписчик узнает, сколько сообщений потерял
+					reportDropped()
+					return
+				}
+				// Сообщения, потерянные до этого, предшествуют ему
+				if !reportDropped() {
+					return
+				}
+				value, ok := convert(msg)
+				if !ok {
+					continue
+				}
+				select {
+				case ch <- value:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// missedComments возвращает неудаленные комментарии поста, созданные после курсора since,
+// в порядке создания. Хранилище читает их keyset запросом не больше maxReplayComments+1:
+// лишний комментарий означает, что пропущено слишком много и клиенту нужно перечитать пост.
+func (r *Resolver) missedComments(ctx context.Context, postID uuid.UUID, since model.Cursor) ([]*model.Comment, error) {
+	comments, err := r.storage.GetCommentsByPostIDAfter(ctx, postID, maxReplayComments+1, &since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+	if len(comments) > maxReplayComments {
+		return nil, errEventsUnavailable
+	}
+
+	missed := make([]*model.Comment, len(comments))
+	for i := range comments {
+		missed[i] = &comments[i]
+	}
+	return missed, nil
+}
+
+// encodeEventCursor кодирует порядковый номер события pub/sub в непрозрачный курсор
+func encodeEventCursor(seq uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("event|" + strconv.FormatUint(seq, 10)))
+}
+
+// decodeEventCursor разбирает курсор, выданный encodeEventCursor.
+// Для пустого курсора возвращает false: повторная доставка не запрашивается.
+func decodeEventCursor(cursor *string) (uint64, bool, error) {
+	if cursor == nil || *cursor == "" {
+		return 0, false, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(*cursor)
+	if err != nil {
+		return 0, false, errInvalidCursor
+	}
+
+	seqStr, found := strings.CutPrefix(string(raw), "event|")
+	if !found {
+		return 0, false, errInvalidCursor
+	}
+
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return 0, false, errInvalidCursor
+	}
+
+	return seq, true, nil
+}
+
+// postEventFromMessage возвращает копию события поста с курсором сообщения.
+// Событие копируется, потому что одно сообщение получают все подписчики топика.
+func postEventFromMessage(msg pubsub.Message) (generated.PostEvent, bool) {
+	cursor := encodeEventCursor(msg.Seq)
+
+	switch event := msg.Data.(type) {
+	case *generated.CommentAdded:
+		copied := *event
+		copied.Cursor = cursor
+		return &copied, true
+	case *generated.CommentEdited:
+		copied := *event
+		copied.Cursor = cursor
+		return &copied, true
+	case *generated.CommentDeleted:
+		copied := *event
+		copied.Cursor = cursor
+		return &copied, true
+	case *generated.CommentsToggled:
+		copied := *event
+		copied.Cursor = cursor
+		return &copied, true
+	case *generated.PostDeleted:
+		copied := *event
+		copied.Cursor = cursor
+		return &copied, true
+	}
+	return nil, false
+}
+
+// postEventsMissed сообщает подписчику postEvents о потерянных событиях
+func postEventsMissed(count int) generated.PostEvent {
+	return &generated.EventsMissed{Count: count}
+}
+
+// replyEvent - новый ответ в ветке комментария, публикуется в топик repliesTopic каждого предка ответа
+type replyEvent struct {
+	comment *model.Comment
+	direct  bool // Ответ непосредственно на комментарий топика, а не на его потомка
+}
+
+// publishReply уведомляет подписчиков replyAdded родителя и всех предков нового ответа.
+// Цепочку предков знает хранилище; если ее не удалось получить, уведомляется только
+// непосредственный родитель, а ошибка возвращается: мутация ее пропускает, чтобы
+// не отменять созданный комментарий, а outbox relay повторяет доставку.
+func (r *Resolver) publishReply(ctx context.Context, reply *model.Comment, publish publishFunc) error {
+	if reply.ParentID == nil {
+		return nil
+	}
+
+	if err := publish(repliesTopic(*reply.ParentID), &replyEvent{comment: reply, direct: true}); err != nil {
+		return err
+	}
+
+	ancestors, err := r.storage.GetCommentAncestorIDs(ctx, reply.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get comment ancestors: %w", err)
+	}
+	for _, ancestorID := range ancestors {
+		if ancestorID == *reply.ParentID {
+			continue
+		}
+		if err := publish(repliesTopic(ancestorID), &replyEvent{comment: reply}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postMatcher проверяет новые посты по фильтру подписки postAdded
+type postMatcher struct {
+	commentsEnabled *bool
+	authorID        *uuid.UUID
+}
+
+// newPostMatcher разбирает фильтр подписки postAdded; nil фильтр пропускает все посты
+func newPostMatcher(filter *generated.PostFilter) (*postMatcher, error) {
+	matcher := &postMatcher{}
+	if filter == nil {
+		return matcher, nil
+	}
+
+	matcher.commentsEnabled = filter.CommentsEnabled
+	if filter.AuthorID != nil {
+		authorID, err := uuid.Parse(*filter.AuthorID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid author id: %w", err)
+		}
+		matcher.authorID = &authorID
+	}
+
+	return matcher, nil
+}
+
+// Match сообщает, должен ли пост быть доставлен подписчику
+func (m *postMatcher) Match(post *model.Post) bool {
+	if m.commentsEnabled != nil && post.CommentsEnabled != *m.commentsEnabled {
+		return false
+	}
+	if m.authorID != nil && !post.IsAuthoredBy(*m.authorID) {
+		return false
+	}
+	return true
+}
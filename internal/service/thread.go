@@ -0,0 +1,119 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/service/generated"
+)
+
+// Ограничения дерева комментариев Post.commentTree
+const (
+	defaultThreadDepth = 5
+	maxThreadDepth     = 20
+	defaultThreadNodes = 200
+	maxThreadNodes     = 1000
+)
+
+// threadLimits проверяет аргументы maxDepth и maxNodes и возвращает их значения
+func threadLimits(maxDepth, maxNodes *int) (int, int, error) {
+	depth := defaultThreadDepth
+	if maxDepth != nil {
+		if *maxDepth <= 0 || *maxDepth > maxThreadDepth {
+			return 0, 0, errors.New("maxDepth must be between 1 and 20")
+		}
+		depth = *maxDepth
+	}
+
+	nodes := defaultThreadNodes
+	if maxNodes != nil {
+		if *maxNodes <= 0 || *maxNodes > maxThreadNodes {
+			return 0, 0, errors.New("maxNodes must be between 1 and 1000")
+		}
+		nodes = *maxNodes
+	}
+
+	return depth, nodes, nil
+}
+
+// threadItem - узел полного дерева, ожидающий включения в ответ
+type threadItem struct {
+	tree   *model.CommentTree
+	depth  int
+	parent *generated.CommentThreadNode // nil для корневых комментариев
+}
+
+// buildThread обрезает полное дерево комментариев до maxDepth уровней и maxNodes узлов.
+//
+// Узлы включаются в ширину: сначала все корни, затем их ответы и так далее,
+// поэтому при нехватке лимита отбрасываются самые глубокие ответы, а у каждого
+// списка ответов обрезается только хвост. Количество отброшенных ответов
+// сохраняется в узле-родителе (или в самом треде для корней), чтобы клиент
+// мог догрузить их через Comment.children с соответствующим offset.
+func buildThread(trees []model.CommentTree, maxDepth, maxNodes int) *generated.CommentThread {
+	thread := &generated.CommentThread{Nodes: []*generated.CommentThreadNode{}}
+
+	queue := make([]threadItem, 0, len(trees))
+	for i := range trees {
+		queue = append(queue, threadItem{tree: &trees[i]})
+		thread.TotalCount += 1 + countDescendants(&trees[i])
+	}
+
+	budget := maxNodes
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+
+		if budget == 0 {
+			// Лимит исчерпан: узел и его ответы остаются за маркером "еще ответы"
+			if item.parent == nil {
+				thread.HasMore = true
+				thread.MoreCount++
+			} else {
+				item.parent.HasMoreReplies = true
+				item.parent.MoreRepliesCount++
+			}
+			continue
+		}
+		budget--
+
+		comment := item.tree.Comment
+		node := &generated.CommentThreadNode{
+			Comment:         &comment,
+			Depth:           item.depth,
+			Replies:         []*generated.CommentThreadNode{},
+			ReplyCount:      len(item.tree.Children),
+			DescendantCount: countDescendants(item.tree),
+		}
+		if item.parent == nil {
+			thread.Nodes = append(thread.Nodes, node)
+		} else {
+			item.parent.Replies = append(item.parent.Replies, node)
+		}
+
+		if item.depth+1 >= maxDepth {
+			// Ответы глубже maxDepth не включаются
+			node.HasMoreReplies = node.ReplyCount > 0
+			node.MoreRepliesCount = node.ReplyCount
+			continue
+		}
+		for i := range item.tree.Children {
+			queue = append(queue, threadItem{
+				tree:   &item.tree.Children[i],
+				depth:  item.depth + 1,
+				parent: node,
+			})
+		}
+	}
+
+	return thread
+}
+
+// countDescendants возвращает количество всех потомков узла дерева
+func countDescendants(tree *model.CommentTree) int {
+	count := 0
+	for i := range tree.Children {
+		count += 1 + countDescendants(&tree.Children[i])
+	}
+	return count
+}
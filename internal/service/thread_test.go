@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NarthurN/CommentsSystem/internal/model"
+	"github.com/NarthurN/CommentsSystem/internal/repository"
+	"github.com/NarthurN/CommentsSystem/pkg/pubsub"
+	"github.com/google/uuid"
+)
+
+// treeNode создает узел дерева с заданными ответами
+func treeNode(children ...model.CommentTree) model.CommentTree {
+	return model.CommentTree{
+		Comment:  model.Comment{ID: uuid.New(), Content: "Comment"},
+		Children: children,
+	}
+}
+
+func TestBuildThread(t *testing.T) {
+	// Корень 0: три ответа, у первого еще один ответ; корень 1: без ответов
+	trees := []model.CommentTree{
+		treeNode(treeNode(treeNode()), treeNode(), treeNode()),
+		treeNode(),
+	}
+
+	t.Run("без ограничений", func(t *testing.T) {
+		thread := buildThread(trees, 10, 100)
+		if thread.TotalCount != 6 || thread.HasMore || len(thread.Nodes) != 2 {
+			t.Fatalf("Unexpected thread: total %d, hasMore %v, %d nodes", thread.TotalCount, thread.HasMore, len(thread.Nodes))
+		}
+		root := thread.Nodes[0]
+		if root.ReplyCount != 3 || root.DescendantCount != 4 || root.HasMoreReplies || len(root.Replies) != 3 {
+			t.Errorf("Unexpected root node: %+v", root)
+		}
+		if root.Replies[0].Depth != 1 || root.Replies[0].Replies[0].Depth != 2 {
+			t.Error("Unexpected node depth")
+		}
+	})
+
+	t.Run("ограничение глубины", func(t *testing.T) {
+		thread := buildThread(trees, 2, 100)
+		reply := thread.Nodes[0].Replies[0]
+		if len(reply.Replies) != 0 || !reply.HasMoreReplies || reply.MoreRepliesCount != 1 {
+			t.Errorf("Expected cut-off marker at max depth, got %+v", reply)
+		}
+		if thread.Nodes[1].HasMoreReplies {
+			t.Error("Comment without replies should not have a marker")
+		}
+	})
+
+	t.Run("ограничение узлов", func(t *testing.T) {
+		// Два корня и первый ответ: остальные ответы отбрасываются с конца
+		thread := buildThread(trees, 10, 3)
+		root := thread.Nodes[0]
+		if len(thread.Nodes) != 2 || len(root.Replies) != 1 {
+			t.Fatalf("Expected 2 roots and 1 reply, got %d and %d", len(thread.Nodes), len(root.Replies))
+		}
+		if !root.HasMoreReplies || root.MoreRepliesCount != 2 {
+			t.Errorf("Expected 2 more replies, got %d", root.MoreRepliesCount)
+		}
+		if !root.Replies[0].HasMoreReplies || root.Replies[0].MoreRepliesCount != 1 {
+			t.Error("Expected marker on the included reply")
+		}
+	})
+
+	t.Run("обрезка корней", func(t *testing.T) {
+		thread := buildThread(trees, 10, 1)
+		if len(thread.Nodes) != 1 || !thread.HasMore || thread.MoreCount != 1 {
+			t.Errorf("Expected 1 root and 1 more, got %d nodes, more %d", len(thread.Nodes), thread.MoreCount)
+		}
+	})
+}
+
+func TestPostResolver_CommentTree(t *testing.T) {
+	storage := repository.NewMemoryStorage()
+	defer storage.Close()
+	resolver := NewResolver(storage, pubsub.New())
+	ctx := context.Background()
+
+	post, err := storage.CreatePost(ctx, &model.Post{Title: "Title", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	root, err := storage.CreateComment(ctx, &model.Comment{PostID: post.ID, Content: "Root"})
+	if err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	base := time.Now().UTC()
+	for i := 0; i < 3; i++ {
+		parentID := root.ID
+		_, err := storage.CreateComment(ctx, &model.Comment{
+			PostID:    post.ID,
+			ParentID:  &parentID,
+			Content:   "Reply",
+			CreatedAt: base.Add(time.Duration(i) * time.Second),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create reply: %v", err)
+		}
+	}
+
+	maxNodes := 2
+	thread, err := resolver.Post().CommentTree(ctx, post, nil, &maxNodes)
+	if err != nil {
+		t.Fatalf("CommentTree() error = %v", err)
+	}
+	node := thread.Nodes[0]
+	if thread.TotalCount != 4 || len(node.Replies) != 1 || node.MoreRepliesCount != 2 {
+		t.Fatalf("Unexpected thread: total %d, %d replies, %d more", thread.TotalCount, len(node.Replies), node.MoreRepliesCount)
+	}
+
+	// Отброшенные ответы догружаются через children с offset
+	offset := len(node.Replies)
+	rest, err := resolver.Comment().Children(ctx, node.Comment, &node.MoreRepliesCount, &offset, nil)
+	if err != nil {
+		t.Fatalf("Children() error = %v", err)
+	}
+	if len(rest) != 2 || rest[0].ID == node.Replies[0].Comment.ID {
+		t.Error("Expected remaining replies after the included ones")
+	}
+
+	for _, bad := range []int{0, 21} {
+		if _, err := resolver.Post().CommentTree(ctx, post, &bad, nil); err == nil {
+			t.Errorf("Expected error for maxDepth %d", bad)
+		}
+	}
+}
@@ -16,21 +16,21 @@ CREATE TABLE IF NOT EXISTS comments (
 );
 
 -- Базовые индексы для поиска комментариев
-CREATE INDEX idx_comments_post_id ON comments(post_id);
-CREATE INDEX idx_comments_parent_id ON comments(parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments(post_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
 
 -- ПРОИЗВОДИТЕЛЬНОСТЬ: Составные индексы для пагинации
 -- Индекс для быстрого поиска корневых комментариев с сортировкой
-CREATE INDEX idx_comments_post_root_created ON comments(post_id, created_at)
+CREATE INDEX IF NOT EXISTS idx_comments_post_root_created ON comments(post_id, created_at)
 WHERE parent_id IS NULL;
 
 -- Индекс для быстрого поиска дочерних комментариев с сортировкой
-CREATE INDEX idx_comments_parent_created ON comments(parent_id, created_at)
+CREATE INDEX IF NOT EXISTS idx_comments_parent_created ON comments(parent_id, created_at)
 WHERE parent_id IS NOT NULL;
 
 -- Индекс для поиска постов по времени создания
-CREATE INDEX idx_posts_created_at ON posts(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts(created_at DESC);
 
 -- Индекс для активных постов (с включенными комментариями)
-CREATE INDEX idx_posts_comments_enabled ON posts(comments_enabled)
+CREATE INDEX IF NOT EXISTS idx_posts_comments_enabled ON posts(comments_enabled)
 WHERE comments_enabled = true;
@@ -0,0 +1,8 @@
+-- migrations/002_comment_tombstones.sql
+-- Мягкое удаление комментариев: удаленный комментарий остается в дереве как "надгробие",
+-- чтобы ответы на него не терялись
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- Индекс для быстрого поиска живых потомков при фильтрации надгробий
+CREATE INDEX IF NOT EXISTS idx_comments_parent_live ON comments(parent_id)
+WHERE deleted_at IS NULL;
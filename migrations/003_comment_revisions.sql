@@ -0,0 +1,14 @@
+-- migrations/003_comment_revisions.sql
+-- Редактирование комментариев с сохранением истории изменений
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
+
+-- Предыдущие версии текста комментариев (для модерации)
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    id UUID PRIMARY KEY,
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    content VARCHAR(2000) NOT NULL, -- Текст до редактирования
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW() -- Время редактирования
+);
+
+-- Индекс для получения истории комментария в хронологическом порядке
+CREATE INDEX IF NOT EXISTS idx_comment_revisions_comment_created ON comment_revisions(comment_id, created_at);
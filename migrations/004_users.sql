@@ -0,0 +1,15 @@
+-- migrations/004_users.sql
+-- Пользователи и авторство постов и комментариев
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY,
+    username VARCHAR(50) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- NULL для контента, созданного до появления пользователей, и для удаленных аккаунтов
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS author_id UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS author_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Индексы для выборки контента автора
+CREATE INDEX IF NOT EXISTS idx_posts_author_id ON posts(author_id) WHERE author_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_author_id ON comments(author_id) WHERE author_id IS NOT NULL;
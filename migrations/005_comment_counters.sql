@@ -0,0 +1,40 @@
+-- migrations/005_comment_counters.sql
+-- Счетчики комментариев: учитываются только неудаленные комментарии.
+-- Поддерживаются приложением в тех же транзакциях, что создают и удаляют комментарии.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comment_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS reply_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS descendant_count INTEGER NOT NULL DEFAULT 0;
+
+-- Заполняем счетчики для существующих данных
+UPDATE posts p
+SET comment_count = (
+    SELECT COUNT(*) FROM comments c
+    WHERE c.post_id = p.id AND c.deleted_at IS NULL
+);
+
+UPDATE comments parent
+SET reply_count = (
+    SELECT COUNT(*) FROM comments c
+    WHERE c.parent_id = parent.id AND c.deleted_at IS NULL
+);
+
+WITH RECURSIVE lineage AS (
+    -- Пары (предок, живой потомок) на всех уровнях
+    SELECT c.parent_id AS ancestor_id, c.id AS descendant_id, c.deleted_at
+    FROM comments c
+    WHERE c.parent_id IS NOT NULL
+    UNION ALL
+    SELECT p.parent_id, l.descendant_id, l.deleted_at
+    FROM lineage l
+    INNER JOIN comments p ON p.id = l.ancestor_id
+    WHERE p.parent_id IS NOT NULL
+)
+UPDATE comments c
+SET descendant_count = counts.total
+FROM (
+    SELECT ancestor_id, COUNT(*) AS total
+    FROM lineage
+    WHERE deleted_at IS NULL
+    GROUP BY ancestor_id
+) counts
+WHERE c.id = counts.ancestor_id;
@@ -0,0 +1,39 @@
+-- migrations/006_comment_sort.sql
+-- Сортировка комментариев: NEW, OLD, TOP, CONTROVERSIAL.
+-- Голоса хранятся как счетчики на комментарии, рейтинг и спорность вычисляются из них.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS upvotes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS downvotes INTEGER NOT NULL DEFAULT 0;
+
+-- Рейтинг для TOP: голоса "за" минус голоса "против"
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS score INTEGER
+    GENERATED ALWAYS AS (upvotes - downvotes) STORED;
+
+-- Спорность для CONTROVERSIAL: (за + против) ^ (меньшее / большее), 0 без голосов одной из сторон.
+-- Формула должна совпадать с model.Comment.Controversy
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS controversy DOUBLE PRECISION
+    GENERATED ALWAYS AS (
+        CASE WHEN upvotes > 0 AND downvotes > 0
+            THEN POWER((upvotes + downvotes)::DOUBLE PRECISION,
+                       LEAST(upvotes, downvotes)::DOUBLE PRECISION / GREATEST(upvotes, downvotes))
+            ELSE 0
+        END
+    ) STORED;
+
+-- NEW использует существующие индексы (post_id, created_at) и (parent_id, created_at) в обратном направлении.
+-- Для согласованного порядка при равном времени добавляем id
+CREATE INDEX IF NOT EXISTS idx_comments_post_root_created_id ON comments(post_id, created_at, id)
+WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_parent_created_id ON comments(parent_id, created_at, id)
+WHERE parent_id IS NOT NULL;
+
+-- TOP
+CREATE INDEX IF NOT EXISTS idx_comments_post_root_score ON comments(post_id, score DESC, created_at, id)
+WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_parent_score ON comments(parent_id, score DESC, created_at, id)
+WHERE parent_id IS NOT NULL;
+
+-- CONTROVERSIAL
+CREATE INDEX IF NOT EXISTS idx_comments_post_root_controversy ON comments(post_id, controversy DESC, created_at, id)
+WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_parent_controversy ON comments(parent_id, controversy DESC, created_at, id)
+WHERE parent_id IS NOT NULL;
@@ -0,0 +1,26 @@
+-- migrations/007_votes.sql
+-- Голоса "за" и "против" для постов и комментариев.
+-- Один голос пользователя на цель; счетчики upvotes/downvotes на цели обновляются вместе с голосом.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS upvotes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS downvotes INTEGER NOT NULL DEFAULT 0;
+
+-- Целью голоса является либо пост, либо комментарий: ровно одна из ссылок заполнена.
+-- Голоса удаляются вместе с целью и вместе с пользователем
+CREATE TABLE IF NOT EXISTS votes (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    value SMALLINT NOT NULL CHECK (value IN (-1, 1)),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+-- Один голос пользователя на пост и на комментарий
+CREATE UNIQUE INDEX IF NOT EXISTS idx_votes_user_post ON votes(user_id, post_id)
+WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_votes_user_comment ON votes(user_id, comment_id)
+WHERE comment_id IS NOT NULL;
+
+-- Индексы для каскадного удаления голосов вместе с целью
+CREATE INDEX IF NOT EXISTS idx_votes_post_id ON votes(post_id) WHERE post_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_votes_comment_id ON votes(comment_id) WHERE comment_id IS NOT NULL;
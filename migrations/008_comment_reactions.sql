@@ -0,0 +1,14 @@
+-- migrations/008_comment_reactions.sql
+-- Реакции эмодзи на комментарии. Список разрешенных эмодзи задается конфигурацией сервиса.
+-- Один пользователь может поставить несколько разных реакций на комментарий, но каждую только один раз
+CREATE TABLE IF NOT EXISTS comment_reactions (
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (comment_id, user_id, emoji)
+);
+
+-- Первичный ключ начинается с comment_id и покрывает пакетную выборку реакций страницы комментариев.
+-- Отдельный индекс нужен для каскадного удаления реакций пользователя
+CREATE INDEX IF NOT EXISTS idx_comment_reactions_user_id ON comment_reactions(user_id);
@@ -0,0 +1,22 @@
+-- migrations/009_outbox.sql
+-- Transactional outbox: события пишутся в одной транзакции с изменением данных
+-- и доставляются в pub/sub и вебхуки фоновым relay как минимум один раз.
+-- dedup_key позволяет получателям отбрасывать повторную доставку
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(64) NOT NULL,
+    dedup_key TEXT NOT NULL UNIQUE,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- До locked_until событие доставляет один экземпляр relay; после истечения его заберет другой
+    locked_until TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+-- Relay выбирает недоставленные события по порядку записи
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(id) WHERE delivered_at IS NULL;
+
+-- Для удаления доставленных событий по времени хранения
+CREATE INDEX IF NOT EXISTS idx_outbox_events_delivered_at ON outbox_events(delivered_at) WHERE delivered_at IS NOT NULL;
@@ -0,0 +1,20 @@
+-- migrations/010_outbox_deliveries.sql
+-- Состояние доставки событий outbox хранится отдельно для каждого получателя (pub/sub, каждый вебхук):
+-- недоступный вебхук повторяет свои доставки и не задерживает остальных получателей.
+-- События, доставленные до этой миграции, сохраняют outbox_events.delivered_at и повторно не доставляются;
+-- недоставленные события получат все получатели.
+CREATE TABLE IF NOT EXISTS outbox_deliveries (
+    event_id BIGINT NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+    consumer TEXT NOT NULL,
+    -- До locked_until событие доставляет получателю один экземпляр relay
+    locked_until TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    PRIMARY KEY (event_id, consumer)
+);
+
+-- Захват и попытки доставки теперь хранятся в outbox_deliveries
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS attempts;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS last_error;
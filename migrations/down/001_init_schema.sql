@@ -0,0 +1,5 @@
+-- migrations/down/001_init_schema.sql
+-- Откат 001_init_schema.sql
+-- Удаляет посты и комментарии вместе с их индексами
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
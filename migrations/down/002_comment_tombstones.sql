@@ -0,0 +1,4 @@
+-- migrations/down/002_comment_tombstones.sql
+-- Откат 002_comment_tombstones.sql
+DROP INDEX IF EXISTS idx_comments_parent_live;
+ALTER TABLE comments DROP COLUMN IF EXISTS deleted_at;
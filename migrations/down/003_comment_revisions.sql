@@ -0,0 +1,4 @@
+-- migrations/down/003_comment_revisions.sql
+-- Откат 003_comment_revisions.sql
+DROP TABLE IF EXISTS comment_revisions;
+ALTER TABLE comments DROP COLUMN IF EXISTS edited_at;
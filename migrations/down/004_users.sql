@@ -0,0 +1,6 @@
+-- migrations/down/004_users.sql
+-- Откат 004_users.sql
+-- Индексы по author_id удаляются вместе с колонками
+ALTER TABLE comments DROP COLUMN IF EXISTS author_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS author_id;
+DROP TABLE IF EXISTS users;
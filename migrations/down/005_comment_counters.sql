@@ -0,0 +1,5 @@
+-- migrations/down/005_comment_counters.sql
+-- Откат 005_comment_counters.sql
+ALTER TABLE comments DROP COLUMN IF EXISTS descendant_count;
+ALTER TABLE comments DROP COLUMN IF EXISTS reply_count;
+ALTER TABLE posts DROP COLUMN IF EXISTS comment_count;
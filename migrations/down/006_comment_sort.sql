@@ -0,0 +1,11 @@
+-- migrations/down/006_comment_sort.sql
+-- Откат 006_comment_sort.sql
+DROP INDEX IF EXISTS idx_comments_post_root_created_id;
+DROP INDEX IF EXISTS idx_comments_parent_created_id;
+
+-- Индексы TOP и CONTROVERSIAL удаляются вместе с вычисляемыми колонками,
+-- которые нужно удалить раньше счетчиков голосов
+ALTER TABLE comments DROP COLUMN IF EXISTS controversy;
+ALTER TABLE comments DROP COLUMN IF EXISTS score;
+ALTER TABLE comments DROP COLUMN IF EXISTS downvotes;
+ALTER TABLE comments DROP COLUMN IF EXISTS upvotes;
@@ -0,0 +1,5 @@
+-- migrations/down/007_votes.sql
+-- Откат 007_votes.sql
+DROP TABLE IF EXISTS votes;
+ALTER TABLE posts DROP COLUMN IF EXISTS downvotes;
+ALTER TABLE posts DROP COLUMN IF EXISTS upvotes;
@@ -0,0 +1,3 @@
+-- migrations/down/008_comment_reactions.sql
+-- Откат 008_comment_reactions.sql
+DROP TABLE IF EXISTS comment_reactions;
@@ -0,0 +1,4 @@
+-- migrations/down/009_outbox.sql
+-- Откат 009_outbox.sql
+-- Недоставленные события outbox теряются
+DROP TABLE IF EXISTS outbox_events;
@@ -0,0 +1,8 @@
+-- migrations/down/010_outbox_deliveries.sql
+-- Откат 010_outbox_deliveries.sql
+-- События, которые получили не все получатели, будут доставлены всем повторно
+DROP TABLE IF EXISTS outbox_deliveries;
+
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS last_error TEXT;
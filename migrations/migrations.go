@@ -0,0 +1,19 @@
+// Package migrations встраивает SQL миграции схемы в бинарный файл приложения.
+//
+// Миграция NNN_name.sql применяется по возрастанию номера NNN, откат лежит в down/NNN_name.sql.
+// Применением миграций PostgreSQL управляет repository.Migrator, миграции SQLite
+// из каталога sqlite/ применяет repository.SQLiteStorage. Примененные миграции менять нельзя -
+// изменения схемы оформляются новой миграцией.
+package migrations
+
+import "embed"
+
+// FS содержит файлы миграций: *.sql и down/*.sql
+//
+//go:embed *.sql down/*.sql
+var FS embed.FS
+
+// SQLite содержит миграции SQLite: sqlite/*.sql и sqlite/down/*.sql
+//
+//go:embed sqlite/*.sql sqlite/down/*.sql
+var SQLite embed.FS
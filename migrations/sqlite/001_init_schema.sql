@@ -0,0 +1,110 @@
+-- migrations/sqlite/001_init_schema.sql
+-- Схема SQLiteStorage: соответствует миграциям PostgreSQL 001-008 (без outbox).
+-- UUID хранятся как TEXT, время - как TEXT фиксированной ширины в UTC
+-- (см. sqliteTimeFormat), поэтому строки времени сравниваются в хронологическом порядке.
+
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+    id TEXT PRIMARY KEY,
+    author_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    comments_enabled BOOLEAN NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL,
+    comment_count INTEGER NOT NULL DEFAULT 0,
+    upvotes INTEGER NOT NULL DEFAULT 0,
+    downvotes INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id TEXT PRIMARY KEY,
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    author_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    parent_id TEXT REFERENCES comments(id) ON DELETE CASCADE, -- NULL для корневых комментариев
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    edited_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    reply_count INTEGER NOT NULL DEFAULT 0,
+    descendant_count INTEGER NOT NULL DEFAULT 0,
+    upvotes INTEGER NOT NULL DEFAULT 0,
+    downvotes INTEGER NOT NULL DEFAULT 0,
+    -- Рейтинг для TOP и спорность для CONTROVERSIAL, как в миграции PostgreSQL 006.
+    -- Формула спорности должна совпадать с model.Comment.Controversy
+    score INTEGER GENERATED ALWAYS AS (upvotes - downvotes) STORED,
+    controversy REAL GENERATED ALWAYS AS (
+        CASE WHEN upvotes > 0 AND downvotes > 0
+            THEN POWER(upvotes + downvotes, CAST(MIN(upvotes, downvotes) AS REAL) / MAX(upvotes, downvotes))
+            ELSE 0
+        END
+    ) STORED
+);
+
+-- Предыдущие версии текста комментариев
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    id TEXT PRIMARY KEY,
+    comment_id TEXT NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    content TEXT NOT NULL, -- Текст до редактирования
+    created_at TIMESTAMP NOT NULL -- Время редактирования
+);
+
+-- Голоса: целью является либо пост, либо комментарий
+CREATE TABLE IF NOT EXISTS votes (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id TEXT REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id TEXT REFERENCES comments(id) ON DELETE CASCADE,
+    value INTEGER NOT NULL CHECK (value IN (-1, 1)),
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+-- Реакции эмодзи на комментарии
+CREATE TABLE IF NOT EXISTS comment_reactions (
+    comment_id TEXT NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (comment_id, user_id, emoji)
+);
+
+-- Посты от новых к старым
+CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_author_id ON posts(author_id) WHERE author_id IS NOT NULL;
+
+-- Комментарии поста и ответы в порядке OLD/NEW и для keyset пагинации
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments(post_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_post_root_created_id ON comments(post_id, created_at, id)
+WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_parent_created_id ON comments(parent_id, created_at, id)
+WHERE parent_id IS NOT NULL;
+
+-- TOP и CONTROVERSIAL
+CREATE INDEX IF NOT EXISTS idx_comments_post_root_score ON comments(post_id, score DESC, created_at, id)
+WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_parent_score ON comments(parent_id, score DESC, created_at, id)
+WHERE parent_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_post_root_controversy ON comments(post_id, controversy DESC, created_at, id)
+WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_parent_controversy ON comments(parent_id, controversy DESC, created_at, id)
+WHERE parent_id IS NOT NULL;
+
+-- Поиск живых потомков при фильтрации надгробий
+CREATE INDEX IF NOT EXISTS idx_comments_parent_live ON comments(parent_id)
+WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_author_id ON comments(author_id) WHERE author_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_comment_revisions_comment_created ON comment_revisions(comment_id, created_at);
+
+-- Один голос пользователя на пост и на комментарий
+CREATE UNIQUE INDEX IF NOT EXISTS idx_votes_user_post ON votes(user_id, post_id)
+WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_votes_user_comment ON votes(user_id, comment_id)
+WHERE comment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_votes_post_id ON votes(post_id) WHERE post_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_votes_comment_id ON votes(comment_id) WHERE comment_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_comment_reactions_user_id ON comment_reactions(user_id);
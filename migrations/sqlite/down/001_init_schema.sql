@@ -0,0 +1,8 @@
+-- migrations/sqlite/down/001_init_schema.sql
+-- Откат 001_init_schema.sql
+DROP TABLE IF EXISTS comment_reactions;
+DROP TABLE IF EXISTS votes;
+DROP TABLE IF EXISTS comment_revisions;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS users;
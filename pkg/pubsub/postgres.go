@@ -0,0 +1,429 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Значения конфигурации PostgreSQL по умолчанию
+const (
+	// DefaultPostgresChannel - канал LISTEN/NOTIFY, через который экземпляры приложения обмениваются сообщениями
+	DefaultPostgresChannel = "commentsystem_events"
+
+	// DefaultPostgresTimeout - таймаут публикации одного сообщения
+	DefaultPostgresTimeout = 5 * time.Second
+
+	// maxNotifyPayload - максимальный размер полезной нагрузки NOTIFY в байтах (ограничение PostgreSQL - меньше 8000)
+	maxNotifyPayload = 7999
+
+	// notificationPartsTTL - сколько ждать недостающие части разделенного сообщения
+	notificationPartsTTL = time.Minute
+
+	// notificationPartPrefix отмечает часть сообщения, не поместившегося в одно уведомление
+	notificationPartPrefix = "~"
+)
+
+// PostgresConfig содержит параметры распределенного PubSub поверх LISTEN/NOTIFY
+type PostgresConfig struct {
+	DSN     string        // Строка подключения PostgreSQL
+	Channel string        // Канал LISTEN/NOTIFY для сообщений всех топиков
+	Timeout time.Duration // Таймаут публикации одного сообщения
+	Codec   Codec         // Сериализация данных сообщений (обязательный)
+	Local   Config        // Параметры доставки подписчикам этого экземпляра
+	OnError func(error)   // Вызывается при ошибках связи с PostgreSQL и сериализации; nil - ошибки игнорируются
+}
+
+// Postgres - распределенный PubSub поверх PostgreSQL LISTEN/NOTIFY для развертываний,
+// где PostgreSQL уже используется как хранилище и отдельный Redis не нужен.
+//
+// Работает так же, как Redis: все топики передаются через один канал, каждый экземпляр
+// слушает его выделенным соединением и доставляет сообщения своим подписчикам через
+// встроенный Memory. Номера сообщений выдает общая последовательность PostgreSQL.
+//
+// Номер и уведомление выдаются в одной транзакции под рекомендательной блокировкой,
+// поэтому уведомления приходят в порядке номеров. После переподключения прослушивания
+// история отмечает разрыв: возобновление с более ранним курсором считается неполным.
+//
+// Сообщение больше 8000 байт (ограничение NOTIFY) делится на части, которые отправляются
+// в одной транзакции: PostgreSQL доставляет уведомления транзакции вместе и по порядку,
+// а получатель собирает сообщение из частей.
+type Postgres struct {
+	local   *Memory
+	cfg     PostgresConfig
+	pool    *pgxpool.Pool
+	seqName string // Имя последовательности номеров сообщений (экранированное)
+
+	cancel    context.CancelFunc
+	done      chan struct{} // Закрывается при завершении прослушивания канала
+	closeOnce sync.Once
+}
+
+// NewPostgres подключается к PostgreSQL, создает последовательность номеров сообщений
+// и начинает слушать канал. Возвращает ошибку, если PostgreSQL недоступен.
+func NewPostgres(ctx context.Context, cfg PostgresConfig) (*Postgres, error) {
+	if cfg.DSN == "" {
+		return nil, errors.New("postgres DSN is required")
+	}
+	if cfg.Codec == nil {
+		return nil, errors.New("postgres pubsub codec is required")
+	}
+	if cfg.Channel == "" {
+		cfg.Channel = DefaultPostgresChannel
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultPostgresTimeout
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+
+	p := &Postgres{
+		local:   NewWithOptions(cfg.Local),
+		cfg:     cfg,
+		pool:    pool,
+		seqName: pgx.Identifier{cfg.Channel + "_seq"}.Sanitize(),
+		done:    make(chan struct{}),
+	}
+
+	if _, err := pool.Exec(ctx, "CREATE SEQUENCE IF NOT EXISTS "+p.seqName); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to create message sequence: %w", err)
+	}
+
+	// Слушаем канал до возврата, чтобы не пропустить сообщения сразу после создания
+	conn, err := p.listenConn(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	listenCtx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go p.listen(listenCtx, conn)
+
+	return p, nil
+}
+
+// Subscribe подписывает клиента на топик на этом экземпляре
+func (p *Postgres) Subscribe(topic string, subscriberID string) *Subscriber {
+	return p.local.Subscribe(topic, subscriberID)
+}
+
+// SubscribeSince подписывает клиента на топик и возвращает сообщения из истории этого экземпляра.
+// История содержит все сообщения, полученные через NOTIFY, независимо от экземпляра-издателя.
+func (p *Postgres) SubscribeSince(topic string, subscriberID string, since uint64) (*Subscriber, []Message, bool) {
+	return p.local.SubscribeSince(topic, subscriberID, since)
+}
+
+// Unsubscribe отписывает клиента от топика
+func (p *Postgres) Unsubscribe(topic string, subscriberID string) {
+	p.local.Unsubscribe(topic, subscriberID)
+}
+
+// GetSubscribersCount возвращает количество подписчиков топика на этом экземпляре
+func (p *Postgres) GetSubscribersCount(topic string) int {
+	return p.local.GetSubscribersCount(topic)
+}
+
+// Publish отправляет сообщение подписчикам топика на всех экземплярах.
+// Если PostgreSQL недоступен, сообщение получают только подписчики этого экземпляра.
+func (p *Postgres) Publish(topic string, data interface{}) {
+	if err := p.PublishErr(topic, data); err != nil {
+		p.fail(err)
+		// Подписчики этого экземпляра получают сообщение и без PostgreSQL. Номер из nextval
+		// не занимаем: уведомление с ним не ушло, и другие экземпляры не знают сообщения
+		// с этим номером
+		p.local.publishLocal(topic, data)
+	}
+}
+
+// PublishErr отправляет сообщение подписчикам топика на всех экземплярах.
+// Сообщение возвращается и этому экземпляру через LISTEN.
+func (p *Postgres) PublishErr(topic string, data interface{}) error {
+	payload, err := p.cfg.Codec.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode message for topic %s: %w", topic, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+	defer cancel()
+
+	err = pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		// Номер берется под блокировкой, которая держится до фиксации: следующий издатель
+		// получит номер только после того, как уведомление этой транзакции встанет в очередь.
+		// Иначе уведомление с меньшим номером могло бы прийти после большего, и клиент
+		// с курсором большего номера пропустил бы его при повторной подписке.
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", p.cfg.Channel); err != nil {
+			return err
+		}
+		var next int64
+		if err := tx.QueryRow(ctx, "SELECT nextval($1)", p.seqName).Scan(&next); err != nil {
+			return err
+		}
+		seq := uint64(next)
+		return p.notify(ctx, tx, envelope{Topic: topic, Seq: seq, Data: payload}, seq)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish message for topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+// CloseTopic закрывает топик на всех экземплярах.
+// На этом экземпляре топик закрывается сразу, не дожидаясь уведомления.
+func (p *Postgres) CloseTopic(topic string) {
+	p.local.CloseTopic(topic)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		return p.notify(ctx, tx, envelope{Topic: topic, Close: true}, 0)
+	})
+	if err != nil {
+		p.fail(fmt.Errorf("failed to close topic %s: %w", topic, err))
+	}
+}
+
+// Close прекращает прослушивание канала, закрывает пул соединений и каналы локальных подписчиков
+func (p *Postgres) Close() {
+	p.closeOnce.Do(func() {
+		p.cancel()
+		<-p.done
+		p.pool.Close()
+		p.local.Close()
+	})
+}
+
+// notify отправляет сообщение в канал в транзакции tx, при необходимости разделяя его на части.
+// Уведомления одной транзакции доставляются при фиксации вместе и по порядку.
+func (p *Postgres) notify(ctx context.Context, tx pgx.Tx, message envelope, id uint64) error {
+	raw, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	for _, part := range splitNotification(raw, id, maxNotifyPayload) {
+		if _, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", p.cfg.Channel, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentSeq возвращает последний выданный номер сообщения (0, если номеров еще не было)
+func (p *Postgres) currentSeq(ctx context.Context) (uint64, error) {
+	var seq int64
+	err := p.pool.QueryRow(ctx, "SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM "+p.seqName).Scan(&seq)
+	return uint64(seq), err
+}
+
+// listenConn забирает соединение из пула и подписывает его на канал.
+// Соединение изымается из пула: слушающее соединение нельзя возвращать для обычных запросов.
+func (p *Postgres) listenConn(ctx context.Context) (*pgx.Conn, error) {
+	poolConn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire listener connection: %w", err)
+	}
+	conn := poolConn.Hijack()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{p.cfg.Channel}.Sanitize()); err != nil {
+		conn.Close(context.Background())
+		return nil, fmt.Errorf("failed to listen on %s: %w", p.cfg.Channel, err)
+	}
+
+	return conn, nil
+}
+
+// listen доставляет уведомления канала локальным подписчикам.
+// При потере соединения берет новое из пула, пока не вызван Close.
+// Уведомления, отправленные во время переподключения, этот экземпляр не получит,
+// поэтому relisten отмечает разрыв в истории.
+func (p *Postgres) listen(ctx context.Context, conn *pgx.Conn) {
+	defer close(p.done)
+
+	for {
+		err := p.receive(ctx, conn)
+		conn.Close(context.Background())
+		if ctx.Err() != nil {
+			return
+		}
+		p.fail(fmt.Errorf("postgres listener lost: %w", err))
+
+		conn = p.relisten(ctx)
+		if conn == nil {
+			return
+		}
+	}
+}
+
+// relisten переподключает прослушивание канала с нарастающей паузой.
+// Возвращает nil, если PubSub закрыт.
+func (p *Postgres) relisten(ctx context.Context) *pgx.Conn {
+	backoff := reconnectMinBackoff
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+
+		conn, err := p.listenConn(ctx)
+		if err == nil {
+			// Номер читается после LISTEN: все более поздние сообщения придут через новое соединение
+			var seq uint64
+			if seq, err = p.currentSeq(ctx); err == nil {
+				p.local.markGap(seq)
+				return conn
+			}
+			conn.Close(context.Background())
+			err = fmt.Errorf("failed to get message number after relisten: %w", err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		p.fail(err)
+		backoff = min(backoff*2, reconnectMaxBackoff)
+	}
+}
+
+// receive читает уведомления до ошибки соединения или отмены ctx
+func (p *Postgres) receive(ctx context.Context, conn *pgx.Conn) error {
+	parts := newNotificationParts()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		raw, complete, err := parts.add(notification.Payload, time.Now())
+		if err != nil {
+			p.fail(err)
+			continue
+		}
+		if !complete {
+			continue
+		}
+
+		if err := p.local.deliverEnvelope(p.cfg.Codec, raw); err != nil {
+			p.fail(err)
+		}
+	}
+}
+
+// fail передает ошибку обработчику OnError
+func (p *Postgres) fail(err error) {
+	if p.cfg.OnError != nil {
+		p.cfg.OnError(err)
+	}
+}
+
+// splitNotification делит сообщение на полезные нагрузки NOTIFY не длиннее limit байт.
+// Сообщение, которое помещается целиком, отправляется как есть; части имеют вид
+// "~<id>:<номер части>:<всего частей>:<фрагмент>". Фрагменты режутся по границам
+// символов UTF-8: PostgreSQL отвергает некорректные строки.
+func splitNotification(raw []byte, id uint64, limit int) []string {
+	if len(raw) <= limit {
+		return []string{string(raw)}
+	}
+
+	// Заголовок с максимально возможными номерами частей
+	maxHeader := len(fmt.Sprintf("%s%d:%d:%d:", notificationPartPrefix, id, len(raw), len(raw)))
+	size := limit - maxHeader
+
+	var fragments []string
+	for len(raw) > 0 {
+		n := min(size, len(raw))
+		for n < len(raw) && n > 0 && !utf8.RuneStart(raw[n]) {
+			n--
+		}
+		if n == 0 {
+			// Лимит меньше одного символа: фрагмент из одного символа
+			_, n = utf8.DecodeRune(raw)
+		}
+		fragments = append(fragments, string(raw[:n]))
+		raw = raw[n:]
+	}
+
+	parts := make([]string, len(fragments))
+	for i, fragment := range fragments {
+		parts[i] = fmt.Sprintf("%s%d:%d:%d:%s", notificationPartPrefix, id, i, len(fragments), fragment)
+	}
+	return parts
+}
+
+// partialNotification - принятые части разделенного сообщения
+type partialNotification struct {
+	fragments []string
+	received  int
+	startedAt time.Time
+}
+
+// notificationParts собирает сообщения, разделенные splitNotification.
+// Используется одной горутиной прослушивания.
+type notificationParts struct {
+	pending map[uint64]*partialNotification
+}
+
+// newNotificationParts создает пустой сборщик частей
+func newNotificationParts() *notificationParts {
+	return &notificationParts{pending: make(map[uint64]*partialNotification)}
+}
+
+// add принимает полезную нагрузку уведомления и возвращает собранное сообщение,
+// когда получены все его части. Части, не собранные за notificationPartsTTL, отбрасываются.
+func (n *notificationParts) add(payload string, now time.Time) ([]byte, bool, error) {
+	if !strings.HasPrefix(payload, notificationPartPrefix) {
+		return []byte(payload), true, nil
+	}
+
+	for id, partial := range n.pending {
+		if now.Sub(partial.startedAt) > notificationPartsTTL {
+			delete(n.pending, id)
+		}
+	}
+
+	fields := strings.SplitN(payload[len(notificationPartPrefix):], ":", 4)
+	if len(fields) != 4 {
+		return nil, false, fmt.Errorf("malformed notification part")
+	}
+	id, errID := strconv.ParseUint(fields[0], 10, 64)
+	index, errIndex := strconv.Atoi(fields[1])
+	total, errTotal := strconv.Atoi(fields[2])
+	if errID != nil || errIndex != nil || errTotal != nil || total <= 0 || index < 0 || index >= total {
+		return nil, false, fmt.Errorf("malformed notification part header")
+	}
+
+	partial, exists := n.pending[id]
+	if !exists {
+		partial = &partialNotification{fragments: make([]string, total), startedAt: now}
+		n.pending[id] = partial
+	}
+	if len(partial.fragments) != total {
+		delete(n.pending, id)
+		return nil, false, fmt.Errorf("notification %d parts count mismatch", id)
+	}
+	if partial.fragments[index] == "" {
+		partial.received++
+	}
+	partial.fragments[index] = fields[3]
+
+	if partial.received < total {
+		return nil, false, nil
+	}
+	delete(n.pending, id)
+	return []byte(strings.Join(partial.fragments, "")), true, nil
+}
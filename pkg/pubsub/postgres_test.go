@@ -0,0 +1,231 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSplitNotification(t *testing.T) {
+	t.Run("короткое сообщение отправляется как есть", func(t *testing.T) {
+		raw := []byte(`{"topic":"post:1:comments","seq":1}`)
+		parts := splitNotification(raw, 1, maxNotifyPayload)
+		if len(parts) != 1 || parts[0] != string(raw) {
+			t.Errorf("Expected message unchanged, got %v", parts)
+		}
+	})
+
+	t.Run("длинное сообщение делится по границам символов", func(t *testing.T) {
+		// Кириллица занимает 2 байта, смещение на 1 байт проверяет границы символов
+		raw := []byte("{" + strings.Repeat("комментарий", 1000) + "}")
+		parts := splitNotification(raw, 42, maxNotifyPayload)
+		if len(parts) < 3 {
+			t.Fatalf("Expected at least 3 parts for %d bytes, got %d", len(raw), len(parts))
+		}
+
+		assembler := newNotificationParts()
+		now := time.Now()
+		for i, part := range parts {
+			if len(part) > maxNotifyPayload {
+				t.Errorf("Part %d is %d bytes, limit is %d", i, len(part), maxNotifyPayload)
+			}
+			if !utf8.ValidString(part) {
+				t.Errorf("Part %d is not valid UTF-8", i)
+			}
+
+			assembled, complete, err := assembler.add(part, now)
+			if err != nil {
+				t.Fatalf("add() error = %v", err)
+			}
+			if complete != (i == len(parts)-1) {
+				t.Fatalf("Part %d: complete = %v", i, complete)
+			}
+			if complete && string(assembled) != string(raw) {
+				t.Error("Assembled message differs from original")
+			}
+		}
+	})
+}
+
+func TestNotificationParts(t *testing.T) {
+	t.Run("сообщения собираются независимо", func(t *testing.T) {
+		first := []byte(strings.Repeat("a", 100))
+		second := []byte(strings.Repeat("b", 100))
+		partsA := splitNotification(first, 1, 40)
+		partsB := splitNotification(second, 2, 40)
+
+		assembler := newNotificationParts()
+		now := time.Now()
+		var assembled []string
+		for i := range partsA {
+			for _, part := range []string{partsB[len(partsB)-1-i], partsA[i]} {
+				raw, complete, err := assembler.add(part, now)
+				if err != nil {
+					t.Fatalf("add() error = %v", err)
+				}
+				if complete {
+					assembled = append(assembled, string(raw))
+				}
+			}
+		}
+
+		if len(assembled) != 2 || assembled[0] != string(second) || assembled[1] != string(first) {
+			t.Errorf("Expected both messages assembled, got %v", assembled)
+		}
+	})
+
+	t.Run("незавершенные сообщения отбрасываются", func(t *testing.T) {
+		parts := splitNotification([]byte(strings.Repeat("a", 100)), 1, 40)
+
+		assembler := newNotificationParts()
+		now := time.Now()
+		if _, complete, _ := assembler.add(parts[0], now); complete {
+			t.Fatal("Expected message to be incomplete")
+		}
+
+		// Недостающие части пришли слишком поздно: сообщение не собирается
+		later := now.Add(notificationPartsTTL + time.Second)
+		for _, part := range parts[1:] {
+			if _, complete, _ := assembler.add(part, later); complete {
+				t.Error("Expected stale message to be dropped")
+			}
+		}
+	})
+
+	t.Run("некорректные части", func(t *testing.T) {
+		assembler := newNotificationParts()
+		for _, payload := range []string{"~1:0", "~x:0:1:data", "~1:1:1:data", "~1:0:0:data"} {
+			if _, _, err := assembler.add(payload, time.Now()); err == nil {
+				t.Errorf("Expected error for %q", payload)
+			}
+		}
+	})
+}
+
+// newTestPostgres создает PubSub поверх PostgreSQL из TEST_DB_DSN в отдельном канале
+func newTestPostgres(t *testing.T, channel string, onError func(error)) *Postgres {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+
+	ps, err := NewPostgres(context.Background(), PostgresConfig{
+		DSN:     dsn,
+		Channel: channel,
+		Codec:   stringCodec{},
+		OnError: onError,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create postgres pubsub: %v", err)
+	}
+	t.Cleanup(ps.Close)
+
+	return ps
+}
+
+// testNotifyChannel возвращает канал, не пересекающийся с запущенными экземплярами приложения
+func testNotifyChannel() string {
+	return fmt.Sprintf("commentsystem_test_%d", time.Now().UnixNano())
+}
+
+// TestPostgres_DeliversAcrossInstances проверяет обмен сообщениями через настоящий PostgreSQL.
+// Строка подключения задается переменной TEST_DB_DSN.
+func TestPostgres_DeliversAcrossInstances(t *testing.T) {
+	channel := testNotifyChannel()
+	podA := newTestPostgres(t, channel, nil)
+	podB := newTestPostgres(t, channel, nil)
+	topic := "post:1:comments"
+
+	subscriberA := podA.Subscribe(topic, "subscriber-a")
+	subscriberB := podB.Subscribe(topic, "subscriber-b")
+
+	large := strings.Repeat("длинный комментарий ", 1000)
+	podA.Publish(topic, "first")
+	podB.Publish(topic, large)
+
+	for _, subscriber := range []*Subscriber{subscriberA, subscriberB} {
+		first := receiveMessage(t, subscriber)
+		second := receiveMessage(t, subscriber)
+		if first.Data != "first" || second.Data != large {
+			t.Errorf("Expected both messages, got %q and %d bytes", first.Data, len(fmt.Sprint(second.Data)))
+		}
+		if second.Seq != first.Seq+1 {
+			t.Errorf("Expected shared sequence numbers, got %d and %d", first.Seq, second.Seq)
+		}
+	}
+
+	podA.CloseTopic(topic)
+	select {
+	case _, ok := <-subscriberB.Channel:
+		if ok {
+			t.Error("Expected subscriber channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Error("Topic was not closed on another instance")
+	}
+}
+
+// TestPostgres_Reconnect проверяет, что экземпляр снова слушает канал после разрыва соединения
+func TestPostgres_Reconnect(t *testing.T) {
+	channel := testNotifyChannel()
+	lost := make(chan error, 10)
+	pod := newTestPostgres(t, channel, func(err error) {
+		select {
+		case lost <- err:
+		default:
+		}
+	})
+	topic := "post:1:comments"
+	subscriber := pod.Subscribe(topic, "subscriber-1")
+	pod.Publish(topic, "before")
+	before := receiveMessage(t, subscriber)
+
+	// Завершаем слушающее соединение на стороне сервера
+	ctx := context.Background()
+	if _, err := pod.pool.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+		WHERE query = $1`, "LISTEN \""+channel+"\""); err != nil {
+		t.Fatalf("Failed to terminate listener: %v", err)
+	}
+	select {
+	case <-lost:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listener loss was not reported")
+	}
+
+	// Сообщение, опубликованное во время переподключения, до экземпляра не дойдет
+	pod.Publish(topic, "lost")
+
+	// Публикуем, пока прослушивание не восстановится
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		pod.Publish(topic, "after reconnect")
+		select {
+		case msg := <-subscriber.Channel:
+			if msg.Data != "after reconnect" {
+				t.Errorf("Expected message after reconnect, got %v", msg.Data)
+			}
+			// Возобновление с курсора до разрыва не может быть полным
+			if _, _, complete := pod.SubscribeSince(topic, "resumed", before.Seq); complete {
+				t.Error("Expected resume across reconnect to be incomplete")
+			}
+			return
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	t.Fatal("Messages are not delivered after reconnect")
+}
+
+func TestNewPostgres_Errors(t *testing.T) {
+	if _, err := NewPostgres(context.Background(), PostgresConfig{Codec: stringCodec{}}); err == nil {
+		t.Error("Expected error for empty DSN")
+	}
+	if _, err := NewPostgres(context.Background(), PostgresConfig{DSN: "postgres://localhost/db"}); err == nil {
+		t.Error("Expected error for missing codec")
+	}
+}
@@ -2,29 +2,164 @@ package pubsub
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Константы конфигурации по умолчанию
 const (
 	// DefaultChannelBufferSize - размер буфера канала подписчика по умолчанию
 	DefaultChannelBufferSize = 100
+
+	// DefaultHistorySize - сколько последних сообщений каждого топика хранится для повторной доставки
+	DefaultHistorySize = 256
+
+	// DefaultHistoryTTL - через сколько после последней публикации удаляется история топика без подписчиков
+	DefaultHistoryTTL = 10 * time.Minute
+
+	// DefaultBlockTimeout - сколько Publish ждет место в канале подписчика при политике OverflowBlock
+	DefaultBlockTimeout = 100 * time.Millisecond
+)
+
+// OverflowPolicy определяет, что делает Publish, когда канал подписчика заполнен
+type OverflowPolicy string
+
+const (
+	OverflowDropNewest OverflowPolicy = "drop_newest" // Отбросить новое сообщение (по умолчанию)
+	OverflowDropOldest OverflowPolicy = "drop_oldest" // Отбросить самое старое сообщение из канала и добавить новое
+	OverflowBlock      OverflowPolicy = "block"       // Ждать место в канале до BlockTimeout, затем отбросить новое сообщение
+	OverflowDisconnect OverflowPolicy = "disconnect"  // Отписать подписчика и закрыть его канал
 )
 
+// IsValid проверяет, что политика переполнения известна
+func (p OverflowPolicy) IsValid() bool {
+	switch p {
+	case OverflowDropNewest, OverflowDropOldest, OverflowBlock, OverflowDisconnect:
+		return true
+	}
+	return false
+}
+
 // Message представляет сообщение в системе Pub/Sub.
 // Содержит топик и данные для передачи подписчикам.
 type Message struct {
 	Topic string      `json:"topic"`          // Название топика
+	Seq   uint64      `json:"seq"`            // Порядковый номер сообщения, монотонно растет в пределах PubSub
 	Data  interface{} `json:"data,omitempty"` // Данные сообщения
 }
 
+// Config содержит параметры PubSub. Нулевые значения заменяются значениями по умолчанию.
+type Config struct {
+	ChannelBufferSize int            // Размер буфера канала подписчика
+	HistorySize       int            // Количество последних сообщений топика для повторной доставки
+	HistoryTTL        time.Duration  // Время хранения истории топика без подписчиков и публикаций
+	OverflowPolicy    OverflowPolicy // Поведение при переполнении канала подписчика
+	BlockTimeout      time.Duration  // Максимальное ожидание места в канале для OverflowBlock
+}
+
+// topicHistory - кольцевой буфер последних сообщений топика
+type topicHistory struct {
+	messages  []Message // Сообщения; после заполнения самое старое находится по индексу start
+	start     int       // Индекс самого старого сообщения
+	floor     uint64    // Сообщения топика с Seq <= floor недоступны (вытеснены или удалены вместе с историей)
+	updatedAt time.Time // Время последней публикации
+}
+
 // Subscriber представляет подписчика на топик.
 // Каждый подписчик имеет уникальный ID и канал для получения сообщений.
 type Subscriber struct {
 	ID      string       `json:"id"` // Уникальный идентификатор подписчика
 	Channel chan Message `json:"-"`  // Канал для получения сообщений (не сериализуется)
+
+	dropped      atomic.Uint64 // Количество сообщений, не доставленных из-за переполнения канала
+	disconnected atomic.Bool   // Подписчик отключен политикой OverflowDisconnect
+
+	sendMu sync.Mutex    // Защищает ожидающую отправку вне мьютекса PubSub (OverflowBlock) от закрытия канала
+	done   chan struct{} // Закрывается перед Channel и прерывает ожидание места в канале
 }
 
-// PubSub представляет простую in-memory систему публикации/подписки.
+// close закрывает канал подписчика.
+// Сначала прерывает ожидающую отправку, поэтому не ждет BlockTimeout.
+// Должно вызываться под мьютексом PubSub, один раз для подписчика.
+func (s *Subscriber) close() {
+	close(s.done)
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	close(s.Channel)
+}
+
+// sendBlocking ждет место в канале не дольше timeout.
+// Вызывается без мьютекса PubSub: канал может закрыться во время ожидания,
+// тогда сообщение не доставляется и не считается потерянным.
+func (s *Subscriber) sendBlocking(message Message, timeout time.Duration) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case s.Channel <- message:
+	case <-s.done:
+	case <-timer.C:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped возвращает количество сообщений, которые подписчик не получил из-за переполнения канала
+func (s *Subscriber) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Disconnected сообщает, что канал подписчика закрыт из-за переполнения (политика OverflowDisconnect),
+// а не из-за отписки или закрытия топика
+func (s *Subscriber) Disconnected() bool {
+	return s.disconnected.Load()
+}
+
+// PubSub - система публикации/подписки, через которую резолверы доставляют события подписчикам.
+//
+// Реализации:
+//   - Memory - в пределах одного процесса
+//   - Redis - между несколькими экземплярами приложения через Redis PUBLISH/SUBSCRIBE
+type PubSub interface {
+	// Subscribe подписывает клиента на топик
+	Subscribe(topic string, subscriberID string) *Subscriber
+
+	// SubscribeSince подписывает клиента на топик и возвращает сообщения топика с Seq > since;
+	// false означает, что часть таких сообщений уже недоступна
+	SubscribeSince(topic string, subscriberID string, since uint64) (*Subscriber, []Message, bool)
+
+	// Unsubscribe отписывает клиента от топика и закрывает его канал
+	Unsubscribe(topic string, subscriberID string)
+
+	// Publish отправляет сообщение всем подписчикам топика.
+	// Если сообщение не удалось передать другим экземплярам, его получают подписчики этого экземпляра.
+	Publish(topic string, data interface{})
+
+	// PublishErr отправляет сообщение всем подписчикам топика и возвращает ошибку, если оно
+	// не дошло до брокера. При ошибке сообщение не доставляется никому: вызывающий повторит
+	// публикацию, и локальные подписчики не получат его дважды.
+	PublishErr(topic string, data interface{}) error
+
+	// CloseTopic закрывает каналы всех подписчиков топика
+	CloseTopic(topic string)
+
+	// GetSubscribersCount возвращает количество подписчиков топика
+	GetSubscribersCount(topic string) int
+
+	// Close закрывает все подписки и освобождает ресурсы
+	Close()
+}
+
+// Memory представляет простую in-memory систему публикации/подписки.
 // Поддерживает множественные топики и подписчиков с thread-safe операциями.
 //
 // Основные возможности:
@@ -32,28 +167,64 @@ type Subscriber struct {
 // - Буферизированные каналы для предотвращения блокировок
 // - Автоматическая очистка пустых топиков
 // - Неблокирующая публикация сообщений
-type PubSub struct {
+// - История последних сообщений топика для повторной доставки (SubscribeSince)
+// - Настраиваемая политика для медленных подписчиков и учет потерянных сообщений
+type Memory struct {
 	mu                sync.RWMutex                      // Мьютекс для thread-safe операций
+	publishMu         sync.Mutex                        // Упорядочивает публикации, пока OverflowBlock ждет подписчиков без mu
 	subscribers       map[string]map[string]*Subscriber // topic -> subscriberID -> subscriber
 	channelBufferSize int                               // Размер буфера для каналов подписчиков
+	overflowPolicy    OverflowPolicy                    // Поведение при переполнении канала подписчика
+	blockTimeout      time.Duration                     // Ожидание места в канале для OverflowBlock
+
+	seq         uint64                   // Номер последнего опубликованного сообщения
+	history     map[string]*topicHistory // topic -> последние сообщения
+	historySize int                      // Емкость истории одного топика
+	historyTTL  time.Duration            // Время хранения истории неактивного топика
+	prunedSeq   uint64                   // Номер сообщения на момент последней очистки истории
+	prunedAt    time.Time                // Время последней очистки истории
 }
 
-// New создает новый экземпляр PubSub с буфером по умолчанию.
-func New() *PubSub {
+// New создает новый экземпляр Memory с буфером по умолчанию.
+func New() *Memory {
 	return NewWithConfig(DefaultChannelBufferSize)
 }
 
-// NewWithConfig создает новый экземпляр PubSub с указанным размером буфера канала.
+// NewWithConfig создает новый экземпляр Memory с указанным размером буфера канала.
 // channelBufferSize определяет размер буфера для каналов подписчиков.
 // Больший буфер снижает вероятность потери сообщений при медленных подписчиках.
-func NewWithConfig(channelBufferSize int) *PubSub {
-	if channelBufferSize <= 0 {
-		channelBufferSize = DefaultChannelBufferSize
+func NewWithConfig(channelBufferSize int) *Memory {
+	return NewWithOptions(Config{ChannelBufferSize: channelBufferSize})
+}
+
+// NewWithOptions создает новый экземпляр Memory с полной конфигурацией.
+// Неположительные значения параметров заменяются значениями по умолчанию.
+func NewWithOptions(cfg Config) *Memory {
+	if cfg.ChannelBufferSize <= 0 {
+		cfg.ChannelBufferSize = DefaultChannelBufferSize
+	}
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = DefaultHistorySize
+	}
+	if cfg.HistoryTTL <= 0 {
+		cfg.HistoryTTL = DefaultHistoryTTL
+	}
+	if !cfg.OverflowPolicy.IsValid() {
+		cfg.OverflowPolicy = OverflowDropNewest
+	}
+	if cfg.BlockTimeout <= 0 {
+		cfg.BlockTimeout = DefaultBlockTimeout
 	}
 
-	return &PubSub{
+	return &Memory{
 		subscribers:       make(map[string]map[string]*Subscriber),
-		channelBufferSize: channelBufferSize,
+		channelBufferSize: cfg.ChannelBufferSize,
+		overflowPolicy:    cfg.OverflowPolicy,
+		blockTimeout:      cfg.BlockTimeout,
+		history:           make(map[string]*topicHistory),
+		historySize:       cfg.HistorySize,
+		historyTTL:        cfg.HistoryTTL,
+		prunedAt:          time.Now(),
 	}
 }
 
@@ -66,10 +237,51 @@ func NewWithConfig(channelBufferSize int) *PubSub {
 //   - subscriberID: уникальный идентификатор подписчика
 //
 // Возвращает Subscriber с каналом для получения сообщений.
-func (ps *PubSub) Subscribe(topic string, subscriberID string) *Subscriber {
+func (ps *Memory) Subscribe(topic string, subscriberID string) *Subscriber {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
+	return ps.subscribe(topic, subscriberID)
+}
+
+// SubscribeSince подписывает клиента на топик и возвращает сохраненные в истории
+// сообщения топика с Seq > since в порядке публикации.
+// Подписка и снимок истории выполняются атомарно: каждое сообщение после since
+// приходит ровно один раз - либо в возвращенном списке, либо через канал.
+//
+// complete = false означает, что часть сообщений после since уже недоступна
+// (вытеснена из истории или since выдан другим экземпляром PubSub);
+// клиенту нужно перечитать состояние из хранилища.
+func (ps *Memory) SubscribeSince(topic string, subscriberID string, since uint64) (*Subscriber, []Message, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	subscriber := ps.subscribe(topic, subscriberID)
+
+	if since > ps.seq {
+		return subscriber, nil, false
+	}
+
+	history, exists := ps.history[topic]
+	if !exists {
+		// С момента последней очистки сообщений в топике не было
+		return subscriber, nil, since >= ps.prunedSeq
+	}
+
+	var missed []Message
+	for i := range history.messages {
+		msg := history.messages[(history.start+i)%len(history.messages)]
+		if msg.Seq > since {
+			missed = append(missed, msg)
+		}
+	}
+
+	return subscriber, missed, since >= history.floor
+}
+
+// subscribe создает подписчика топика.
+// Должно вызываться под мьютексом.
+func (ps *Memory) subscribe(topic string, subscriberID string) *Subscriber {
 	// Создаем топик если он не существует
 	if ps.subscribers[topic] == nil {
 		ps.subscribers[topic] = make(map[string]*Subscriber)
@@ -78,6 +290,7 @@ func (ps *PubSub) Subscribe(topic string, subscriberID string) *Subscriber {
 	subscriber := &Subscriber{
 		ID:      subscriberID,
 		Channel: make(chan Message, ps.channelBufferSize),
+		done:    make(chan struct{}),
 	}
 
 	ps.subscribers[topic][subscriberID] = subscriber
@@ -91,7 +304,7 @@ func (ps *PubSub) Subscribe(topic string, subscriberID string) *Subscriber {
 // Параметры:
 //   - topic: название топика
 //   - subscriberID: идентификатор подписчика для отписки
-func (ps *PubSub) Unsubscribe(topic string, subscriberID string) {
+func (ps *Memory) Unsubscribe(topic string, subscriberID string) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
@@ -102,7 +315,7 @@ func (ps *PubSub) Unsubscribe(topic string, subscriberID string) {
 
 	sub, subExists := topicSubs[subscriberID]
 	if subExists {
-		close(sub.Channel)
+		sub.close()
 		delete(topicSubs, subscriberID)
 	}
 
@@ -120,32 +333,189 @@ func (ps *PubSub) Unsubscribe(topic string, subscriberID string) {
 //   - topic: название топика для публикации
 //   - data: данные для отправки подписчикам
 //
-// Операция thread-safe и не блокирует при переполненных каналах.
-func (ps *PubSub) Publish(topic string, data interface{}) {
-	ps.mu.RLock()
-	defer ps.mu.RUnlock()
+// Операция thread-safe и не блокирует при переполненных каналах;
+// при политике OverflowBlock ожидание места не блокирует подписку и отписку.
+func (ps *Memory) Publish(topic string, data interface{}) {
+	ps.publishMu.Lock()
+	defer ps.publishMu.Unlock()
 
-	message := Message{
+	ps.mu.Lock()
+	ps.seq++
+	blocked := ps.deliver(Message{
 		Topic: topic,
+		Seq:   ps.seq,
 		Data:  data,
+	})
+	ps.mu.Unlock()
+
+	ps.waitBlocked(blocked)
+}
+
+// PublishErr отправляет сообщение подписчикам топика.
+// Брокера нет, поэтому ошибка всегда nil.
+func (ps *Memory) PublishErr(topic string, data interface{}) error {
+	ps.Publish(topic, data)
+	return nil
+}
+
+// publishMessage доставляет сообщение с номером, назначенным вне экземпляра
+// (например, общим счетчиком распределенного PubSub). Последующие локальные
+// публикации получают номера больше него.
+func (ps *Memory) publishMessage(message Message) {
+	ps.publishMu.Lock()
+	defer ps.publishMu.Unlock()
+
+	ps.mu.Lock()
+	if message.Seq > ps.seq {
+		ps.seq = message.Seq
 	}
+	blocked := ps.deliver(message)
+	ps.mu.Unlock()
+
+	ps.waitBlocked(blocked)
+}
+
+// blockedSend - отправка подписчику с заполненным каналом при политике OverflowBlock
+type blockedSend struct {
+	subscriber *Subscriber
+	message    Message
+}
+
+// waitBlocked ждет место в каналах подписчиков после освобождения мьютекса.
+// Следующая публикация ждет завершения под publishMu, поэтому порядок сообщений сохраняется.
+func (ps *Memory) waitBlocked(blocked []blockedSend) {
+	for _, send := range blocked {
+		send.subscriber.sendBlocking(send.message, ps.blockTimeout)
+	}
+}
+
+// deliver сохраняет сообщение в истории и отправляет его подписчикам топика.
+// Возвращает отправки, которые при политике OverflowBlock нужно дождаться без мьютекса.
+// Должно вызываться под мьютексом.
+func (ps *Memory) deliver(message Message) []blockedSend {
+	// Сохраняем сообщение в истории даже без подписчиков: клиент может переподключаться
+	ps.remember(message)
+
+	return ps.send(message)
+}
+
+// send отправляет сообщение подписчикам топика, не сохраняя его в истории.
+// Должно вызываться под мьютексом.
+func (ps *Memory) send(message Message) []blockedSend {
+	topic := message.Topic
 
 	topicSubs, exists := ps.subscribers[topic]
 	if !exists {
-		return // Топик не существует или нет подписчиков
+		return nil // Топик не существует или нет подписчиков
 	}
 
+	var blocked []blockedSend
+
 	// Отправляем сообщение всем подписчикам топика
-	for _, subscriber := range topicSubs {
+	for subscriberID, subscriber := range topicSubs {
 		// Неблокирующая отправка - защита от медленных подписчиков
 		select {
 		case subscriber.Channel <- message:
 			// Сообщение успешно отправлено
+			continue
+		default:
+		}
+
+		// Канал переполнен: поступаем согласно политике и учитываем потерянные сообщения
+		switch ps.overflowPolicy {
+		case OverflowDropOldest:
+			select {
+			case <-subscriber.Channel:
+				subscriber.dropped.Add(1)
+			default:
+				// Подписчик успел освободить место сам
+			}
+			select {
+			case subscriber.Channel <- message:
+			default:
+				subscriber.dropped.Add(1)
+			}
+		case OverflowBlock:
+			// Ожидание выполняется после освобождения мьютекса: медленный подписчик
+			// притормаживает только публикации, но не подписку и отписку
+			blocked = append(blocked, blockedSend{subscriber: subscriber, message: message})
+		case OverflowDisconnect:
+			subscriber.dropped.Add(1)
+			subscriber.disconnected.Store(true)
+			subscriber.close()
+			delete(topicSubs, subscriberID)
 		default:
-			// Канал переполнен, пропускаем сообщение
-			// В production можно добавить логирование или метрики
+			subscriber.dropped.Add(1)
+		}
+	}
+
+	if len(topicSubs) == 0 {
+		delete(ps.subscribers, topic)
+	}
+
+	return blocked
+}
+
+// CloseTopic закрывает каналы всех подписчиков топика и удаляет топик.
+// Используется, когда источник событий перестал существовать (например, пост удален):
+// подписчики видят закрытый канал и завершают обработку.
+//
+// Параметры:
+//   - topic: название топика для закрытия
+func (ps *Memory) CloseTopic(topic string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	topicSubs, exists := ps.subscribers[topic]
+	if !exists {
+		return
+	}
+
+	for _, subscriber := range topicSubs {
+		subscriber.close()
+	}
+
+	delete(ps.subscribers, topic)
+
+	// Оставляем пустую историю, чтобы повторная подписка с курсором до закрытия
+	// считалась неполной; она будет удалена вместе с историей неактивных топиков
+	if _, exists := ps.history[topic]; exists {
+		ps.history[topic] = &topicHistory{floor: ps.seq, updatedAt: time.Now()}
+	}
+}
+
+// remember добавляет сообщение в историю топика и удаляет устаревшую историю других топиков.
+// Должно вызываться под мьютексом.
+func (ps *Memory) remember(message Message) {
+	now := time.Now()
+
+	history, exists := ps.history[message.Topic]
+	if !exists {
+		// Сообщения, опубликованные до последней очистки, могли быть удалены вместе с историей
+		history = &topicHistory{floor: ps.prunedSeq}
+		ps.history[message.Topic] = history
+	}
+	history.updatedAt = now
+
+	if len(history.messages) < ps.historySize {
+		history.messages = append(history.messages, message)
+	} else {
+		history.floor = history.messages[history.start].Seq
+		history.messages[history.start] = message
+		history.start = (history.start + 1) % len(history.messages)
+	}
+
+	// Очищаем историю неактивных топиков не чаще раза в historyTTL
+	if now.Sub(ps.prunedAt) < ps.historyTTL {
+		return
+	}
+	for topic, h := range ps.history {
+		if now.Sub(h.updatedAt) >= ps.historyTTL && len(ps.subscribers[topic]) == 0 {
+			delete(ps.history, topic)
 		}
 	}
+	ps.prunedSeq = ps.seq
+	ps.prunedAt = now
 }
 
 // GetSubscribersCount возвращает количество подписчиков на топик.
@@ -155,7 +525,7 @@ func (ps *PubSub) Publish(topic string, data interface{}) {
 //   - topic: название топика
 //
 // Возвращает количество активных подписчиков.
-func (ps *PubSub) GetSubscribersCount(topic string) int {
+func (ps *Memory) GetSubscribersCount(topic string) int {
 	ps.mu.RLock()
 	defer ps.mu.RUnlock()
 
@@ -168,17 +538,19 @@ func (ps *PubSub) GetSubscribersCount(topic string) int {
 
 // Close закрывает все каналы подписчиков и очищает внутренние структуры.
 // Используется при завершении работы приложения для корректной очистки ресурсов.
-func (ps *PubSub) Close() {
+func (ps *Memory) Close() {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
 	// Закрываем все каналы подписчиков
 	for _, topicSubs := range ps.subscribers {
 		for _, subscriber := range topicSubs {
-			close(subscriber.Channel)
+			subscriber.close()
 		}
 	}
 
 	// Очищаем все структуры данных
 	ps.subscribers = make(map[string]map[string]*Subscriber)
+	ps.history = make(map[string]*topicHistory)
+	ps.prunedSeq = ps.seq
 }
@@ -275,6 +275,38 @@ func TestPubSub_Close(t *testing.T) {
 	}
 }
 
+func TestPubSub_CloseTopic(t *testing.T) {
+	ps := NewWithConfig(10)
+
+	closed := ps.Subscribe("closed-topic", "subscriber-1")
+	other := ps.Subscribe("other-topic", "subscriber-2")
+
+	ps.CloseTopic("closed-topic")
+
+	if _, ok := <-closed.Channel; ok {
+		t.Error("Channel of closed topic should be closed")
+	}
+
+	if count := ps.GetSubscribersCount("closed-topic"); count != 0 {
+		t.Errorf("Expected 0 subscribers after CloseTopic, got %d", count)
+	}
+
+	// Другие топики не затрагиваются
+	ps.Publish("other-topic", "still alive")
+	select {
+	case msg := <-other.Channel:
+		if msg.Data != "still alive" {
+			t.Errorf("Unexpected message data: %v", msg.Data)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Error("Other topic should keep receiving messages")
+	}
+
+	// Повторное закрытие и отписка после закрытия не должны паниковать
+	ps.CloseTopic("closed-topic")
+	ps.Unsubscribe("closed-topic", "subscriber-1")
+}
+
 func TestPubSub_ThreadSafety(t *testing.T) {
 	ps := NewWithConfig(100)
 	topic := "test-topic"
@@ -392,6 +424,275 @@ func TestPubSub_TopicCleanup(t *testing.T) {
 	}
 }
 
+func TestPubSub_SubscribeSince(t *testing.T) {
+	ps := NewWithOptions(Config{ChannelBufferSize: 10, HistorySize: 10})
+	topic := "test-topic"
+
+	// История хранится и без подписчиков
+	ps.Publish(topic, "first")
+	ps.Publish("other-topic", "other")
+	ps.Publish(topic, "second")
+
+	subscriber, missed, complete := ps.SubscribeSince(topic, "subscriber-1", 0)
+	if !complete {
+		t.Fatal("Expected complete replay from the beginning")
+	}
+	if len(missed) != 2 || missed[0].Data != "first" || missed[1].Data != "second" {
+		t.Fatalf("Expected [first second], got %+v", missed)
+	}
+	if missed[0].Seq >= missed[1].Seq {
+		t.Errorf("Expected increasing sequence numbers, got %d and %d", missed[0].Seq, missed[1].Seq)
+	}
+
+	// Сообщения после подписки приходят только через канал
+	ps.Publish(topic, "third")
+	select {
+	case msg := <-subscriber.Channel:
+		if msg.Data != "third" || msg.Seq <= missed[1].Seq {
+			t.Errorf("Expected live message after replay, got %+v", msg)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Live message was not delivered")
+	}
+	ps.Unsubscribe(topic, "subscriber-1")
+
+	// Повторная подписка с последнего полученного номера
+	_, missed, complete = ps.SubscribeSince(topic, "subscriber-2", missed[0].Seq)
+	if !complete || len(missed) != 2 || missed[0].Data != "second" || missed[1].Data != "third" {
+		t.Errorf("Expected complete replay [second third], got %+v (complete=%v)", missed, complete)
+	}
+
+	// Номер из будущего выдан другим экземпляром PubSub
+	if _, _, complete := ps.SubscribeSince(topic, "subscriber-3", 1000); complete {
+		t.Error("Expected incomplete replay for unknown sequence number")
+	}
+}
+
+func TestPubSub_SubscribeSince_Evicted(t *testing.T) {
+	ps := NewWithOptions(Config{HistorySize: 2})
+	topic := "test-topic"
+
+	for i := 1; i <= 3; i++ {
+		ps.Publish(topic, i)
+	}
+
+	// Первое сообщение вытеснено из истории
+	_, missed, complete := ps.SubscribeSince(topic, "subscriber-1", 0)
+	if complete {
+		t.Error("Expected incomplete replay after eviction")
+	}
+	if len(missed) != 2 || missed[0].Data != 2 || missed[1].Data != 3 {
+		t.Errorf("Expected retained messages [2 3], got %+v", missed)
+	}
+
+	_, missed, complete = ps.SubscribeSince(topic, "subscriber-2", 1)
+	if !complete || len(missed) != 2 {
+		t.Errorf("Expected complete replay of 2 messages, got %+v (complete=%v)", missed, complete)
+	}
+
+	// После закрытия топика его история недоступна
+	ps.CloseTopic(topic)
+	if _, _, complete := ps.SubscribeSince(topic, "subscriber-3", 1); complete {
+		t.Error("Expected incomplete replay after topic was closed")
+	}
+}
+
+func TestPubSub_HistoryTTL(t *testing.T) {
+	ps := NewWithOptions(Config{HistoryTTL: 20 * time.Millisecond})
+
+	ps.Publish("idle-topic", "old")
+	ps.Subscribe("active-topic", "subscriber-1")
+	ps.Publish("active-topic", "old")
+
+	time.Sleep(30 * time.Millisecond)
+	ps.Publish("new-topic", "new")
+
+	ps.mu.RLock()
+	_, idleExists := ps.history["idle-topic"]
+	_, activeExists := ps.history["active-topic"]
+	ps.mu.RUnlock()
+
+	if idleExists {
+		t.Error("History of idle topic without subscribers should be pruned")
+	}
+	if !activeExists {
+		t.Error("History of topic with subscribers should be kept")
+	}
+
+	// Удаленная история делает повторную доставку неполной, а начатая после очистки - полной
+	if _, _, complete := ps.SubscribeSince("idle-topic", "subscriber-2", 0); complete {
+		t.Error("Expected incomplete replay for pruned topic")
+	}
+	if _, missed, complete := ps.SubscribeSince("new-topic", "subscriber-3", 2); !complete || len(missed) != 1 {
+		t.Errorf("Expected complete replay of new topic, got %+v (complete=%v)", missed, complete)
+	}
+}
+
+func TestPubSub_OverflowPolicies(t *testing.T) {
+	// drain читает все сообщения из буфера канала
+	drain := func(subscriber *Subscriber) []interface{} {
+		var data []interface{}
+		for {
+			select {
+			case msg, ok := <-subscriber.Channel:
+				if !ok {
+					return data
+				}
+				data = append(data, msg.Data)
+			default:
+				return data
+			}
+		}
+	}
+
+	tests := []struct {
+		name         string
+		policy       OverflowPolicy
+		expected     []interface{}
+		disconnected bool
+	}{
+		{
+			name:     "отбросить новые",
+			policy:   OverflowDropNewest,
+			expected: []interface{}{1, 2},
+		},
+		{
+			name:     "отбросить старые",
+			policy:   OverflowDropOldest,
+			expected: []interface{}{3, 4},
+		},
+		{
+			name:     "ожидание с таймаутом",
+			policy:   OverflowBlock,
+			expected: []interface{}{1, 2},
+		},
+		{
+			name:         "отключение подписчика",
+			policy:       OverflowDisconnect,
+			expected:     []interface{}{1, 2},
+			disconnected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := NewWithOptions(Config{
+				ChannelBufferSize: 2,
+				OverflowPolicy:    tt.policy,
+				BlockTimeout:      time.Millisecond,
+			})
+			topic := "test-topic"
+			subscriber := ps.Subscribe(topic, "subscriber-1")
+
+			for i := 1; i <= 4; i++ {
+				ps.Publish(topic, i)
+			}
+
+			received := drain(subscriber)
+			if fmt.Sprint(received) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected messages %v, got %v", tt.expected, received)
+			}
+			if subscriber.Disconnected() != tt.disconnected {
+				t.Errorf("Expected disconnected=%v, got %v", tt.disconnected, subscriber.Disconnected())
+			}
+
+			// Отключенный подписчик теряет только сообщение, вызвавшее переполнение
+			expectedDropped := uint64(2)
+			if tt.disconnected {
+				expectedDropped = 1
+				if count := ps.GetSubscribersCount(topic); count != 0 {
+					t.Errorf("Expected disconnected subscriber to be removed, got %d subscribers", count)
+				}
+			}
+			if subscriber.Dropped() != expectedDropped {
+				t.Errorf("Expected %d dropped messages, got %d", expectedDropped, subscriber.Dropped())
+			}
+		})
+	}
+}
+
+func TestPubSub_OverflowBlock_Delivers(t *testing.T) {
+	ps := NewWithOptions(Config{
+		ChannelBufferSize: 1,
+		OverflowPolicy:    OverflowBlock,
+		BlockTimeout:      time.Second,
+	})
+	topic := "test-topic"
+	subscriber := ps.Subscribe(topic, "subscriber-1")
+
+	// Подписчик успевает освободить место, пока публикация ждет
+	received := make(chan int, 2)
+	go func() {
+		for i := 0; i < 2; i++ {
+			msg := <-subscriber.Channel
+			received <- msg.Data.(int)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	ps.Publish(topic, 1)
+	ps.Publish(topic, 2)
+	ps.Publish(topic, 3)
+
+	if subscriber.Dropped() != 0 {
+		t.Errorf("Expected no dropped messages, got %d", subscriber.Dropped())
+	}
+	if first, second := <-received, <-received; first != 1 || second != 2 {
+		t.Errorf("Expected messages 1 and 2, got %d and %d", first, second)
+	}
+}
+
+func TestPubSub_OverflowBlock_DoesNotBlockSubscriptions(t *testing.T) {
+	ps := NewWithOptions(Config{
+		ChannelBufferSize: 1,
+		OverflowPolicy:    OverflowBlock,
+		BlockTimeout:      time.Minute,
+	})
+	topic := "test-topic"
+	slow := ps.Subscribe(topic, "slow")
+	ps.Publish(topic, 1)
+
+	// Публикация ждет место в заполненном канале медленного подписчика
+	published := make(chan struct{})
+	go func() {
+		ps.Publish(topic, 2)
+		close(published)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// Подписка, отписка и подсчет подписчиков других топиков не ждут публикацию
+	done := make(chan struct{})
+	go func() {
+		ps.Subscribe("other-topic", "fast")
+		ps.GetSubscribersCount(topic)
+		ps.Unsubscribe("other-topic", "fast")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Subscribe blocked while publish waits for a slow subscriber")
+	}
+
+	// Отписка медленного подписчика прерывает ожидание, не дожидаясь BlockTimeout
+	ps.Unsubscribe(topic, "slow")
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("Publish kept waiting after the subscriber was removed")
+	}
+	var received []interface{}
+	for msg := range slow.Channel {
+		received = append(received, msg.Data)
+	}
+	if fmt.Sprint(received) != "[1]" {
+		t.Errorf("Expected only the first message, got %v", received)
+	}
+	if slow.Dropped() != 0 {
+		t.Errorf("Expected message for removed subscriber not to count as dropped, got %d", slow.Dropped())
+	}
+}
+
 // Benchmark тесты для производительности
 func BenchmarkPubSub_Subscribe(b *testing.B) {
 	ps := NewWithConfig(100)
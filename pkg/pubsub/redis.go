@@ -0,0 +1,387 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Значения конфигурации Redis по умолчанию
+const (
+	// DefaultRedisChannel - канал Redis, через который экземпляры приложения обмениваются сообщениями
+	DefaultRedisChannel = "commentsystem:events"
+
+	// DefaultRedisTimeout - таймаут подключения и выполнения одной команды Redis
+	DefaultRedisTimeout = 5 * time.Second
+)
+
+// errRedisClosed возвращается командами после вызова Close
+var errRedisClosed = errors.New("redis pubsub is closed")
+
+// publishScript атомарно выдает номер сообщения и публикует его в канал:
+// между INCR и PUBLISH не вклинится другой издатель, поэтому все экземпляры
+// получают сообщения в порядке номеров. Сообщение собирается в формате envelope.
+// KEYS[1] - ключ счетчика, ARGV[1] - канал, ARGV[2] - топик в JSON, ARGV[3] - данные в JSON.
+const publishScript = `local seq = redis.call('INCR', KEYS[1])
+redis.call('PUBLISH', ARGV[1], '{"topic":' .. ARGV[2] .. ',"seq":' .. string.format('%d', seq) .. ',"data":' .. ARGV[3] .. '}')
+return seq`
+
+// RedisConfig содержит параметры распределенного PubSub
+type RedisConfig struct {
+	Addr     string        // Адрес сервера Redis (host:port)
+	Password string        // Пароль (AUTH); пустой - без аутентификации
+	DB       int           // Номер базы данных (SELECT); влияет только на ключ счетчика сообщений
+	Channel  string        // Канал Redis для сообщений всех топиков
+	Timeout  time.Duration // Таймаут подключения и выполнения одной команды
+	Codec    Codec         // Сериализация данных сообщений (обязательный)
+	Local    Config        // Параметры доставки подписчикам этого экземпляра
+	OnError  func(error)   // Вызывается при ошибках связи с Redis и сериализации; nil - ошибки игнорируются
+}
+
+// Redis - распределенный PubSub поверх Redis PUBLISH/SUBSCRIBE.
+//
+// Все топики передаются через один канал Redis: каждый экземпляр приложения
+// подписан на него и доставляет полученные сообщения своим подписчикам через
+// встроенный Memory, поэтому политики переполнения и история топиков работают так же.
+// Номера сообщений выдает общий счетчик Redis (INCR) в одном скрипте с PUBLISH,
+// поэтому курсоры повторной доставки, полученные на одном экземпляре, понятны остальным,
+// а сообщения приходят в порядке номеров. После переподключения подписки история
+// считается неполной до текущего значения счетчика: сообщения, опубликованные
+// во время обрыва, этот экземпляр не получил.
+//
+// Сообщения, опубликованные этим экземпляром, доставляются локальным подписчикам
+// после возврата из Redis. Если Redis недоступен, сообщение доставляется только
+// подписчикам этого экземпляра, а ошибка передается в OnError.
+type Redis struct {
+	local  *Memory
+	cfg    RedisConfig
+	seqKey string // Ключ счетчика номеров сообщений
+
+	cmdMu sync.Mutex
+	cmd   *respConn // Соединение для команд (EVAL, PUBLISH, GET); nil после ошибки связи
+
+	subMu sync.Mutex
+	sub   *respConn // Соединение подписки на канал
+
+	closed    chan struct{}
+	done      chan struct{} // Закрывается при завершении чтения подписки
+	closeOnce sync.Once
+}
+
+// NewRedis подключается к Redis и подписывается на канал сообщений.
+// Возвращает ошибку, если Redis недоступен: экземпляр без связи с остальными
+// не должен молча принимать подписки.
+func NewRedis(cfg RedisConfig) (*Redis, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("redis address is required")
+	}
+	if cfg.Codec == nil {
+		return nil, errors.New("redis pubsub codec is required")
+	}
+	if cfg.Channel == "" {
+		cfg.Channel = DefaultRedisChannel
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultRedisTimeout
+	}
+
+	r := &Redis{
+		local:  NewWithOptions(cfg.Local),
+		cfg:    cfg,
+		seqKey: cfg.Channel + ":seq",
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	cmd, err := r.dial()
+	if err != nil {
+		return nil, err
+	}
+	r.cmd = cmd
+
+	sub, err := r.subscribe()
+	if err != nil {
+		cmd.Close()
+		return nil, err
+	}
+	r.sub = sub
+
+	go r.receive(sub)
+
+	return r, nil
+}
+
+// Subscribe подписывает клиента на топик на этом экземпляре
+func (r *Redis) Subscribe(topic string, subscriberID string) *Subscriber {
+	return r.local.Subscribe(topic, subscriberID)
+}
+
+// SubscribeSince подписывает клиента на топик и возвращает сообщения из истории этого экземпляра.
+// История содержит все сообщения, полученные из Redis, независимо от экземпляра-издателя.
+func (r *Redis) SubscribeSince(topic string, subscriberID string, since uint64) (*Subscriber, []Message, bool) {
+	return r.local.SubscribeSince(topic, subscriberID, since)
+}
+
+// Unsubscribe отписывает клиента от топика
+func (r *Redis) Unsubscribe(topic string, subscriberID string) {
+	r.local.Unsubscribe(topic, subscriberID)
+}
+
+// GetSubscribersCount возвращает количество подписчиков топика на этом экземпляре
+func (r *Redis) GetSubscribersCount(topic string) int {
+	return r.local.GetSubscribersCount(topic)
+}
+
+// Publish отправляет сообщение подписчикам топика на всех экземплярах.
+// Если Redis недоступен, сообщение получают только подписчики этого экземпляра.
+func (r *Redis) Publish(topic string, data interface{}) {
+	if err := r.PublishErr(topic, data); err != nil {
+		r.fail(err)
+		// Подписчики этого экземпляра получают сообщение и без Redis
+		r.local.publishLocal(topic, data)
+	}
+}
+
+// PublishErr отправляет сообщение подписчикам топика на всех экземплярах.
+// Сообщение возвращается и этому экземпляру через канал Redis.
+func (r *Redis) PublishErr(topic string, data interface{}) error {
+	payload, err := r.cfg.Codec.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode message for topic %s: %w", topic, err)
+	}
+
+	topicJSON, err := json.Marshal(topic)
+	if err != nil {
+		return fmt.Errorf("failed to encode topic %s: %w", topic, err)
+	}
+
+	reply, err := r.command("EVAL", publishScript, "1", r.seqKey, r.cfg.Channel, string(topicJSON), string(payload))
+	if seq, ok := reply.(int64); err != nil || !ok || seq <= 0 {
+		if err == nil {
+			err = errUnexpectedReply
+		}
+		return fmt.Errorf("failed to publish message for topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+// CloseTopic закрывает топик на всех экземплярах.
+// На этом экземпляре топик закрывается сразу, не дожидаясь возврата сообщения из Redis.
+func (r *Redis) CloseTopic(topic string) {
+	r.local.CloseTopic(topic)
+
+	if err := r.publish(envelope{Topic: topic, Close: true}); err != nil {
+		r.fail(fmt.Errorf("failed to close topic %s: %w", topic, err))
+	}
+}
+
+// Close отписывается от канала Redis, закрывает соединения и каналы локальных подписчиков
+func (r *Redis) Close() {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+
+		r.subMu.Lock()
+		r.sub.Close()
+		r.subMu.Unlock()
+		<-r.done
+
+		r.cmdMu.Lock()
+		if r.cmd != nil {
+			r.cmd.Close()
+			r.cmd = nil
+		}
+		r.cmdMu.Unlock()
+
+		r.local.Close()
+	})
+}
+
+// publish отправляет сообщение в канал Redis
+func (r *Redis) publish(message envelope) error {
+	raw, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = r.command("PUBLISH", r.cfg.Channel, string(raw))
+	return err
+}
+
+// command выполняет команду в соединении для команд, переподключаясь после ошибок связи
+func (r *Redis) command(args ...string) (interface{}, error) {
+	r.cmdMu.Lock()
+	defer r.cmdMu.Unlock()
+
+	if r.isClosed() {
+		return nil, errRedisClosed
+	}
+
+	if r.cmd == nil {
+		conn, err := r.dial()
+		if err != nil {
+			return nil, err
+		}
+		r.cmd = conn
+	}
+
+	reply, err := r.cmd.do(args...)
+	if err != nil {
+		var replyErr respError
+		if !errors.As(err, &replyErr) {
+			// После ошибки связи состояние соединения неизвестно
+			r.cmd.Close()
+			r.cmd = nil
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+// currentSeq возвращает номер последнего сообщения, выданного общим счетчиком
+func (r *Redis) currentSeq() (uint64, error) {
+	reply, err := r.command("GET", r.seqKey)
+	if err != nil {
+		return 0, err
+	}
+	raw, ok := reply.([]byte)
+	if !ok {
+		return 0, errUnexpectedReply
+	}
+	if raw == nil {
+		return 0, nil // Сообщений еще не было
+	}
+	return strconv.ParseUint(string(raw), 10, 64)
+}
+
+// dial открывает новое соединение с Redis
+func (r *Redis) dial() (*respConn, error) {
+	return dialRESP(r.cfg.Addr, r.cfg.Password, r.cfg.DB, r.cfg.Timeout)
+}
+
+// subscribe открывает соединение, подписанное на канал сообщений
+func (r *Redis) subscribe() (*respConn, error) {
+	conn, err := r.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do("SUBSCRIBE", r.cfg.Channel)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", r.cfg.Channel, err)
+	}
+	if items, ok := reply.([]interface{}); !ok || len(items) != 3 {
+		conn.Close()
+		return nil, errUnexpectedReply
+	} else if kind, _ := replyString(items[0]); kind != "subscribe" {
+		conn.Close()
+		return nil, errUnexpectedReply
+	}
+
+	return conn, nil
+}
+
+// receive доставляет сообщения канала локальным подписчикам.
+// При обрыве соединения переподключается, пока не вызван Close;
+// сообщения, опубликованные во время переподключения, этот экземпляр не получит,
+// поэтому resubscribe отмечает их как пропуск в истории.
+func (r *Redis) receive(conn *respConn) {
+	defer close(r.done)
+
+	for {
+		err := r.readMessages(conn)
+		conn.Close()
+		if r.isClosed() {
+			return
+		}
+		r.fail(fmt.Errorf("redis subscription lost: %w", err))
+
+		conn = r.resubscribe()
+		if conn == nil {
+			return
+		}
+	}
+}
+
+// resubscribe переподключает подписку с нарастающей паузой и поднимает нижнюю
+// границу истории всех топиков до текущего номера сообщения.
+// Возвращает nil, если PubSub закрыт.
+func (r *Redis) resubscribe() *respConn {
+	backoff := reconnectMinBackoff
+	for {
+		select {
+		case <-r.closed:
+			return nil
+		case <-time.After(backoff):
+		}
+
+		conn, err := r.subscribe()
+		if err != nil {
+			r.fail(err)
+			backoff = min(backoff*2, reconnectMaxBackoff)
+			continue
+		}
+
+		// Счетчик читается после SUBSCRIBE: сообщения с большими номерами придут по новой подписке
+		seq, err := r.currentSeq()
+		if err != nil {
+			conn.Close()
+			r.fail(fmt.Errorf("failed to get message number after reconnect: %w", err))
+			backoff = min(backoff*2, reconnectMaxBackoff)
+			continue
+		}
+		r.local.markGap(seq)
+
+		r.subMu.Lock()
+		defer r.subMu.Unlock()
+		if r.isClosed() {
+			conn.Close()
+			return nil
+		}
+		r.sub = conn
+		return conn
+	}
+}
+
+// readMessages читает сообщения подписки до ошибки соединения
+func (r *Redis) readMessages(conn *respConn) error {
+	for {
+		reply, err := conn.receiveBlocking()
+		if err != nil {
+			return err
+		}
+
+		// Сообщение канала: ["message", channel, payload]
+		items, ok := reply.([]interface{})
+		if !ok || len(items) != 3 {
+			continue
+		}
+		if kind, _ := replyString(items[0]); kind != "message" {
+			continue
+		}
+		if payload, ok := items[2].([]byte); ok {
+			if err := r.local.deliverEnvelope(r.cfg.Codec, payload); err != nil {
+				r.fail(err)
+			}
+		}
+	}
+}
+
+// isClosed сообщает, вызван ли Close
+func (r *Redis) isClosed() bool {
+	select {
+	case <-r.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// fail передает ошибку обработчику OnError
+func (r *Redis) fail(err error) {
+	if r.cfg.OnError != nil {
+		r.cfg.OnError(err)
+	}
+}
@@ -0,0 +1,491 @@
+package pubsub
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stringCodec сериализует строковые сообщения тестов
+type stringCodec struct{}
+
+func (stringCodec) Marshal(data interface{}) ([]byte, error) {
+	s, ok := data.(string)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %T", data)
+	}
+	return json.Marshal(s)
+}
+
+func (stringCodec) Unmarshal(raw []byte) (interface{}, error) {
+	var s string
+	err := json.Unmarshal(raw, &s)
+	return s, err
+}
+
+// fakeRedis - сервер, говорящий на RESP и поддерживающий команды, которые использует Redis PubSub
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	counters map[string]int64
+	conns    map[*fakeRedisConn]bool
+
+	evalMu sync.Mutex // EVAL выполняется атомарно, как в Redis
+}
+
+// fakeRedisConn - клиентское соединение fakeRedis
+type fakeRedisConn struct {
+	conn       net.Conn
+	writeMu    sync.Mutex
+	subscribed map[string]bool
+	authorized bool
+}
+
+// newFakeRedis запускает fakeRedis на свободном порту; сервер останавливается в конце теста
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	s := &fakeRedis{
+		listener: listener,
+		password: password,
+		counters: make(map[string]int64),
+		conns:    make(map[*fakeRedisConn]bool),
+	}
+	go s.serve()
+	t.Cleanup(func() {
+		listener.Close()
+		s.dropConnections()
+	})
+
+	return s
+}
+
+func (s *fakeRedis) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &fakeRedisConn{conn: conn, subscribed: make(map[string]bool), authorized: s.password == ""}
+		s.mu.Lock()
+		s.conns[c] = true
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+// dropConnections разрывает все клиентские соединения, имитируя перезапуск Redis
+func (s *fakeRedis) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.conn.Close()
+		delete(s.conns, c)
+	}
+}
+
+func (s *fakeRedis) handle(c *fakeRedisConn) {
+	defer func() {
+		c.conn.Close()
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		request, err := readRESP(reader)
+		if err != nil {
+			return
+		}
+		items, _ := request.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = replyString(item)
+		}
+		if len(args) == 0 {
+			c.write("-ERR empty command\r\n")
+			continue
+		}
+
+		switch command := args[0]; {
+		case command == "AUTH":
+			if len(args) == 2 && args[1] == s.password {
+				c.authorized = true
+				c.write("+OK\r\n")
+			} else {
+				c.write("-WRONGPASS invalid password\r\n")
+			}
+		case !c.authorized:
+			c.write("-NOAUTH Authentication required.\r\n")
+		case command == "SELECT" || command == "PING":
+			c.write("+OK\r\n")
+		case command == "SUBSCRIBE" && len(args) == 2:
+			s.mu.Lock()
+			c.subscribed[args[1]] = true
+			s.mu.Unlock()
+			c.write(fmt.Sprintf("*3\r\n$9\r\nsubscribe\r\n%s:1\r\n", bulk(args[1])))
+		case command == "GET" && len(args) == 2:
+			s.mu.Lock()
+			value, exists := s.counters[args[1]]
+			s.mu.Unlock()
+			if !exists {
+				c.write("$-1\r\n")
+			} else {
+				c.write(bulk(strconv.FormatInt(value, 10)))
+			}
+		case command == "EVAL" && len(args) == 7 && args[1] == publishScript && args[2] == "1":
+			// publishScript: INCR KEYS[1] и PUBLISH envelope в ARGV[1]
+			s.evalMu.Lock()
+			s.mu.Lock()
+			s.counters[args[3]]++
+			value := s.counters[args[3]]
+			s.mu.Unlock()
+			s.publish(args[4], fmt.Sprintf(`{"topic":%s,"seq":%d,"data":%s}`, args[5], value, args[6]))
+			s.evalMu.Unlock()
+			c.write(":" + strconv.FormatInt(value, 10) + "\r\n")
+		case command == "PUBLISH" && len(args) == 3:
+			c.write(":" + strconv.Itoa(s.publish(args[1], args[2])) + "\r\n")
+		default:
+			c.write("-ERR unknown command\r\n")
+		}
+	}
+}
+
+// publish рассылает сообщение подписчикам канала и возвращает их количество
+func (s *fakeRedis) publish(channel, payload string) int {
+	s.mu.Lock()
+	var receivers []*fakeRedisConn
+	for c := range s.conns {
+		if c.subscribed[channel] {
+			receivers = append(receivers, c)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, c := range receivers {
+		c.write(fmt.Sprintf("*3\r\n$7\r\nmessage\r\n%s%s", bulk(channel), bulk(payload)))
+	}
+	return len(receivers)
+}
+
+func (c *fakeRedisConn) write(reply string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.Write([]byte(reply))
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+// newTestRedis создает Redis PubSub, который закрывается в конце теста
+func newTestRedis(t *testing.T, cfg RedisConfig) *Redis {
+	t.Helper()
+
+	if cfg.Codec == nil {
+		cfg.Codec = stringCodec{}
+	}
+	ps, err := NewRedis(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create redis pubsub: %v", err)
+	}
+	t.Cleanup(ps.Close)
+
+	return ps
+}
+
+// receiveMessage ждет сообщение подписчика
+func receiveMessage(t *testing.T, subscriber *Subscriber) Message {
+	t.Helper()
+
+	select {
+	case msg, ok := <-subscriber.Channel:
+		if !ok {
+			t.Fatal("Subscriber channel was closed")
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("Message was not delivered")
+		return Message{}
+	}
+}
+
+func TestRedis_DeliversAcrossInstances(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	cfg := RedisConfig{Addr: server.addr(), Password: "secret", DB: 1}
+	podA := newTestRedis(t, cfg)
+	podB := newTestRedis(t, cfg)
+	topic := "post:1:comments"
+
+	subscriberA := podA.Subscribe(topic, "subscriber-a")
+	subscriberB := podB.Subscribe(topic, "subscriber-b")
+
+	podA.Publish(topic, "first")
+	podB.Publish(topic, "second")
+
+	// Оба экземпляра получают сообщения друг друга и свои собственные с общими номерами
+	for _, subscriber := range []*Subscriber{subscriberA, subscriberB} {
+		first := receiveMessage(t, subscriber)
+		second := receiveMessage(t, subscriber)
+		if first.Data != "first" || second.Data != "second" {
+			t.Errorf("Expected [first second], got [%v %v]", first.Data, second.Data)
+		}
+		if first.Seq != 1 || second.Seq != 2 {
+			t.Errorf("Expected shared sequence numbers 1 and 2, got %d and %d", first.Seq, second.Seq)
+		}
+		if first.Topic != topic {
+			t.Errorf("Expected topic %s, got %s", topic, first.Topic)
+		}
+	}
+
+	if count := podB.GetSubscribersCount(topic); count != 1 {
+		t.Errorf("Expected 1 local subscriber, got %d", count)
+	}
+}
+
+func TestRedis_ResumeOnAnotherInstance(t *testing.T) {
+	server := newFakeRedis(t, "")
+	podA := newTestRedis(t, RedisConfig{Addr: server.addr()})
+	podB := newTestRedis(t, RedisConfig{Addr: server.addr()})
+	topic := "post:1:comments"
+
+	// Клиент получил первое сообщение на экземпляре A и переподключился к B
+	subscriber := podA.Subscribe(topic, "subscriber-1")
+	for _, data := range []string{"first", "second", "third"} {
+		podA.Publish(topic, data)
+	}
+	first := receiveMessage(t, subscriber)
+	receiveMessage(t, subscriber)
+	receiveMessage(t, subscriber)
+
+	// Экземпляр B получает сообщения асинхронно
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, missed, complete := podB.SubscribeSince(topic, "subscriber-2", first.Seq)
+		podB.Unsubscribe(topic, "subscriber-2")
+		if complete && len(missed) == 2 {
+			if missed[0].Data != "second" || missed[1].Data != "third" {
+				t.Errorf("Expected [second third], got %+v", missed)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected complete replay of 2 messages, got %+v (complete=%v)", missed, complete)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRedis_CloseTopic(t *testing.T) {
+	server := newFakeRedis(t, "")
+	podA := newTestRedis(t, RedisConfig{Addr: server.addr()})
+	podB := newTestRedis(t, RedisConfig{Addr: server.addr()})
+	topic := "post:1:comments"
+
+	subscriberA := podA.Subscribe(topic, "subscriber-a")
+	subscriberB := podB.Subscribe(topic, "subscriber-b")
+
+	podA.CloseTopic(topic)
+
+	for _, subscriber := range []*Subscriber{subscriberA, subscriberB} {
+		select {
+		case _, ok := <-subscriber.Channel:
+			if ok {
+				t.Error("Expected closed channel, got message")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Topic was not closed on every instance")
+		}
+	}
+}
+
+func TestRedis_Reconnect(t *testing.T) {
+	server := newFakeRedis(t, "")
+	var errorsCount atomic.Int64
+	cfg := RedisConfig{
+		Addr:    server.addr(),
+		OnError: func(error) { errorsCount.Add(1) },
+	}
+	podA := newTestRedis(t, cfg)
+	podB := newTestRedis(t, cfg)
+	topic := "post:1:comments"
+	subscriber := podB.Subscribe(topic, "subscriber-1")
+
+	server.dropConnections()
+
+	// После перезапуска Redis оба экземпляра переподключаются сами
+	deadline := time.After(3 * time.Second)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg := <-subscriber.Channel:
+			if msg.Data != "after reconnect" {
+				t.Errorf("Unexpected message %+v", msg)
+			}
+			if errorsCount.Load() == 0 {
+				t.Error("Expected connection errors to be reported")
+			}
+			return
+		case <-ticker.C:
+			podA.Publish(topic, "after reconnect")
+		case <-deadline:
+			t.Fatal("Message was not delivered after reconnect")
+		}
+	}
+}
+
+func TestRedis_ReconnectMarksGap(t *testing.T) {
+	server := newFakeRedis(t, "")
+	podA := newTestRedis(t, RedisConfig{Addr: server.addr()})
+	podB := newTestRedis(t, RedisConfig{Addr: server.addr()})
+	topic := "post:1:comments"
+	subscriber := podB.Subscribe(topic, "subscriber-1")
+
+	podA.Publish(topic, "before")
+	before := receiveMessage(t, subscriber)
+
+	// Сообщение попадает в Redis, пока подписка B переподключается, и до B не доходит.
+	// Первая публикация после обрыва может уйти только локально: A тоже переподключается
+	server.dropConnections()
+	counter := func() uint64 {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return uint64(server.counters[DefaultRedisChannel+":seq"])
+	}
+	for counter() <= before.Seq {
+		podA.Publish(topic, "lost")
+	}
+
+	// Ждем, пока B переподключится и начнет получать новые сообщения
+	deadline := time.After(3 * time.Second)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for reconnected := false; !reconnected; {
+		select {
+		case msg := <-subscriber.Channel:
+			reconnected = msg.Data == "after reconnect"
+		case <-ticker.C:
+			podA.Publish(topic, "after reconnect")
+		case <-deadline:
+			t.Fatal("Message was not delivered after reconnect")
+		}
+	}
+
+	// Курсор до обрыва не может быть продолжен без пропуска
+	if _, _, complete := podB.SubscribeSince(topic, "subscriber-2", before.Seq); complete {
+		t.Error("Expected resume across the outage to be incomplete")
+	}
+}
+
+func TestRedis_PublishWithoutRedis(t *testing.T) {
+	server := newFakeRedis(t, "")
+	var errorsCount atomic.Int64
+	ps := newTestRedis(t, RedisConfig{
+		Addr:    server.addr(),
+		OnError: func(error) { errorsCount.Add(1) },
+	})
+	topic := "post:1:comments"
+	subscriber := ps.Subscribe(topic, "subscriber-1")
+
+	// Redis недоступен: локальные подписчики все равно получают сообщение
+	server.listener.Close()
+	server.dropConnections()
+	ps.Publish(topic, "local only")
+
+	msg := receiveMessage(t, subscriber)
+	if msg.Data != "local only" {
+		t.Errorf("Expected local delivery, got %+v", msg)
+	}
+	if errorsCount.Load() == 0 {
+		t.Error("Expected publish error to be reported")
+	}
+
+	// Счетчик Redis номер не выдавал: сообщение не занимает позицию в последовательности
+	// и не попадает в историю
+	if msg.Seq != 0 {
+		t.Errorf("Expected fallback message to keep the last known sequence number, got %d", msg.Seq)
+	}
+	if _, missed, _ := ps.SubscribeSince(topic, "subscriber-2", 0); len(missed) != 0 {
+		t.Errorf("Expected fallback message to stay out of history, got %+v", missed)
+	}
+}
+
+func TestNewRedis_Errors(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+
+	tests := []struct {
+		name string
+		cfg  RedisConfig
+	}{
+		{
+			name: "без адреса",
+			cfg:  RedisConfig{Codec: stringCodec{}},
+		},
+		{
+			name: "без кодека",
+			cfg:  RedisConfig{Addr: server.addr()},
+		},
+		{
+			name: "неверный пароль",
+			cfg:  RedisConfig{Addr: server.addr(), Password: "wrong", Codec: stringCodec{}},
+		},
+		{
+			name: "Redis недоступен",
+			cfg:  RedisConfig{Addr: "127.0.0.1:1", Codec: stringCodec{}, Timeout: 100 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps, err := NewRedis(tt.cfg)
+			if err == nil {
+				ps.Close()
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+// TestRedis_RealServer проверяет обмен сообщениями через настоящий Redis.
+// Адрес задается переменной TEST_REDIS_ADDR.
+func TestRedis_RealServer(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	// Отдельный канал, чтобы не пересекаться с запущенными экземплярами приложения
+	channel := fmt.Sprintf("commentsystem:test:%d", time.Now().UnixNano())
+	cfg := RedisConfig{Addr: addr, Password: os.Getenv("TEST_REDIS_PASSWORD"), Channel: channel}
+	podA := newTestRedis(t, cfg)
+	podB := newTestRedis(t, cfg)
+	topic := "post:1:comments"
+
+	subscriber := podB.Subscribe(topic, "subscriber-1")
+	podA.Publish(topic, "hello")
+
+	msg := receiveMessage(t, subscriber)
+	if msg.Data != "hello" || msg.Seq == 0 {
+		t.Errorf("Expected message with sequence number, got %+v", msg)
+	}
+}
@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Пауза перед повторным подключением распределенного PubSub растет от минимальной до максимальной
+const (
+	reconnectMinBackoff = 100 * time.Millisecond
+	reconnectMaxBackoff = 5 * time.Second
+)
+
+// Проверяем, что реализации удовлетворяют интерфейсу
+var (
+	_ PubSub = (*Memory)(nil)
+	_ PubSub = (*Redis)(nil)
+	_ PubSub = (*Postgres)(nil)
+)
+
+// Codec сериализует данные сообщений для передачи между экземплярами приложения.
+// Unmarshal должен восстанавливать значения тех же типов, что были переданы в Marshal:
+// подписчики различают сообщения по типу данных.
+type Codec interface {
+	Marshal(data interface{}) ([]byte, error)
+	Unmarshal(raw []byte) (interface{}, error)
+}
+
+// envelope - сообщение, которым обмениваются экземпляры распределенного PubSub
+type envelope struct {
+	Topic string          `json:"topic"`
+	Seq   uint64          `json:"seq,omitempty"`
+	Close bool            `json:"close,omitempty"` // Топик закрыт издателем
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// deliverEnvelope доставляет сообщение, полученное от другого экземпляра, локальным подписчикам
+func (ps *Memory) deliverEnvelope(codec Codec, raw []byte) error {
+	var message envelope
+	if err := json.Unmarshal(raw, &message); err != nil {
+		return fmt.Errorf("failed to decode remote message: %w", err)
+	}
+
+	if message.Close {
+		ps.CloseTopic(message.Topic)
+		return nil
+	}
+
+	data, err := codec.Unmarshal(message.Data)
+	if err != nil {
+		return fmt.Errorf("failed to decode message for topic %s: %w", message.Topic, err)
+	}
+
+	ps.publishMessage(Message{Topic: message.Topic, Seq: message.Seq, Data: data})
+	return nil
+}
+
+// publishLocal доставляет подписчикам этого экземпляра сообщение, которое не удалось
+// передать другим экземплярам. Общий счетчик такому сообщению номер не выдавал, а локальный
+// номер совпал бы с номером, который счетчик позже выдаст другому сообщению, и курсор
+// повторной подписки пропустил бы или повторил события. Поэтому сообщение не попадает
+// в историю и несет номер последнего полученного сообщения: курсор после него указывает
+// на уже известную позицию в общей последовательности.
+func (ps *Memory) publishLocal(topic string, data interface{}) {
+	ps.publishMu.Lock()
+	defer ps.publishMu.Unlock()
+
+	ps.mu.Lock()
+	blocked := ps.send(Message{Topic: topic, Seq: ps.seq, Data: data})
+	ps.mu.Unlock()
+
+	ps.waitBlocked(blocked)
+}
+
+// markGap отмечает, что сообщения с номерами <= seq могли не дойти до экземпляра
+// (например, были опубликованы, пока подписка на другие экземпляры переподключалась).
+// Повторная подписка с курсором меньше seq в любом топике считается неполной:
+// клиент перечитает состояние из хранилища вместо того, чтобы молча пропустить события.
+func (ps *Memory) markGap(seq uint64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if seq > ps.seq {
+		ps.seq = seq
+	}
+	for _, history := range ps.history {
+		if seq > history.floor {
+			history.floor = seq
+		}
+	}
+	// Топики без истории сравнивают курсор с prunedSeq
+	if seq > ps.prunedSeq {
+		ps.prunedSeq = seq
+	}
+}
@@ -0,0 +1,185 @@
+package pubsub
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// respError - ошибка, которую вернул сервер Redis (ответ "-ERR ...")
+type respError string
+
+// Error возвращает текст ошибки сервера
+func (e respError) Error() string {
+	return "redis: " + string(e)
+}
+
+// errUnexpectedReply возвращается, если ответ сервера не соответствует команде
+var errUnexpectedReply = errors.New("redis: unexpected reply")
+
+// respConn - соединение с Redis по протоколу RESP2.
+// Поддерживает только то, что нужно PubSub: команды со строковыми аргументами
+// и чтение ответов и сообщений подписки. Не потокобезопасно.
+type respConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+	timeout time.Duration // Таймаут ввода-вывода одной команды; 0 - без таймаута
+}
+
+// dialRESP подключается к Redis, аутентифицируется и выбирает базу данных
+func dialRESP(addr, password string, db int, timeout time.Duration) (*respConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	c := &respConn{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		writer:  bufio.NewWriter(conn),
+		timeout: timeout,
+	}
+
+	if password != "" {
+		if _, err := c.do("AUTH", password); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+	if db != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(db)); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to select database %d: %w", db, err)
+		}
+	}
+
+	return c, nil
+}
+
+// do отправляет команду и возвращает ответ сервера.
+// Ошибка сервера возвращается как respError.
+func (c *respConn) do(args ...string) (interface{}, error) {
+	if err := c.send(args...); err != nil {
+		return nil, err
+	}
+
+	reply, err := c.receive()
+	if err != nil {
+		return nil, err
+	}
+	if replyErr, ok := reply.(respError); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+// send записывает команду в виде массива bulk строк
+func (c *respConn) send(args ...string) error {
+	if c.timeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(c.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return c.writer.Flush()
+}
+
+// receive читает следующий ответ сервера с учетом таймаута команды
+func (c *respConn) receive() (interface{}, error) {
+	if c.timeout > 0 {
+		if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+			return nil, err
+		}
+	}
+	return readRESP(c.reader)
+}
+
+// receiveBlocking читает следующее сообщение без таймаута: подписка может молчать сколько угодно
+func (c *respConn) receiveBlocking() (interface{}, error) {
+	if err := c.conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return readRESP(c.reader)
+}
+
+// Close закрывает соединение
+func (c *respConn) Close() error {
+	return c.conn.Close()
+}
+
+// readRESP читает одно значение RESP2:
+// простая строка - string, ошибка - respError, целое - int64,
+// bulk строка - []byte (nil для null), массив - []interface{} (nil для null).
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return respError(body), nil
+	case ':':
+		n, err := strconv.ParseInt(body, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed integer %q", body)
+		}
+		return n, nil
+	case '$':
+		size, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", body)
+		}
+		if size < 0 {
+			return []byte(nil), nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	case '*':
+		count, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", body)
+		}
+		if count < 0 {
+			return []interface{}(nil), nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = readRESP(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
+
+// replyString возвращает строковое значение простой или bulk строки
+func replyString(reply interface{}) (string, bool) {
+	switch v := reply.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), v != nil
+	}
+	return "", false
+}